@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"seedance-client/config"
@@ -24,6 +25,18 @@ import (
 type App struct {
 	ctx         context.Context
 	volcService *services.VolcEngineService
+
+	exportMu   sync.Mutex
+	exportJobs map[string]context.CancelFunc
+
+	decomposeMu   sync.Mutex
+	decomposeJobs map[string]context.CancelFunc
+
+	roughCutMu   sync.Mutex
+	roughCutJobs map[string]context.CancelFunc
+
+	promptRewriteMu sync.Mutex
+	promptRewrites  map[uint]promptRewrite // by take ID, see compileTakePrompt
 }
 
 func (a *App) requireAPIKey() error {
@@ -43,6 +56,11 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.volcService = services.NewVolcEngineService()
 
+	// Keys saved by older versions are plaintext; encrypt them in place
+	if err := migratePlaintextSecrets(); err != nil {
+		log.Printf("Secret migration failed: %v", err)
+	}
+
 	// Load saved API key from database
 	apiKey := a.GetSavedAPIKey()
 	if apiKey != "" {
@@ -60,7 +78,12 @@ func (a *App) GetSavedAPIKey() string {
 	if err := models.DB.Where("`key` = ?", "ark_api_key").First(&setting).Error; err != nil {
 		return os.Getenv("ARK_API_KEY")
 	}
-	return setting.Value
+	key, err := services.DecryptSecret(setting.Value)
+	if err != nil {
+		log.Printf("Saved API key: %v", err)
+		return ""
+	}
+	return key
 }
 
 // UpdateAPIKey saves the API key and updates the service
@@ -73,7 +96,11 @@ func (a *App) UpdateAPIKey(apiKey string) error {
 		return fmt.Errorf("[E_SERVICE_NOT_READY] 服务尚未初始化，请重启应用后再试")
 	}
 
-	if err := models.DB.Where("`key` = ?", "ark_api_key").Assign(models.Setting{Value: key}).FirstOrCreate(&models.Setting{Key: "ark_api_key"}).Error; err != nil {
+	encrypted, err := services.EncryptSecret(key)
+	if err != nil {
+		return fmt.Errorf("[E_SECRET] 加密 API Key 失败：%w", err)
+	}
+	if err := models.DB.Where("`key` = ?", "ark_api_key").Assign(models.Setting{Value: encrypted}).FirstOrCreate(&models.Setting{Key: "ark_api_key"}).Error; err != nil {
 		return fmt.Errorf("[E_DB_WRITE] 保存 API Key 失败：%w", err)
 	}
 	a.volcService.SetAPIKey(key)
@@ -191,9 +218,20 @@ func (a *App) DeleteProject(id uint) error {
 		}
 		return fmt.Errorf("查询项目失败：%w", err)
 	}
-	if err := models.DB.Delete(&p).Error; err != nil {
-		return fmt.Errorf("删除项目失败：%w", err)
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		shots := tx.Model(&models.Storyboard{}).Select("id").Where("project_id = ?", id)
+		if err := tx.Where("storyboard_id IN (?)", shots).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+			return fmt.Errorf("删除分镜资产引用失败：%w", err)
+		}
+		if err := tx.Delete(&p).Error; err != nil {
+			return fmt.Errorf("删除项目失败：%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	services.InvalidateRoughCut(id)
 	return nil
 }
 
@@ -225,7 +263,16 @@ type TakeResponse struct {
 	IsGood             bool      `json:"is_good"`
 	ChainFromPrev      bool      `json:"chain_from_prev"`
 	GenerationMode     string    `json:"generation_mode"`
+	SubmittedPrompt    string    `json:"submitted_prompt"`
+	RequestSnapshot    string    `json:"request_snapshot"`
 	CreatedAt          time.Time `json:"created_at"`
+	VideoWidth         int       `json:"video_width"`
+	VideoHeight        int       `json:"video_height"`
+	VideoFPS           float64   `json:"video_fps"`
+	VideoCodec         string    `json:"video_codec"`
+	VideoFrames        int       `json:"video_frames"`
+	MediaDuration      float64   `json:"media_duration"`
+	HasAudio           bool      `json:"has_audio"`
 }
 
 func takeToResponse(take *models.Take) TakeResponse {
@@ -252,7 +299,16 @@ func takeToResponse(take *models.Take) TakeResponse {
 		IsGood:             take.IsGood,
 		ChainFromPrev:      take.ChainFromPrev,
 		GenerationMode:     take.GenerationMode,
+		SubmittedPrompt:    take.SubmittedPrompt,
+		RequestSnapshot:    take.RequestSnapshot,
 		CreatedAt:          take.CreatedAt,
+		VideoWidth:         take.VideoWidth,
+		VideoHeight:        take.VideoHeight,
+		VideoFPS:           take.VideoFPS,
+		VideoCodec:         take.VideoCodec,
+		VideoFrames:        take.VideoFrames,
+		MediaDuration:      take.MediaDuration,
+		HasAudio:           take.HasAudio,
 	}
 }
 
@@ -480,8 +536,17 @@ func (a *App) DeleteStoryboard(id uint) (uint, error) {
 		return 0, fmt.Errorf("查询分镜失败：%w", err)
 	}
 	projectID := sb.ProjectID
-	if err := models.DB.Delete(&sb).Error; err != nil {
-		return projectID, fmt.Errorf("删除分镜失败：%w", err)
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("storyboard_id = ?", id).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+			return fmt.Errorf("删除分镜资产引用失败：%w", err)
+		}
+		if err := tx.Delete(&sb).Error; err != nil {
+			return fmt.Errorf("删除分镜失败：%w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return projectID, err
 	}
 	return projectID, nil
 }
@@ -604,7 +669,8 @@ func (a *App) UpdateStoryboard(params UpdateStoryboardParams) error {
 // Takes
 // ============================================================
 
-// GenerateTakeVideo starts video generation for a take
+// GenerateTakeVideo starts video generation for a take. The result carries the notes of the
+// prompt compilation as "prompt_notes".
 func (a *App) GenerateTakeVideo(id uint) (map[string]interface{}, error) {
 	if err := a.requireAPIKey(); err != nil {
 		return nil, err
@@ -639,48 +705,105 @@ func (a *App) GenerateTakeVideo(id uint) (map[string]interface{}, error) {
 	// Persist frame fallbacks on this take so users can inspect and reuse them.
 	models.DB.Save(&take)
 
-	var firstFrameURL, lastFrameURL string
+	if strings.TrimSpace(take.ModelID) == "" {
+		return nil, fmt.Errorf("缺少模型 ID：请先在右侧“生成参数”里选择目标模型")
+	}
+	compiled, err := a.compileTakePrompt(take, storyboard)
+	if err != nil {
+		return nil, err
+	}
+	finalPrompt := compiled.Prompt
+	if strings.TrimSpace(finalPrompt) == "" {
+		return nil, fmt.Errorf("提示词为空：请先填写视频提示词")
+	}
+
+	req := services.NewVideoTaskRequest(
+		take.ModelID, finalPrompt,
+		take.Ratio, take.Duration, take.GenerateAudio,
+		take.ServiceTier, take.ExpiresAfter,
+	)
 	if take.FirstFramePath != "" {
-		b64, err := imageToBase64(take.FirstFramePath)
-		if err != nil {
+		if err := req.AddFrame("first_frame", take.FirstFramePath); err != nil {
 			return nil, fmt.Errorf("处理首帧失败：%w", err)
 		}
-		firstFrameURL = b64
 	}
 	if take.LastFramePath != "" {
-		b64, err := imageToBase64(take.LastFramePath)
-		if err != nil {
+		if err := req.AddFrame("last_frame", take.LastFramePath); err != nil {
 			return nil, fmt.Errorf("处理尾帧失败：%w", err)
 		}
-		lastFrameURL = b64
 	}
+	result, err := a.submitVideoTask(&take, req)
+	if err != nil {
+		return nil, err
+	}
+	result["prompt_notes"] = compiled.Notes
+	return result, nil
+}
 
-	finalPrompt := take.Prompt
-	if storyboard.ID > 0 {
-		finalPrompt = composeTakePromptWithAssetRefs(storyboard, take.Prompt)
+// ReproduceTake resubmits the request snapshot of a take as a new take: same prompt, frames and
+// parameters, regardless of later changes to the shot, catalog or compile settings. It fails when
+// a frame file has changed since the original submission.
+func (a *App) ReproduceTake(id uint) (map[string]interface{}, error) {
+	if err := a.requireAPIKey(); err != nil {
+		return nil, err
+	}
+	var source models.Take
+	if err := models.DB.First(&source, id).Error; err != nil {
+		return nil, fmt.Errorf("take not found")
+	}
+	if source.RequestSnapshot == "" {
+		return nil, fmt.Errorf("[E_SNAPSHOT_MISSING] 该 Take 没有请求快照，请先提交生成")
 	}
+	req, err := services.ParseVideoTaskRequest(source.RequestSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	req.ReproducedFromTakeID = source.ID
 
-	if strings.TrimSpace(take.ModelID) == "" {
-		return nil, fmt.Errorf("缺少模型 ID：请先在右侧“生成参数”里选择目标模型")
+	take := models.Take{
+		StoryboardID:   source.StoryboardID,
+		Prompt:         source.Prompt,
+		FirstFramePath: req.FramePath("first_frame"),
+		LastFramePath:  req.FramePath("last_frame"),
+		ModelID:        req.ModelID,
+		Ratio:          req.Ratio,
+		Duration:       req.Duration,
+		GenerateAudio:  req.GenerateAudio,
+		ServiceTier:    "standard",
+		ExpiresAfter:   req.ExecutionExpiresAfter,
+		ChainFromPrev:  source.ChainFromPrev,
+		GenerationMode: source.GenerationMode,
+		Status:         "Draft",
+		CreatedAt:      time.Now(),
 	}
-	if strings.TrimSpace(finalPrompt) == "" {
-		return nil, fmt.Errorf("提示词为空：请先填写视频提示词")
+	if req.ServiceTier == "flex" {
+		take.ServiceTier = "flex"
+	}
+	if err := models.DB.Create(&take).Error; err != nil {
+		return nil, fmt.Errorf("保存 Take 失败：%w", err)
+	}
+	result, err := a.submitVideoTask(&take, req)
+	if err != nil {
+		return nil, err
 	}
+	result["take_id"] = take.ID
+	return result, nil
+}
 
-	taskID, err := a.volcService.CreateVideoTask(
-		take.ModelID, finalPrompt, firstFrameURL, lastFrameURL,
-		take.Ratio, take.Duration, take.GenerateAudio,
-		take.ServiceTier, take.ExpiresAfter,
-	)
+// submitVideoTask submits req for take and records the prompt, snapshot and task on the take.
+func (a *App) submitVideoTask(take *models.Take, req *services.VideoTaskRequest) (map[string]interface{}, error) {
+	taskID, err := a.volcService.CreateVideoTask(req)
+	take.SubmittedPrompt = req.Prompt
+	take.RequestSnapshot = req.JSON()
 	if err != nil {
 		take.Status = "Failed"
-		models.DB.Save(&take)
+		models.DB.Save(take)
 		return nil, fmt.Errorf("提交生成任务失败：%v（请检查 API Key/网络/模型是否可用）", err)
 	}
 
 	take.TaskID = taskID
 	take.Status = "Running"
-	models.DB.Save(&take)
+	models.DB.Save(take)
 
 	return map[string]interface{}{
 		"status":  "submitted",
@@ -848,9 +971,16 @@ func (a *App) DeleteTake(id uint) (*DeleteTakeResult, error) {
 
 	storyboardDeleted := false
 	if count == 0 {
-		if err := models.DB.Delete(&models.Storyboard{}, storyboardID).Error; err == nil {
-			storyboardDeleted = true
+		err := models.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("storyboard_id = ?", storyboardID).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.Storyboard{}, storyboardID).Error
+		})
+		if err != nil {
+			log.Printf("Failed to delete empty storyboard %d: %v", storyboardID, err)
 		}
+		storyboardDeleted = err == nil
 	}
 
 	return &DeleteTakeResult{
@@ -864,19 +994,120 @@ func (a *App) DeleteTake(id uint) (*DeleteTakeResult, error) {
 // Export
 // ============================================================
 
-// ExportProject exports project videos as a ZIP with FCPXML via save dialog
-func (a *App) ExportProject(id uint) error {
+// Export job events. Every payload carries the job_id returned by the export call.
+const (
+	exportProgressEvent = "export:progress"
+	exportDoneEvent     = "export:done"
+)
+
+// Export job final states reported in ExportDoneEvent.Status
+const (
+	exportStatusSucceeded = "succeeded"
+	exportStatusFailed    = "failed"
+	exportStatusCancelled = "cancelled"
+)
+
+// ExportProgressEvent is the payload of "export:progress"
+type ExportProgressEvent struct {
+	JobID string `json:"job_id"`
+	services.ExportProgress
+}
+
+// ExportDoneEvent is the payload of "export:done"
+type ExportDoneEvent struct {
+	JobID  string                       `json:"job_id"`
+	Status string                       `json:"status"`
+	Error  string                       `json:"error,omitempty"`
+	Path   string                       `json:"path"`
+	Result *services.FolderExportResult `json:"result,omitempty"` // folder exports only
+}
+
+// loadExportData loads a project and selects what to export, with user-facing errors.
+func loadExportData(id uint, opts *services.ExportOptions) (models.Project, []services.ExportData, error) {
 	var project models.Project
+	if err := opts.Normalize(); err != nil {
+		return project, nil, fmt.Errorf("导出选项无效：%w", err)
+	}
 	if err := models.DB.Preload("Storyboards.Takes").First(&project, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("项目不存在")
+			return project, nil, fmt.Errorf("项目不存在")
 		}
-		return fmt.Errorf("加载项目失败：%w", err)
+		return project, nil, fmt.Errorf("加载项目失败：%w", err)
 	}
 
-	exports := services.PrepareExportData(project.Storyboards)
+	exports, err := services.PrepareExportData(project.Storyboards, *opts)
+	if err != nil {
+		return project, nil, fmt.Errorf("导出选项无效：%w", err)
+	}
 	if len(exports) == 0 {
-		return fmt.Errorf("没有可导出的已成功视频（请先生成至少一个成功的 Take）")
+		if opts.TakeSelection == services.TakeSelectionGoodOnly {
+			return project, nil, fmt.Errorf("所选范围内没有 Good Take 可导出")
+		}
+		return project, nil, fmt.Errorf("没有可导出的已成功视频（请先生成至少一个成功的 Take）")
+	}
+	return project, exports, nil
+}
+
+// startExportJob runs an export in the background and returns its job ID.
+// run receives a cancellable context and a progress callback that emits "export:progress";
+// "export:done" is emitted once run returns.
+func (a *App) startExportJob(path string, run func(ctx context.Context, progress services.ExportProgressFunc) (*services.FolderExportResult, error)) string {
+	jobID := uuid.New().String()
+	ctx, cancel := context.WithCancel(a.ctx)
+
+	a.exportMu.Lock()
+	if a.exportJobs == nil {
+		a.exportJobs = map[string]context.CancelFunc{}
+	}
+	a.exportJobs[jobID] = cancel
+	a.exportMu.Unlock()
+
+	go func() {
+		defer func() {
+			a.exportMu.Lock()
+			delete(a.exportJobs, jobID)
+			a.exportMu.Unlock()
+			cancel()
+		}()
+
+		result, err := run(ctx, func(p services.ExportProgress) {
+			wailsRuntime.EventsEmit(a.ctx, exportProgressEvent, ExportProgressEvent{JobID: jobID, ExportProgress: p})
+		})
+
+		done := ExportDoneEvent{JobID: jobID, Status: exportStatusSucceeded, Path: path, Result: result}
+		switch {
+		case errors.Is(err, context.Canceled):
+			done.Status = exportStatusCancelled
+		case err != nil:
+			done.Status = exportStatusFailed
+			done.Error = fmt.Sprintf("导出失败：%v", err)
+		}
+		wailsRuntime.EventsEmit(a.ctx, exportDoneEvent, done)
+	}()
+
+	return jobID
+}
+
+// CancelExport stops a running export job. Partial output is removed by the job itself.
+func (a *App) CancelExport(jobID string) error {
+	a.exportMu.Lock()
+	cancel, ok := a.exportJobs[jobID]
+	a.exportMu.Unlock()
+	if !ok {
+		return fmt.Errorf("导出任务不存在或已结束")
+	}
+	cancel()
+	return nil
+}
+
+// ExportProject exports project videos as a ZIP with FCPXML via save dialog.
+// opts selects the shot range, take strategy, extras and filename template.
+// The export runs in the background; the returned job ID identifies its
+// "export:progress" and "export:done" events. An empty ID means the dialog was cancelled.
+func (a *App) ExportProject(id uint, opts services.ExportOptions) (string, error) {
+	project, exports, err := loadExportData(id, &opts)
+	if err != nil {
+		return "", err
 	}
 
 	filename := services.GetExportFilename(project.Name)
@@ -888,24 +1119,135 @@ func (a *App) ExportProject(id uint) error {
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("打开保存对话框失败：%w", err)
+		return "", fmt.Errorf("打开保存对话框失败：%w", err)
 	}
 	if savePath == "" {
-		return nil // User cancelled
+		return "", nil // User cancelled
+	}
+
+	// Write next to the target and rename on success, so a failed or cancelled
+	// export never leaves a truncated ZIP (or clobbers an existing one).
+	partialPath := savePath + ".partial"
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return "", fmt.Errorf("创建导出文件失败：%w", err)
 	}
 
-	file, err := os.Create(savePath)
+	return a.startExportJob(savePath, func(ctx context.Context, progress services.ExportProgressFunc) (*services.FolderExportResult, error) {
+		err := services.CreateExportZIP(ctx, file, project.Name, exports, opts, progress)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(partialPath, savePath)
+		}
+		if err != nil {
+			os.Remove(partialPath)
+			return nil, err
+		}
+		return nil, nil
+	}), nil
+}
+
+// ExportProjectToFolder exports project videos and FCPXML into a chosen directory without zipping.
+// Re-exporting into the same directory only rewrites media that changed.
+// Like ExportProject it returns a background job ID, or "" if the dialog was cancelled;
+// the FolderExportResult arrives with the "export:done" event.
+func (a *App) ExportProjectToFolder(id uint, opts services.ExportOptions) (string, error) {
+	project, exports, err := loadExportData(id, &opts)
 	if err != nil {
-		return fmt.Errorf("创建导出文件失败：%w", err)
+		return "", err
 	}
-	defer file.Close()
 
-	if err := services.CreateExportZIP(file, project.Name, exports); err != nil {
-		return fmt.Errorf("导出失败：%w", err)
+	dir, err := wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:                "选择导出目录",
+		CanCreateDirectories: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("打开目录选择框失败：%w", err)
+	}
+	if dir == "" {
+		return "", nil // User cancelled
 	}
+
+	return a.startExportJob(dir, func(ctx context.Context, progress services.ExportProgressFunc) (*services.FolderExportResult, error) {
+		return services.CreateExportFolder(ctx, dir, project.Name, exports, opts, progress)
+	}), nil
+}
+
+// ============================================================
+// Rough Cut
+// ============================================================
+
+// BuildRoughCutParams builds a rough cut; RequestID lets CancelRoughCut abort it.
+type BuildRoughCutParams struct {
+	ProjectID uint   `json:"project_id"`
+	RequestID string `json:"request_id"`
+}
+
+// BuildRoughCut joins the active take of every shot into one MP4 for previewing the sequence.
+// Clips are remuxed without re-encoding, so all takes must share codec and resolution.
+// The result is cached and only rebuilt after an active take changes.
+func (a *App) BuildRoughCut(params BuildRoughCutParams) (*services.RoughCutResult, error) {
+	var project models.Project
+	if err := models.DB.Preload("Storyboards.Takes").First(&project, params.ProjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("项目不存在")
+		}
+		return nil, fmt.Errorf("加载项目失败：%w", err)
+	}
+
+	ctx, done := a.beginRoughCut(params.RequestID)
+	defer done()
+	result, err := services.BuildRoughCut(ctx, project)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("粗剪已取消")
+		}
+		if errors.Is(err, services.ErrMP4Incompatible) {
+			return nil, fmt.Errorf("各镜头视频的编码或分辨率不一致，无法直接拼接粗剪：%w", err)
+		}
+		return nil, fmt.Errorf("生成粗剪失败：%w", err)
+	}
+	return result, nil
+}
+
+// CancelRoughCut aborts a running BuildRoughCut started with the given request_id.
+func (a *App) CancelRoughCut(requestID string) error {
+	a.roughCutMu.Lock()
+	cancel, ok := a.roughCutJobs[requestID]
+	a.roughCutMu.Unlock()
+	if !ok {
+		return fmt.Errorf("粗剪任务不存在或已结束")
+	}
+	cancel()
 	return nil
 }
 
+// beginRoughCut registers a cancellable rough-cut build; the returned func must be called when it ends.
+func (a *App) beginRoughCut(requestID string) (context.Context, func()) {
+	base := a.ctx
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithCancel(base)
+	if requestID == "" {
+		return ctx, cancel
+	}
+	a.roughCutMu.Lock()
+	if a.roughCutJobs == nil {
+		a.roughCutJobs = map[string]context.CancelFunc{}
+	}
+	a.roughCutJobs[requestID] = cancel
+	a.roughCutMu.Unlock()
+	return ctx, func() {
+		a.roughCutMu.Lock()
+		delete(a.roughCutJobs, requestID)
+		a.roughCutMu.Unlock()
+		cancel()
+	}
+}
+
 // ============================================================
 // File Operations
 // ============================================================
@@ -994,30 +1336,6 @@ func (a *App) CopyToUploads(srcPath string) (string, error) {
 // Helper Functions
 // ============================================================
 
-func imageToBase64(path string) (string, error) {
-	// Resolve relative path to absolute in data directory
-	absPath := config.ToAbsolutePath(path)
-	file, err := os.Open(absPath)
-	if err != nil {
-		return "", fmt.Errorf("打开图片失败：%w", err)
-	}
-	defer file.Close()
-
-	bytes, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("读取图片失败：%w", err)
-	}
-
-	mimeType := "image/png"
-	lower := strings.ToLower(path)
-	if strings.HasSuffix(lower, ".jpg") || strings.HasSuffix(lower, ".jpeg") {
-		mimeType = "image/jpeg"
-	}
-
-	b64 := base64.StdEncoding.EncodeToString(bytes)
-	return fmt.Sprintf("data:%s;base64,%s", mimeType, b64), nil
-}
-
 func ensureDir(dir string) {
 	absDir := config.ToAbsolutePath(dir)
 	if _, err := os.Stat(absDir); os.IsNotExist(err) {
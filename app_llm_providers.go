@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
+)
+
+const (
+	defaultArkBaseURL       = "https://ark.cn-beijing.volces.com/api/v3"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	defaultOllamaBaseURL    = "http://localhost:11434"
+
+	anthropicAPIVersion = "2023-06-01"
+	anthropicMaxTokens  = 16384
+
+	structuredOutputName = "storyboard_decompose"
+)
+
+// llmRequest is one structured-output completion: the answer must be JSON matching Schema.
+type llmRequest struct {
+	ModelID      string
+	SystemPrompt string
+	UserPrompt   string
+	Schema       map[string]interface{}
+}
+
+// llmProvider runs a streaming completion and returns the full JSON text. Each implementation
+// uses the structured-output mechanism native to its API. onText, when set, receives the JSON text
+// as it streams; cancelling ctx aborts the request.
+type llmProvider interface {
+	complete(ctx context.Context, req llmRequest, onText llmTextFunc) (string, error)
+}
+
+// newLLMProvider validates the connection settings of a provider type and returns its client.
+func (a *App) newLLMProvider(provider string, apiKey string, baseURL string) (llmProvider, error) {
+	key := strings.TrimSpace(apiKey)
+	url := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	switch provider {
+	case "ark_default":
+		if !a.HasAPIKey() {
+			return nil, fmt.Errorf("[E_APIKEY_MISSING] 未配置 API Key：当前选择了“全局 Ark（使用设置里的 API Key）”，请先在右上角【设置】填写 API Key")
+		}
+		return arkProvider{client: a.volcService.Client}, nil
+	case "ark_custom":
+		if key == "" {
+			return nil, fmt.Errorf("[E_APIKEY_EMPTY] 自定义 Ark 模式需要填写 API Key")
+		}
+		if url == "" {
+			url = defaultArkBaseURL
+		}
+		return arkProvider{client: arkruntime.NewClientWithApiKey(key, arkruntime.WithBaseUrl(url))}, nil
+	case "openai_compatible":
+		if key == "" {
+			return nil, fmt.Errorf("[E_APIKEY_EMPTY] OpenAI Compatible 模式需要填写 API Key")
+		}
+		if url == "" {
+			return nil, fmt.Errorf("[E_BASEURL_EMPTY] OpenAI Compatible 模式需要填写 Base URL")
+		}
+		return openAICompatibleProvider{apiKey: key, baseURL: url}, nil
+	case "anthropic":
+		if key == "" {
+			return nil, fmt.Errorf("[E_APIKEY_EMPTY] Anthropic 模式需要填写 API Key")
+		}
+		if url == "" {
+			url = defaultAnthropicBaseURL
+		}
+		return anthropicProvider{apiKey: key, baseURL: url}, nil
+	case "ollama":
+		// Local models need no key; one is still sent when set, e.g. for an authenticating proxy
+		if url == "" {
+			url = defaultOllamaBaseURL
+		}
+		return ollamaProvider{apiKey: key, baseURL: url}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// newStreamingHTTPClient has no overall timeout, so a long decomposition keeps streaming.
+// Only the wait for response headers is bounded.
+func newStreamingHTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 120 * time.Second,
+	}}
+}
+
+// httpStatusErrorCode maps a failed HTTP status to the error code the frontend understands.
+func httpStatusErrorCode(statusCode int) string {
+	switch statusCode {
+	case 401, 403:
+		return "E_HTTP_401"
+	case 429:
+		return "E_HTTP_429"
+	}
+	return "E_PROVIDER_HTTP"
+}
+
+// ============================================================
+// Ark / OpenAI Compatible (response_format json_schema)
+// ============================================================
+
+type arkProvider struct {
+	client *arkruntime.Client
+}
+
+func (p arkProvider) complete(ctx context.Context, req llmRequest, onText llmTextFunc) (string, error) {
+	return requestWithArkClient(ctx, p.client, req.ModelID, req.SystemPrompt, req.UserPrompt, req.Schema, onText)
+}
+
+type openAICompatibleProvider struct {
+	apiKey  string
+	baseURL string
+}
+
+func (p openAICompatibleProvider) complete(ctx context.Context, req llmRequest, onText llmTextFunc) (string, error) {
+	return requestWithOpenAICompatible(ctx, p.apiKey, p.baseURL, req.ModelID, req.SystemPrompt, req.UserPrompt, req.Schema, onText)
+}
+
+// ============================================================
+// Anthropic Messages API (forced tool use)
+// ============================================================
+
+// anthropicProvider gets structured output by offering a single tool whose input_schema is the
+// output schema and forcing the model to call it; the tool input is the JSON answer.
+type anthropicProvider struct {
+	apiKey  string
+	baseURL string
+}
+
+func (p anthropicProvider) endpoint() string {
+	if strings.HasSuffix(p.baseURL, "/v1") {
+		return p.baseURL + "/messages"
+	}
+	return p.baseURL + "/v1/messages"
+}
+
+func (p anthropicProvider) complete(ctx context.Context, req llmRequest, onText llmTextFunc) (string, error) {
+	payload := map[string]interface{}{
+		"model":       req.ModelID,
+		"max_tokens":  anthropicMaxTokens,
+		"system":      req.SystemPrompt,
+		"temperature": 0.2,
+		"stream":      true,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": req.UserPrompt},
+		},
+		"tools": []map[string]interface{}{
+			{
+				"name":         structuredOutputName,
+				"description":  "Submit the storyboard decomposition JSON",
+				"input_schema": req.Schema,
+			},
+		},
+		"tool_choice": map[string]interface{}{"type": "tool", "name": structuredOutputName},
+	}
+	requestBody, _ := json.Marshal(payload)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint(), bytes.NewReader(requestBody))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := newStreamingHTTPClient().Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", llmCancelled(ctx)
+		}
+		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 请求失败（Anthropic, model=%s）：%w", req.ModelID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", llmCancelled(ctx)
+			}
+			return "", fmt.Errorf("读取 LLM 响应失败：%w", err)
+		}
+		text, err := parseAnthropicResponse(resp.StatusCode, body, req.ModelID)
+		if err == nil && onText != nil {
+			onText(text)
+		}
+		return text, err
+	}
+
+	// The tool input arrives as input_json_delta fragments; plain text is kept only as a
+	// fallback for models that answer without calling the tool.
+	var toolInput, text strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "error":
+			if event.Error != nil && event.Error.Message != "" {
+				return "", fmt.Errorf("[E_PROVIDER_HTTP] 提供方返回错误：%s", event.Error.Message)
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "input_json_delta":
+				toolInput.WriteString(event.Delta.PartialJSON)
+				if onText != nil && event.Delta.PartialJSON != "" {
+					onText(event.Delta.PartialJSON)
+				}
+			case "text_delta":
+				text.WriteString(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Delta.StopReason == "max_tokens" {
+				return "", anthropicTruncated(req.ModelID)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return "", llmCancelled(ctx)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 流式响应中断（Anthropic, model=%s）：%w", req.ModelID, err)
+	}
+	result := strings.TrimSpace(toolInput.String())
+	if result == "" {
+		result = strings.TrimSpace(text.String())
+		if result != "" && onText != nil {
+			onText(result)
+		}
+	}
+	if result == "" {
+		return "", fmt.Errorf("[E_LLM_EMPTY] LLM 返回为空（Anthropic, model=%s）", req.ModelID)
+	}
+	return result, nil
+}
+
+// parseAnthropicResponse extracts the tool input of a non-streamed message, or its text when the
+// model did not call the tool.
+func parseAnthropicResponse(statusCode int, body []byte, modelID string) (string, error) {
+	var parsed struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Error      *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		if statusCode >= 300 {
+			return "", fmt.Errorf("[%s] 提供方返回错误：HTTP %d", httpStatusErrorCode(statusCode), statusCode)
+		}
+		return "", fmt.Errorf("解析 Anthropic 响应失败：%w", err)
+	}
+	if statusCode >= 300 {
+		if parsed.Error != nil && strings.TrimSpace(parsed.Error.Message) != "" {
+			return "", fmt.Errorf("[%s] 提供方返回错误（HTTP %d）：%s", httpStatusErrorCode(statusCode), statusCode, parsed.Error.Message)
+		}
+		return "", fmt.Errorf("[%s] 提供方返回错误：HTTP %d", httpStatusErrorCode(statusCode), statusCode)
+	}
+	if parsed.StopReason == "max_tokens" {
+		return "", anthropicTruncated(modelID)
+	}
+
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		switch block.Type {
+		case "tool_use":
+			if len(block.Input) > 0 {
+				return strings.TrimSpace(string(block.Input)), nil
+			}
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+	if result := strings.TrimSpace(text.String()); result != "" {
+		return result, nil
+	}
+	return "", fmt.Errorf("[E_LLM_EMPTY] LLM 返回为空（Anthropic, model=%s）", modelID)
+}
+
+// anthropicTruncated reports an answer cut off at max_tokens; the partial JSON is not usable.
+func anthropicTruncated(modelID string) error {
+	return fmt.Errorf("[E_LLM_TRUNCATED] LLM 输出超过 %d tokens 被截断（Anthropic, model=%s），请缩短文案后重试", anthropicMaxTokens, modelID)
+}
+
+// ============================================================
+// Ollama (format=json)
+// ============================================================
+
+// ollamaProvider talks to a local Ollama server. format=json only guarantees syntactically valid
+// JSON, so the schema is spelled out in the system prompt and the output is validated as usual.
+type ollamaProvider struct {
+	apiKey  string
+	baseURL string
+}
+
+func (p ollamaProvider) complete(ctx context.Context, req llmRequest, onText llmTextFunc) (string, error) {
+	schemaJSON, _ := json.Marshal(req.Schema)
+	systemPrompt := req.SystemPrompt + "\n\n只输出一个 JSON 对象，必须符合以下 JSON Schema：\n" + string(schemaJSON)
+	payload := map[string]interface{}{
+		"model": req.ModelID,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": req.UserPrompt},
+		},
+		"format":  "json",
+		"stream":  true,
+		"options": map[string]interface{}{"temperature": 0.2},
+	}
+	requestBody, _ := json.Marshal(payload)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(requestBody))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := newStreamingHTTPClient().Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", llmCancelled(ctx)
+		}
+		return "", fmt.Errorf("[E_LLM_REQUEST] 无法连接 Ollama（%s）：%w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		var parsed struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &parsed) == nil && strings.TrimSpace(parsed.Error) != "" {
+			return "", fmt.Errorf("[%s] Ollama 返回错误（HTTP %d）：%s", httpStatusErrorCode(resp.StatusCode), resp.StatusCode, parsed.Error)
+		}
+		return "", fmt.Errorf("[%s] Ollama 返回错误：HTTP %d", httpStatusErrorCode(resp.StatusCode), resp.StatusCode)
+	}
+
+	// Streamed responses are newline-delimited JSON objects; a non-streamed one is a single line
+	var out strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Done  bool   `json:"done"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("[E_PROVIDER_HTTP] Ollama 返回错误：%s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			out.WriteString(chunk.Message.Content)
+			if onText != nil {
+				onText(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return "", llmCancelled(ctx)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 流式响应中断（Ollama, model=%s）：%w", req.ModelID, err)
+	}
+	result := strings.TrimSpace(out.String())
+	if result == "" {
+		return "", fmt.Errorf("[E_LLM_EMPTY] LLM 返回为空（Ollama, model=%s）", req.ModelID)
+	}
+	return result, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testSchema = map[string]interface{}{"type": "object"}
+
+// sseBody renders Anthropic stream events as server-sent events.
+func sseBody(events ...string) string {
+	var b strings.Builder
+	for _, e := range events {
+		var typed struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(e), &typed)
+		fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", typed.Type, e)
+	}
+	return b.String()
+}
+
+// serve starts a server answering every request with status, content type and body, and
+// records the last request body.
+func serve(t *testing.T, status int, contentType string, body string, got *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got != nil {
+			raw, _ := io.ReadAll(r.Body)
+			json.Unmarshal(raw, got)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAnthropicStream(t *testing.T) {
+	cases := []struct {
+		name    string
+		events  []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tool input",
+			events: []string{
+				`{"type":"message_start","message":{"id":"msg_1"}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","name":"storyboard_decompose","input":{}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"shots\":"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"[]}"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+				`{"type":"message_stop"}`,
+			},
+			want: `{"shots":[]}`,
+		},
+		{
+			name: "text fallback",
+			events: []string{
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" {\"shots\""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":":[]} "}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			},
+			want: `{"shots":[]}`,
+		},
+		{
+			name: "error event",
+			events: []string{
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{"}}`,
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			},
+			wantErr: "[E_PROVIDER_HTTP] 提供方返回错误：Overloaded",
+		},
+		{
+			name: "max tokens",
+			events: []string{
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"shots\":[{"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"}}`,
+			},
+			wantErr: "[E_LLM_TRUNCATED]",
+		},
+		{
+			name:    "empty",
+			events:  []string{`{"type":"message_stop"}`},
+			wantErr: "[E_LLM_EMPTY]",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req map[string]interface{}
+			srv := serve(t, 200, "text/event-stream", sseBody(tc.events...), &req)
+			var streamed strings.Builder
+			got, err := anthropicProvider{apiKey: "k", baseURL: srv.URL}.complete(context.Background(),
+				llmRequest{ModelID: "claude-test", UserPrompt: "u", Schema: testSchema},
+				func(d string) { streamed.WriteString(d) })
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want || strings.TrimSpace(streamed.String()) != tc.want {
+				t.Errorf("got %q, streamed %q, want %q", got, streamed.String(), tc.want)
+			}
+			choice, _ := req["tool_choice"].(map[string]interface{})
+			if req["stream"] != true || choice["name"] != structuredOutputName {
+				t.Errorf("request = %v", req)
+			}
+		})
+	}
+}
+
+func TestAnthropicResponse(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tool input",
+			body: `{"content":[{"type":"text","text":"ok"},{"type":"tool_use","name":"storyboard_decompose","input":{"shots":[]}}],"stop_reason":"tool_use"}`,
+			want: `{"shots":[]}`,
+		},
+		{
+			name: "text fallback",
+			body: `{"content":[{"type":"text","text":"{\"shots\":[]}"}],"stop_reason":"end_turn"}`,
+			want: `{"shots":[]}`,
+		},
+		{
+			name:    "max tokens",
+			body:    `{"content":[{"type":"tool_use","input":{}}],"stop_reason":"max_tokens"}`,
+			wantErr: "[E_LLM_TRUNCATED]",
+		},
+		{
+			name:    "empty",
+			body:    `{"content":[]}`,
+			wantErr: "[E_LLM_EMPTY]",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := serve(t, 200, "application/json", tc.body, nil)
+			got, err := anthropicProvider{apiKey: "k", baseURL: srv.URL + "/v1"}.complete(context.Background(),
+				llmRequest{ModelID: "claude-test", Schema: testSchema}, nil)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("got %q, %v, want %q", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "ndjson",
+			body: `{"message":{"role":"assistant","content":"{\"shots\""},"done":false}` + "\n\n" +
+				`{"message":{"role":"assistant","content":":[]}"},"done":false}` + "\n" +
+				`{"message":{"role":"assistant","content":""},"done":true}` + "\n" +
+				`{"message":{"role":"assistant","content":"ignored"},"done":false}` + "\n",
+			want: `{"shots":[]}`,
+		},
+		{
+			name: "single response",
+			body: `{"message":{"role":"assistant","content":"{\"shots\":[]}"},"done":true}`,
+			want: `{"shots":[]}`,
+		},
+		{
+			name:    "error line",
+			body:    `{"message":{"content":"{"}}` + "\n" + `{"error":"model crashed"}` + "\n",
+			wantErr: "[E_PROVIDER_HTTP] Ollama 返回错误：model crashed",
+		},
+		{
+			name:    "empty",
+			body:    `{"message":{"content":"  "},"done":true}`,
+			wantErr: "[E_LLM_EMPTY]",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req map[string]interface{}
+			srv := serve(t, 200, "application/x-ndjson", tc.body, &req)
+			var streamed strings.Builder
+			got, err := ollamaProvider{baseURL: srv.URL}.complete(context.Background(),
+				llmRequest{ModelID: "qwen", SystemPrompt: "s", UserPrompt: "u", Schema: testSchema},
+				func(d string) { streamed.WriteString(d) })
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want || streamed.String() != tc.want {
+				t.Errorf("got %q, streamed %q, want %q", got, streamed.String(), tc.want)
+			}
+			if req["format"] != "json" || req["stream"] != true {
+				t.Errorf("request = %v", req)
+			}
+		})
+	}
+}
+
+func TestProviderHTTPStatus(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{401, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, "[E_HTTP_401] 提供方返回错误（HTTP 401）：invalid x-api-key"},
+		{403, `forbidden`, "[E_HTTP_401] 提供方返回错误：HTTP 403"},
+		{429, `{"error":{"message":"rate limited"}}`, "[E_HTTP_429] 提供方返回错误（HTTP 429）：rate limited"},
+		{529, `{"error":{"message":"Overloaded"}}`, "[E_PROVIDER_HTTP] 提供方返回错误（HTTP 529）：Overloaded"},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("anthropic %d", tc.status), func(t *testing.T) {
+			srv := serve(t, tc.status, "application/json", tc.body, nil)
+			_, err := anthropicProvider{apiKey: "k", baseURL: srv.URL}.complete(context.Background(), llmRequest{Schema: testSchema}, nil)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	ollama := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{401, `{"error":"unauthorized"}`, "[E_HTTP_401] Ollama 返回错误（HTTP 401）：unauthorized"},
+		{404, `{"error":"model \"qwen\" not found"}`, `[E_PROVIDER_HTTP] Ollama 返回错误（HTTP 404）：model "qwen" not found`},
+		{429, ``, "[E_HTTP_429] Ollama 返回错误：HTTP 429"},
+	}
+	for _, tc := range ollama {
+		t.Run(fmt.Sprintf("ollama %d", tc.status), func(t *testing.T) {
+			srv := serve(t, tc.status, "application/json", tc.body, nil)
+			_, err := ollamaProvider{baseURL: srv.URL}.complete(context.Background(), llmRequest{Schema: testSchema}, nil)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"seedance-client/config"
+	"seedance-client/models"
+	"seedance-client/services"
+)
+
+const (
+	promptCompileSettingKey = "prompt_compile"
+	promptRewriteTimeout    = 2 * time.Minute
+)
+
+// promptRewrite is the LLM rewrite of a take prompt, kept so a preview and the following
+// submission use the same text. Input identifies the compiled prompt and settings it was made from.
+type promptRewrite struct {
+	Input  string
+	Output string
+}
+
+// PromptCompileSettings configures how a take prompt is turned into the prompt submitted to the
+// video model.
+type PromptCompileSettings struct {
+	StripLocalPaths bool `json:"strip_local_paths"`
+	// EnforceMaxLength truncates prompts to the model's limit. It is off by default because the
+	// limits in config/models.json are conservative estimates, not limits published for the API.
+	EnforceMaxLength bool `json:"enforce_max_length"`
+	// MaxLengthOverrides replaces the built-in limit of a video model, keyed by model ID.
+	MaxLengthOverrides map[string]int `json:"max_length_overrides"`
+	// LLMRewrite rewrites the compiled prompt into Seedance's camera/motion phrasing.
+	LLMRewrite        bool   `json:"llm_rewrite"`
+	ProviderProfileID uint   `json:"provider_profile_id"` // 0 uses the global Ark key
+	LLMModelID        string `json:"llm_model_id"`
+}
+
+// PromptModelLimit is the prompt length limit of one video model.
+type PromptModelLimit struct {
+	ModelID   string `json:"model_id"`
+	Name      string `json:"name"`
+	Default   int    `json:"default"`
+	Effective int    `json:"effective"`
+}
+
+// TakePromptPreview is the result of compiling a take prompt.
+type TakePromptPreview struct {
+	Original  string   `json:"original"` // prompt with asset references, before compilation
+	Prompt    string   `json:"prompt"`   // prompt that is submitted
+	Notes     []string `json:"notes"`
+	MaxLength int      `json:"max_length"` // 0 when no limit applies
+	Rewritten bool     `json:"rewritten"`
+}
+
+// ============================================================
+// Prompt Compilation
+// ============================================================
+
+// GetPromptCompileSettings returns the prompt compilation settings.
+func (a *App) GetPromptCompileSettings() PromptCompileSettings {
+	return loadPromptCompileSettings()
+}
+
+// SavePromptCompileSettings stores the prompt compilation settings.
+func (a *App) SavePromptCompileSettings(settings PromptCompileSettings) error {
+	for modelID, limit := range settings.MaxLengthOverrides {
+		if limit <= 0 {
+			delete(settings.MaxLengthOverrides, modelID)
+		}
+	}
+	settings.LLMModelID = strings.TrimSpace(settings.LLMModelID)
+	if settings.ProviderProfileID != 0 {
+		var count int64
+		models.DB.Model(&models.LLMProvider{}).Where("id = ?", settings.ProviderProfileID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("提供方配置不存在")
+		}
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := models.DB.Where("`key` = ?", promptCompileSettingKey).Assign(models.Setting{Value: string(data)}).FirstOrCreate(&models.Setting{Key: promptCompileSettingKey}).Error; err != nil {
+		return fmt.Errorf("保存提示词编译设置失败：%w", err)
+	}
+	return nil
+}
+
+// GetPromptModelLimits lists the prompt length limit of every video model.
+func (a *App) GetPromptModelLimits() []PromptModelLimit {
+	settings := loadPromptCompileSettings()
+	var limits []PromptModelLimit
+	for _, m := range config.GetModels() {
+		limits = append(limits, PromptModelLimit{
+			ModelID:   m.ID,
+			Name:      m.Name,
+			Default:   config.GetMaxPromptLength(m.ID),
+			Effective: settings.maxLength(m.ID),
+		})
+	}
+	return limits
+}
+
+// PreviewTakePrompt compiles a take prompt as GenerateTakeVideo would, without submitting it.
+func (a *App) PreviewTakePrompt(takeID uint) (*TakePromptPreview, error) {
+	var take models.Take
+	if err := models.DB.First(&take, takeID).Error; err != nil {
+		return nil, fmt.Errorf("take not found")
+	}
+	var storyboard models.Storyboard
+	_ = models.DB.First(&storyboard, take.StoryboardID).Error
+	preview, err := a.compileTakePrompt(take, storyboard)
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// compileTakePrompt turns a take prompt and its asset references into the submitted prompt. A
+// failed LLM rewrite falls back to the deterministic result and is reported in the notes. The
+// rewrite is reused while the compiled prompt and settings are unchanged, so a preview shows what
+// GenerateTakeVideo submits.
+func (a *App) compileTakePrompt(take models.Take, sb models.Storyboard) (TakePromptPreview, error) {
+	settings := loadPromptCompileSettings()
+	parts := services.PromptParts{Base: strings.TrimSpace(take.Prompt)}
+	if sb.ID > 0 {
+		var err error
+		if parts, err = takePromptParts(sb, take.Prompt); err != nil {
+			return TakePromptPreview{}, err
+		}
+	}
+	limit := 0
+	if settings.EnforceMaxLength {
+		limit = settings.maxLength(take.ModelID)
+	}
+	prompt, notes := services.CompilePrompt(parts, services.PromptCompileOptions{
+		StripLocalPaths: settings.StripLocalPaths,
+		MaxRunes:        limit,
+	})
+	out := TakePromptPreview{Original: parts.String(), Prompt: prompt, Notes: notes, MaxLength: limit}
+	if !settings.LLMRewrite || strings.TrimSpace(prompt) == "" {
+		return out, nil
+	}
+
+	input := fmt.Sprintf("%d\x00%s\x00%d\x00%d\x00%s", settings.ProviderProfileID, settings.LLMModelID, take.Duration, limit, prompt)
+	a.promptRewriteMu.Lock()
+	cached, ok := a.promptRewrites[take.ID]
+	a.promptRewriteMu.Unlock()
+	rewritten := cached.Output
+	if !ok || cached.Input != input {
+		var err error
+		rewritten, err = a.rewriteVideoPrompt(settings, prompt, take.Duration, limit)
+		if err != nil {
+			log.Printf("Prompt rewrite failed for take %d: %v", take.ID, err)
+			out.Notes = append(out.Notes, "LLM 改写失败，已使用编译结果："+err.Error())
+			return out, nil
+		}
+		a.promptRewriteMu.Lock()
+		if a.promptRewrites == nil {
+			a.promptRewrites = map[uint]promptRewrite{}
+		}
+		a.promptRewrites[take.ID] = promptRewrite{Input: input, Output: rewritten}
+		a.promptRewriteMu.Unlock()
+	}
+	if limit > 0 && utf8.RuneCountInString(rewritten) > limit {
+		rewritten = services.TruncatePrompt(rewritten, limit)
+		out.Notes = append(out.Notes, fmt.Sprintf("LLM 改写结果超出长度上限 %d 字，已截断", limit))
+	}
+	out.Prompt = rewritten
+	out.Rewritten = true
+	out.Notes = append(out.Notes, "已由 LLM 改写为 Seedance 镜头语法")
+	return out, nil
+}
+
+// rewriteVideoPrompt asks the configured LLM to restate a compiled prompt in Seedance's preferred
+// subject/motion/camera phrasing.
+func (a *App) rewriteVideoPrompt(settings PromptCompileSettings, prompt string, duration int, limit int) (string, error) {
+	llm, err := resolveLLMSettings(settings.ProviderProfileID, llmSettings{ModelID: settings.LLMModelID})
+	if err != nil {
+		return "", err
+	}
+	base := a.ctx
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(base, promptRewriteTimeout)
+	defer cancel()
+
+	systemPrompt := "你是 Seedance 视频生成模型的提示词优化师。把给定的视频提示词改写成 Seedance 偏好的写法：" +
+		"按“主体 + 动作，场景 + 环境变化，镜头 + 运镜”组织；用简洁的短句按时间顺序描述动作；" +
+		"运镜使用明确的术语（推近、拉远、左摇/右摇、平移、跟随、环绕、升降、固定镜头）；" +
+		"保留参考信息中对画面有用的外观描述，删除与画面无关的说明；不要编造原文没有的情节；输出语言与原文一致。" +
+		"只输出 JSON，不要附加任何解释。"
+	var userPrompt strings.Builder
+	if duration > 0 {
+		fmt.Fprintf(&userPrompt, "视频时长：%d 秒\n", duration)
+	}
+	if limit > 0 {
+		fmt.Fprintf(&userPrompt, "长度上限：%d 字\n", limit)
+	}
+	fmt.Fprintf(&userPrompt, "\n原提示词：\n%s\n\n请输出 {\"prompt\": \"改写后的提示词\"}。", prompt)
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"prompt"},
+		"additionalProperties": false,
+	}
+	raw, err := a.requestDecomposeLLM(ctx, llm.Provider, llm.APIKey, llm.BaseURL, llm.ModelID, systemPrompt, userPrompt.String(), schema, nil)
+	if err != nil {
+		return "", err
+	}
+	text, _ := services.RepairJSON(raw)
+	var decoded struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		return "", fmt.Errorf("[E_LLM_INVALID_OUTPUT] 解析改写结果失败：%w", err)
+	}
+	if result := strings.TrimSpace(decoded.Prompt); result != "" {
+		return result, nil
+	}
+	return "", fmt.Errorf("[E_LLM_EMPTY] 改写结果为空")
+}
+
+// loadPromptCompileSettings reads the stored settings; paths are stripped unless turned off, and
+// limits are enforced only when turned on.
+func loadPromptCompileSettings() PromptCompileSettings {
+	settings := PromptCompileSettings{StripLocalPaths: true}
+	var row models.Setting
+	if err := models.DB.Where("`key` = ?", promptCompileSettingKey).First(&row).Error; err == nil && row.Value != "" {
+		if err := json.Unmarshal([]byte(row.Value), &settings); err != nil {
+			log.Printf("Invalid prompt compile settings: %v", err)
+		}
+	}
+	return settings
+}
+
+func (s PromptCompileSettings) maxLength(modelID string) int {
+	if limit := s.MaxLengthOverrides[modelID]; limit > 0 {
+		return limit
+	}
+	return config.GetMaxPromptLength(modelID)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"seedance-client/models"
+	"seedance-client/services"
+
+	"gorm.io/gorm"
+)
+
+// LLMProviderData is a saved provider profile as shown to the frontend; the key is never returned.
+type LLMProviderData struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	ProviderType string    `json:"provider_type"`
+	BaseURL      string    `json:"base_url"`
+	Model        string    `json:"model"`
+	HasAPIKey    bool      `json:"has_api_key"`
+	MaskedAPIKey string    `json:"masked_api_key"`
+	KeyError     string    `json:"key_error,omitempty"` // set when the stored key cannot be decrypted
+	IsDefault    bool      `json:"is_default"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type SaveLLMProviderParams struct {
+	ID           uint   `json:"id"` // 0 creates a profile
+	Name         string `json:"name"`
+	ProviderType string `json:"provider_type"`
+	BaseURL      string `json:"base_url"`
+	Model        string `json:"model"`
+	APIKey       string `json:"api_key"` // empty keeps the stored key when updating
+	IsDefault    bool   `json:"is_default"`
+}
+
+// ============================================================
+// LLM Provider Profiles
+// ============================================================
+
+// ListLLMProviders returns the saved provider profiles.
+func (a *App) ListLLMProviders() ([]LLMProviderData, error) {
+	var rows []models.LLMProvider
+	if err := models.DB.Order("name asc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("加载提供方配置失败：%w", err)
+	}
+	out := make([]LLMProviderData, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, providerToData(row))
+	}
+	return out, nil
+}
+
+// SaveLLMProvider creates or updates a provider profile. The key is encrypted before it is stored.
+func (a *App) SaveLLMProvider(params SaveLLMProviderParams) (*LLMProviderData, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, fmt.Errorf("配置名称不能为空")
+	}
+	providerType := strings.TrimSpace(params.ProviderType)
+	baseURL := strings.TrimSpace(params.BaseURL)
+	switch providerType {
+	case "ark_default":
+		baseURL = ""
+	case "ark_custom":
+	case "openai_compatible":
+		if baseURL == "" {
+			return nil, fmt.Errorf("[E_BASEURL_EMPTY] OpenAI Compatible 模式需要填写 Base URL")
+		}
+	case "anthropic", "ollama":
+		if strings.TrimSpace(params.Model) == "" {
+			return nil, fmt.Errorf("[E_MODEL_EMPTY] %s 模式需要填写模型名称", providerLabel(providerType))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", providerType)
+	}
+
+	var row models.LLMProvider
+	if params.ID != 0 {
+		if err := models.DB.First(&row, params.ID).Error; err != nil {
+			return nil, fmt.Errorf("提供方配置不存在")
+		}
+	}
+	var clash int64
+	models.DB.Model(&models.LLMProvider{}).Where("name = ? AND id <> ?", name, params.ID).Count(&clash)
+	if clash > 0 {
+		return nil, fmt.Errorf("已存在同名配置：%s", name)
+	}
+
+	if key := strings.TrimSpace(params.APIKey); key != "" {
+		encrypted, err := services.EncryptSecret(key)
+		if err != nil {
+			return nil, fmt.Errorf("[E_SECRET] 加密 API Key 失败：%w", err)
+		}
+		row.APIKey = encrypted
+	}
+	if providerType == "ark_default" {
+		row.APIKey = "" // uses the global key from settings
+	} else if row.APIKey == "" && providerType != "ollama" {
+		return nil, fmt.Errorf("[E_APIKEY_EMPTY] 该提供方需要填写 API Key")
+	}
+
+	now := time.Now()
+	row.Name = name
+	row.ProviderType = providerType
+	row.BaseURL = baseURL
+	row.Model = strings.TrimSpace(params.Model)
+	row.IsDefault = params.IsDefault
+	row.UpdatedAt = now
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = now
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if row.IsDefault {
+			if err := tx.Model(&models.LLMProvider{}).Where("id <> ?", row.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存提供方配置失败：%w", err)
+	}
+	data := providerToData(row)
+	return &data, nil
+}
+
+// DeleteLLMProvider removes a provider profile.
+func (a *App) DeleteLLMProvider(id uint) error {
+	res := models.DB.Delete(&models.LLMProvider{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("删除提供方配置失败：%w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("提供方配置不存在")
+	}
+	return nil
+}
+
+// llmSettings is the provider connection and model of one LLM request.
+type llmSettings struct {
+	Provider string
+	APIKey   string
+	BaseURL  string
+	ModelID  string
+}
+
+// resolveLLMSettings applies a saved provider profile, when profileID is set, and fills the
+// default provider and model. Without a profile or an explicit provider the default profile is
+// used, if one is marked. The profile's model is used only when it is set; Anthropic and Ollama
+// have no default model, so one must be given.
+func resolveLLMSettings(profileID uint, s llmSettings) (llmSettings, error) {
+	var row models.LLMProvider
+	switch {
+	case profileID != 0:
+		if err := models.DB.First(&row, profileID).Error; err != nil {
+			return s, fmt.Errorf("提供方配置不存在")
+		}
+	case s.Provider == "":
+		err := models.DB.Where("is_default = ?", true).First(&row).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return s, fmt.Errorf("加载默认提供方配置失败：%w", err)
+		}
+	}
+	if row.ID != 0 {
+		key, err := services.DecryptSecret(row.APIKey)
+		if err != nil {
+			return s, fmt.Errorf("[E_SECRET] 提供方配置“%s”：%w", row.Name, err)
+		}
+		s.Provider = row.ProviderType
+		s.APIKey = key
+		s.BaseURL = row.BaseURL
+		if row.Model != "" {
+			s.ModelID = row.Model
+		}
+	}
+	if s.ModelID == "" {
+		// The default model is an Ark model ID, which other endpoints would reject
+		switch s.Provider {
+		case "anthropic", "ollama":
+			return s, fmt.Errorf("[E_MODEL_EMPTY] %s 模式需要填写模型名称", providerLabel(s.Provider))
+		}
+		s.ModelID = getDefaultLLMModel()
+	}
+	if s.Provider == "" {
+		s.Provider = "ark_default"
+	}
+	return s, nil
+}
+
+// providerLabel is the display name of a provider type in error messages.
+func providerLabel(providerType string) string {
+	switch providerType {
+	case "anthropic":
+		return "Anthropic"
+	case "ollama":
+		return "Ollama"
+	case "openai_compatible":
+		return "OpenAI Compatible"
+	}
+	return providerType
+}
+
+func providerToData(row models.LLMProvider) LLMProviderData {
+	data := LLMProviderData{
+		ID:           row.ID,
+		Name:         row.Name,
+		ProviderType: row.ProviderType,
+		BaseURL:      row.BaseURL,
+		Model:        row.Model,
+		IsDefault:    row.IsDefault,
+		UpdatedAt:    row.UpdatedAt,
+	}
+	if row.APIKey != "" {
+		key, err := services.DecryptSecret(row.APIKey)
+		if err != nil {
+			data.KeyError = err.Error()
+		} else {
+			data.HasAPIKey = true
+			data.MaskedAPIKey = services.MaskSecret(key)
+		}
+	}
+	return data
+}
+
+// migratePlaintextSecrets encrypts keys that older versions stored as plaintext.
+func migratePlaintextSecrets() error {
+	var setting models.Setting
+	err := models.DB.Where("`key` = ?", "ark_api_key").First(&setting).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("加载 API Key 设置失败：%w", err)
+	}
+	if err == nil && setting.Value != "" && !services.IsEncryptedSecret(setting.Value) {
+		encrypted, err := services.EncryptSecret(setting.Value)
+		if err != nil {
+			return fmt.Errorf("加密 API Key 失败：%w", err)
+		}
+		if err := models.DB.Model(&models.Setting{}).Where("`key` = ?", "ark_api_key").Update("value", encrypted).Error; err != nil {
+			return fmt.Errorf("保存加密的 API Key 失败：%w", err)
+		}
+	}
+
+	var rows []models.LLMProvider
+	if err := models.DB.Where("api_key <> '' AND api_key NOT LIKE ?", "enc:%").Find(&rows).Error; err != nil {
+		return fmt.Errorf("加载提供方配置失败：%w", err)
+	}
+	for _, row := range rows {
+		encrypted, err := services.EncryptSecret(row.APIKey)
+		if err != nil {
+			return fmt.Errorf("加密提供方配置“%s”的 API Key 失败：%w", row.Name, err)
+		}
+		if err := models.DB.Model(&models.LLMProvider{}).Where("id = ?", row.ID).Update("api_key", encrypted).Error; err != nil {
+			return fmt.Errorf("保存提供方配置“%s”失败：%w", row.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"seedance-client/models"
+	"seedance-client/services"
+)
+
+func TestProviderModelRequired(t *testing.T) {
+	newTestDB(t)
+	a := &App{}
+	for _, providerType := range []string{"anthropic", "ollama"} {
+		_, err := a.SaveLLMProvider(SaveLLMProviderParams{Name: providerType, ProviderType: providerType, APIKey: "k"})
+		if err == nil || !strings.HasPrefix(err.Error(), "[E_MODEL_EMPTY]") {
+			t.Errorf("%s saved without a model: %v", providerType, err)
+		}
+		if _, err := resolveLLMSettings(0, llmSettings{Provider: providerType}); err == nil || !strings.HasPrefix(err.Error(), "[E_MODEL_EMPTY]") {
+			t.Errorf("%s resolved without a model: %v", providerType, err)
+		}
+	}
+
+	saved, err := a.SaveLLMProvider(SaveLLMProviderParams{Name: "local", ProviderType: "ollama", Model: "qwen2.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := resolveLLMSettings(saved.ID, llmSettings{})
+	if err != nil || s.Provider != "ollama" || s.ModelID != "qwen2.5" {
+		t.Errorf("settings = %+v, %v", s, err)
+	}
+
+	// Profiles saved before a model was required fail instead of using the Ark default model
+	models.DB.Model(&models.LLMProvider{}).Where("id = ?", saved.ID).Update("model", "")
+	if _, err := resolveLLMSettings(saved.ID, llmSettings{}); err == nil {
+		t.Error("resolved an ollama profile without a model")
+	}
+	s, err = resolveLLMSettings(0, llmSettings{Provider: "ark_custom"})
+	if err != nil || s.ModelID != getDefaultLLMModel() {
+		t.Errorf("ark settings = %+v, %v", s, err)
+	}
+}
+
+func TestMigratePlaintextSecrets(t *testing.T) {
+	newTestDB(t)
+	models.DB.Create(&models.Setting{Key: "ark_api_key", Value: "ark-plain-key"})
+	encrypted, err := services.EncryptSecret("already-encrypted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := models.LLMProvider{Name: "legacy", ProviderType: "openai_compatible", APIKey: "sk-plain"}
+	done := models.LLMProvider{Name: "new", ProviderType: "anthropic", APIKey: encrypted}
+	keyless := models.LLMProvider{Name: "local", ProviderType: "ollama"}
+	for _, row := range []*models.LLMProvider{&plain, &done, &keyless} {
+		models.DB.Create(row)
+	}
+
+	if err := migratePlaintextSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	// A second run has nothing left to migrate
+	if err := migratePlaintextSecrets(); err != nil {
+		t.Fatal(err)
+	}
+
+	var setting models.Setting
+	models.DB.Where("`key` = ?", "ark_api_key").First(&setting)
+	want := map[string]string{"ark_api_key": "ark-plain-key"}
+	got := map[string]string{"ark_api_key": setting.Value}
+	for _, row := range []models.LLMProvider{plain, done, keyless} {
+		var saved models.LLMProvider
+		models.DB.First(&saved, row.ID)
+		got[row.Name] = saved.APIKey
+	}
+	want["legacy"], want["new"], want["local"] = "sk-plain", "already-encrypted", ""
+	for name, stored := range got {
+		if want[name] != "" && !services.IsEncryptedSecret(stored) {
+			t.Errorf("%s still stored as %q", name, stored)
+		}
+		if plain, err := services.DecryptSecret(stored); err != nil || plain != want[name] {
+			t.Errorf("%s decrypts to %q, %v, want %q", name, plain, err, want[name])
+		}
+	}
+	if got["new"] != encrypted {
+		t.Error("an encrypted key was encrypted again")
+	}
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"seedance-client/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateAssetCatalogParams creates a catalog entry by hand.
+type CreateAssetCatalogParams struct {
+	ProjectID uint   `json:"project_id"`
+	AssetType string `json:"asset_type"` // character | scene | element | style
+	AssetCode string `json:"asset_code"` // empty generates one, e.g. character_03
+	Name      string `json:"name"`
+	Prompt    string `json:"prompt"`
+}
+
+// RecodeAssetCatalogParams changes an entry's type and/or code. Empty fields keep their value,
+// except that a retyped entry with a generated code ("character_02") gets one for its new type.
+type RecodeAssetCatalogParams struct {
+	CatalogID uint   `json:"catalog_id"`
+	AssetType string `json:"asset_type"`
+	AssetCode string `json:"asset_code"`
+}
+
+// AssetShotsParams attaches an entry to shots or detaches it from them.
+type AssetShotsParams struct {
+	CatalogID     uint   `json:"catalog_id"`
+	StoryboardIDs []uint `json:"storyboard_ids"`
+}
+
+// AssetCatalogChange reports a catalog change and the number of shots whose refs it changed.
+type AssetCatalogChange struct {
+	Catalog *AssetCatalogResponse `json:"catalog,omitempty"` // nil after a delete
+	Shots   int                   `json:"shots"`
+}
+
+// AssetShotRef is a shot referencing a catalog entry. Name and Prompt are as the shot uses them;
+// the overrides are empty where the shot follows the entry.
+type AssetShotRef struct {
+	StoryboardID   uint   `json:"storyboard_id"`
+	ShotOrder      int    `json:"shot_order"`
+	ShotNo         string `json:"shot_no"`
+	FrameContent   string `json:"frame_content"`
+	Name           string `json:"name"`
+	Prompt         string `json:"prompt"`
+	NameOverride   string `json:"name_override"`
+	PromptOverride string `json:"prompt_override"`
+}
+
+// ============================================================
+// Asset Catalog CRUD
+// ============================================================
+
+// CreateAssetCatalog adds an entry to a project's catalog without decomposing or editing shots.
+func (a *App) CreateAssetCatalog(params CreateAssetCatalogParams) (*AssetCatalogResponse, error) {
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	assetType := strings.TrimSpace(params.AssetType)
+	if !isCatalogAssetType(assetType) {
+		return nil, fmt.Errorf("不支持的资产类型：%s", params.AssetType)
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, fmt.Errorf("资产名称不能为空")
+	}
+
+	var catalog models.AssetCatalog
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.AssetCatalog
+		if err := tx.Where("project_id = ? AND asset_type = ?", project.ID, assetType).Find(&existing).Error; err != nil {
+			return err
+		}
+		key := entityNameKey(name)
+		for _, c := range existing {
+			if key != "" && entityNameKey(c.Name) == key {
+				return fmt.Errorf("已有同名资产 %s（%s）", c.Name, c.AssetCode)
+			}
+		}
+		code, err := claimAssetCode(existing, assetType, params.AssetCode, 0)
+		if err != nil {
+			return err
+		}
+		catalog = models.AssetCatalog{
+			ProjectID: project.ID,
+			AssetType: assetType,
+			AssetCode: code,
+			Name:      name,
+			Prompt:    strings.TrimSpace(params.Prompt),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		return tx.Create(&catalog).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建资产失败：%w", err)
+	}
+	return loadAssetCatalogResponse(catalog.ID)
+}
+
+// DeleteAssetCatalog deletes an entry with its versions and removes its refs from every shot,
+// so editing a shot does not bring it back.
+func (a *App) DeleteAssetCatalog(catalogID uint) (*AssetCatalogChange, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.First(&catalog, catalogID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	change := &AssetCatalogChange{}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		n, err := countCatalogShotsTx(tx, catalog.ID)
+		if err != nil {
+			return err
+		}
+		change.Shots = n
+		if err := tx.Where("catalog_id = ?", catalog.ID).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("catalog_id = ?", catalog.ID).Delete(&models.AssetVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&catalog).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("删除资产失败：%w", err)
+	}
+	return change, nil
+}
+
+// RecodeAssetCatalog changes an entry's type and/or code. Shots reference the entry itself, so
+// their refs follow; a retyped ref moves to the shot's list for the new type. An entry linked to
+// the asset library is unlinked when its type changes.
+func (a *App) RecodeAssetCatalog(params RecodeAssetCatalogParams) (*AssetCatalogChange, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.First(&catalog, params.CatalogID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	newType := strings.TrimSpace(params.AssetType)
+	if newType == "" {
+		newType = catalog.AssetType
+	}
+	if !isCatalogAssetType(newType) {
+		return nil, fmt.Errorf("不支持的资产类型：%s", params.AssetType)
+	}
+	requested := strings.TrimSpace(params.AssetCode)
+	if requested == "" && (newType == catalog.AssetType || !strings.HasPrefix(catalog.AssetCode, catalog.AssetType+"_")) {
+		requested = catalog.AssetCode
+	}
+	if newType == catalog.AssetType && requested == catalog.AssetCode {
+		resp, err := loadAssetCatalogResponse(catalog.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &AssetCatalogChange{Catalog: resp}, nil
+	}
+
+	change := &AssetCatalogChange{}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.AssetCatalog
+		if err := tx.Where("project_id = ? AND asset_type = ?", catalog.ProjectID, newType).Find(&existing).Error; err != nil {
+			return err
+		}
+		code, err := claimAssetCode(existing, newType, requested, catalog.ID)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"asset_type": newType, "asset_code": code}
+		if newType != catalog.AssetType && catalog.LibraryAssetID != nil {
+			updates["library_asset_id"] = nil
+		}
+		if err := tx.Model(&catalog).Updates(updates).Error; err != nil {
+			return err
+		}
+		if newType != catalog.AssetType {
+			// Retyped refs go after the ones the shots already have of the new type
+			if err := tx.Exec(`UPDATE storyboard_asset_refs SET position = (
+				SELECT COALESCE(MAX(r.position), 0) + 1 FROM storyboard_asset_refs r
+				JOIN asset_catalogs c ON c.id = r.catalog_id
+				WHERE r.storyboard_id = storyboard_asset_refs.storyboard_id AND c.asset_type = ?
+			) WHERE catalog_id = ?`, newType, catalog.ID).Error; err != nil {
+				return err
+			}
+		}
+		n, err := countCatalogShotsTx(tx, catalog.ID)
+		change.Shots = n
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("修改资产编号失败：%w", err)
+	}
+	resp, err := loadAssetCatalogResponse(catalog.ID)
+	if err != nil {
+		return nil, err
+	}
+	change.Catalog = resp
+	return change, nil
+}
+
+// claimAssetCode validates a requested code against the entries of its type, or generates the
+// next free one ("<type>_NN") when requested is empty. selfID is the entry being recoded.
+func claimAssetCode(existing []models.AssetCatalog, assetType string, requested string, selfID uint) (string, error) {
+	used := map[string]bool{}
+	for _, c := range existing {
+		if c.ID != selfID {
+			used[c.AssetCode] = true
+		}
+	}
+	code := strings.TrimSpace(requested)
+	if code != "" {
+		if strings.ContainsAny(code, " \t\n") {
+			return "", fmt.Errorf("资产编号不能包含空白：%s", code)
+		}
+		if used[code] {
+			return "", fmt.Errorf("资产编号 %s 已被使用；如为同一资产，请使用合并", code)
+		}
+		return code, nil
+	}
+	for n := len(existing) + 1; ; n++ {
+		code = fmt.Sprintf("%s_%02d", assetType, n)
+		if !used[code] {
+			return code, nil
+		}
+	}
+}
+
+// ============================================================
+// Shot References
+// ============================================================
+
+// AttachAssetCatalog adds a ref to the entry to each of the shots that lack one.
+func (a *App) AttachAssetCatalog(params AssetShotsParams) (*AssetCatalogChange, error) {
+	return a.editAssetShots(params, func(tx *gorm.DB, catalog models.AssetCatalog, storyboardIDs []uint) (int, error) {
+		var have []uint
+		if err := tx.Model(&models.StoryboardAssetRef{}).Where("catalog_id = ? AND storyboard_id IN ?", catalog.ID, storyboardIDs).
+			Pluck("storyboard_id", &have).Error; err != nil {
+			return 0, err
+		}
+		added := 0
+		for _, id := range storyboardIDs {
+			if indexOfUint(have, id) >= 0 {
+				continue
+			}
+			refs, err := loadShotRefs(tx, []uint{id})
+			if err != nil {
+				return 0, err
+			}
+			ref := models.StoryboardAssetRef{StoryboardID: id, CatalogID: catalog.ID, Position: len(refs[id][catalog.AssetType]) + 1}
+			if err := tx.Create(&ref).Error; err != nil {
+				return 0, err
+			}
+			added++
+		}
+		return added, nil
+	})
+}
+
+// DetachAssetCatalog removes the entry's refs from the shots. The entry itself is kept.
+func (a *App) DetachAssetCatalog(params AssetShotsParams) (*AssetCatalogChange, error) {
+	return a.editAssetShots(params, func(tx *gorm.DB, catalog models.AssetCatalog, storyboardIDs []uint) (int, error) {
+		res := tx.Where("catalog_id = ? AND storyboard_id IN ?", catalog.ID, storyboardIDs).Delete(&models.StoryboardAssetRef{})
+		return int(res.RowsAffected), res.Error
+	})
+}
+
+func (a *App) editAssetShots(params AssetShotsParams, edit func(*gorm.DB, models.AssetCatalog, []uint) (int, error)) (*AssetCatalogChange, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.First(&catalog, params.CatalogID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	ids := uniqueUints(params.StoryboardIDs)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请选择镜头")
+	}
+	var count int64
+	if err := models.DB.Model(&models.Storyboard{}).Where("id IN ? AND project_id = ?", ids, catalog.ProjectID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if int(count) != len(ids) {
+		return nil, fmt.Errorf("镜头不存在或不属于该项目")
+	}
+
+	change := &AssetCatalogChange{}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		n, err := edit(tx, catalog, ids)
+		change.Shots = n
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("更新镜头资产失败：%w", err)
+	}
+	resp, err := loadAssetCatalogResponse(catalog.ID)
+	if err != nil {
+		return nil, err
+	}
+	change.Catalog = resp
+	return change, nil
+}
+
+// GetAssetCatalogShots lists the shots referencing a catalog entry, in shot order.
+func (a *App) GetAssetCatalogShots(catalogID uint) ([]AssetShotRef, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.First(&catalog, catalogID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	var shots []AssetShotRef
+	if err := models.DB.Table("storyboard_asset_refs AS r").
+		Select("s.id AS storyboard_id, s.shot_order, s.shot_no, s.frame_content, r.name_override, r.prompt_override").
+		Joins("JOIN storyboards s ON s.id = r.storyboard_id").
+		Where("r.catalog_id = ?", catalog.ID).
+		Order("s.shot_order asc, s.id asc").
+		Scan(&shots).Error; err != nil {
+		return nil, fmt.Errorf("查询资产引用失败：%w", err)
+	}
+	for i := range shots {
+		shots[i].Name = firstNonEmpty(shots[i].NameOverride, catalog.Name)
+		shots[i].Prompt = firstNonEmpty(shots[i].PromptOverride, catalog.Prompt)
+	}
+	return shots, nil
+}
+
+// shotRefRow is a storyboard_asset_refs row with its catalog entry.
+type shotRefRow struct {
+	StoryboardID   uint
+	CatalogID      uint
+	NameOverride   string
+	PromptOverride string
+	AssetType      string
+	AssetCode      string
+	Name           string
+	Prompt         string
+}
+
+// loadShotRefs returns the refs of the given shots by storyboard ID and asset type, in order;
+// every shot has a list for each type. Each ref carries the entry's code, and its name and
+// prompt unless the shot overrides them.
+func loadShotRefs(db *gorm.DB, storyboardIDs []uint) (map[uint]map[string][]EntityRef, error) {
+	out := make(map[uint]map[string][]EntityRef, len(storyboardIDs))
+	for _, id := range storyboardIDs {
+		out[id] = map[string][]EntityRef{}
+		for _, t := range catalogAssetTypes {
+			out[id][t] = []EntityRef{}
+		}
+	}
+	if len(storyboardIDs) == 0 {
+		return out, nil
+	}
+	var rows []shotRefRow
+	if err := db.Table("storyboard_asset_refs AS r").
+		Select("r.storyboard_id, r.catalog_id, r.name_override, r.prompt_override, c.asset_type, c.asset_code, c.name, c.prompt").
+		Joins("JOIN asset_catalogs c ON c.id = r.catalog_id").
+		Where("r.storyboard_id IN ?", storyboardIDs).
+		Order("r.storyboard_id asc, r.position asc, r.id asc").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.StoryboardID][row.AssetType] = append(out[row.StoryboardID][row.AssetType], EntityRef{
+			ID:     row.AssetCode,
+			Name:   firstNonEmpty(row.NameOverride, row.Name),
+			Prompt: firstNonEmpty(row.PromptOverride, row.Prompt),
+		})
+	}
+	return out, nil
+}
+
+// storyboardIDs lists the IDs of shots.
+func storyboardIDs(shots []models.Storyboard) []uint {
+	ids := make([]uint, 0, len(shots))
+	for _, sb := range shots {
+		ids = append(ids, sb.ID)
+	}
+	return ids
+}
+
+// shotRefs is loadShotRefs for one shot.
+func shotRefs(db *gorm.DB, storyboardID uint) (map[string][]EntityRef, error) {
+	refs, err := loadShotRefs(db, []uint{storyboardID})
+	if err != nil {
+		return nil, err
+	}
+	return refs[storyboardID], nil
+}
+
+// saveShotRefsTx replaces a shot's refs of each asset type present in refs. Refs are matched to
+// catalog entries by code, creating the missing ones; a name or prompt differing from the
+// entry's is kept as the shot's override.
+func saveShotRefsTx(tx *gorm.DB, projectID, storyboardID uint, refs map[string][]EntityRef) error {
+	for _, assetType := range catalogAssetTypes {
+		list, ok := refs[assetType]
+		if !ok {
+			continue
+		}
+		if err := syncCatalogRefsTx(tx, projectID, storyboardID, assetType, list); err != nil {
+			return fmt.Errorf("同步%s资产引用失败：%w", assetTypeLabels[assetType], err)
+		}
+		if err := tx.Where("storyboard_id = ? AND catalog_id IN (?)", storyboardID,
+			tx.Model(&models.AssetCatalog{}).Select("id").Where("project_id = ? AND asset_type = ?", projectID, assetType)).
+			Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+			return fmt.Errorf("保存%s资产引用失败：%w", assetTypeLabels[assetType], err)
+		}
+		var codes []string
+		for _, ref := range list {
+			if code := strings.TrimSpace(ref.ID); code != "" {
+				codes = append(codes, code)
+			}
+		}
+		if len(codes) == 0 {
+			continue
+		}
+		var catalogs []models.AssetCatalog
+		if err := tx.Where("project_id = ? AND asset_type = ? AND asset_code IN ?", projectID, assetType, codes).Find(&catalogs).Error; err != nil {
+			return fmt.Errorf("保存%s资产引用失败：%w", assetTypeLabels[assetType], err)
+		}
+		byCode := make(map[string]models.AssetCatalog, len(catalogs))
+		for _, c := range catalogs {
+			byCode[c.AssetCode] = c
+		}
+		seen := map[uint]bool{}
+		for _, ref := range list {
+			catalog, ok := byCode[strings.TrimSpace(ref.ID)]
+			if !ok || seen[catalog.ID] {
+				continue
+			}
+			seen[catalog.ID] = true
+			row := models.StoryboardAssetRef{StoryboardID: storyboardID, CatalogID: catalog.ID, Position: len(seen)}
+			if name := strings.TrimSpace(ref.Name); name != catalog.Name {
+				row.NameOverride = name
+			}
+			if prompt := strings.TrimSpace(ref.Prompt); prompt != catalog.Prompt {
+				row.PromptOverride = prompt
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return fmt.Errorf("保存%s资产引用失败：%w", assetTypeLabels[assetType], err)
+			}
+		}
+	}
+	return nil
+}
+
+// countCatalogShotsTx counts the shots referencing any of the catalog entries.
+func countCatalogShotsTx(tx *gorm.DB, catalogIDs ...uint) (int, error) {
+	var n int64
+	err := tx.Model(&models.StoryboardAssetRef{}).Where("catalog_id IN ?", catalogIDs).Distinct("storyboard_id").Count(&n).Error
+	return int(n), err
+}
+
+// firstNonEmpty returns override unless it is empty.
+func firstNonEmpty(override, value string) string {
+	if override != "" {
+		return override
+	}
+	return value
+}
+
+func uniqueUints(list []uint) []uint {
+	var out []uint
+	for _, v := range list {
+		if indexOfUint(out, v) < 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// catalogAssetTypes are the asset types shots reference, in display order.
+var catalogAssetTypes = []string{"character", "scene", "element", "style"}
+
+var assetTypeLabels = map[string]string{
+	"character": "角色",
+	"scene":     "场景",
+	"element":   "元素",
+	"style":     "风格",
+}
+
+func isCatalogAssetType(assetType string) bool {
+	_, ok := assetTypeLabels[assetType]
+	return ok
+}
+
+// loadAssetCatalogResponse loads an entry with its versions as the workspace returns it.
+func loadAssetCatalogResponse(id uint) (*AssetCatalogResponse, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version_no asc")
+	}).First(&catalog, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("asset catalog not found")
+		}
+		return nil, err
+	}
+	versions := make([]AssetVersionResponse, 0, len(catalog.Versions))
+	for _, v := range catalog.Versions {
+		versions = append(versions, toAssetVersionResponse(v))
+	}
+	return &AssetCatalogResponse{
+		ID:             catalog.ID,
+		ProjectID:      catalog.ProjectID,
+		AssetType:      catalog.AssetType,
+		AssetCode:      catalog.AssetCode,
+		Name:           catalog.Name,
+		Prompt:         catalog.Prompt,
+		StoryboardID:   catalog.StoryboardID,
+		LibraryAssetID: catalog.LibraryAssetID,
+		Versions:       versions,
+		Active:         chooseActiveAssetVersion(versions),
+		UpdatedAt:      catalog.UpdatedAt,
+	}, nil
+}
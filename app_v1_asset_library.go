@@ -0,0 +1,441 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"seedance-client/models"
+
+	"gorm.io/gorm"
+)
+
+// The asset library holds characters, scenes and styles shared by all projects. A catalog entry
+// is published with its good versions; other projects import a library asset by reference, which
+// links the entry so later publishes update it, or by copy, which does not.
+
+// PublishAssetParams publishes a catalog entry to the library.
+type PublishAssetParams struct {
+	CatalogID uint   `json:"catalog_id"`
+	Tags      string `json:"tags"` // comma separated; empty keeps the library asset's tags
+}
+
+// ImportLibraryAssetParams imports a library asset into a project.
+type ImportLibraryAssetParams struct {
+	ProjectID      uint   `json:"project_id"`
+	LibraryAssetID uint   `json:"library_asset_id"`
+	Mode           string `json:"mode"` // reference | copy
+}
+
+// SearchAssetLibraryParams browses the library. Every word of Query must appear in the name,
+// prompt or tags.
+type SearchAssetLibraryParams struct {
+	Query     string `json:"query"`
+	AssetType string `json:"asset_type"` // empty for all
+	Limit     int    `json:"limit"`      // 0 means 50
+	Offset    int    `json:"offset"`
+}
+
+type LibraryAssetVersionResponse struct {
+	ID             uint      `json:"id"`
+	LibraryAssetID uint      `json:"library_asset_id"`
+	VersionNo      int       `json:"version_no"`
+	ImagePath      string    `json:"image_path"`
+	SourceType     string    `json:"source_type"`
+	ModelID        string    `json:"model_id"`
+	Prompt         string    `json:"prompt"`
+	IsGood         bool      `json:"is_good"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type LibraryAssetResponse struct {
+	ID        uint                          `json:"id"`
+	AssetType string                        `json:"asset_type"`
+	Name      string                        `json:"name"`
+	Prompt    string                        `json:"prompt"`
+	Tags      string                        `json:"tags"`
+	Versions  []LibraryAssetVersionResponse `json:"versions"`
+	Active    *LibraryAssetVersionResponse  `json:"active"`
+	// LinkedProjects counts the projects with an entry linked to the asset.
+	LinkedProjects int       `json:"linked_projects"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type AssetLibraryPage struct {
+	Total int64                  `json:"total"`
+	Items []LibraryAssetResponse `json:"items"`
+}
+
+// ============================================================
+// Publish
+// ============================================================
+
+// PublishAssetToLibrary publishes a catalog entry's name, prompt and good versions (the active
+// version when none is marked good). Publishing an entry again, or one imported by reference,
+// updates its library asset and every entry linked to it. The entry itself becomes linked.
+func (a *App) PublishAssetToLibrary(params PublishAssetParams) (*LibraryAssetResponse, error) {
+	var catalog models.AssetCatalog
+	if err := models.DB.Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version_no asc")
+	}).First(&catalog, params.CatalogID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	if strings.TrimSpace(catalog.Name) == "" && strings.TrimSpace(catalog.Prompt) == "" {
+		return nil, fmt.Errorf("资产没有名称和提示词，无法发布")
+	}
+
+	var publish []models.AssetVersion
+	for _, v := range catalog.Versions {
+		if v.IsGood && v.ImagePath != "" {
+			publish = append(publish, v)
+		}
+	}
+	if len(publish) == 0 {
+		for i := len(catalog.Versions) - 1; i >= 0; i-- {
+			if v := catalog.Versions[i]; v.ImagePath != "" && (v.Status == "" || v.Status == "Succeeded") {
+				publish = append(publish, v)
+				break
+			}
+		}
+	}
+
+	var lib models.LibraryAsset
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if catalog.LibraryAssetID != nil {
+			err = tx.First(&lib, *catalog.LibraryAssetID).Error
+		} else {
+			err = tx.Where("source_catalog_id = ?", catalog.ID).First(&lib).Error
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if lib.ID == 0 {
+			lib = models.LibraryAsset{AssetType: catalog.AssetType, SourceCatalogID: catalog.ID, CreatedAt: time.Now()}
+		}
+		lib.Name = strings.TrimSpace(catalog.Name)
+		lib.Prompt = strings.TrimSpace(catalog.Prompt)
+		if tags := normalizeLibraryTags(params.Tags); tags != "" {
+			lib.Tags = tags
+		}
+		lib.UpdatedAt = time.Now()
+		if err := tx.Save(&lib).Error; err != nil {
+			return err
+		}
+
+		var maxNo int
+		if err := tx.Model(&models.LibraryAssetVersion{}).Where("library_asset_id = ?", lib.ID).
+			Select("COALESCE(MAX(version_no),0)").Scan(&maxNo).Error; err != nil {
+			return err
+		}
+		for _, v := range publish {
+			if v.LibraryVersionID != nil {
+				var count int64
+				if err := tx.Model(&models.LibraryAssetVersion{}).Where("id = ? AND library_asset_id = ?", *v.LibraryVersionID, lib.ID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue // published before, or taken from the library
+				}
+			}
+			maxNo++
+			lv := models.LibraryAssetVersion{
+				LibraryAssetID:  lib.ID,
+				VersionNo:       maxNo,
+				ImagePath:       v.ImagePath,
+				SourceType:      v.SourceType,
+				ModelID:         v.ModelID,
+				Prompt:          v.Prompt,
+				SourceVersionID: v.ID,
+				IsGood:          v.IsGood,
+				CreatedAt:       time.Now(),
+			}
+			if lv.IsGood {
+				if err := tx.Model(&models.LibraryAssetVersion{}).Where("library_asset_id = ?", lib.ID).Update("is_good", false).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&lv).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.AssetVersion{}).Where("id = ?", v.ID).Update("library_version_id", lv.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.AssetCatalog{}).Where("id = ?", catalog.ID).Update("library_asset_id", lib.ID).Error; err != nil {
+			return err
+		}
+		var linked []models.AssetCatalog
+		if err := tx.Where("library_asset_id = ?", lib.ID).Find(&linked).Error; err != nil {
+			return err
+		}
+		for i := range linked {
+			if err := syncCatalogFromLibraryTx(tx, &linked[i], lib.ID, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("发布资产失败：%w", err)
+	}
+	return loadLibraryAssetResponse(lib.ID)
+}
+
+// normalizeLibraryTags trims the tags and drops empty and repeated ones.
+func normalizeLibraryTags(raw string) string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, t := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '，' || r == ';' || r == '；' }) {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[strings.ToLower(t)] {
+			seen[strings.ToLower(t)] = true
+			tags = append(tags, t)
+		}
+	}
+	return strings.Join(tags, ",")
+}
+
+// ============================================================
+// Import
+// ============================================================
+
+// ImportLibraryAsset adds a library asset to a project's catalog. A project entry of the same
+// type and name receives it instead of a new entry being created. By reference the entry is
+// linked and follows later publishes; by copy it is independent.
+func (a *App) ImportLibraryAsset(params ImportLibraryAssetParams) (*AssetCatalogResponse, error) {
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	var lib models.LibraryAsset
+	if err := models.DB.First(&lib, params.LibraryAssetID).Error; err != nil {
+		return nil, fmt.Errorf("资产库中没有该资产")
+	}
+	link := params.Mode != "copy"
+
+	var catalogID uint
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var catalog models.AssetCatalog
+		err := tx.Where("project_id = ? AND library_asset_id = ?", project.ID, lib.ID).First(&catalog).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if catalog.ID == 0 {
+			var catalogs []models.AssetCatalog
+			if err := tx.Where("project_id = ?", project.ID).Order("id asc").Find(&catalogs).Error; err != nil {
+				return err
+			}
+			r, ok := newEntityReconcilers(catalogs)[lib.AssetType]
+			if !ok {
+				return fmt.Errorf("不支持的资产类型：%s", lib.AssetType)
+			}
+			existing := len(r.canon)
+			ref := r.canon[r.resolve(EntityRef{Name: lib.Name})]
+			if len(r.canon) == existing {
+				if err := tx.Where("project_id = ? AND asset_type = ? AND asset_code = ?", project.ID, lib.AssetType, ref.ID).First(&catalog).Error; err != nil {
+					return err
+				}
+			} else {
+				catalog = models.AssetCatalog{
+					ProjectID: project.ID,
+					AssetType: lib.AssetType,
+					AssetCode: ref.ID,
+					Name:      lib.Name,
+					Prompt:    lib.Prompt,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				if err := tx.Create(&catalog).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if link {
+			if err := tx.Model(&catalog).Update("library_asset_id", lib.ID).Error; err != nil {
+				return err
+			}
+		} else if strings.TrimSpace(catalog.Prompt) == "" && lib.Prompt != "" {
+			if err := tx.Model(&catalog).Update("prompt", lib.Prompt).Error; err != nil {
+				return err
+			}
+		}
+		catalogID = catalog.ID
+		return syncCatalogFromLibraryTx(tx, &catalog, lib.ID, link)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("导入资产失败：%w", err)
+	}
+
+	return loadAssetCatalogResponse(catalogID)
+}
+
+// syncCatalogFromLibraryTx adds the library versions a catalog entry lacks, after its own. A
+// library version marked good becomes the entry's good version. When linked, the entry's name
+// and prompt follow the library too.
+func syncCatalogFromLibraryTx(tx *gorm.DB, catalog *models.AssetCatalog, libraryAssetID uint, linked bool) error {
+	var lib models.LibraryAsset
+	if err := tx.Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version_no asc")
+	}).First(&lib, libraryAssetID).Error; err != nil {
+		return err
+	}
+	if linked && (catalog.Name != lib.Name || catalog.Prompt != lib.Prompt) {
+		catalog.Name, catalog.Prompt = lib.Name, lib.Prompt
+		if err := tx.Model(catalog).Updates(map[string]interface{}{"name": lib.Name, "prompt": lib.Prompt}).Error; err != nil {
+			return err
+		}
+	}
+
+	var have []uint
+	if err := tx.Model(&models.AssetVersion{}).Where("catalog_id = ? AND library_version_id IS NOT NULL", catalog.ID).
+		Pluck("library_version_id", &have).Error; err != nil {
+		return err
+	}
+	var maxNo int
+	if err := tx.Model(&models.AssetVersion{}).Where("catalog_id = ?", catalog.ID).
+		Select("COALESCE(MAX(version_no),0)").Scan(&maxNo).Error; err != nil {
+		return err
+	}
+	for _, lv := range lib.Versions {
+		if indexOfUint(have, lv.ID) >= 0 {
+			continue
+		}
+		maxNo++
+		libraryVersionID := lv.ID
+		v := models.AssetVersion{
+			CatalogID:        catalog.ID,
+			VersionNo:        maxNo,
+			ImagePath:        lv.ImagePath,
+			SourceType:       "library",
+			ModelID:          lv.ModelID,
+			Prompt:           lv.Prompt,
+			Status:           "Succeeded",
+			IsGood:           lv.IsGood,
+			CreatedAt:        time.Now(),
+			LibraryVersionID: &libraryVersionID,
+		}
+		if v.IsGood {
+			if err := tx.Model(&models.AssetVersion{}).Where("catalog_id = ?", catalog.ID).Update("is_good", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&v).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// Browse
+// ============================================================
+
+// SearchAssetLibrary lists library assets, most recently updated first.
+func (a *App) SearchAssetLibrary(params SearchAssetLibraryParams) (*AssetLibraryPage, error) {
+	query := func() *gorm.DB {
+		q := models.DB.Model(&models.LibraryAsset{})
+		if t := strings.TrimSpace(params.AssetType); t != "" {
+			q = q.Where("asset_type = ?", t)
+		}
+		for _, word := range strings.Fields(params.Query) {
+			like := "%" + word + "%"
+			q = q.Where("name LIKE ? OR prompt LIKE ? OR tags LIKE ?", like, like, like)
+		}
+		return q
+	}
+	page := &AssetLibraryPage{Items: []LibraryAssetResponse{}}
+	if err := query().Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	var assets []models.LibraryAsset
+	if err := query().Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version_no asc")
+	}).Order("updated_at desc, id desc").Limit(limit).Offset(max(params.Offset, 0)).Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	for _, lib := range assets {
+		page.Items = append(page.Items, toLibraryAssetResponse(lib))
+	}
+	return page, nil
+}
+
+// DeleteLibraryAsset removes an asset from the library. Entries linked to it keep their name,
+// prompt and versions and stop following updates.
+func (a *App) DeleteLibraryAsset(id uint) error {
+	var lib models.LibraryAsset
+	if err := models.DB.First(&lib, id).Error; err != nil {
+		return fmt.Errorf("资产库中没有该资产")
+	}
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AssetCatalog{}).Where("library_asset_id = ?", lib.ID).Update("library_asset_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AssetVersion{}).
+			Where("library_version_id IN (?)", tx.Model(&models.LibraryAssetVersion{}).Select("id").Where("library_asset_id = ?", lib.ID)).
+			Update("library_version_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("library_asset_id = ?", lib.ID).Delete(&models.LibraryAssetVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&lib).Error
+	})
+}
+
+func loadLibraryAssetResponse(id uint) (*LibraryAssetResponse, error) {
+	var lib models.LibraryAsset
+	if err := models.DB.Preload("Versions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version_no asc")
+	}).First(&lib, id).Error; err != nil {
+		return nil, err
+	}
+	resp := toLibraryAssetResponse(lib)
+	return &resp, nil
+}
+
+func toLibraryAssetResponse(lib models.LibraryAsset) LibraryAssetResponse {
+	resp := LibraryAssetResponse{
+		ID:        lib.ID,
+		AssetType: lib.AssetType,
+		Name:      lib.Name,
+		Prompt:    lib.Prompt,
+		Tags:      lib.Tags,
+		Versions:  make([]LibraryAssetVersionResponse, 0, len(lib.Versions)),
+		UpdatedAt: lib.UpdatedAt,
+	}
+	for _, v := range lib.Versions {
+		resp.Versions = append(resp.Versions, LibraryAssetVersionResponse{
+			ID:             v.ID,
+			LibraryAssetID: v.LibraryAssetID,
+			VersionNo:      v.VersionNo,
+			ImagePath:      v.ImagePath,
+			SourceType:     v.SourceType,
+			ModelID:        v.ModelID,
+			Prompt:         v.Prompt,
+			IsGood:         v.IsGood,
+			CreatedAt:      v.CreatedAt,
+		})
+	}
+	// Active follows catalog entries: the latest good version, else the latest
+	for i := len(resp.Versions) - 1; i >= 0 && resp.Active == nil; i-- {
+		if resp.Versions[i].IsGood {
+			v := resp.Versions[i]
+			resp.Active = &v
+		}
+	}
+	if resp.Active == nil && len(resp.Versions) > 0 {
+		v := resp.Versions[len(resp.Versions)-1]
+		resp.Active = &v
+	}
+	var linked int64
+	models.DB.Model(&models.AssetCatalog{}).Where("library_asset_id = ?", lib.ID).Distinct("project_id").Count(&linked)
+	resp.LinkedProjects = int(linked)
+	return resp
+}
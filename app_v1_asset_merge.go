@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"seedance-client/models"
+
+	"gorm.io/gorm"
+)
+
+// Decomposition names entities per shot, so the same character can end up in the catalog twice,
+// e.g. "李明" as character_02_01 and "李明（少年）" as character_05_01. Duplicates are found by name
+// and prompt similarity and merged into one entry.
+
+// assetDuplicateThreshold is the default minimum similarity for two entries to be reported.
+const assetDuplicateThreshold = 0.75
+
+// FindAssetDuplicatesParams looks for duplicate catalog entries in a project.
+type FindAssetDuplicatesParams struct {
+	ProjectID uint   `json:"project_id"`
+	AssetType string `json:"asset_type"` // character | scene | element | style; empty for all
+	// Threshold is the minimum similarity, 0–1; 0 means the default.
+	Threshold float64 `json:"threshold"`
+}
+
+// AssetDuplicateMember is one catalog entry of a duplicate group.
+type AssetDuplicateMember struct {
+	ID        uint   `json:"id"`
+	AssetCode string `json:"asset_code"`
+	Name      string `json:"name"`
+	Prompt    string `json:"prompt"`
+	Versions  int    `json:"versions"`
+	Shots     int    `json:"shots"` // shots referencing the entry
+}
+
+// AssetDuplicateGroup is a set of entries that look like the same asset.
+type AssetDuplicateGroup struct {
+	AssetType string `json:"asset_type"`
+	// KeepID suggests the entry to keep: the most used, then the one with most versions.
+	KeepID uint `json:"keep_id"`
+	// Similarity is the weakest link of the group, with its reason.
+	Similarity float64                `json:"similarity"`
+	Reason     string                 `json:"reason"`
+	Members    []AssetDuplicateMember `json:"members"`
+}
+
+// MergeAssetCatalogsParams merges catalog entries into one.
+type MergeAssetCatalogsParams struct {
+	KeepID   uint   `json:"keep_id"`
+	MergeIDs []uint `json:"merge_ids"`
+}
+
+// AssetMergeResult reports a merge.
+type AssetMergeResult struct {
+	KeepID         uint `json:"keep_id"`
+	Merged         int  `json:"merged"`
+	MovedVersions  int  `json:"moved_versions"`
+	RewrittenShots int  `json:"rewritten_shots"`
+}
+
+// ============================================================
+// Duplicate Detection
+// ============================================================
+
+// FindAssetDuplicates groups catalog entries of the same type whose names or prompts are
+// similar enough to be the same asset.
+func (a *App) FindAssetDuplicates(params FindAssetDuplicatesParams) ([]AssetDuplicateGroup, error) {
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	threshold := params.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = assetDuplicateThreshold
+	}
+
+	query := models.DB.Preload("Versions").Where("project_id = ?", project.ID)
+	if t := strings.TrimSpace(params.AssetType); t != "" {
+		query = query.Where("asset_type = ?", t)
+	}
+	var catalogs []models.AssetCatalog
+	if err := query.Order("asset_type asc, id asc").Find(&catalogs).Error; err != nil {
+		return nil, err
+	}
+	usage, err := assetUsage(models.DB, catalogs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are linked pairwise and the links joined into groups
+	parent := make([]int, len(catalogs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	type link struct {
+		score  float64
+		reason string
+	}
+	weakest := map[int]link{}
+	for i := range catalogs {
+		for j := i + 1; j < len(catalogs); j++ {
+			if catalogs[i].AssetType != catalogs[j].AssetType {
+				continue
+			}
+			score, reason := assetSimilarity(catalogs[i], catalogs[j])
+			if score < threshold {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			if ri == rj {
+				continue
+			}
+			w := link{score, reason}
+			for _, r := range []int{ri, rj} {
+				if l, ok := weakest[r]; ok && l.score < w.score {
+					w = l
+				}
+			}
+			parent[rj] = ri
+			weakest[ri] = w
+		}
+	}
+
+	members := map[int][]int{}
+	var roots []int
+	for i := range catalogs {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], i)
+	}
+	groups := []AssetDuplicateGroup{}
+	for _, r := range roots {
+		if len(members[r]) < 2 {
+			continue
+		}
+		group := AssetDuplicateGroup{
+			AssetType:  catalogs[r].AssetType,
+			Similarity: roundSimilarity(weakest[r].score),
+			Reason:     weakest[r].reason,
+		}
+		for _, i := range members[r] {
+			c := catalogs[i]
+			group.Members = append(group.Members, AssetDuplicateMember{
+				ID:        c.ID,
+				AssetCode: c.AssetCode,
+				Name:      c.Name,
+				Prompt:    c.Prompt,
+				Versions:  len(c.Versions),
+				Shots:     usage[c.ID],
+			})
+		}
+		best := group.Members[0]
+		for _, m := range group.Members[1:] {
+			if m.Shots > best.Shots || (m.Shots == best.Shots && m.Versions > best.Versions) {
+				best = m
+			}
+		}
+		group.KeepID = best.ID
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// assetQualifier matches a parenthesized qualifier such as "（少年）" or "(young)".
+var assetQualifier = regexp.MustCompile(`[(（\[【][^)）\]】]*[)）\]】]`)
+
+// assetNameKey is the entry's name without qualifiers, normalized for matching. Names that are
+// just the generated code say nothing about the asset and give "".
+func assetNameKey(c models.AssetCatalog) string {
+	name := strings.TrimSpace(c.Name)
+	if name == "" || name == c.AssetCode {
+		return ""
+	}
+	if key := entityNameKey(assetQualifier.ReplaceAllString(name, "")); key != "" {
+		return key
+	}
+	return entityNameKey(name)
+}
+
+// assetSimilarity scores two entries of the same type from 0 to 1, with the reason behind it.
+func assetSimilarity(a, b models.AssetCatalog) (float64, string) {
+	na, nb := assetNameKey(a), assetNameKey(b)
+	pa, pb := strings.TrimSpace(a.Prompt), strings.TrimSpace(b.Prompt)
+	prompt := -1.0
+	if pa != "" && pb != "" {
+		prompt = contentSimilarity(pa, pb)
+	}
+	if na == "" || nb == "" {
+		if prompt < 0 {
+			return 0, ""
+		}
+		return prompt, "提示词相似"
+	}
+
+	name, reason := 0.0, "名称相似"
+	switch {
+	case na == nb:
+		name, reason = 1, "名称相同"
+	case min(len([]rune(na)), len([]rune(nb))) >= 2 && (strings.Contains(na, nb) || strings.Contains(nb, na)):
+		name, reason = 0.85, "名称包含"
+	default:
+		name = contentSimilarity(na, nb)
+	}
+	if prompt < 0 {
+		return name, reason
+	}
+	score := 0.7*name + 0.3*prompt
+	// Near-identical descriptions under different names are still worth a look
+	if prompt >= 0.85 && prompt*0.9 > score {
+		return prompt * 0.9, "提示词相似"
+	}
+	return score, reason
+}
+
+// assetUsage counts the shots referencing each catalog entry, by catalog ID.
+func assetUsage(db *gorm.DB, catalogs []models.AssetCatalog) (map[uint]int, error) {
+	ids := make([]uint, 0, len(catalogs))
+	for _, c := range catalogs {
+		ids = append(ids, c.ID)
+	}
+	usage := map[uint]int{}
+	if len(ids) == 0 {
+		return usage, nil
+	}
+	var rows []struct {
+		CatalogID uint
+		Shots     int
+	}
+	if err := db.Model(&models.StoryboardAssetRef{}).Select("catalog_id, COUNT(DISTINCT storyboard_id) AS shots").
+		Where("catalog_id IN ?", ids).Group("catalog_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		usage[r.CatalogID] = r.Shots
+	}
+	return usage, nil
+}
+
+// ============================================================
+// Merge
+// ============================================================
+
+// MergeAssetCatalogs merges entries into the kept one: their versions move over, every shot
+// referencing them is rewritten to reference it, and they are deleted.
+func (a *App) MergeAssetCatalogs(params MergeAssetCatalogsParams) (*AssetMergeResult, error) {
+	var keep models.AssetCatalog
+	if err := models.DB.First(&keep, params.KeepID).Error; err != nil {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	var ids []uint
+	for _, id := range params.MergeIDs {
+		if id != keep.ID && indexOfUint(ids, id) < 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请选择要合并的资产")
+	}
+	var losers []models.AssetCatalog
+	if err := models.DB.Where("id IN ?", ids).Order("id asc").Find(&losers).Error; err != nil {
+		return nil, err
+	}
+	if len(losers) != len(ids) {
+		return nil, fmt.Errorf("asset catalog not found")
+	}
+	for _, c := range losers {
+		if c.ProjectID != keep.ProjectID || c.AssetType != keep.AssetType {
+			return nil, fmt.Errorf("只能合并同一项目中同类型的资产：%s", c.AssetCode)
+		}
+	}
+
+	result := &AssetMergeResult{KeepID: keep.ID, Merged: len(losers)}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		moved, err := moveAssetVersionsTx(tx, keep.ID, ids)
+		if err != nil {
+			return err
+		}
+		result.MovedVersions = moved
+
+		updates := map[string]interface{}{}
+		for _, c := range losers {
+			if strings.TrimSpace(keep.Name) == "" || keep.Name == keep.AssetCode {
+				if name := strings.TrimSpace(c.Name); name != "" && name != c.AssetCode {
+					keep.Name = name
+					updates["name"] = name
+				}
+			}
+			if strings.TrimSpace(keep.Prompt) == "" && strings.TrimSpace(c.Prompt) != "" {
+				keep.Prompt = strings.TrimSpace(c.Prompt)
+				updates["prompt"] = keep.Prompt
+			}
+			// The kept entry follows the library asset a merged one was linked to
+			if keep.LibraryAssetID == nil && c.LibraryAssetID != nil {
+				keep.LibraryAssetID = c.LibraryAssetID
+				updates["library_asset_id"] = *c.LibraryAssetID
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&keep).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		rewritten, err := rewriteAssetRefsTx(tx, keep.ID, ids)
+		if err != nil {
+			return err
+		}
+		result.RewrittenShots = rewritten
+
+		return tx.Where("id IN ?", ids).Delete(&models.AssetCatalog{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("合并资产失败：%w", err)
+	}
+	return result, nil
+}
+
+// moveAssetVersionsTx moves the versions of catalogs fromIDs to catalog toID, numbered after its
+// own versions in creation order. Only one version stays marked good: the kept entry's, or else
+// the most recent good one moved over.
+func moveAssetVersionsTx(tx *gorm.DB, toID uint, fromIDs []uint) (int, error) {
+	var versions []models.AssetVersion
+	if err := tx.Where("catalog_id IN ?", fromIDs).Order("created_at asc, id asc").Find(&versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	var maxNo int
+	if err := tx.Model(&models.AssetVersion{}).Where("catalog_id = ?", toID).Select("COALESCE(MAX(version_no),0)").Scan(&maxNo).Error; err != nil {
+		return 0, err
+	}
+	var goodCount int64
+	if err := tx.Model(&models.AssetVersion{}).Where("catalog_id = ? AND is_good = ?", toID, true).Count(&goodCount).Error; err != nil {
+		return 0, err
+	}
+	lastGood := -1
+	if goodCount == 0 {
+		for i, v := range versions {
+			if v.IsGood {
+				lastGood = i
+			}
+		}
+	}
+	for i, v := range versions {
+		if err := tx.Model(&models.AssetVersion{}).Where("id = ?", v.ID).Updates(map[string]interface{}{
+			"catalog_id": toID,
+			"version_no": maxNo + i + 1,
+			"is_good":    i == lastGood,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(versions), nil
+}
+
+// rewriteAssetRefsTx points the refs to catalogs fromIDs at catalog keepID. A shot referencing
+// several merged entries keeps one ref, which takes over the name and prompt overrides of the
+// others where it has none. Returns the number of shots changed.
+func rewriteAssetRefsTx(tx *gorm.DB, keepID uint, fromIDs []uint) (int, error) {
+	var refs []models.StoryboardAssetRef
+	if err := tx.Where("catalog_id IN ?", fromIDs).Order("storyboard_id asc, position asc, id asc").Find(&refs).Error; err != nil {
+		return 0, err
+	}
+	var kept []models.StoryboardAssetRef
+	if err := tx.Where("catalog_id = ?", keepID).Find(&kept).Error; err != nil {
+		return 0, err
+	}
+	has := map[uint]models.StoryboardAssetRef{}
+	for _, ref := range kept {
+		has[ref.StoryboardID] = ref
+	}
+	changed := map[uint]bool{}
+	for _, ref := range refs {
+		changed[ref.StoryboardID] = true
+		keep, ok := has[ref.StoryboardID]
+		if !ok {
+			if err := tx.Model(&models.StoryboardAssetRef{}).Where("id = ?", ref.ID).Update("catalog_id", keepID).Error; err != nil {
+				return 0, err
+			}
+			ref.CatalogID = keepID
+			has[ref.StoryboardID] = ref
+			continue
+		}
+		if err := tx.Delete(&models.StoryboardAssetRef{}, ref.ID).Error; err != nil {
+			return 0, err
+		}
+		updates := map[string]interface{}{}
+		if keep.NameOverride == "" && ref.NameOverride != "" {
+			keep.NameOverride = ref.NameOverride
+			updates["name_override"] = ref.NameOverride
+		}
+		if keep.PromptOverride == "" && ref.PromptOverride != "" {
+			keep.PromptOverride = ref.PromptOverride
+			updates["prompt_override"] = ref.PromptOverride
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&models.StoryboardAssetRef{}).Where("id = ?", keep.ID).Updates(updates).Error; err != nil {
+				return 0, err
+			}
+			has[ref.StoryboardID] = keep
+		}
+	}
+	return len(changed), nil
+}
@@ -0,0 +1,1091 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"seedance-client/config"
+	"seedance-client/models"
+	"seedance-client/services"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+// Shot change kinds in a decomposition changeset
+const (
+	shotChangeAdded     = "added"
+	shotChangeChanged   = "changed"
+	shotChangeRemoved   = "removed"
+	shotChangeUnchanged = "unchanged"
+)
+
+// shotMatchThreshold is the minimum frame-content similarity for pairing a draft shot
+// with an existing shot whose ShotNo does not match.
+const shotMatchThreshold = 0.6
+
+// DraftShot is a shot proposed by decomposition that has not been persisted.
+type DraftShot struct {
+	ShotNo            string      `json:"shot_no"`
+	ShotSize          string      `json:"shot_size"`
+	CameraMovement    string      `json:"camera_movement"`
+	FrameContent      string      `json:"frame_content"`
+	Characters        []EntityRef `json:"characters"`
+	Scenes            []EntityRef `json:"scenes"`
+	Elements          []EntityRef `json:"elements"`
+	Styles            []EntityRef `json:"styles"`
+	SoundDesign       string      `json:"sound_design"`
+	EstimatedDuration int         `json:"estimated_duration"`
+	// Extra holds fields defined by the decomposition template; nil leaves stored values alone.
+	Extra map[string]interface{} `json:"extra,omitempty"`
+}
+
+// ShotChange pairs a draft shot with an existing shot.
+// ID is stable for the same draft and project state and is what ApplyStoryboardChangeset selects.
+type ShotChange struct {
+	ID           string     `json:"id"`
+	Kind         string     `json:"kind"`          // added | changed | removed | unchanged
+	DraftIndex   int        `json:"draft_index"`   // -1 for removed shots
+	StoryboardID uint       `json:"storyboard_id"` // 0 for added shots
+	MatchedBy    string     `json:"matched_by"`    // shot_no | similarity, empty when unmatched
+	Similarity   float64    `json:"similarity"`
+	Fields       []string   `json:"fields"` // changed fields of a changed shot
+	Before       *DraftShot `json:"before,omitempty"`
+	After        *DraftShot `json:"after,omitempty"`
+	TakeCount    int        `json:"take_count"` // takes on the existing shot, lost if it is removed
+}
+
+// DecomposeChangeset is a decomposition result diffed against the project's current shots.
+type DecomposeChangeset struct {
+	ProjectID uint         `json:"project_id"`
+	Shots     []DraftShot  `json:"shots"`
+	Changes   []ShotChange `json:"changes"`
+	Added     int          `json:"added"`
+	Changed   int          `json:"changed"`
+	Removed   int          `json:"removed"`
+	Unchanged int          `json:"unchanged"`
+}
+
+// decomposeShotEvent is emitted for every shot as soon as it has streamed in.
+const decomposeShotEvent = "decompose:shot"
+
+// DecomposeShotEvent carries one streamed shot. Entity IDs are provisional until the
+// decomposition returns, because chunks are reconciled only at the end.
+type DecomposeShotEvent struct {
+	RequestID  string    `json:"request_id"`
+	Chunk      int       `json:"chunk"`       // 0-based chunk of the source text
+	ChunkCount int       `json:"chunk_count"` // number of chunks
+	Index      int       `json:"index"`       // 0-based position within the chunk
+	Shot       DraftShot `json:"shot"`
+}
+
+// ApplyChangesetParams applies the selected changes of a previewed changeset.
+type ApplyChangesetParams struct {
+	ProjectID uint        `json:"project_id"`
+	Shots     []DraftShot `json:"shots"`    // the draft returned by the preview
+	Selected  []string    `json:"selected"` // ShotChange IDs to apply
+}
+
+// ============================================================
+// Decomposition Preview & Apply
+// ============================================================
+
+// PreviewStoryboardDecomposition runs the LLM decomposition and diffs the result against
+// the existing shots without writing anything.
+func (a *App) PreviewStoryboardDecomposition(params DecomposeStoryboardParams) (*DecomposeChangeset, error) {
+	shots, err := a.decomposeToDraft(&params)
+	if err != nil {
+		return nil, err
+	}
+	return buildDecomposeChangeset(params.ProjectID, shots)
+}
+
+// ApplyStoryboardChangeset applies the selected changes of a preview. The diff is recomputed
+// against the current shots, so a selection made on an outdated preview is rejected.
+// Matched shots are updated in place and keep their takes and frame versions.
+func (a *App) ApplyStoryboardChangeset(params ApplyChangesetParams) (*V1WorkspaceData, error) {
+	if params.ProjectID == 0 {
+		return nil, fmt.Errorf("project_id 不能为空")
+	}
+	cs, err := buildDecomposeChangeset(params.ProjectID, params.Shots)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]ShotChange{}
+	for _, c := range cs.Changes {
+		byID[c.ID] = c
+	}
+	selected := make([]ShotChange, 0, len(params.Selected))
+	for _, id := range params.Selected {
+		c, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("变更 %s 已失效：分镜在预览后发生了变化，请重新预览", id)
+		}
+		if c.Kind != shotChangeUnchanged {
+			selected = append(selected, c)
+		}
+	}
+
+	if err := applyDecomposeChanges(params.ProjectID, cs, selected); err != nil {
+		return nil, err
+	}
+	return a.GetV1Workspace(params.ProjectID)
+}
+
+// CancelDecomposition aborts a running decomposition started with the given request_id.
+// The pending DecomposeStoryboardWithLLM / PreviewStoryboardDecomposition call returns an error.
+func (a *App) CancelDecomposition(requestID string) error {
+	a.decomposeMu.Lock()
+	cancel, ok := a.decomposeJobs[requestID]
+	a.decomposeMu.Unlock()
+	if !ok {
+		return fmt.Errorf("拆解任务不存在或已结束")
+	}
+	cancel()
+	return nil
+}
+
+// beginDecompose registers a cancellable decomposition; the returned func must be called when it ends.
+func (a *App) beginDecompose(requestID string) (context.Context, func()) {
+	base := a.ctx
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithCancel(base)
+	if requestID == "" {
+		return ctx, cancel
+	}
+	a.decomposeMu.Lock()
+	if a.decomposeJobs == nil {
+		a.decomposeJobs = map[string]context.CancelFunc{}
+	}
+	a.decomposeJobs[requestID] = cancel
+	a.decomposeMu.Unlock()
+	return ctx, func() {
+		a.decomposeMu.Lock()
+		delete(a.decomposeJobs, requestID)
+		a.decomposeMu.Unlock()
+		cancel()
+	}
+}
+
+// decomposeToDraft validates params, calls the LLM and normalizes the shots it returns.
+// Shots are emitted as "decompose:shot" events while the response streams in.
+func (a *App) decomposeToDraft(params *DecomposeStoryboardParams) ([]DraftShot, error) {
+	if params.ProjectID == 0 {
+		return nil, fmt.Errorf("project_id is required")
+	}
+	if strings.TrimSpace(params.SourceText) == "" {
+		return nil, fmt.Errorf("source_text is required")
+	}
+	settings, err := resolveLLMSettings(params.ProviderProfileID, llmSettings{
+		Provider: params.Provider,
+		APIKey:   params.APIKey,
+		BaseURL:  params.BaseURL,
+		ModelID:  params.LLMModelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	params.Provider, params.APIKey, params.BaseURL, params.LLMModelID = settings.Provider, settings.APIKey, settings.BaseURL, settings.ModelID
+
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	template, err := resolveDecomposeTemplate(params.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	prompt := newDecomposePrompt(*template, project)
+	if err := models.DB.Where("project_id = ?", project.ID).Order("id asc").Find(&prompt.catalogs).Error; err != nil {
+		return nil, fmt.Errorf("加载资产目录失败：%w", err)
+	}
+
+	ctx, done := a.beginDecompose(params.RequestID)
+	defer done()
+
+	var emitMu sync.Mutex
+	emitted := map[int]int{}
+	onShot := func(chunk, chunks int, shot llmDecomposeShot) {
+		if a.ctx == nil {
+			return
+		}
+		emitMu.Lock()
+		index := emitted[chunk]
+		emitted[chunk]++
+		emitMu.Unlock()
+		wailsRuntime.EventsEmit(a.ctx, decomposeShotEvent, DecomposeShotEvent{
+			RequestID:  params.RequestID,
+			Chunk:      chunk,
+			ChunkCount: chunks,
+			Index:      index,
+			Shot:       draftFromLLMShot(shot, index+1),
+		})
+	}
+
+	decoded, err := a.callStoryboardDecomposeLLM(ctx, params.Provider, params.APIKey, params.BaseURL, params.LLMModelID, prompt, params.SourceText, onShot)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded.Shots) == 0 {
+		return nil, fmt.Errorf("no shots returned by LLM")
+	}
+
+	shots := make([]DraftShot, 0, len(decoded.Shots))
+	for i, shot := range decoded.Shots {
+		shot.EstimatedDuration = nearestDuration(shot.EstimatedDuration, template.DurationOptions)
+		shots = append(shots, draftFromLLMShot(shot, i+1))
+	}
+	return shots, nil
+}
+
+func draftFromLLMShot(shot llmDecomposeShot, index int) DraftShot {
+	return DraftShot{
+		ShotNo:            shot.ShotNo,
+		ShotSize:          shot.ShotSize,
+		CameraMovement:    shot.CameraMovement,
+		FrameContent:      shot.FrameContent,
+		Characters:        normalizeRefs("character", shot.Characters, index),
+		Scenes:            normalizeRefs("scene", shot.Scenes, index),
+		Elements:          normalizeRefs("element", shot.SpecialElements, index),
+		Styles:            normalizeRefs("style", shot.VisualStyle, index),
+		SoundDesign:       shot.SoundDesign,
+		EstimatedDuration: shot.EstimatedDuration,
+		Extra:             shot.Extra,
+	}
+}
+
+// nearestDuration snaps v to the closest allowed duration.
+func nearestDuration(v int, options []int) int {
+	if len(options) == 0 {
+		return normalizeDuration(v)
+	}
+	best := options[0]
+	for _, o := range options[1:] {
+		if abs(o-v) < abs(best-v) {
+			best = o
+		}
+	}
+	return best
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// normalizeDraftShot trims text fields and fills ref IDs, as done when shots are saved.
+func normalizeDraftShot(d DraftShot, index int) DraftShot {
+	return DraftShot{
+		ShotNo:            strings.TrimSpace(d.ShotNo),
+		ShotSize:          strings.TrimSpace(d.ShotSize),
+		CameraMovement:    strings.TrimSpace(d.CameraMovement),
+		FrameContent:      strings.TrimSpace(d.FrameContent),
+		Characters:        normalizeRefs("character", d.Characters, index),
+		Scenes:            normalizeRefs("scene", d.Scenes, index),
+		Elements:          normalizeRefs("element", d.Elements, index),
+		Styles:            normalizeRefs("style", d.Styles, index),
+		SoundDesign:       strings.TrimSpace(d.SoundDesign),
+		EstimatedDuration: normalizeDuration(d.EstimatedDuration),
+		Extra:             d.Extra,
+	}
+}
+
+// storyboardToDraft is a shot as a draft; refs are its refs by asset type (see loadShotRefs).
+func storyboardToDraft(sb models.Storyboard, refs map[string][]EntityRef) DraftShot {
+	return DraftShot{
+		ShotNo:            sb.ShotNo,
+		ShotSize:          sb.ShotSize,
+		CameraMovement:    sb.CameraMovement,
+		FrameContent:      sb.FrameContent,
+		Characters:        refs["character"],
+		Scenes:            refs["scene"],
+		Elements:          refs["element"],
+		Styles:            refs["style"],
+		SoundDesign:       sb.SoundDesign,
+		EstimatedDuration: normalizeDuration(sb.EstimatedDuration),
+		Extra:             parseShotExtra(sb.ExtraJSON),
+	}
+}
+
+// parseShotExtra decodes Storyboard.ExtraJSON; empty or invalid JSON yields nil.
+func parseShotExtra(raw string) map[string]interface{} {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var extra map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &extra); err != nil || len(extra) == 0 {
+		return nil
+	}
+	return extra
+}
+
+func shotExtraJSON(extra map[string]interface{}) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extra)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// buildDecomposeChangeset matches draft shots to existing shots, first by ShotNo and then by
+// frame-content similarity, and classifies every shot as added, changed, removed or unchanged.
+func buildDecomposeChangeset(projectID uint, shots []DraftShot) (*DecomposeChangeset, error) {
+	if len(shots) == 0 {
+		return nil, fmt.Errorf("草稿中没有分镜")
+	}
+	var project models.Project
+	if err := models.DB.Preload("Storyboards.Takes").First(&project, projectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	existing := project.Storyboards
+	sort.SliceStable(existing, func(i, j int) bool {
+		if existing[i].ShotOrder != existing[j].ShotOrder {
+			return existing[i].ShotOrder < existing[j].ShotOrder
+		}
+		return existing[i].ID < existing[j].ID
+	})
+	refs, err := loadShotRefs(models.DB, storyboardIDs(existing))
+	if err != nil {
+		return nil, fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+
+	drafts := make([]DraftShot, len(shots))
+	for i, s := range shots {
+		drafts[i] = normalizeDraftShot(s, i+1)
+	}
+
+	draftMatch := make([]int, len(drafts)) // index into existing, -1 if unmatched
+	for i := range draftMatch {
+		draftMatch[i] = -1
+	}
+	matchedBy := make([]string, len(drafts))
+	similarity := make([]float64, len(drafts))
+	taken := make([]bool, len(existing))
+
+	// 1) Unique ShotNo matches
+	byShotNo := map[string][]int{}
+	for j, sb := range existing {
+		if key := shotNoKey(sb.ShotNo); key != "" {
+			byShotNo[key] = append(byShotNo[key], j)
+		}
+	}
+	draftShotNos := map[string]int{}
+	for _, d := range drafts {
+		draftShotNos[shotNoKey(d.ShotNo)]++
+	}
+	for i, d := range drafts {
+		key := shotNoKey(d.ShotNo)
+		if key == "" || draftShotNos[key] != 1 || len(byShotNo[key]) != 1 {
+			continue
+		}
+		j := byShotNo[key][0]
+		draftMatch[i] = j
+		taken[j] = true
+		matchedBy[i] = "shot_no"
+		similarity[i] = contentSimilarity(d.FrameContent, existing[j].FrameContent)
+	}
+
+	// 2) Greedy best-first pairing of the rest by frame content
+	type candidate struct {
+		draft, existing int
+		score           float64
+	}
+	var candidates []candidate
+	for i, d := range drafts {
+		if draftMatch[i] >= 0 {
+			continue
+		}
+		for j, sb := range existing {
+			if taken[j] {
+				continue
+			}
+			if score := contentSimilarity(d.FrameContent, sb.FrameContent); score >= shotMatchThreshold {
+				candidates = append(candidates, candidate{i, j, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+	for _, c := range candidates {
+		if draftMatch[c.draft] >= 0 || taken[c.existing] {
+			continue
+		}
+		draftMatch[c.draft] = c.existing
+		taken[c.existing] = true
+		matchedBy[c.draft] = "similarity"
+		similarity[c.draft] = c.score
+	}
+
+	cs := &DecomposeChangeset{ProjectID: projectID, Shots: drafts}
+	for i := range drafts {
+		after := drafts[i]
+		j := draftMatch[i]
+		if j < 0 {
+			cs.Changes = append(cs.Changes, ShotChange{
+				ID:         fmt.Sprintf("add:%d", i),
+				Kind:       shotChangeAdded,
+				DraftIndex: i,
+				After:      &after,
+			})
+			cs.Added++
+			continue
+		}
+		sb := existing[j]
+		before := storyboardToDraft(sb, refs[sb.ID])
+		change := ShotChange{
+			DraftIndex:   i,
+			StoryboardID: sb.ID,
+			MatchedBy:    matchedBy[i],
+			Similarity:   roundSimilarity(similarity[i]),
+			Fields:       diffDraftShots(before, after),
+			Before:       &before,
+			After:        &after,
+			TakeCount:    len(sb.Takes),
+		}
+		if len(change.Fields) == 0 {
+			change.ID = fmt.Sprintf("keep:%d", sb.ID)
+			change.Kind = shotChangeUnchanged
+			cs.Unchanged++
+		} else {
+			change.ID = fmt.Sprintf("change:%d", sb.ID)
+			change.Kind = shotChangeChanged
+			cs.Changed++
+		}
+		cs.Changes = append(cs.Changes, change)
+	}
+	for j, sb := range existing {
+		if taken[j] {
+			continue
+		}
+		before := storyboardToDraft(sb, refs[sb.ID])
+		cs.Changes = append(cs.Changes, ShotChange{
+			ID:           fmt.Sprintf("remove:%d", sb.ID),
+			Kind:         shotChangeRemoved,
+			DraftIndex:   -1,
+			StoryboardID: sb.ID,
+			Before:       &before,
+			TakeCount:    len(sb.Takes),
+		})
+		cs.Removed++
+	}
+	return cs, nil
+}
+
+// applyDecomposeChanges writes the selected changes in one transaction.
+// Existing shots keep their relative order; added shots are placed after the shot
+// that precedes them in the draft.
+func applyDecomposeChanges(projectID uint, cs *DecomposeChangeset, selected []ShotChange) error {
+	if len(selected) == 0 {
+		return nil
+	}
+	var project models.Project
+	if err := models.DB.First(&project, projectID).Error; err != nil {
+		return fmt.Errorf("project not found")
+	}
+
+	tx := models.DB.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("数据库事务启动失败：%w", tx.Error)
+	}
+
+	var current []models.Storyboard
+	if err := tx.Where("project_id = ?", projectID).Order("shot_order asc, id asc").Find(&current).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("加载分镜失败：%w", err)
+	}
+	order := make([]uint, 0, len(current))
+	for _, sb := range current {
+		order = append(order, sb.ID)
+	}
+
+	// Draft index -> storyboard ID, for anchoring added shots
+	resolved := map[int]uint{}
+	for _, c := range cs.Changes {
+		if c.Kind == shotChangeChanged || c.Kind == shotChangeUnchanged {
+			resolved[c.DraftIndex] = c.StoryboardID
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].DraftIndex < selected[j].DraftIndex })
+	for _, c := range selected {
+		switch c.Kind {
+		case shotChangeChanged:
+			var sb models.Storyboard
+			if err := tx.Where("id = ? AND project_id = ?", c.StoryboardID, projectID).First(&sb).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("分镜 %d 不存在：%w", c.StoryboardID, err)
+			}
+			if err := updateStoryboardFromDraftTx(tx, &sb, cs.Shots[c.DraftIndex]); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+		case shotChangeAdded:
+			sb, err := createStoryboardFromDraftTx(tx, project, cs.Shots[c.DraftIndex], 0)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			resolved[c.DraftIndex] = sb.ID
+			anchor := -1
+			for k := c.DraftIndex - 1; k >= 0 && anchor < 0; k-- {
+				if id, ok := resolved[k]; ok {
+					anchor = indexOfUint(order, id)
+				}
+			}
+			order = append(order, 0)
+			copy(order[anchor+2:], order[anchor+1:])
+			order[anchor+1] = sb.ID
+
+		case shotChangeRemoved:
+			if err := tx.Where("storyboard_id = ?", c.StoryboardID).Delete(&models.ShotFrameVersion{}).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("删除分镜首尾帧版本失败：%w", err)
+			}
+			if err := tx.Where("storyboard_id = ?", c.StoryboardID).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("删除分镜资产引用失败：%w", err)
+			}
+			if err := tx.Where("id = ? AND project_id = ?", c.StoryboardID, projectID).Delete(&models.Storyboard{}).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("删除分镜失败：%w", err)
+			}
+			if k := indexOfUint(order, c.StoryboardID); k >= 0 {
+				order = append(order[:k], order[k+1:]...)
+			}
+		}
+	}
+
+	for i, id := range order {
+		if err := tx.Model(&models.Storyboard{}).Where("id = ?", id).Update("shot_order", i+1).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("重排分镜顺序失败：%w", err)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("提交变更失败：%w", err)
+	}
+	return nil
+}
+
+// createStoryboardFromDraftTx inserts a shot with its catalog refs and an initial draft take.
+func createStoryboardFromDraftTx(tx *gorm.DB, project models.Project, d DraftShot, shotOrder int) (models.Storyboard, error) {
+	now := time.Now()
+	sb := models.Storyboard{
+		ProjectID:         project.ID,
+		ShotOrder:         shotOrder,
+		ShotNo:            d.ShotNo,
+		ShotSize:          d.ShotSize,
+		CameraMovement:    d.CameraMovement,
+		FrameContent:      d.FrameContent,
+		SoundDesign:       d.SoundDesign,
+		EstimatedDuration: normalizeDuration(d.EstimatedDuration),
+		ExtraJSON:         shotExtraJSON(d.Extra),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := tx.Create(&sb).Error; err != nil {
+		return sb, err
+	}
+	if err := saveShotRefsTx(tx, project.ID, sb.ID, draftRefs(d)); err != nil {
+		return sb, err
+	}
+
+	ratio := strings.TrimSpace(project.AspectRatio)
+	if ratio == "" {
+		ratio = "16:9"
+	}
+	take := models.Take{
+		StoryboardID:   sb.ID,
+		Prompt:         composeShotPrompt(sb.FrameContent, d.Characters, d.Scenes, d.Elements, d.Styles, sb.SoundDesign),
+		ModelID:        defaultVideoModelID(),
+		Ratio:          ratio,
+		Duration:       sb.EstimatedDuration,
+		GenerateAudio:  false,
+		ServiceTier:    "standard",
+		GenerationMode: "standard",
+		Status:         "Draft",
+		CreatedAt:      now,
+	}
+	if err := tx.Create(&take).Error; err != nil {
+		return sb, err
+	}
+	return sb, nil
+}
+
+// updateStoryboardFromDraftTx overwrites a shot's metadata; takes and frame versions are untouched.
+func updateStoryboardFromDraftTx(tx *gorm.DB, sb *models.Storyboard, d DraftShot) error {
+	sb.ShotNo = d.ShotNo
+	sb.ShotSize = d.ShotSize
+	sb.CameraMovement = d.CameraMovement
+	sb.FrameContent = d.FrameContent
+	sb.SoundDesign = d.SoundDesign
+	sb.EstimatedDuration = normalizeDuration(d.EstimatedDuration)
+	if d.Extra != nil {
+		sb.ExtraJSON = shotExtraJSON(d.Extra)
+	}
+	sb.UpdatedAt = time.Now()
+	if err := tx.Save(sb).Error; err != nil {
+		return fmt.Errorf("保存分镜失败：%w", err)
+	}
+	return saveShotRefsTx(tx, sb.ProjectID, sb.ID, draftRefs(d))
+}
+
+// draftRefs is a draft's refs by asset type, for saveShotRefsTx.
+func draftRefs(d DraftShot) map[string][]EntityRef {
+	return map[string][]EntityRef{
+		"character": d.Characters,
+		"scene":     d.Scenes,
+		"element":   d.Elements,
+		"style":     d.Styles,
+	}
+}
+
+func defaultVideoModelID() string {
+	if m := config.GetDefaultModel(); m != nil && strings.TrimSpace(m.ID) != "" {
+		return strings.TrimSpace(m.ID)
+	}
+	return "doubao-seedance-1-5-pro-251215"
+}
+
+// diffDraftShots lists the fields that differ between two shots.
+func diffDraftShots(before, after DraftShot) []string {
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	add("shot_no", before.ShotNo != after.ShotNo)
+	add("shot_size", before.ShotSize != after.ShotSize)
+	add("camera_movement", before.CameraMovement != after.CameraMovement)
+	add("frame_content", before.FrameContent != after.FrameContent)
+	add("characters", !sameRefs(before.Characters, after.Characters))
+	add("scenes", !sameRefs(before.Scenes, after.Scenes))
+	add("elements", !sameRefs(before.Elements, after.Elements))
+	add("styles", !sameRefs(before.Styles, after.Styles))
+	add("sound_design", before.SoundDesign != after.SoundDesign)
+	add("estimated_duration", before.EstimatedDuration != after.EstimatedDuration)
+	add("extra", after.Extra != nil && shotExtraJSON(before.Extra) != shotExtraJSON(after.Extra))
+	return fields
+}
+
+func sameRefs(a, b []EntityRef) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// shotNoKey normalizes a shot number for matching: case, spaces and a leading '#' are ignored.
+func shotNoKey(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	return strings.TrimPrefix(s, "#")
+}
+
+// contentSimilarity is the Dice coefficient over character bigrams, ignoring whitespace
+// and punctuation. It works for CJK text, which has no word boundaries.
+func contentSimilarity(a, b string) float64 {
+	ga, gb := bigrams(a), bigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+	total := 0
+	for _, n := range ga {
+		total += n
+	}
+	for _, n := range gb {
+		total += n
+	}
+	common := 0
+	for g, n := range ga {
+		if m := gb[g]; m > 0 {
+			common += min(n, m)
+		}
+	}
+	return 2 * float64(common) / float64(total)
+}
+
+func bigrams(s string) map[string]int {
+	runes := make([]rune, 0, len(s))
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	out := map[string]int{}
+	if len(runes) == 1 {
+		out[string(runes)]++
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		out[string(runes[i:i+2])]++
+	}
+	return out
+}
+
+func roundSimilarity(v float64) float64 {
+	return float64(int(v*1000+0.5)) / 1000
+}
+
+func indexOfUint(list []uint, v uint) int {
+	for i, x := range list {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// ============================================================
+// Chunked Decomposition
+// ============================================================
+
+// Long sources are decomposed in chunks so a feature-length script fits the model's context window.
+const (
+	decomposeChunkRunes       = 6000
+	decomposeChunkConcurrency = 3
+)
+
+// decomposeShotFunc receives streamed shots; chunk is 0-based and chunks is the number of chunks.
+type decomposeShotFunc func(chunk, chunks int, shot llmDecomposeShot)
+
+// callStoryboardDecomposeLLM splits the source at scene boundaries, decomposes the chunks in
+// parallel and merges the shots in source order with entity IDs reconciled across chunks.
+// The first failing chunk cancels the others.
+func (a *App) callStoryboardDecomposeLLM(ctx context.Context, provider string, apiKey string, baseURL string, modelID string, prompt *decomposePrompt, sourceText string, onShot decomposeShotFunc) (*llmDecomposeResponse, error) {
+	chunks := services.SplitScriptChunks(sourceText, decomposeChunkRunes)
+	chunkShot := func(i int) func(llmDecomposeShot) {
+		if onShot == nil {
+			return nil
+		}
+		return func(shot llmDecomposeShot) { onShot(i, len(chunks), shot) }
+	}
+	if len(chunks) <= 1 {
+		decoded, err := a.decomposeChunkLLM(ctx, provider, apiKey, baseURL, modelID, prompt, sourceText, "", chunkShot(0))
+		if err != nil {
+			return nil, err
+		}
+		return mergeDecomposedChunks([]*llmDecomposeResponse{decoded}, prompt.catalogs), nil
+	}
+
+	chunkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]*llmDecomposeResponse, len(chunks))
+	sem := make(chan struct{}, decomposeChunkConcurrency)
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-chunkCtx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			res, err := a.decomposeChunkLLM(chunkCtx, provider, apiKey, baseURL, modelID, prompt, chunk, prompt.partNote(i, len(chunks)), chunkShot(i))
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("第 %d/%d 段拆解失败：%w", i+1, len(chunks), err)
+					cancel()
+				})
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, llmCancelled(ctx)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return mergeDecomposedChunks(results, prompt.catalogs), nil
+}
+
+// mergeDecomposedChunks concatenates chunk results and gives every distinct entity one ID.
+// Within a chunk the model's own IDs are trusted, so two scenes it kept apart stay apart. Across
+// chunks, entities are matched by name, since each chunk numbers its entities independently.
+// Entities named like an existing catalog entry take its code, and new entities never take the
+// code of an existing one. Shot numbers are renumbered when chunks restarted the numbering.
+func mergeDecomposedChunks(results []*llmDecomposeResponse, catalogs []models.AssetCatalog) *llmDecomposeResponse {
+	reconcilers := newEntityReconcilers(catalogs)
+
+	merged := &llmDecomposeResponse{}
+	var assigned []entityAssignment
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		for _, r := range reconcilers {
+			r.startChunk()
+		}
+		start := len(merged.Shots)
+		merged.Shots = append(merged.Shots, res.Shots...)
+		for i := start; i < len(merged.Shots); i++ {
+			shot := &merged.Shots[i]
+			assigned = reconcilers["character"].resolveAll(shot.Characters, assigned)
+			assigned = reconcilers["scene"].resolveAll(shot.Scenes, assigned)
+			assigned = reconcilers["element"].resolveAll(shot.SpecialElements, assigned)
+			assigned = reconcilers["style"].resolveAll(shot.VisualStyle, assigned)
+		}
+	}
+	// Canonical prompts may be filled in by later chunks, so refs are written only now
+	for _, a := range assigned {
+		*a.ref = a.rec.canon[a.index]
+	}
+	for i := range merged.Shots {
+		shot := &merged.Shots[i]
+		shot.Characters = mergeRefs(shot.Characters, nil)
+		shot.Scenes = mergeRefs(shot.Scenes, nil)
+		shot.SpecialElements = mergeRefs(shot.SpecialElements, nil)
+		shot.VisualStyle = mergeRefs(shot.VisualStyle, nil)
+	}
+
+	if len(results) > 1 {
+		seen := map[string]bool{}
+		duplicate := false
+		for _, shot := range merged.Shots {
+			key := shotNoKey(shot.ShotNo)
+			if key == "" || seen[key] {
+				duplicate = true
+				break
+			}
+			seen[key] = true
+		}
+		if duplicate {
+			for i := range merged.Shots {
+				merged.Shots[i].ShotNo = fmt.Sprintf("%d", i+1)
+			}
+		}
+	}
+	return merged
+}
+
+type entityAssignment struct {
+	ref   *EntityRef
+	rec   *entityReconciler
+	index int
+}
+
+// entityReconciler assigns canonical entities of one asset type across chunks.
+type entityReconciler struct {
+	prefix string
+	canon  []EntityRef
+	keys   []string        // normalized name of each canonical entity
+	used   map[string]bool // canonical IDs handed out
+	known  map[string]int  // catalog code -> canonical index; the model is given these codes
+	// per chunk state
+	local   map[string]int // chunk-local ID -> canonical index
+	claimed map[int]bool   // canonical entities already matched in this chunk
+}
+
+// newEntityReconcilers creates a reconciler per asset type, seeded with the project's catalog.
+func newEntityReconcilers(catalogs []models.AssetCatalog) map[string]*entityReconciler {
+	reconcilers := map[string]*entityReconciler{}
+	for _, t := range []string{"character", "scene", "element", "style"} {
+		reconcilers[t] = &entityReconciler{prefix: t, used: map[string]bool{}, known: map[string]int{}}
+		reconcilers[t].startChunk()
+	}
+	for _, c := range catalogs {
+		r, ok := reconcilers[c.AssetType]
+		if !ok {
+			continue
+		}
+		key := entityNameKey(c.Name)
+		if key == "" {
+			key = entityNameKey(c.AssetCode)
+		}
+		r.used[c.AssetCode] = true
+		r.known[c.AssetCode] = len(r.canon)
+		r.canon = append(r.canon, EntityRef{ID: c.AssetCode, Name: c.Name, Prompt: c.Prompt})
+		r.keys = append(r.keys, key)
+	}
+	return reconcilers
+}
+
+func (r *entityReconciler) startChunk() {
+	r.local = map[string]int{}
+	r.claimed = map[int]bool{}
+}
+
+func (r *entityReconciler) resolveAll(refs []EntityRef, assigned []entityAssignment) []entityAssignment {
+	for i := range refs {
+		assigned = append(assigned, entityAssignment{ref: &refs[i], rec: r, index: r.resolve(refs[i])})
+	}
+	return assigned
+}
+
+func (r *entityReconciler) resolve(ref EntityRef) int {
+	id := strings.TrimSpace(ref.ID)
+	name := strings.TrimSpace(ref.Name)
+	key := entityNameKey(name)
+	if key == "" {
+		key = entityNameKey(id)
+	}
+
+	idx := -1
+	if id != "" {
+		if i, ok := r.local[id]; ok {
+			idx = i
+		} else if i, ok := r.known[id]; ok {
+			idx = i
+		}
+	}
+	if idx < 0 && id == "" && key != "" {
+		// Refs without an ID join an entity of the same name already seen in this chunk
+		for i := range r.claimed {
+			if r.keys[i] == key {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 && key != "" {
+		for i, k := range r.keys {
+			if k == key && !r.claimed[i] {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 {
+		canonID := id
+		if canonID == "" || r.used[canonID] {
+			for n := len(r.canon) + 1; ; n++ {
+				canonID = fmt.Sprintf("%s_%02d", r.prefix, n)
+				if !r.used[canonID] {
+					break
+				}
+			}
+		}
+		if name == "" {
+			name = canonID
+		}
+		r.used[canonID] = true
+		r.canon = append(r.canon, EntityRef{ID: canonID, Name: name, Prompt: strings.TrimSpace(ref.Prompt)})
+		r.keys = append(r.keys, key)
+		idx = len(r.canon) - 1
+	} else if r.canon[idx].Prompt == "" {
+		r.canon[idx].Prompt = strings.TrimSpace(ref.Prompt)
+	}
+
+	r.claimed[idx] = true
+	if id != "" {
+		r.local[id] = idx
+	}
+	return idx
+}
+
+// entityNameKey normalizes an entity name for matching: case, spaces and punctuation are ignored.
+func entityNameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ============================================================
+// Output Validation
+// ============================================================
+
+// maxReportedProblems bounds the validation errors quoted in a re-ask or error message.
+const maxReportedProblems = 20
+
+// parseDecomposeOutput repairs, coerces and validates raw model output against the decomposition
+// schema. Problems that lenient repair cannot fix are returned for a re-ask.
+func parseDecomposeOutput(raw string, schema map[string]interface{}, extraFields []string) (*llmDecomposeResponse, []string) {
+	text, repairs := services.RepairJSON(raw)
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, []string{fmt.Sprintf("输出不是合法的 JSON：%v", err)}
+	}
+	value, fixes := services.CoerceJSONSchema(value, schema)
+	if len(repairs)+len(fixes) > 0 {
+		log.Printf("Decompose: repaired output: %s", strings.Join(append(repairs, fixes...), "; "))
+	}
+	if problems := services.ValidateJSONSchema(value, schema); len(problems) > 0 {
+		return nil, problems
+	}
+
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	var decoded llmDecomposeResponse
+	if err := json.Unmarshal(normalized, &decoded); err != nil {
+		return nil, []string{fmt.Sprintf("输出结构不符合要求：%v", err)}
+	}
+	if len(extraFields) > 0 {
+		shots, _ := value.(map[string]interface{})["shots"].([]interface{})
+		for i := range decoded.Shots {
+			obj, _ := shots[i].(map[string]interface{})
+			decoded.Shots[i].Extra = map[string]interface{}{}
+			for _, name := range extraFields {
+				decoded.Shots[i].Extra[name] = obj[name]
+			}
+		}
+	}
+
+	var problems []string
+	if len(decoded.Shots) == 0 {
+		problems = append(problems, "$.shots: 没有任何分镜")
+	}
+	for i, shot := range decoded.Shots {
+		if strings.TrimSpace(shot.FrameContent) == "" {
+			problems = append(problems, fmt.Sprintf("$.shots[%d].frame_content: 画面内容为空", i))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return &decoded, nil
+}
+
+// decomposeRepairPrompt asks the model to fix its previous output. The invalid output is quoted
+// so the model can keep what was right instead of starting over.
+func decomposeRepairPrompt(userPrompt string, previous string, problems []string) string {
+	const maxQuoted = 12000
+	quoted := previous
+	if runes := []rune(quoted); len(runes) > maxQuoted {
+		quoted = string(runes[:maxQuoted]) + "\n…（已截断）"
+	}
+	return fmt.Sprintf("%s\n\n你上一次的输出未通过 JSON schema 校验，问题如下：\n%s\n\n上一次的输出：\n%s\n\n请修正以上问题，重新输出完整、合法的 JSON，不要附加任何解释。",
+		userPrompt, "- "+strings.Join(limitProblems(problems), "\n- "), quoted)
+}
+
+func summarizeProblems(problems []string) string {
+	return strings.Join(limitProblems(problems), "；")
+}
+
+func limitProblems(problems []string) []string {
+	if len(problems) <= maxReportedProblems {
+		return problems
+	}
+	out := append([]string(nil), problems[:maxReportedProblems]...)
+	return append(out, fmt.Sprintf("……另有 %d 个问题", len(problems)-maxReportedProblems))
+}
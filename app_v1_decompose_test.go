@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"seedance-client/config"
+	"seedance-client/models"
+)
+
+// newTestDB points the data directory at a temporary directory and opens a fresh database.
+func newTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("SEEDANCE_DATA_DIR", t.TempDir())
+	config.InitDataDir()
+	models.InitDB()
+	t.Cleanup(func() {
+		if db, err := models.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+}
+
+// seedShots creates a project with one shot per frame content, in order, numbered 1..n.
+func seedShots(t *testing.T, contents ...string) (models.Project, []models.Storyboard) {
+	t.Helper()
+	project := models.Project{Name: "test", AspectRatio: "16:9"}
+	if err := models.DB.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	shots := make([]models.Storyboard, len(contents))
+	for i, c := range contents {
+		shots[i] = models.Storyboard{
+			ProjectID:         project.ID,
+			ShotOrder:         i + 1,
+			ShotNo:            fmt.Sprint(i + 1),
+			FrameContent:      c,
+			EstimatedDuration: 5,
+		}
+		if err := models.DB.Create(&shots[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return project, shots
+}
+
+// shotOrder lists a project's frame contents by shot order.
+func shotOrder(t *testing.T, projectID uint) []string {
+	t.Helper()
+	var rows []models.Storyboard
+	if err := models.DB.Where("project_id = ?", projectID).Order("shot_order asc").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(rows))
+	for i, sb := range rows {
+		out[i] = sb.FrameContent
+	}
+	return out
+}
+
+func TestBuildDecomposeChangeset(t *testing.T) {
+	existing := []string{
+		"男孩推开木门，走进昏暗的房间",
+		"女孩站在窗前望着窗外的大雨",
+		"老人坐在院子里慢慢地喝茶",
+	}
+	type want struct {
+		kind      string
+		existing  int // index into existing, -1 for added
+		matchedBy string
+		fields    []string
+	}
+	cases := []struct {
+		name   string
+		drafts []DraftShot
+		want   []want
+	}{
+		{
+			name: "unique shot numbers",
+			drafts: []DraftShot{
+				{ShotNo: "1", FrameContent: existing[0], EstimatedDuration: 5},
+				{ShotNo: " #2 ", FrameContent: "女孩转身离开窗边", EstimatedDuration: 5},
+				{ShotNo: "3", FrameContent: existing[2], ShotSize: "全景", EstimatedDuration: 5},
+			},
+			want: []want{
+				{shotChangeUnchanged, 0, "shot_no", nil},
+				{shotChangeChanged, 1, "shot_no", []string{"shot_no", "frame_content"}},
+				{shotChangeChanged, 2, "shot_no", []string{"shot_size"}},
+			},
+		},
+		{
+			name: "similarity fallback after renumbering",
+			drafts: []DraftShot{
+				{ShotNo: "10", FrameContent: "女孩站在窗前，望着窗外的大雨", EstimatedDuration: 5},
+				{ShotNo: "11", FrameContent: "一辆卡车在高速公路上飞驰", EstimatedDuration: 5},
+				{ShotNo: "12", FrameContent: "男孩推开木门走进昏暗的小房间", EstimatedDuration: 5},
+			},
+			want: []want{
+				{shotChangeChanged, 1, "similarity", []string{"shot_no", "frame_content"}},
+				{shotChangeAdded, -1, "", nil},
+				{shotChangeChanged, 0, "similarity", []string{"shot_no", "frame_content"}},
+				{shotChangeRemoved, 2, "", nil},
+			},
+		},
+		{
+			name: "duplicate draft shot numbers fall back to similarity",
+			drafts: []DraftShot{
+				{ShotNo: "1", FrameContent: "老人坐在院子里慢慢地喝茶", EstimatedDuration: 5},
+				{ShotNo: "1", FrameContent: "男孩推开木门，走进昏暗的房间", EstimatedDuration: 5},
+			},
+			want: []want{
+				{shotChangeChanged, 2, "similarity", []string{"shot_no"}},
+				{shotChangeUnchanged, 0, "similarity", nil},
+				{shotChangeRemoved, 1, "", nil},
+			},
+		},
+		{
+			name: "below the similarity threshold",
+			drafts: []DraftShot{
+				{ShotNo: "A", FrameContent: "男孩在雨中奔跑", EstimatedDuration: 5},
+			},
+			want: []want{
+				{shotChangeAdded, -1, "", nil},
+				{shotChangeRemoved, 0, "", nil},
+				{shotChangeRemoved, 1, "", nil},
+				{shotChangeRemoved, 2, "", nil},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestDB(t)
+			project, shots := seedShots(t, existing...)
+			cs, err := buildDecomposeChangeset(project.ID, tc.drafts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cs.Changes) != len(tc.want) {
+				t.Fatalf("%d changes, want %d: %+v", len(cs.Changes), len(tc.want), cs.Changes)
+			}
+			for i, c := range cs.Changes {
+				w := tc.want[i]
+				var id uint
+				if w.existing >= 0 {
+					id = shots[w.existing].ID
+				}
+				if c.Kind != w.kind || c.StoryboardID != id || c.MatchedBy != w.matchedBy || !reflect.DeepEqual(c.Fields, w.fields) {
+					t.Errorf("change %d = %s %d %q %v, want %s %d %q %v", i, c.Kind, c.StoryboardID, c.MatchedBy, c.Fields, w.kind, id, w.matchedBy, w.fields)
+				}
+				if c.MatchedBy == "similarity" && c.Similarity < shotMatchThreshold {
+					t.Errorf("change %d matched at similarity %v", i, c.Similarity)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDecomposeChanges(t *testing.T) {
+	existing := []string{"甲", "乙", "丙"}
+	drafts := []DraftShot{
+		{ShotNo: "0", FrameContent: "开场", EstimatedDuration: 5},
+		{ShotNo: "1", FrameContent: "甲", EstimatedDuration: 5},
+		{ShotNo: "x", FrameContent: "插入一", EstimatedDuration: 5},
+		{ShotNo: "y", FrameContent: "插入二", EstimatedDuration: 5},
+		{ShotNo: "3", FrameContent: "丙改", EstimatedDuration: 5},
+	}
+	cases := []struct {
+		name     string
+		selected []string // change kinds and draft contents, "remove" for the removed shot
+		want     []string
+	}{
+		{
+			name:     "everything",
+			selected: []string{"add:开场", "add:插入一", "add:插入二", "change:丙改", "remove"},
+			want:     []string{"开场", "甲", "插入一", "插入二", "丙改"},
+		},
+		{
+			name:     "additions only",
+			selected: []string{"add:插入一", "add:开场"},
+			want:     []string{"开场", "甲", "插入一", "乙", "丙"},
+		},
+		{
+			name:     "anchor skips unselected additions",
+			selected: []string{"add:插入二"},
+			want:     []string{"甲", "插入二", "乙", "丙"},
+		},
+		{
+			name:     "change and remove keep the rest in order",
+			selected: []string{"remove", "change:丙改"},
+			want:     []string{"甲", "丙改"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestDB(t)
+			project, _ := seedShots(t, existing...)
+			cs, err := buildDecomposeChangeset(project.ID, drafts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var selected []ShotChange
+			for _, key := range tc.selected {
+				found := false
+				for _, c := range cs.Changes {
+					var k string
+					switch c.Kind {
+					case shotChangeAdded:
+						k = "add:" + c.After.FrameContent
+					case shotChangeChanged:
+						k = "change:" + c.After.FrameContent
+					case shotChangeRemoved:
+						k = "remove"
+					}
+					if k == key {
+						selected = append(selected, c)
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("no change %q in %+v", key, cs.Changes)
+				}
+			}
+			if err := applyDecomposeChanges(project.ID, cs, selected); err != nil {
+				t.Fatal(err)
+			}
+			if got := shotOrder(t, project.ID); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("shots = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeDecomposedChunks(t *testing.T) {
+	ref := func(id, name, prompt string) EntityRef { return EntityRef{ID: id, Name: name, Prompt: prompt} }
+	catalogs := []models.AssetCatalog{
+		{AssetType: "character", AssetCode: "character_01", Name: "王五", Prompt: "灰色长衫"},
+	}
+	results := []*llmDecomposeResponse{
+		{Shots: []llmDecomposeShot{
+			{ShotNo: "1", Characters: []EntityRef{ref("character_02", "张三", ""), ref("c9", "王 五", "")}, Scenes: []EntityRef{ref("scene_01", "街道", "白天")}},
+			{ShotNo: "2", Characters: []EntityRef{ref("", "张三", "")}, Scenes: []EntityRef{ref("scene_02", "街道", "夜晚")}},
+		}},
+		nil, // a chunk without result is skipped
+		{Shots: []llmDecomposeShot{
+			{ShotNo: "1", Characters: []EntityRef{ref("character_02", "李四", ""), ref("character_03", "张三", "黑色风衣")}},
+			{ShotNo: "2", Scenes: []EntityRef{ref("scene_01", "街道", "")}, Characters: []EntityRef{ref("", "赵六", "")}},
+		}},
+	}
+	merged := mergeDecomposedChunks(results, catalogs)
+
+	want := []llmDecomposeShot{
+		{
+			ShotNo:     "1",
+			Characters: []EntityRef{ref("character_02", "张三", "黑色风衣"), ref("character_01", "王五", "灰色长衫")},
+			Scenes:     []EntityRef{ref("scene_01", "街道", "白天")},
+		},
+		{
+			ShotNo:     "2",
+			Characters: []EntityRef{ref("character_02", "张三", "黑色风衣")},
+			Scenes:     []EntityRef{ref("scene_02", "街道", "夜晚")},
+		},
+		{
+			ShotNo:     "3",
+			Characters: []EntityRef{ref("character_03", "李四", ""), ref("character_02", "张三", "黑色风衣")},
+		},
+		{
+			ShotNo:     "4",
+			Characters: []EntityRef{ref("character_04", "赵六", "")},
+			Scenes:     []EntityRef{ref("scene_01", "街道", "白天")},
+		},
+	}
+	if len(merged.Shots) != len(want) {
+		t.Fatalf("%d shots, want %d", len(merged.Shots), len(want))
+	}
+	for i, got := range merged.Shots {
+		w := want[i]
+		if got.ShotNo != w.ShotNo || !sameRefs(got.Characters, w.Characters) || !sameRefs(got.Scenes, w.Scenes) {
+			t.Errorf("shot %d = %s %+v %+v\nwant %s %+v %+v", i, got.ShotNo, got.Characters, got.Scenes, w.ShotNo, w.Characters, w.Scenes)
+		}
+	}
+}
+
+func TestMergeDecomposedChunksShotNumbers(t *testing.T) {
+	chunk := func(nos ...string) *llmDecomposeResponse {
+		res := &llmDecomposeResponse{}
+		for _, no := range nos {
+			res.Shots = append(res.Shots, llmDecomposeShot{ShotNo: no})
+		}
+		return res
+	}
+	cases := []struct {
+		name   string
+		chunks []*llmDecomposeResponse
+		want   []string
+	}{
+		{"single chunk keeps numbers", []*llmDecomposeResponse{chunk("3", "3", "")}, []string{"3", "3", ""}},
+		{"continued numbering is kept", []*llmDecomposeResponse{chunk("1", "2"), chunk("3A", "4")}, []string{"1", "2", "3A", "4"}},
+		{"restarted numbering is renumbered", []*llmDecomposeResponse{chunk("1", "2"), chunk("#1", "2")}, []string{"1", "2", "3", "4"}},
+		{"missing number is renumbered", []*llmDecomposeResponse{chunk("1", "2"), chunk("", "9")}, []string{"1", "2", "3", "4"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeDecomposedChunks(tc.chunks, nil)
+			var got []string
+			for _, s := range merged.Shots {
+				got = append(got, s.ShotNo)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("shot numbers = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
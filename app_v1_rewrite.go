@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"seedance-client/models"
+
+	"gorm.io/gorm"
+)
+
+// shotRewriteInstructions are the preset rewrite actions; "custom" takes free text instead.
+var shotRewriteInstructions = map[string]string{
+	"cinematic":    "让这个分镜更有电影感：丰富景别、运镜、构图、光影与氛围的描写，不改变剧情、人物和动作。",
+	"translate_en": "把所有描述性文本（shot_size、camera_movement、frame_content、sound_design，以及角色/场景/元素/风格的 prompt）翻译成英文，内容不增不减；实体的 id 和 name 不要改。",
+	"split_beats":  "把这个分镜拆成前后两个连续的节拍，输出 2 个分镜：每个分镜描述一个节拍，人物与场景保持连贯，两者时长之和接近原时长。",
+}
+
+type RewriteShotParams struct {
+	StoryboardID uint   `json:"storyboard_id"`
+	Action       string `json:"action"` // cinematic | translate_en | split_beats | custom
+	// Instruction is required for "custom"; for the presets it is added as an extra note.
+	Instruction       string `json:"instruction"`
+	LLMModelID        string `json:"llm_model_id"`
+	Provider          string `json:"provider"`
+	APIKey            string `json:"api_key"`
+	BaseURL           string `json:"base_url"`
+	ProviderProfileID uint   `json:"provider_profile_id"`
+	// TemplateID selects the decomposition template whose prompt, extra fields and durations the
+	// rewrite follows, normally the one the shots were decomposed with; 0 uses the default one.
+	TemplateID uint `json:"template_id"`
+	// RequestID identifies the request for CancelDecomposition.
+	RequestID string `json:"request_id"`
+}
+
+// ShotRewriteProposal is an edit of one shot proposed by the LLM; nothing is saved until it is
+// accepted. Proposed is applied with UpdateShotMetadata. For split_beats, SplitShot creates the
+// second shot first, and Second is then applied to it.
+type ShotRewriteProposal struct {
+	StoryboardID  uint              `json:"storyboard_id"`
+	Action        string            `json:"action"`
+	Before        DraftShot         `json:"before"`
+	Proposed      UpdateShotParams  `json:"proposed"`
+	ChangedFields []string          `json:"changed_fields"`
+	Second        *UpdateShotParams `json:"second,omitempty"`
+}
+
+// rewriteNeighbour is the context sent for the shots before and after the rewritten one.
+type rewriteNeighbour struct {
+	ShotNo            string `json:"shot_no"`
+	ShotSize          string `json:"shot_size"`
+	CameraMovement    string `json:"camera_movement"`
+	FrameContent      string `json:"frame_content"`
+	EstimatedDuration int    `json:"estimated_duration"`
+}
+
+// ============================================================
+// Shot Rewrite
+// ============================================================
+
+// RewriteShotWithLLM asks the LLM to rewrite one shot, with its neighbours as context, and returns
+// the proposed change without saving it.
+func (a *App) RewriteShotWithLLM(params RewriteShotParams) (*ShotRewriteProposal, error) {
+	if params.StoryboardID == 0 {
+		return nil, fmt.Errorf("storyboard_id 不能为空")
+	}
+	instruction, err := shotRewriteInstruction(params.Action, params.Instruction)
+	if err != nil {
+		return nil, err
+	}
+	var sb models.Storyboard
+	if err := models.DB.First(&sb, params.StoryboardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("分镜不存在")
+		}
+		return nil, fmt.Errorf("加载分镜失败：%w", err)
+	}
+	refs, err := shotRefs(models.DB, sb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+	var project models.Project
+	if err := models.DB.First(&project, sb.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	settings, err := resolveLLMSettings(params.ProviderProfileID, llmSettings{
+		Provider: params.Provider,
+		APIKey:   params.APIKey,
+		BaseURL:  params.BaseURL,
+		ModelID:  params.LLMModelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	template, err := resolveDecomposeTemplate(params.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	prompt := newDecomposePrompt(*template, project)
+
+	split := params.Action == "split_beats"
+	ctx, done := a.beginDecompose(params.RequestID)
+	defer done()
+	decoded, err := a.requestShotsLLM(ctx, settings.Provider, settings.APIKey, settings.BaseURL, settings.ModelID,
+		shotRewriteSystemPrompt(prompt), shotRewriteUserPrompt(sb, refs, neighbourShot(sb, true), neighbourShot(sb, false), instruction, split),
+		prompt.schema(), prompt.extraFieldNames(), nil)
+	if err != nil {
+		return nil, err
+	}
+	want := 1
+	if split {
+		want = 2
+	}
+	if len(decoded.Shots) != want {
+		return nil, fmt.Errorf("[E_LLM_INVALID_OUTPUT] 改写结果应为 %d 个分镜，实际为 %d 个", want, len(decoded.Shots))
+	}
+
+	if split {
+		// The halves share the original duration; the model's estimates only set the proportion
+		first, second := splitDuration(normalizeDuration(sb.EstimatedDuration), decoded.Shots[0].EstimatedDuration, decoded.Shots[1].EstimatedDuration)
+		decoded.Shots[0].EstimatedDuration, decoded.Shots[1].EstimatedDuration = first, second
+	} else {
+		decoded.Shots[0].EstimatedDuration = nearestDuration(decoded.Shots[0].EstimatedDuration, template.DurationOptions)
+	}
+	drafts := make([]DraftShot, len(decoded.Shots))
+	for i, shot := range decoded.Shots {
+		drafts[i] = normalizeDraftShot(draftFromLLMShot(shot, sb.ShotOrder), sb.ShotOrder)
+	}
+	before := storyboardToDraft(sb, refs)
+	proposal := &ShotRewriteProposal{
+		StoryboardID:  sb.ID,
+		Action:        params.Action,
+		Before:        before,
+		Proposed:      draftToUpdateParams(sb.ID, drafts[0], sb.DurationFine),
+		ChangedFields: diffDraftShots(before, drafts[0]),
+	}
+	if split {
+		second := draftToUpdateParams(0, drafts[1], sb.DurationFine)
+		if second.ShotNo == "" || shotNoKey(second.ShotNo) == shotNoKey(proposal.Proposed.ShotNo) {
+			second.ShotNo = proposal.Proposed.ShotNo + ".B"
+		}
+		proposal.Second = &second
+	}
+	return proposal, nil
+}
+
+// splitDuration divides total seconds between two shots in proportion to the estimates a and b,
+// keeping both at least minShotDuration long. Shots too short to split get the minimum each.
+func splitDuration(total, a, b int) (int, int) {
+	if total < 2*minShotDuration {
+		return minShotDuration, minShotDuration
+	}
+	if a <= 0 || b <= 0 {
+		a, b = 1, 1
+	}
+	first := (total*a + (a+b)/2) / (a + b)
+	first = max(minShotDuration, min(first, total-minShotDuration))
+	return first, total - first
+}
+
+// neighbourShot returns the shot right before (or after) sb, or nil at the ends.
+func neighbourShot(sb models.Storyboard, before bool) *models.Storyboard {
+	query := models.DB.Where("project_id = ?", sb.ProjectID)
+	if before {
+		query = query.Where("shot_order < ?", sb.ShotOrder).Order("shot_order desc")
+	} else {
+		query = query.Where("shot_order > ?", sb.ShotOrder).Order("shot_order asc")
+	}
+	var n models.Storyboard
+	if err := query.First(&n).Error; err != nil {
+		return nil
+	}
+	return &n
+}
+
+func shotRewriteInstruction(action string, note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if action == "custom" {
+		if note == "" {
+			return "", fmt.Errorf("请填写改写指令")
+		}
+		return note, nil
+	}
+	instruction, ok := shotRewriteInstructions[action]
+	if !ok {
+		return "", fmt.Errorf("不支持的改写方式：%s", action)
+	}
+	if note != "" {
+		instruction += "\n补充要求：" + note
+	}
+	return instruction, nil
+}
+
+func shotRewriteSystemPrompt(prompt *decomposePrompt) string {
+	return "你是专业的影视分镜师，负责按照指令改写单个分镜。上一镜和下一镜仅用于保持连贯，不要改写它们。" +
+		"除非指令要求，保持原文的语言；指令没有涉及的字段保持原值。只输出 JSON，不要附加任何解释。" +
+		prompt.extraFieldsNote()
+}
+
+func shotRewriteUserPrompt(sb models.Storyboard, refs map[string][]EntityRef, prev, next *models.Storyboard, instruction string, split bool) string {
+	var b strings.Builder
+	b.WriteString("当前分镜：\n")
+	b.WriteString(shotPromptJSON(sb, refs))
+	for _, n := range []struct {
+		label string
+		sb    *models.Storyboard
+	}{{"上一镜", prev}, {"下一镜", next}} {
+		if n.sb == nil {
+			fmt.Fprintf(&b, "\n\n%s：无", n.label)
+			continue
+		}
+		ctxJSON, _ := json.Marshal(rewriteNeighbour{
+			ShotNo:            n.sb.ShotNo,
+			ShotSize:          n.sb.ShotSize,
+			CameraMovement:    n.sb.CameraMovement,
+			FrameContent:      n.sb.FrameContent,
+			EstimatedDuration: n.sb.EstimatedDuration,
+		})
+		fmt.Fprintf(&b, "\n\n%s（仅供参考）：\n%s", n.label, ctxJSON)
+	}
+	fmt.Fprintf(&b, "\n\n指令：%s", instruction)
+	count := "1 个分镜"
+	if split {
+		count = "2 个分镜"
+	}
+	fmt.Fprintf(&b, "\n\n请输出 {\"shots\": [...]}，其中只包含改写后的 %s。角色、场景、元素、风格沿用已有的 id，只有确实新增的实体才使用新的 id。", count)
+	return b.String()
+}
+
+// shotPromptJSON renders a shot with the field names of the decomposition schema.
+func shotPromptJSON(sb models.Storyboard, refs map[string][]EntityRef) string {
+	obj := map[string]interface{}{}
+	raw, _ := json.Marshal(llmDecomposeShot{
+		ShotNo:            sb.ShotNo,
+		ShotSize:          sb.ShotSize,
+		CameraMovement:    sb.CameraMovement,
+		FrameContent:      sb.FrameContent,
+		Characters:        refs["character"],
+		Scenes:            refs["scene"],
+		SpecialElements:   refs["element"],
+		VisualStyle:       refs["style"],
+		SoundDesign:       sb.SoundDesign,
+		EstimatedDuration: sb.EstimatedDuration,
+	})
+	json.Unmarshal(raw, &obj)
+	for k, v := range parseShotExtra(sb.ExtraJSON) {
+		if _, core := obj[k]; !core {
+			obj[k] = v
+		}
+	}
+	out, _ := json.MarshalIndent(obj, "", "  ")
+	return string(out)
+}
+
+func draftToUpdateParams(storyboardID uint, d DraftShot, durationFine int) UpdateShotParams {
+	return UpdateShotParams{
+		StoryboardID:      storyboardID,
+		ShotNo:            d.ShotNo,
+		ShotSize:          d.ShotSize,
+		CameraMovement:    d.CameraMovement,
+		FrameContent:      d.FrameContent,
+		Characters:        d.Characters,
+		Scenes:            d.Scenes,
+		Elements:          d.Elements,
+		Styles:            d.Styles,
+		SoundDesign:       d.SoundDesign,
+		EstimatedDuration: d.EstimatedDuration,
+		DurationFine:      durationFine,
+		Extra:             d.Extra,
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestSplitDuration(t *testing.T) {
+	cases := []struct {
+		total, a, b   int
+		first, second int
+	}{
+		{5, 5, 5, 3, 2},
+		{5, 2, 3, 2, 3},
+		{10, 5, 5, 5, 5},
+		{10, 3, 7, 3, 7},
+		{12, 1, 20, 2, 10},
+		{8, 0, 4, 4, 4},
+		{4, 9, 1, 2, 2},
+		{3, 2, 1, 2, 2},
+	}
+	for _, tc := range cases {
+		first, second := splitDuration(tc.total, tc.a, tc.b)
+		if first != tc.first || second != tc.second {
+			t.Errorf("splitDuration(%d, %d, %d) = %d, %d, want %d, %d", tc.total, tc.a, tc.b, first, second, tc.first, tc.second)
+		}
+	}
+}
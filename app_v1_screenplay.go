@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"seedance-client/models"
+	"seedance-client/services"
+)
+
+// ScreenplayCatalogSeed reports the catalog entries created from a screenplay.
+type ScreenplayCatalogSeed struct {
+	Characters []string `json:"characters"` // names of the new character entries
+	Scenes     []string `json:"scenes"`     // names of the new scene entries
+}
+
+// ============================================================
+// Screenplay Import
+// ============================================================
+
+// SeedScreenplayCatalog creates character and scene catalog entries for the speaking characters
+// and scene locations of a screenplay, so decomposition reuses them instead of inventing new
+// ones. Names that already have an entry are skipped.
+func (a *App) SeedScreenplayCatalog(projectID uint, screenplay services.Screenplay) (*ScreenplayCatalogSeed, error) {
+	if projectID == 0 {
+		return nil, fmt.Errorf("project_id 不能为空")
+	}
+	var catalogs []models.AssetCatalog
+	if err := models.DB.Where("project_id = ?", projectID).Order("id asc").Find(&catalogs).Error; err != nil {
+		return nil, fmt.Errorf("加载资产目录失败：%w", err)
+	}
+	reconcilers := newEntityReconcilers(catalogs)
+	seed := &ScreenplayCatalogSeed{Characters: []string{}, Scenes: []string{}}
+
+	tx := models.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	for _, group := range []struct {
+		assetType string
+		names     []string
+		created   *[]string
+	}{
+		{"character", screenplay.Characters(), &seed.Characters},
+		{"scene", screenplay.Locations(), &seed.Scenes},
+	} {
+		r := reconcilers[group.assetType]
+		for _, name := range group.names {
+			existing := len(r.canon)
+			ref := r.canon[r.resolve(EntityRef{Name: name})]
+			if len(r.canon) == existing {
+				continue
+			}
+			now := time.Now()
+			if err := tx.Create(&models.AssetCatalog{
+				ProjectID: projectID,
+				AssetType: group.assetType,
+				AssetCode: ref.ID,
+				Name:      ref.Name,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("创建资产失败：%w", err)
+			}
+			*group.created = append(*group.created, ref.Name)
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// readScreenplayFile parses a Fountain (.fountain, .spmd) or Final Draft (.fdx) file.
+func readScreenplayFile(path string) (*services.Screenplay, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".fdx") {
+		return services.ParseFDX(b)
+	}
+	return services.ParseFountain(string(b)), nil
+}
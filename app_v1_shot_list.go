@@ -0,0 +1,784 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"seedance-client/models"
+	"seedance-client/services"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+// A shot list is the storyboard as a spreadsheet for offline review. Every row carries the shot's
+// ID and a version: a short hash of each field as exported. On import the version tells which
+// side changed a field since the export, so edits merge field by field and only a field changed
+// both in the app and in the sheet is a conflict.
+
+// shotListColumns are the fixed columns of an exported shot list, in order. The header shows
+// the label; the key is accepted as well.
+var shotListColumns = []struct{ key, label string }{
+	{"shot_id", "镜头ID"},
+	{"version", "版本"},
+	{"shot_order", "序号"},
+	{"shot_no", "镜号"},
+	{"shot_size", "景别"},
+	{"camera_movement", "运镜"},
+	{"frame_content", "画面内容"},
+	{"characters", "角色"},
+	{"scenes", "场景"},
+	{"elements", "元素"},
+	{"styles", "风格"},
+	{"sound_design", "声音设计"},
+	{"estimated_duration", "时长"},
+	{"duration_fine", "时长微调"},
+}
+
+// shotListFields are the editable fields, in the order their hashes appear in the version.
+// Template-defined extra fields share the last one.
+var shotListFields = []string{
+	"shot_no", "shot_size", "camera_movement", "frame_content", "characters", "scenes",
+	"elements", "styles", "sound_design", "estimated_duration", "duration_fine", "extra",
+}
+
+const (
+	// shotListExtraPrefix heads a column holding one template-defined extra field.
+	shotListExtraPrefix = "扩展:"
+	// shotListVersionPrefix starts a version, followed by shotListHashLen hex digits per field.
+	shotListVersionPrefix = "v1-"
+	shotListHashLen       = 6
+)
+
+// ExportShotListParams exports a project's shots.
+type ExportShotListParams struct {
+	ProjectID uint   `json:"project_id"`
+	Format    string `json:"format"` // xlsx | csv
+}
+
+// ImportShotListParams merges an edited shot list back into a project.
+type ImportShotListParams struct {
+	ProjectID uint   `json:"project_id"`
+	Path      string `json:"path"`
+	// DryRun reports what the import would do without saving.
+	DryRun bool `json:"dry_run"`
+	// UseSheet lists ShotListConflict IDs resolved in favour of the sheet; other conflicts keep
+	// the app's value.
+	UseSheet []string `json:"use_sheet"`
+}
+
+// ShotListConflict is a field changed both in the app and in the sheet since the export.
+type ShotListConflict struct {
+	ID           string `json:"id"` // "<storyboard_id>:<field>"
+	StoryboardID uint   `json:"storyboard_id"`
+	ShotNo       string `json:"shot_no"`
+	Row          int    `json:"row"` // 1-based row in the sheet
+	Field        string `json:"field"`
+	Label        string `json:"label"`
+	AppValue     string `json:"app_value"`
+	SheetValue   string `json:"sheet_value"`
+	Resolved     string `json:"resolved"` // app | sheet
+}
+
+// ShotListImportResult reports an import. Workspace is nil for a dry run.
+type ShotListImportResult struct {
+	ProjectID uint               `json:"project_id"`
+	DryRun    bool               `json:"dry_run"`
+	Updated   int                `json:"updated"`
+	Created   int                `json:"created"`
+	Unchanged int                `json:"unchanged"`
+	Missing   int                `json:"missing"` // shots not in the sheet; they are kept
+	Conflicts []ShotListConflict `json:"conflicts"`
+	Notes     []string           `json:"notes"`
+	Workspace *V1WorkspaceData   `json:"workspace,omitempty"`
+}
+
+// ============================================================
+// Shot List Export
+// ============================================================
+
+// ExportShotList saves the project's shots as an .xlsx or .csv file for offline editing.
+// Returns the saved path, or "" when the dialog was cancelled.
+func (a *App) ExportShotList(params ExportShotListParams) (string, error) {
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return "", fmt.Errorf("project not found")
+	}
+	var shots []models.Storyboard
+	if err := models.DB.Where("project_id = ?", project.ID).Order("shot_order asc, id asc").Find(&shots).Error; err != nil {
+		return "", fmt.Errorf("加载分镜失败：%w", err)
+	}
+	refs, err := loadShotRefs(models.DB, storyboardIDs(shots))
+	if err != nil {
+		return "", fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+	table := shotListTable(shots, refs)
+
+	format := strings.ToLower(strings.TrimSpace(params.Format))
+	if format != "csv" {
+		format = "xlsx"
+	}
+	filter := wailsRuntime.FileFilter{DisplayName: "Excel Workbook (*.xlsx)", Pattern: "*.xlsx"}
+	if format == "csv" {
+		filter = wailsRuntime.FileFilter{DisplayName: "CSV Files (*.csv)", Pattern: "*.csv"}
+	}
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		DefaultFilename: sanitizeTemplateFilename(project.Name) + "_分镜表." + format,
+		Filters:         []wailsRuntime.FileFilter{filter},
+	})
+	if err != nil {
+		return "", fmt.Errorf("打开保存对话框失败：%w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if format == "csv" {
+		err = services.WriteDelimitedTable(&buf, table, ',')
+	} else {
+		err = services.WriteXLSX(&buf, []services.SheetTable{{Name: project.Name, Rows: table}})
+	}
+	if err != nil {
+		return "", fmt.Errorf("生成分镜表失败：%w", err)
+	}
+	if err := os.WriteFile(savePath, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("写入分镜表失败：%w", err)
+	}
+	return savePath, nil
+}
+
+// shotListTable renders shots as a header row and one row per shot. Extra fields get a column
+// each, sorted by key. refs are the shots' refs from loadShotRefs.
+func shotListTable(shots []models.Storyboard, refs map[uint]map[string][]EntityRef) [][]string {
+	extraKeys := map[string]bool{}
+	for _, sb := range shots {
+		for k := range parseShotExtra(sb.ExtraJSON) {
+			extraKeys[k] = true
+		}
+	}
+	keys := make([]string, 0, len(extraKeys))
+	for k := range extraKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	header := make([]string, 0, len(shotListColumns)+len(keys))
+	for _, c := range shotListColumns {
+		header = append(header, c.label)
+	}
+	for _, k := range keys {
+		header = append(header, shotListExtraPrefix+k)
+	}
+	table := [][]string{header}
+	for _, sb := range shots {
+		values := shotListValues(sb, refs[sb.ID])
+		extra := parseShotExtra(sb.ExtraJSON)
+		row := make([]string, 0, len(header))
+		for _, c := range shotListColumns {
+			switch c.key {
+			case "shot_id":
+				row = append(row, strconv.FormatUint(uint64(sb.ID), 10))
+			case "version":
+				row = append(row, shotListVersion(values))
+			case "shot_order":
+				row = append(row, strconv.Itoa(sb.ShotOrder))
+			default:
+				row = append(row, values[c.key])
+			}
+		}
+		for _, k := range keys {
+			row = append(row, extraCellText(extra[k]))
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+// shotListValues renders a shot's editable fields as cell text. The extra fields are joined
+// into one canonical value so they hash as a single field.
+func shotListValues(sb models.Storyboard, refs map[string][]EntityRef) map[string]string {
+	return map[string]string{
+		"shot_no":            sb.ShotNo,
+		"shot_size":          sb.ShotSize,
+		"camera_movement":    sb.CameraMovement,
+		"frame_content":      sb.FrameContent,
+		"characters":         formatRefCell(refs["character"]),
+		"scenes":             formatRefCell(refs["scene"]),
+		"elements":           formatRefCell(refs["element"]),
+		"styles":             formatRefCell(refs["style"]),
+		"sound_design":       sb.SoundDesign,
+		"estimated_duration": strconv.Itoa(normalizeDuration(sb.EstimatedDuration)),
+		"duration_fine":      strconv.Itoa(sb.DurationFine),
+		"extra":              canonicalExtra(parseShotExtra(sb.ExtraJSON)),
+	}
+}
+
+func shotListVersion(values map[string]string) string {
+	var b strings.Builder
+	b.WriteString(shotListVersionPrefix)
+	for _, f := range shotListFields {
+		b.WriteString(shotListHash(values[f]))
+	}
+	return b.String()
+}
+
+// parseShotListVersion splits a version into per-field hashes; nil when it is missing or malformed.
+func parseShotListVersion(v string) map[string]string {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, shotListVersionPrefix) || len(v) != len(shotListVersionPrefix)+shotListHashLen*len(shotListFields) {
+		return nil
+	}
+	v = v[len(shotListVersionPrefix):]
+	hashes := map[string]string{}
+	for i, f := range shotListFields {
+		hashes[f] = v[i*shotListHashLen : (i+1)*shotListHashLen]
+	}
+	return hashes
+}
+
+func shotListHash(s string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.TrimSpace(s)))
+	return fmt.Sprintf("%016x", h.Sum64())[:shotListHashLen]
+}
+
+// formatRefCell renders refs as "name [code]; name [code]".
+func formatRefCell(refs []EntityRef) string {
+	parts := make([]string, 0, len(refs))
+	for _, r := range refs {
+		switch {
+		case r.ID == "":
+			parts = append(parts, r.Name)
+		case r.Name == "" || r.Name == r.ID:
+			parts = append(parts, "["+r.ID+"]")
+		default:
+			parts = append(parts, r.Name+" ["+r.ID+"]")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+var refCellItem = regexp.MustCompile(`^(.*?)\s*\[([^\[\]]+)\]$`)
+
+// parseRefCell parses a ref cell. Items are separated by semicolons or new lines, and an item
+// without a "[code]" has an empty ID.
+func parseRefCell(cell string) []EntityRef {
+	var refs []EntityRef
+	for _, item := range strings.FieldsFunc(cell, func(r rune) bool { return r == ';' || r == '；' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if m := refCellItem.FindStringSubmatch(item); m != nil {
+			refs = append(refs, EntityRef{ID: strings.TrimSpace(m[2]), Name: strings.TrimSpace(m[1])})
+			continue
+		}
+		refs = append(refs, EntityRef{Name: item})
+	}
+	return refs
+}
+
+// extraCellText renders an extra field: strings as they are, anything else as JSON.
+func extraCellText(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func canonicalExtra(extra map[string]interface{}) string {
+	keys := make([]string, 0, len(extra))
+	for k, v := range extra {
+		if extraCellText(v) != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + strings.TrimSpace(extraCellText(extra[k])) + "\x00")
+	}
+	return b.String()
+}
+
+// ============================================================
+// Shot List Import
+// ============================================================
+
+// SelectShotListFile picks an exported shot list. Returns "" when the dialog was cancelled.
+func (a *App) SelectShotListFile() (string, error) {
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Import Shot List",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "Shot List (*.xlsx;*.csv)", Pattern: "*.xlsx;*.csv;*.tsv"},
+		},
+	})
+}
+
+// ImportShotList merges an exported and edited shot list into the project. Rows with a shot ID
+// update that shot under the rules of UpdateShotMetadata, taking only the fields changed in the
+// sheet since the export; rows without one create shots after the row above them. Shots missing
+// from the sheet are kept.
+func (a *App) ImportShotList(params ImportShotListParams) (*ShotListImportResult, error) {
+	if params.ProjectID == 0 {
+		return nil, fmt.Errorf("project_id 不能为空")
+	}
+	var project models.Project
+	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found")
+	}
+	table, err := readShotTableFile(params.Path, "")
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := parseShotListSheet(table)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := models.DB.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("数据库事务启动失败：%w", tx.Error)
+	}
+	result, err := importShotListTx(tx, project, sheet, params.UseSheet)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	result.DryRun = params.DryRun
+	if params.DryRun {
+		tx.Rollback()
+		return result, nil
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("提交导入失败：%w", err)
+	}
+	if result.Workspace, err = a.GetV1Workspace(project.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type shotListRow struct {
+	line    int               // 1-based row in the sheet
+	id      string            // shot ID cell
+	version map[string]string // per-field hashes at export; nil when missing
+	values  map[string]string // cells of the fields present in the sheet
+	extra   map[string]string // extra field key -> cell
+}
+
+type shotListSheet struct {
+	rows  []shotListRow
+	notes []string
+}
+
+// parseShotListSheet finds the header row (the one with the shot ID column) and reads the rows
+// below it. Columns that are not part of a shot list are ignored.
+func parseShotListSheet(table [][]string) (*shotListSheet, error) {
+	labels := map[string]string{}
+	for _, c := range shotListColumns {
+		labels[strings.ToLower(c.label)] = c.key
+		labels[c.key] = c.key
+	}
+	headerRow := -1
+	for r := 0; r < len(table) && r < 10 && headerRow < 0; r++ {
+		for _, cell := range table[r] {
+			if labels[strings.ToLower(strings.TrimSpace(cell))] == "shot_id" {
+				headerRow = r
+				break
+			}
+		}
+	}
+	if headerRow < 0 {
+		return nil, fmt.Errorf("不是本应用导出的分镜表：缺少“镜头ID”列。没有镜头ID的表格请使用“按列导入表格”")
+	}
+
+	sheet := &shotListSheet{}
+	keys := make([]string, len(table[headerRow]))
+	extraKeys := make([]string, len(table[headerRow]))
+	var ignored []string
+	for i, cell := range table[headerRow] {
+		cell = strings.TrimSpace(cell)
+		switch {
+		case labels[strings.ToLower(cell)] != "":
+			keys[i] = labels[strings.ToLower(cell)]
+		case strings.HasPrefix(cell, shotListExtraPrefix):
+			extraKeys[i] = strings.TrimSpace(strings.TrimPrefix(cell, shotListExtraPrefix))
+		case strings.HasPrefix(strings.ToLower(cell), "extra:"):
+			extraKeys[i] = strings.TrimSpace(cell[len("extra:"):])
+		case cell != "":
+			ignored = append(ignored, cell)
+		}
+	}
+	if len(ignored) > 0 {
+		sheet.notes = append(sheet.notes, fmt.Sprintf("已忽略无法识别的列：%s", strings.Join(ignored, "、")))
+	}
+
+	for r := headerRow + 1; r < len(table); r++ {
+		row := shotListRow{line: r + 1, values: map[string]string{}}
+		empty := true
+		for i, cell := range table[r] {
+			if i >= len(keys) {
+				break
+			}
+			cell = strings.TrimSpace(cell)
+			if cell != "" {
+				empty = false
+			}
+			switch keys[i] {
+			case "":
+				if extraKeys[i] != "" {
+					if row.extra == nil {
+						row.extra = map[string]string{}
+					}
+					row.extra[extraKeys[i]] = cell
+				}
+			case "shot_id":
+				row.id = cell
+			case "version":
+				row.version = parseShotListVersion(cell)
+			case "shot_order":
+			default:
+				row.values[keys[i]] = cell
+			}
+		}
+		if empty {
+			continue
+		}
+		// A short row leaves its trailing cells empty
+		for i, k := range keys {
+			if k != "" && k != "shot_id" && k != "version" && k != "shot_order" && i >= len(table[r]) {
+				row.values[k] = ""
+			}
+		}
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet, nil
+}
+
+func importShotListTx(tx *gorm.DB, project models.Project, sheet *shotListSheet, useSheet []string) (*ShotListImportResult, error) {
+	result := &ShotListImportResult{ProjectID: project.ID, Conflicts: []ShotListConflict{}, Notes: sheet.notes}
+
+	var current []models.Storyboard
+	if err := tx.Where("project_id = ?", project.ID).Order("shot_order asc, id asc").Find(&current).Error; err != nil {
+		return nil, fmt.Errorf("加载分镜失败：%w", err)
+	}
+	byID := map[uint]*models.Storyboard{}
+	order := make([]uint, 0, len(current))
+	for i := range current {
+		byID[current[i].ID] = &current[i]
+		order = append(order, current[i].ID)
+	}
+	currentRefs, err := loadShotRefs(tx, order)
+	if err != nil {
+		return nil, fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+	var catalogs []models.AssetCatalog
+	if err := tx.Where("project_id = ?", project.ID).Order("id asc").Find(&catalogs).Error; err != nil {
+		return nil, fmt.Errorf("加载资产目录失败：%w", err)
+	}
+	catalogPrompts := map[string]string{}
+	for _, c := range catalogs {
+		catalogPrompts[c.AssetType+"\x00"+c.AssetCode] = c.Prompt
+	}
+	reconcilers := newEntityReconcilers(catalogs)
+	selected := map[string]bool{}
+	for _, id := range useSheet {
+		selected[id] = true
+	}
+	labels := map[string]string{"extra": "扩展字段"}
+	for _, c := range shotListColumns {
+		labels[c.key] = c.label
+	}
+
+	// refsFromCell resolves a ref cell: coded items keep their code and take the prompt of the
+	// shot's or catalog's entity; names alone reuse or create catalog entries by name.
+	refsFromCell := func(assetType, cell string, existing []EntityRef) []EntityRef {
+		prompts := map[string]string{}
+		for _, r := range existing {
+			prompts[r.ID] = r.Prompt
+		}
+		rec := reconcilers[assetType]
+		var out []EntityRef
+		for _, ref := range parseRefCell(cell) {
+			if ref.ID == "" {
+				out = append(out, rec.canon[rec.resolve(ref)])
+				continue
+			}
+			if p, ok := prompts[ref.ID]; ok {
+				ref.Prompt = p
+			} else {
+				ref.Prompt = catalogPrompts[assetType+"\x00"+ref.ID]
+			}
+			out = append(out, ref)
+		}
+		return mergeRefs(out, nil)
+	}
+
+	seen := map[uint]bool{}
+	resolved := map[int]uint{} // row index -> storyboard ID, for anchoring new shots
+	var created []int
+	for i, row := range sheet.rows {
+		var sb *models.Storyboard
+		if row.id != "" {
+			id, err := strconv.ParseUint(row.id, 10, 64)
+			switch {
+			case err != nil || byID[uint(id)] == nil:
+				result.Notes = append(result.Notes, fmt.Sprintf("第 %d 行的镜头ID %s 不属于当前项目，已作为新镜头导入", row.line, row.id))
+			case seen[uint(id)]:
+				result.Notes = append(result.Notes, fmt.Sprintf("第 %d 行的镜头ID %s 重复，已跳过", row.line, row.id))
+				continue
+			default:
+				sb = byID[uint(id)]
+			}
+		}
+		if sb == nil {
+			created = append(created, i)
+			continue
+		}
+		seen[sb.ID] = true
+		resolved[i] = sb.ID
+
+		app := shotListValues(*sb, currentRefs[sb.ID])
+		sheetValues := map[string]string{}
+		for k, v := range row.values {
+			sheetValues[k] = canonicalShotListCell(k, v)
+		}
+		if row.extra != nil {
+			extra := parseShotExtra(sb.ExtraJSON)
+			if extra == nil {
+				extra = map[string]interface{}{}
+			}
+			for k, v := range row.extra {
+				extra[k] = extraCellValue(v, extra[k])
+			}
+			sheetValues["extra"] = canonicalExtra(extra)
+		}
+		if d, ok := sheetValues["estimated_duration"]; ok && d == "" && row.values["estimated_duration"] != "" {
+			result.Notes = append(result.Notes, fmt.Sprintf("第 %d 行的时长“%s”无法识别，已保留原值", row.line, row.values["estimated_duration"]))
+			delete(sheetValues, "estimated_duration")
+		}
+		if row.version == nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("第 %d 行缺少版本信息，以表格内容为准", row.line))
+		}
+
+		// Three-way merge per field against the version at export
+		take := map[string]bool{}
+		for _, f := range shotListFields {
+			sv, ok := sheetValues[f]
+			if !ok || strings.TrimSpace(sv) == strings.TrimSpace(app[f]) {
+				continue
+			}
+			if row.version == nil {
+				take[f] = true
+				continue
+			}
+			base := row.version[f]
+			if shotListHash(sv) == base {
+				continue // unchanged in the sheet
+			}
+			if shotListHash(app[f]) == base {
+				take[f] = true
+				continue
+			}
+			conflict := ShotListConflict{
+				ID:           fmt.Sprintf("%d:%s", sb.ID, f),
+				StoryboardID: sb.ID,
+				ShotNo:       sb.ShotNo,
+				Row:          row.line,
+				Field:        f,
+				Label:        labels[f],
+				AppValue:     app[f],
+				SheetValue:   sv,
+				Resolved:     "app",
+			}
+			if f == "extra" {
+				conflict.AppValue = strings.TrimSuffix(strings.ReplaceAll(app[f], "\x00", "\n"), "\n")
+				conflict.SheetValue = strings.TrimSuffix(strings.ReplaceAll(sv, "\x00", "\n"), "\n")
+			}
+			if selected[conflict.ID] {
+				conflict.Resolved = "sheet"
+				take[f] = true
+			}
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+		if len(take) == 0 {
+			result.Unchanged++
+			continue
+		}
+
+		draft := storyboardToDraft(*sb, currentRefs[sb.ID])
+		params := UpdateShotParams{
+			StoryboardID:      sb.ID,
+			ShotNo:            draft.ShotNo,
+			ShotSize:          draft.ShotSize,
+			CameraMovement:    draft.CameraMovement,
+			FrameContent:      draft.FrameContent,
+			Characters:        draft.Characters,
+			Scenes:            draft.Scenes,
+			Elements:          draft.Elements,
+			Styles:            draft.Styles,
+			SoundDesign:       draft.SoundDesign,
+			EstimatedDuration: draft.EstimatedDuration,
+			DurationFine:      sb.DurationFine,
+		}
+		for f := range take {
+			v := sheetValues[f]
+			switch f {
+			case "shot_no":
+				params.ShotNo = v
+			case "shot_size":
+				params.ShotSize = v
+			case "camera_movement":
+				params.CameraMovement = v
+			case "frame_content":
+				params.FrameContent = v
+			case "characters":
+				params.Characters = refsFromCell("character", v, draft.Characters)
+			case "scenes":
+				params.Scenes = refsFromCell("scene", v, draft.Scenes)
+			case "elements":
+				params.Elements = refsFromCell("element", v, draft.Elements)
+			case "styles":
+				params.Styles = refsFromCell("style", v, draft.Styles)
+			case "sound_design":
+				params.SoundDesign = v
+			case "estimated_duration":
+				params.EstimatedDuration, _ = strconv.Atoi(v)
+			case "duration_fine":
+				params.DurationFine, _ = strconv.Atoi(v)
+			case "extra":
+				extra := parseShotExtra(sb.ExtraJSON)
+				if extra == nil {
+					extra = map[string]interface{}{}
+				}
+				for k, cell := range row.extra {
+					if value := extraCellValue(cell, extra[k]); value == "" {
+						delete(extra, k)
+					} else {
+						extra[k] = value
+					}
+				}
+				params.Extra = extra
+			}
+		}
+		if err := updateShotMetadataTx(tx, sb, params); err != nil {
+			return nil, fmt.Errorf("第 %d 行：%w", row.line, err)
+		}
+		result.Updated++
+	}
+
+	for _, i := range created {
+		row := sheet.rows[i]
+		draft := DraftShot{
+			ShotNo:         row.values["shot_no"],
+			ShotSize:       row.values["shot_size"],
+			CameraMovement: row.values["camera_movement"],
+			FrameContent:   row.values["frame_content"],
+			Characters:     refsFromCell("character", row.values["characters"], nil),
+			Scenes:         refsFromCell("scene", row.values["scenes"], nil),
+			Elements:       refsFromCell("element", row.values["elements"], nil),
+			Styles:         refsFromCell("style", row.values["styles"], nil),
+			SoundDesign:    row.values["sound_design"],
+		}
+		if d, ok := services.ParseShotDuration(row.values["estimated_duration"]); ok {
+			draft.EstimatedDuration = d
+		}
+		for k, cell := range row.extra {
+			if cell == "" {
+				continue
+			}
+			if draft.Extra == nil {
+				draft.Extra = map[string]interface{}{}
+			}
+			draft.Extra[k] = extraCellValue(cell, nil)
+		}
+		sb, err := createStoryboardFromDraftTx(tx, project, normalizeDraftShot(draft, len(order)+1), 0)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行：%w", row.line, err)
+		}
+		if fine, err := strconv.Atoi(row.values["duration_fine"]); err == nil && fine != 0 {
+			if err := tx.Model(&sb).Update("duration_fine", fine).Error; err != nil {
+				return nil, fmt.Errorf("第 %d 行：%w", row.line, err)
+			}
+		}
+		resolved[i] = sb.ID
+		anchor := -1
+		for k := i - 1; k >= 0 && anchor < 0; k-- {
+			if id, ok := resolved[k]; ok {
+				anchor = indexOfUint(order, id)
+			}
+		}
+		order = append(order, 0)
+		copy(order[anchor+2:], order[anchor+1:])
+		order[anchor+1] = sb.ID
+		result.Created++
+	}
+
+	for i, id := range order {
+		if err := tx.Model(&models.Storyboard{}).Where("id = ?", id).Update("shot_order", i+1).Error; err != nil {
+			return nil, fmt.Errorf("重排分镜顺序失败：%w", err)
+		}
+	}
+	result.Missing = len(current) - len(seen)
+	if result.Missing > 0 {
+		result.Notes = append(result.Notes, fmt.Sprintf("表格中缺少 %d 个现有镜头，已保留未删除", result.Missing))
+	}
+	return result, nil
+}
+
+// canonicalShotListCell normalizes a cell the way the export renders the field, so formatting
+// differences alone do not count as edits. An unreadable duration becomes "".
+func canonicalShotListCell(field, cell string) string {
+	cell = strings.TrimSpace(cell)
+	switch field {
+	case "characters", "scenes", "elements", "styles":
+		return formatRefCell(parseRefCell(cell))
+	case "estimated_duration":
+		if cell == "" {
+			return strconv.Itoa(normalizeDuration(0))
+		}
+		d, ok := services.ParseShotDuration(cell)
+		if !ok {
+			return ""
+		}
+		return strconv.Itoa(normalizeDuration(d))
+	case "duration_fine":
+		if cell == "" {
+			return "0"
+		}
+		if n, err := strconv.ParseFloat(cell, 64); err == nil {
+			return strconv.Itoa(int(n))
+		}
+		return "0"
+	}
+	return cell
+}
+
+// extraCellValue converts an extra field cell back to a value. A field that held a non-string
+// value takes the cell as JSON when it parses; otherwise the cell is a string.
+func extraCellValue(cell string, previous interface{}) interface{} {
+	cell = strings.TrimSpace(cell)
+	if _, isString := previous.(string); previous != nil && !isString && cell != "" {
+		var v interface{}
+		if err := json.Unmarshal([]byte(cell), &v); err == nil {
+			return v
+		}
+	}
+	return cell
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"seedance-client/models"
+
+	"gorm.io/gorm"
+)
+
+// exportShotList renders a project's shots the way ExportShotList does.
+func exportShotList(t *testing.T, projectID uint) [][]string {
+	t.Helper()
+	var shots []models.Storyboard
+	if err := models.DB.Where("project_id = ?", projectID).Order("shot_order asc, id asc").Find(&shots).Error; err != nil {
+		t.Fatal(err)
+	}
+	refs, err := loadShotRefs(models.DB, storyboardIDs(shots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shotListTable(shots, refs)
+}
+
+// setShotListCell edits one cell of an exported shot list; row 1 is the first shot.
+func setShotListCell(table [][]string, row int, key, value string) {
+	for i, c := range shotListColumns {
+		if c.key == key {
+			table[row][i] = value
+			return
+		}
+	}
+	panic("unknown shot list column " + key)
+}
+
+func TestImportShotListMerge(t *testing.T) {
+	cases := []struct {
+		name      string
+		useSheet  bool
+		updated   int
+		unchanged int
+		conflicts []string // resolutions
+		content   []string
+		sound     []string
+		size      []string
+	}{
+		{
+			name:      "app wins conflicts by default",
+			updated:   2,
+			unchanged: 2,
+			conflicts: []string{"app"},
+			content:   []string{"sheet a", "b", "app c", "d"},
+			sound:     []string{"", "app sound b", "", "app sound d"},
+			size:      []string{"", "", "", "sheet size d"},
+		},
+		{
+			name:      "conflict resolved to the sheet",
+			useSheet:  true,
+			updated:   3,
+			unchanged: 1,
+			conflicts: []string{"sheet"},
+			content:   []string{"sheet a", "b", "sheet c", "d"},
+			sound:     []string{"", "app sound b", "", "app sound d"},
+			size:      []string{"", "", "", "sheet size d"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestDB(t)
+			project, shots := seedShots(t, "a", "b", "c", "d")
+			table := exportShotList(t, project.ID)
+
+			// Shot 1 is edited in the sheet only, shot 2 in the app only, shot 3 in both on the
+			// same field and shot 4 in both on different fields
+			setShotListCell(table, 1, "frame_content", "sheet a")
+			setShotListCell(table, 3, "frame_content", "sheet c")
+			setShotListCell(table, 4, "shot_size", "sheet size d")
+			appEdits := []struct {
+				shot         int
+				field, value string
+			}{
+				{1, "sound_design", "app sound b"},
+				{2, "frame_content", "app c"},
+				{3, "sound_design", "app sound d"},
+			}
+			for _, e := range appEdits {
+				if err := models.DB.Model(&shots[e.shot]).Update(e.field, e.value).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sheet, err := parseShotListSheet(table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var useSheet []string
+			if tc.useSheet {
+				useSheet = []string{fmt.Sprintf("%d:frame_content", shots[2].ID)}
+			}
+			var result *ShotListImportResult
+			err = models.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				result, err = importShotListTx(tx, project, sheet, useSheet)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Updated != tc.updated || result.Unchanged != tc.unchanged || result.Created != 0 || result.Missing != 0 {
+				t.Errorf("updated %d, unchanged %d, created %d, missing %d; want %d, %d, 0, 0",
+					result.Updated, result.Unchanged, result.Created, result.Missing, tc.updated, tc.unchanged)
+			}
+			var resolutions []string
+			for _, c := range result.Conflicts {
+				if c.StoryboardID != shots[2].ID || c.Field != "frame_content" || c.AppValue != "app c" || c.SheetValue != "sheet c" {
+					t.Errorf("conflict = %+v", c)
+				}
+				resolutions = append(resolutions, c.Resolved)
+			}
+			if !reflect.DeepEqual(resolutions, tc.conflicts) {
+				t.Errorf("resolutions = %q, want %q", resolutions, tc.conflicts)
+			}
+
+			var rows []models.Storyboard
+			if err := models.DB.Where("project_id = ?", project.ID).Order("shot_order asc").Find(&rows).Error; err != nil {
+				t.Fatal(err)
+			}
+			var content, sound, size []string
+			for _, sb := range rows {
+				content = append(content, sb.FrameContent)
+				sound = append(sound, sb.SoundDesign)
+				size = append(size, sb.ShotSize)
+			}
+			if !reflect.DeepEqual(content, tc.content) {
+				t.Errorf("frame content = %q, want %q", content, tc.content)
+			}
+			if !reflect.DeepEqual(sound, tc.sound) {
+				t.Errorf("sound design = %q, want %q", sound, tc.sound)
+			}
+			if !reflect.DeepEqual(size, tc.size) {
+				t.Errorf("shot size = %q, want %q", size, tc.size)
+			}
+		})
+	}
+}
+
+func TestImportShotListRows(t *testing.T) {
+	newTestDB(t)
+	project, shots := seedShots(t, "a", "b", "c")
+	table := exportShotList(t, project.ID)
+	if err := models.DB.Model(&shots[0]).Update("frame_content", "app a").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a version the sheet wins; a row without an ID is a new shot after the row above
+	setShotListCell(table, 1, "version", "")
+	setShotListCell(table, 1, "frame_content", "sheet a")
+	newRow := make([]string, len(table[0]))
+	table = append(table[:2], append([][]string{newRow}, table[2:3]...)...)
+	setShotListCell(table, 2, "frame_content", "new")
+	setShotListCell(table, 2, "estimated_duration", "8s")
+
+	sheet, err := parseShotListSheet(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result *ShotListImportResult
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = importShotListTx(tx, project, sheet, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 || result.Created != 1 || result.Unchanged != 1 || result.Missing != 1 || len(result.Conflicts) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if got, want := shotOrder(t, project.ID), []string{"sheet a", "new", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+	var created models.Storyboard
+	if err := models.DB.Where("project_id = ? AND frame_content = ?", project.ID, "new").First(&created).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created.EstimatedDuration != 8 {
+		t.Errorf("new shot duration = %d, want 8", created.EstimatedDuration)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"seedance-client/services"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ExportStoryboardSheetParams exports a printable storyboard.
+type ExportStoryboardSheetParams struct {
+	ProjectID uint   `json:"project_id"`
+	Format    string `json:"format"` // pdf | html
+	// ShotsPerPage is 1–12; 0 means 6.
+	ShotsPerPage   int  `json:"shots_per_page"`
+	IncludePrompts bool `json:"include_prompts"`
+}
+
+// ExportStoryboardSheet saves the project's storyboard as a paginated PDF or HTML sheet: each shot
+// with its active start and end frames (or the active take's last frame when it has neither) and
+// its details. Returns the saved path, or "" when the dialog was cancelled.
+func (a *App) ExportStoryboardSheet(params ExportStoryboardSheetParams) (string, error) {
+	ws, err := a.GetV1Workspace(params.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	if len(ws.Storyboards) == 0 {
+		return "", fmt.Errorf("项目还没有分镜")
+	}
+	sheet := storyboardSheetFromWorkspace(ws)
+	opts := services.StoryboardSheetOptions{ShotsPerPage: params.ShotsPerPage, IncludePrompts: params.IncludePrompts}
+	if opts.ShotsPerPage <= 0 {
+		opts.ShotsPerPage = 6
+	}
+
+	format := strings.ToLower(strings.TrimSpace(params.Format))
+	if format != "html" {
+		format = "pdf"
+	}
+	filter := wailsRuntime.FileFilter{DisplayName: "PDF Files (*.pdf)", Pattern: "*.pdf"}
+	if format == "html" {
+		filter = wailsRuntime.FileFilter{DisplayName: "HTML Files (*.html)", Pattern: "*.html"}
+	}
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		DefaultFilename: sanitizeTemplateFilename(ws.Project.Name) + "_故事板." + format,
+		Filters:         []wailsRuntime.FileFilter{filter},
+	})
+	if err != nil {
+		return "", fmt.Errorf("打开保存对话框失败：%w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+
+	var content []byte
+	if format == "html" {
+		content, err = services.RenderStoryboardHTML(sheet, opts)
+	} else {
+		content, err = services.RenderStoryboardPDF(sheet, opts)
+	}
+	if err != nil {
+		return "", fmt.Errorf("生成故事板失败：%w", err)
+	}
+	if err := os.WriteFile(savePath, content, 0644); err != nil {
+		return "", fmt.Errorf("写入故事板失败：%w", err)
+	}
+	return savePath, nil
+}
+
+// storyboardSheetFromWorkspace collects what the sheet shows of each shot, in workspace order.
+func storyboardSheetFromWorkspace(ws *V1WorkspaceData) services.StoryboardSheet {
+	sheet := services.StoryboardSheet{
+		Title:       ws.Project.Name,
+		Subtitle:    fmt.Sprintf("%d 个镜头 · %s · %s", len(ws.Storyboards), ws.Project.AspectRatio, time.Now().Format("2006-01-02")),
+		AspectRatio: ws.Project.AspectRatio,
+	}
+	names := func(refs []EntityRef) []string {
+		var out []string
+		for _, r := range refs {
+			if name := strings.TrimSpace(r.Name); name != "" {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	for _, shot := range ws.Storyboards {
+		s := services.StoryboardSheetShot{
+			ShotNo:         shot.ShotNo,
+			ShotSize:       shot.ShotSize,
+			CameraMovement: shot.CameraMovement,
+			Duration:       shot.EstimatedDuration,
+			FrameContent:   shot.FrameContent,
+			Characters:     names(shot.Characters),
+			Scenes:         names(shot.Scenes),
+			SoundDesign:    shot.SoundDesign,
+		}
+		if shot.ActiveStartFrame != nil && shot.ActiveStartFrame.ImagePath != "" {
+			s.Images = append(s.Images, services.StoryboardSheetImage{Label: "首帧", Path: shot.ActiveStartFrame.ImagePath})
+		}
+		if shot.ActiveEndFrame != nil && shot.ActiveEndFrame.ImagePath != "" {
+			s.Images = append(s.Images, services.StoryboardSheetImage{Label: "尾帧", Path: shot.ActiveEndFrame.ImagePath})
+		}
+		if take := shot.ActiveTake; take != nil {
+			s.Prompt = take.Prompt
+			if len(s.Images) == 0 && take.LocalLastFramePath != "" {
+				s.Images = append(s.Images, services.StoryboardSheetImage{Label: "视频尾帧", Path: take.LocalLastFramePath})
+			}
+		}
+		sheet.Shots = append(sheet.Shots, s)
+	}
+	return sheet
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"seedance-client/models"
+	"seedance-client/services"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+// shotTablePreviewRows is the number of data rows returned for previewing a column mapping.
+const shotTablePreviewRows = 5
+
+// ShotTableFile is a spreadsheet inspected for a column-mapping import.
+type ShotTableFile struct {
+	Path     string   `json:"path"`
+	Filename string   `json:"filename"`
+	Sheet    string   `json:"sheet"`  // inspected sheet of an .xlsx file
+	Sheets   []string `json:"sheets"` // all sheets of an .xlsx file
+	// HeaderRow is 0-based; -1 when no header row was found
+	HeaderRow int `json:"header_row"`
+	// Columns holds the detected mapping; a column with an empty field is ignored.
+	Columns  []services.ShotTableColumn `json:"columns"`
+	Preview  [][]string                 `json:"preview"`   // first data rows below the header
+	RowCount int                        `json:"row_count"` // data rows below the header
+}
+
+// ImportShotTableParams imports a spreadsheet through a column mapping.
+type ImportShotTableParams struct {
+	ProjectID uint                       `json:"project_id"`
+	Path      string                     `json:"path"`
+	Sheet     string                     `json:"sheet"`      // "" reads the first sheet
+	HeaderRow int                        `json:"header_row"` // -1 when the table has no header row
+	Columns   []services.ShotTableColumn `json:"columns"`
+	// ReplaceExisting merges the rows into the existing shots as DecomposeStoryboardWithLLM does,
+	// keeping shots that no row matches; otherwise they are appended.
+	ReplaceExisting bool `json:"replace_existing"`
+	// NewProjectName imports into a new project with this name instead, created with the model
+	// version and aspect ratio of ProjectID.
+	NewProjectName string `json:"new_project_name"`
+}
+
+// ShotTableImportResult is the workspace after an import, with notes on cells that were skipped.
+type ShotTableImportResult struct {
+	ProjectID uint             `json:"project_id"` // the new project when NewProjectName was set
+	Sheet     string           `json:"sheet"`
+	Workspace *V1WorkspaceData `json:"workspace"`
+	Imported  int              `json:"imported"`
+	Notes     []string         `json:"notes"`
+}
+
+// ============================================================
+// Shot Table Import
+// ============================================================
+
+// SelectShotTableFile picks a CSV/TSV/XLSX shot list and detects its header row and columns.
+func (a *App) SelectShotTableFile() (*ShotTableFile, error) {
+	result, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Select Shot List",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "Shot List (*.csv;*.tsv;*.xlsx)", Pattern: "*.csv;*.tsv;*.xlsx"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == "" {
+		return &ShotTableFile{}, nil
+	}
+	return a.InspectShotTable(result, "", -1)
+}
+
+// InspectShotTable reads one sheet of a shot list and detects the column mapping below
+// headerRow. A negative headerRow detects the header row as well; an empty sheet reads the
+// first one.
+func (a *App) InspectShotTable(path string, sheet string, headerRow int) (*ShotTableFile, error) {
+	var sheets []string
+	if strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		names, err := services.ListXLSXSheets(path)
+		if err != nil {
+			return nil, err
+		}
+		sheets = names
+		if sheet == "" {
+			sheet = names[0]
+		}
+	}
+	table, err := readShotTableFile(path, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return inspectShotTable(path, sheet, sheets, table, headerRow)
+}
+
+// InspectShotTableSheets inspects several sheets of an .xlsx shot list at once, detecting the
+// header row and columns of each, so every sheet can be imported on its own.
+func (a *App) InspectShotTableSheets(path string, sheets []string) ([]ShotTableFile, error) {
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("请选择工作表")
+	}
+	all, err := services.ListXLSXSheets(path)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := services.ReadXLSXSheets(path, sheets)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]ShotTableFile, 0, len(tables))
+	for _, t := range tables {
+		file, err := inspectShotTable(path, t.Name, all, t.Rows, -1)
+		if err != nil {
+			return nil, fmt.Errorf("工作表 %s：%w", t.Name, err)
+		}
+		files = append(files, *file)
+	}
+	return files, nil
+}
+
+func inspectShotTable(path string, sheet string, sheets []string, table [][]string, headerRow int) (*ShotTableFile, error) {
+	if len(table) == 0 {
+		return nil, fmt.Errorf("表格为空")
+	}
+	if headerRow < 0 {
+		headerRow = services.DetectShotTableHeader(table)
+	}
+	if headerRow >= len(table) {
+		return nil, fmt.Errorf("表头行超出表格范围")
+	}
+
+	file := &ShotTableFile{
+		Path:      path,
+		Filename:  filepath.Base(path),
+		Sheet:     sheet,
+		Sheets:    sheets,
+		HeaderRow: headerRow,
+	}
+	if headerRow >= 0 {
+		file.Columns = services.DetectShotColumns(table[headerRow])
+	} else {
+		// Without a header, offer every column unmapped
+		for i := range table[0] {
+			file.Columns = append(file.Columns, services.ShotTableColumn{Index: i, Header: fmt.Sprintf("第 %d 列", i+1)})
+		}
+	}
+	data := table[headerRow+1:]
+	file.RowCount = len(data)
+	if len(data) > shotTablePreviewRows {
+		data = data[:shotTablePreviewRows]
+	}
+	file.Preview = data
+	return file, nil
+}
+
+// ImportShotTable creates shots from a shot list without an LLM. Character, scene, element and
+// style names reuse catalog entries of the same name.
+func (a *App) ImportShotTable(params ImportShotTableParams) (*ShotTableImportResult, error) {
+	if params.ProjectID == 0 {
+		return nil, fmt.Errorf("project_id 不能为空")
+	}
+	mapped := false
+	for _, c := range params.Columns {
+		if c.Field == services.ShotFieldFrameContent {
+			mapped = true
+		}
+	}
+	if !mapped {
+		return nil, fmt.Errorf("请指定“画面内容”对应的列")
+	}
+	table, err := readShotTableFile(params.Path, params.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	if params.HeaderRow < -1 || params.HeaderRow >= len(table) {
+		return nil, fmt.Errorf("表头行超出表格范围")
+	}
+
+	rows, notes := services.MapShotTable(table, params.HeaderRow, params.Columns)
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("表格中没有可导入的分镜")
+	}
+	projectID := params.ProjectID
+	var shots []DraftShot
+	if name := strings.TrimSpace(params.NewProjectName); name != "" {
+		// The project and its shots are created together, so a failed import leaves no project
+		err := models.DB.Transaction(func(tx *gorm.DB) error {
+			project, err := createProjectLikeTx(tx, params.ProjectID, name)
+			if err != nil {
+				return err
+			}
+			projectID = project.ID
+			var durationNotes []string
+			shots, durationNotes = shotTableDrafts(rows, nil)
+			notes = append(notes, durationNotes...)
+			return appendDraftShotsTx(tx, project, shots)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var catalogs []models.AssetCatalog
+		if err := models.DB.Where("project_id = ?", projectID).Order("id asc").Find(&catalogs).Error; err != nil {
+			return nil, fmt.Errorf("加载资产目录失败：%w", err)
+		}
+		var durationNotes []string
+		shots, durationNotes = shotTableDrafts(rows, catalogs)
+		notes = append(notes, durationNotes...)
+		if err := saveDraftShots(projectID, shots, params.ReplaceExisting); err != nil {
+			return nil, err
+		}
+	}
+
+	workspace, err := a.GetV1Workspace(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &ShotTableImportResult{
+		ProjectID: projectID,
+		Sheet:     params.Sheet,
+		Workspace: workspace,
+		Imported:  len(shots),
+		Notes:     notes,
+	}, nil
+}
+
+// createProjectLikeTx creates a project with the model version and aspect ratio of another one.
+func createProjectLikeTx(tx *gorm.DB, templateID uint, name string) (models.Project, error) {
+	var tmpl models.Project
+	if err := tx.First(&tmpl, templateID).Error; err != nil {
+		return models.Project{}, fmt.Errorf("project not found")
+	}
+	project := models.Project{Name: name, ModelVersion: tmpl.ModelVersion, AspectRatio: tmpl.AspectRatio}
+	if err := tx.Create(&project).Error; err != nil {
+		return models.Project{}, fmt.Errorf("创建项目失败：%w", err)
+	}
+	return project, nil
+}
+
+// readShotTableFile reads a .csv, .tsv or .xlsx file into rows of cells. For .xlsx, sheet selects
+// the sheet and "" reads the first one.
+func readShotTableFile(path string, sheet string) ([][]string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv", ".tsv":
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		comma := ','
+		if ext == ".tsv" {
+			comma = '\t'
+		}
+		return services.ReadDelimitedTable(b, comma)
+	case ".xlsx":
+		if sheet == "" {
+			return services.ReadXLSXTable(path)
+		}
+		tables, err := services.ReadXLSXSheets(path, []string{sheet})
+		if err != nil {
+			return nil, err
+		}
+		return tables[0].Rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported file type: %s", ext)
+	}
+}
+
+// shotTableDrafts turns mapped rows into draft shots. Entity names are matched against the
+// project's catalog by name, and new names get fresh catalog codes that do not collide with it.
+// Rows without a shot number are numbered by position, and rows without a scene take the
+// section they are in as their scene. Durations outside the supported range are clamped and
+// rows without one get the default; both are reported in the returned notes.
+func shotTableDrafts(rows []services.ShotTableRow, catalogs []models.AssetCatalog) ([]DraftShot, []string) {
+	reconcilers := newEntityReconcilers(catalogs)
+
+	refs := func(assetType string, names []string) []EntityRef {
+		r := reconcilers[assetType]
+		out := make([]EntityRef, 0, len(names))
+		for _, name := range names {
+			out = append(out, r.canon[r.resolve(EntityRef{Name: name})])
+		}
+		return mergeRefs(out, nil)
+	}
+
+	var notes []string
+	missing := 0
+	shots := make([]DraftShot, 0, len(rows))
+	for i, row := range rows {
+		duration := normalizeDuration(row.Duration)
+		if row.Duration <= 0 {
+			missing++
+		} else if duration != row.Duration {
+			notes = append(notes, fmt.Sprintf("第 %d 行：时长 %d 秒超出 %d-%d 秒，已改为 %d 秒", row.Line, row.Duration, minShotDuration, maxShotDuration, duration))
+		}
+		shotNo := row.ShotNo
+		if shotNo == "" {
+			shotNo = fmt.Sprintf("%d", i+1)
+		}
+		scenes := row.Scenes
+		if len(scenes) == 0 && row.Section != "" {
+			scenes = []string{row.Section}
+		}
+		shots = append(shots, DraftShot{
+			ShotNo:            shotNo,
+			ShotSize:          row.ShotSize,
+			CameraMovement:    row.CameraMovement,
+			FrameContent:      row.FrameContent,
+			Characters:        refs("character", row.Characters),
+			Scenes:            refs("scene", scenes),
+			Elements:          refs("element", row.Elements),
+			Styles:            refs("style", row.Styles),
+			SoundDesign:       row.SoundDesign,
+			EstimatedDuration: duration,
+		})
+	}
+	if missing > 0 {
+		notes = append(notes, fmt.Sprintf("%d 行没有时长，已使用默认的 %d 秒", missing, normalizeDuration(0)))
+	}
+	return shots, notes
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"seedance-client/models"
+	"seedance-client/services"
+)
+
+func TestShotTableDurations(t *testing.T) {
+	newTestDB(t)
+	project, _ := seedShots(t)
+	rows := []services.ShotTableRow{
+		{Line: 2, FrameContent: "a", Duration: 3},
+		{Line: 3, FrameContent: "b", Duration: 7},
+		{Line: 4, FrameContent: "c", Duration: 8},
+		{Line: 5, FrameContent: "d", Duration: 90},
+		{Line: 6, FrameContent: "e", Duration: 1},
+		{Line: 7, FrameContent: "f"},
+	}
+	shots, notes := shotTableDrafts(rows, nil)
+	wantNotes := []string{
+		"第 5 行：时长 90 秒超出 2-12 秒，已改为 12 秒",
+		"第 6 行：时长 1 秒超出 2-12 秒，已改为 2 秒",
+		"1 行没有时长，已使用默认的 5 秒",
+	}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", notes, wantNotes)
+	}
+	if err := saveDraftShots(project.ID, shots, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved []models.Storyboard
+	models.DB.Preload("Takes").Where("project_id = ?", project.ID).Order("shot_order asc").Find(&saved)
+	var got, takes []int
+	for _, sb := range saved {
+		got = append(got, sb.EstimatedDuration)
+		takes = append(takes, sb.Takes[0].Duration)
+	}
+	want := []int{3, 7, 8, 12, 2, 5}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(takes, want) {
+		t.Errorf("durations = %v, take durations = %v, want %v", got, takes, want)
+	}
+}
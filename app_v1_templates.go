@@ -0,0 +1,682 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"seedance-client/models"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+// Shot durations accepted by the video models, in seconds
+const (
+	minShotDuration = 2
+	maxShotDuration = 12
+)
+
+// decomposeTemplateFileVersion is written to exported template files.
+const decomposeTemplateFileVersion = 1
+
+// DecomposeExtraField is an additional per-shot output field requested by a template.
+// Values are stored on the storyboard as Extra.
+type DecomposeExtraField struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // string | integer | number | boolean
+	Description string   `json:"description"`
+	Enum        []string `json:"enum,omitempty"` // allowed values of a string field
+}
+
+// DecomposeTemplateData is a decomposition prompt profile. The same shape is used for
+// responses, SaveDecomposeTemplate params and the JSON import/export file.
+//
+// Prompts may use the variables {{source_text}}, {{part}}, {{project_name}}, {{aspect_ratio}}
+// and {{duration_options}}.
+type DecomposeTemplateData struct {
+	ID                 uint                  `json:"id"` // 0 for the built-in template
+	Name               string                `json:"name"`
+	Description        string                `json:"description"`
+	Language           string                `json:"language"` // zh | en | ...
+	SystemPrompt       string                `json:"system_prompt"`
+	UserPromptTemplate string                `json:"user_prompt_template"`
+	DurationOptions    []int                 `json:"duration_options"`
+	ExtraFields        []DecomposeExtraField `json:"extra_fields"`
+	IsDefault          bool                  `json:"is_default"`
+	Builtin            bool                  `json:"builtin"`
+	UpdatedAt          time.Time             `json:"updated_at"`
+	// Error is set on a listed template whose stored JSON cannot be read; it cannot be used until saved again.
+	Error string `json:"error,omitempty"`
+}
+
+type decomposeTemplateFile struct {
+	Version   int                     `json:"version"`
+	Templates []DecomposeTemplateData `json:"templates"`
+}
+
+// coreShotFields are the schema fields every template produces; extra fields may not reuse them.
+var coreShotFields = []string{
+	"shot_no",
+	"shot_size",
+	"camera_movement",
+	"frame_content",
+	"characters",
+	"scenes",
+	"special_elements",
+	"visual_style",
+	"sound_design",
+	"estimated_duration",
+}
+
+var (
+	templateVarPattern  = regexp.MustCompile(`\{\{\s*([a-zA-Z_]+)\s*\}\}`)
+	extraFieldNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+)
+
+// builtinDecomposeTemplate is used when no template is chosen and none is marked default.
+func builtinDecomposeTemplate() DecomposeTemplateData {
+	return DecomposeTemplateData{
+		Name:        "默认（内置）",
+		Description: "通用影视分镜拆解",
+		Language:    "zh",
+		SystemPrompt: `你是影视分镜结构化助手。将输入文案严格拆解为分镜JSON，确保字段完整并可用于后续视频生产流水线。要求：
+1) 输出必须是JSON，且必须符合给定schema；
+2) 每个分镜必须包含镜号、景别、运镜、画面内容、角色、场景、特殊元素、风格、声音设计、预估时长；
+3) 角色/场景/元素/风格均需包含 id/name/prompt；
+4) 同一场景的不同拍摄角度应视为不同场景，使用不同 id；
+5) estimated_duration 仅允许 {{duration_options}}。`,
+		UserPromptTemplate: "{{part}}请将以下分镜需求转换为结构化数据：\n\n{{source_text}}",
+		DurationOptions:    []int{5, 10},
+		ExtraFields:        []DecomposeExtraField{},
+		Builtin:            true,
+	}
+}
+
+// ============================================================
+// Decomposition Templates
+// ============================================================
+
+// ListDecomposeTemplates returns the built-in template followed by the saved ones.
+// Templates that cannot be read are listed with Error set.
+func (a *App) ListDecomposeTemplates() ([]DecomposeTemplateData, error) {
+	var rows []models.DecomposeTemplate
+	if err := models.DB.Order("name asc").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("加载拆解模板失败：%w", err)
+	}
+	builtin := builtinDecomposeTemplate()
+	out := []DecomposeTemplateData{builtin}
+	hasDefault := false
+	for _, row := range rows {
+		t, err := templateFromModel(row)
+		if err != nil {
+			t.Error = err.Error()
+		}
+		hasDefault = hasDefault || (t.IsDefault && t.Error == "")
+		out = append(out, t)
+	}
+	out[0].IsDefault = !hasDefault
+	return out, nil
+}
+
+// SaveDecomposeTemplate creates a template when ID is 0 and updates it otherwise.
+// Marking a template as default clears the flag on the others.
+func (a *App) SaveDecomposeTemplate(params DecomposeTemplateData) (*DecomposeTemplateData, error) {
+	if err := normalizeDecomposeTemplate(&params); err != nil {
+		return nil, err
+	}
+	row := models.DecomposeTemplate{}
+	if params.ID != 0 {
+		if err := models.DB.First(&row, params.ID).Error; err != nil {
+			return nil, fmt.Errorf("拆解模板不存在")
+		}
+	}
+	var clash int64
+	models.DB.Model(&models.DecomposeTemplate{}).Where("name = ? AND id <> ?", params.Name, params.ID).Count(&clash)
+	if clash > 0 {
+		return nil, fmt.Errorf("已存在同名模板：%s", params.Name)
+	}
+	applyTemplateToModel(&row, params)
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if row.IsDefault {
+			if err := tx.Model(&models.DecomposeTemplate{}).Where("id <> ?", row.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存拆解模板失败：%w", err)
+	}
+	t, err := templateFromModel(row)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// DeleteDecomposeTemplate removes a saved template.
+func (a *App) DeleteDecomposeTemplate(id uint) error {
+	if id == 0 {
+		return fmt.Errorf("内置模板不能删除")
+	}
+	res := models.DB.Delete(&models.DecomposeTemplate{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("删除拆解模板失败：%w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("拆解模板不存在")
+	}
+	return nil
+}
+
+// ExportDecomposeTemplates writes the given templates (all saved ones when ids is empty) to a
+// JSON file chosen by the user. It returns the file path, or "" when the dialog was cancelled.
+func (a *App) ExportDecomposeTemplates(ids []uint) (string, error) {
+	query := models.DB.Order("name asc")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	var rows []models.DecomposeTemplate
+	if err := query.Find(&rows).Error; err != nil {
+		return "", fmt.Errorf("加载拆解模板失败：%w", err)
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("没有可导出的拆解模板")
+	}
+
+	file := decomposeTemplateFile{Version: decomposeTemplateFileVersion}
+	for _, row := range rows {
+		t, err := templateFromModel(row)
+		if err != nil {
+			return "", err
+		}
+		t.ID = 0
+		t.IsDefault = false
+		t.UpdatedAt = time.Time{}
+		file.Templates = append(file.Templates, t)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	defaultName := "decompose_templates.json"
+	if len(rows) == 1 {
+		defaultName = sanitizeTemplateFilename(rows[0].Name) + ".json"
+	}
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		DefaultFilename: defaultName,
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("打开保存对话框失败：%w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+	if err := os.WriteFile(savePath, data, 0644); err != nil {
+		return "", fmt.Errorf("写入模板文件失败：%w", err)
+	}
+	return savePath, nil
+}
+
+// ImportDecomposeTemplates reads templates from a JSON file chosen by the user and saves them.
+// A name that is already taken gets a numeric suffix. Returns the imported templates.
+func (a *App) ImportDecomposeTemplates() ([]DecomposeTemplateData, error) {
+	path, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Import Decomposition Templates",
+		Filters: []wailsRuntime.FileFilter{
+			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return []DecomposeTemplateData{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模板文件失败：%w", err)
+	}
+	templates, err := parseDecomposeTemplateFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make([]DecomposeTemplateData, 0, len(templates))
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range templates {
+			t := templates[i]
+			t.ID = 0
+			t.IsDefault = false
+			if err := normalizeDecomposeTemplate(&t); err != nil {
+				return fmt.Errorf("模板 %q 无效：%w", templates[i].Name, err)
+			}
+			t.Name = uniqueTemplateName(tx, t.Name)
+			var row models.DecomposeTemplate
+			applyTemplateToModel(&row, t)
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			saved, err := templateFromModel(row)
+			if err != nil {
+				return err
+			}
+			imported = append(imported, saved)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("导入拆解模板失败：%w", err)
+	}
+	return imported, nil
+}
+
+// parseDecomposeTemplateFile accepts an export file, a bare template array or a single template.
+func parseDecomposeTemplateFile(data []byte) ([]DecomposeTemplateData, error) {
+	var file decomposeTemplateFile
+	if err := json.Unmarshal(data, &file); err == nil && len(file.Templates) > 0 {
+		if file.Version > decomposeTemplateFileVersion {
+			return nil, fmt.Errorf("模板文件版本 %d 过新，请升级应用", file.Version)
+		}
+		return file.Templates, nil
+	}
+	var list []DecomposeTemplateData
+	if err := json.Unmarshal(data, &list); err == nil && len(list) > 0 {
+		return list, nil
+	}
+	var single DecomposeTemplateData
+	if err := json.Unmarshal(data, &single); err == nil && strings.TrimSpace(single.SystemPrompt) != "" {
+		return []DecomposeTemplateData{single}, nil
+	}
+	return nil, fmt.Errorf("不是有效的拆解模板文件")
+}
+
+// resolveDecomposeTemplate returns the template with the given ID; 0 selects the default.
+// An unreadable default template falls back to the built-in one.
+func resolveDecomposeTemplate(id uint) (*DecomposeTemplateData, error) {
+	var row models.DecomposeTemplate
+	if id != 0 {
+		if err := models.DB.First(&row, id).Error; err != nil {
+			return nil, fmt.Errorf("拆解模板不存在")
+		}
+		t, err := templateFromModel(row)
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+	err := models.DB.Where("is_default = ?", true).First(&row).Error
+	if err == nil {
+		t, err := templateFromModel(row)
+		if err == nil {
+			return &t, nil
+		}
+		log.Printf("Default decomposition template %d is unreadable, using the built-in template: %v", row.ID, err)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	t := builtinDecomposeTemplate()
+	return &t, nil
+}
+
+// normalizeDecomposeTemplate trims and validates a template before it is saved.
+func normalizeDecomposeTemplate(t *DecomposeTemplateData) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Description = strings.TrimSpace(t.Description)
+	t.Language = strings.ToLower(strings.TrimSpace(t.Language))
+	t.SystemPrompt = strings.TrimSpace(t.SystemPrompt)
+	t.UserPromptTemplate = strings.TrimSpace(t.UserPromptTemplate)
+	if t.Name == "" {
+		return fmt.Errorf("模板名称不能为空")
+	}
+	if t.SystemPrompt == "" {
+		return fmt.Errorf("系统提示词不能为空")
+	}
+	if t.UserPromptTemplate == "" {
+		t.UserPromptTemplate = builtinDecomposeTemplate().UserPromptTemplate
+	}
+	for _, m := range templateVarPattern.FindAllStringSubmatch(t.SystemPrompt+"\n"+t.UserPromptTemplate, -1) {
+		if !isTemplateVar(m[1]) {
+			return fmt.Errorf("未知的模板变量 {{%s}}", m[1])
+		}
+	}
+
+	seen := map[int]bool{}
+	durations := make([]int, 0, len(t.DurationOptions))
+	for _, d := range t.DurationOptions {
+		if d < minShotDuration || d > maxShotDuration {
+			return fmt.Errorf("时长选项 %d 超出范围（%d-%d 秒）", d, minShotDuration, maxShotDuration)
+		}
+		if !seen[d] {
+			seen[d] = true
+			durations = append(durations, d)
+		}
+	}
+	sort.Ints(durations)
+	if len(durations) == 0 {
+		durations = []int{5, 10}
+	}
+	t.DurationOptions = durations
+
+	names := map[string]bool{}
+	for _, f := range coreShotFields {
+		names[f] = true
+	}
+	fields := make([]DecomposeExtraField, 0, len(t.ExtraFields))
+	for _, f := range t.ExtraFields {
+		f.Name = strings.TrimSpace(f.Name)
+		f.Type = strings.ToLower(strings.TrimSpace(f.Type))
+		f.Description = strings.TrimSpace(f.Description)
+		if f.Type == "" {
+			f.Type = "string"
+		}
+		if !extraFieldNameRegex.MatchString(f.Name) {
+			return fmt.Errorf("字段名 %q 无效：只能使用小写字母、数字和下划线，且以字母开头", f.Name)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("字段名 %q 重复或与内置字段冲突", f.Name)
+		}
+		names[f.Name] = true
+		switch f.Type {
+		case "string", "integer", "number", "boolean":
+		default:
+			return fmt.Errorf("字段 %s 的类型 %q 不受支持", f.Name, f.Type)
+		}
+		if len(f.Enum) > 0 && f.Type != "string" {
+			return fmt.Errorf("只有字符串字段可以设置可选值（%s）", f.Name)
+		}
+		fields = append(fields, f)
+	}
+	t.ExtraFields = fields
+	return nil
+}
+
+func templateHasVar(text string, name string) bool {
+	for _, m := range templateVarPattern.FindAllStringSubmatch(text, -1) {
+		if m[1] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isTemplateVar(name string) bool {
+	switch name {
+	case "source_text", "part", "project_name", "aspect_ratio", "duration_options":
+		return true
+	}
+	return false
+}
+
+func templateFromModel(row models.DecomposeTemplate) (DecomposeTemplateData, error) {
+	t := DecomposeTemplateData{
+		ID:                 row.ID,
+		Name:               row.Name,
+		Description:        row.Description,
+		Language:           row.Language,
+		SystemPrompt:       row.SystemPrompt,
+		UserPromptTemplate: row.UserPromptTemplate,
+		DurationOptions:    []int{},
+		ExtraFields:        []DecomposeExtraField{},
+		IsDefault:          row.IsDefault,
+		UpdatedAt:          row.UpdatedAt,
+	}
+	if row.DurationOptions != "" {
+		if err := json.Unmarshal([]byte(row.DurationOptions), &t.DurationOptions); err != nil {
+			return t, fmt.Errorf("拆解模板 %q 的时长选项无效：%w", row.Name, err)
+		}
+	}
+	if row.ExtraFieldsJSON != "" {
+		if err := json.Unmarshal([]byte(row.ExtraFieldsJSON), &t.ExtraFields); err != nil {
+			return t, fmt.Errorf("拆解模板 %q 的附加字段无效：%w", row.Name, err)
+		}
+	}
+	if len(t.DurationOptions) == 0 {
+		t.DurationOptions = []int{5, 10}
+	}
+	return t, nil
+}
+
+func applyTemplateToModel(row *models.DecomposeTemplate, t DecomposeTemplateData) {
+	durations, _ := json.Marshal(t.DurationOptions)
+	fields, _ := json.Marshal(t.ExtraFields)
+	row.Name = t.Name
+	row.Description = t.Description
+	row.Language = t.Language
+	row.SystemPrompt = t.SystemPrompt
+	row.UserPromptTemplate = t.UserPromptTemplate
+	row.DurationOptions = string(durations)
+	row.ExtraFieldsJSON = string(fields)
+	row.IsDefault = t.IsDefault
+	row.UpdatedAt = time.Now()
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = row.UpdatedAt
+	}
+}
+
+func uniqueTemplateName(tx *gorm.DB, name string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		var count int64
+		tx.Model(&models.DecomposeTemplate{}).Where("name = ?", candidate).Count(&count)
+		if count == 0 {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)", name, n)
+	}
+}
+
+func sanitizeTemplateFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return "decompose_template"
+	}
+	return name
+}
+
+// ============================================================
+// Prompt Rendering
+// ============================================================
+
+// decomposePrompt is a template bound to the variables of one decomposition run.
+type decomposePrompt struct {
+	template DecomposeTemplateData
+	vars     map[string]string
+	// catalogs are the project's existing entities; the model is asked to reuse their codes.
+	catalogs []models.AssetCatalog
+}
+
+func newDecomposePrompt(t DecomposeTemplateData, project models.Project) *decomposePrompt {
+	durations := make([]string, len(t.DurationOptions))
+	for i, d := range t.DurationOptions {
+		durations[i] = strconv.Itoa(d)
+	}
+	return &decomposePrompt{
+		template: t,
+		vars: map[string]string{
+			"project_name":     project.Name,
+			"aspect_ratio":     project.AspectRatio,
+			"duration_options": strings.Join(durations, " / "),
+		},
+	}
+}
+
+func (p *decomposePrompt) render(text string, extra map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := templateVarPattern.FindStringSubmatch(m)[1]
+		if v, ok := extra[name]; ok {
+			return v
+		}
+		if v, ok := p.vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// systemPrompt renders the system prompt and appends the output language and extra fields.
+func (p *decomposePrompt) systemPrompt() string {
+	var b strings.Builder
+	b.WriteString(p.render(p.template.SystemPrompt, nil))
+	switch p.template.Language {
+	case "":
+	case "zh":
+		b.WriteString("\n所有文本字段请使用中文。")
+	case "en":
+		b.WriteString("\nWrite every text field in English.")
+	default:
+		fmt.Fprintf(&b, "\nWrite every text field in this language: %s.", p.template.Language)
+	}
+	b.WriteString(p.extraFieldsNote())
+	return b.String()
+}
+
+// extraFieldsNote lists the template's extra fields for the system prompt; empty without any.
+func (p *decomposePrompt) extraFieldsNote() string {
+	if len(p.template.ExtraFields) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if p.template.Language == "en" {
+		b.WriteString("\nEach shot must also include these fields:")
+	} else {
+		b.WriteString("\n每个分镜还需包含以下字段：")
+	}
+	for _, f := range p.template.ExtraFields {
+		fmt.Fprintf(&b, "\n- %s (%s)", f.Name, f.Type)
+		if f.Description != "" {
+			b.WriteString(": " + f.Description)
+		}
+		if len(f.Enum) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(f.Enum, " / "))
+		}
+	}
+	return b.String()
+}
+
+// userPrompt renders the user prompt for one chunk of source text; part is empty when unchunked.
+// The source and part note are added even if the template forgot the variables.
+func (p *decomposePrompt) userPrompt(sourceText string, part string) string {
+	tmpl := p.template.UserPromptTemplate
+	if !templateHasVar(tmpl, "source_text") {
+		tmpl += "\n\n{{source_text}}"
+	}
+	if part != "" && !templateHasVar(tmpl, "part") {
+		tmpl = "{{part}}" + tmpl
+	}
+	return p.render(tmpl, map[string]string{"source_text": sourceText, "part": part}) + p.catalogNote()
+}
+
+// catalogNote lists the project's existing characters and scenes so the model reuses their IDs;
+// empty when there are none.
+func (p *decomposePrompt) catalogNote() string {
+	en := p.template.Language == "en"
+	var b strings.Builder
+	for _, t := range []struct{ assetType, zh, en string }{
+		{"character", "角色", "Characters"},
+		{"scene", "场景", "Scenes"},
+		{"element", "元素", "Elements"},
+		{"style", "风格", "Styles"},
+	} {
+		var items []string
+		for _, c := range p.catalogs {
+			if c.AssetType == t.assetType {
+				items = append(items, fmt.Sprintf("%s=%s", c.AssetCode, c.Name))
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		if en {
+			b.WriteString("\n" + t.en + ": " + strings.Join(items, ", "))
+		} else {
+			b.WriteString("\n" + t.zh + "：" + strings.Join(items, "、"))
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	if en {
+		return "\n\nThe project already has these entities (id=name). When the script refers to one of them, use its id and name; give new entities new ids." + b.String()
+	}
+	return "\n\n项目中已有以下实体（id=name）。剧本中出现这些实体时请沿用其 id 和 name，新出现的实体使用新的 id。" + b.String()
+}
+
+// partNote describes chunk i (0-based) of n for the user prompt.
+func (p *decomposePrompt) partNote(i, n int) string {
+	if p.template.Language == "en" {
+		return fmt.Sprintf("The following is part %d of %d of the full script. Decompose only this part, and use the names from the script as the name of characters, scenes and elements.\n", i+1, n)
+	}
+	return fmt.Sprintf("以下是完整剧本的第 %d/%d 部分，请只拆解这一部分；角色、场景、元素请使用剧本中的原名作为 name。\n", i+1, n)
+}
+
+// schema is the response JSON schema: the core shot fields plus the template's extra fields.
+func (p *decomposePrompt) schema() map[string]interface{} {
+	props := map[string]interface{}{
+		"shot_no":            map[string]interface{}{"type": "string"},
+		"shot_size":          map[string]interface{}{"type": "string"},
+		"camera_movement":    map[string]interface{}{"type": "string"},
+		"frame_content":      map[string]interface{}{"type": "string"},
+		"characters":         entityRefSchema(),
+		"scenes":             entityRefSchema(),
+		"special_elements":   entityRefSchema(),
+		"visual_style":       entityRefSchema(),
+		"sound_design":       map[string]interface{}{"type": "string"},
+		"estimated_duration": map[string]interface{}{"type": "integer", "enum": p.template.DurationOptions},
+	}
+	required := append([]string(nil), coreShotFields...)
+	for _, f := range p.template.ExtraFields {
+		prop := map[string]interface{}{"type": f.Type}
+		if f.Description != "" {
+			prop["description"] = f.Description
+		}
+		if len(f.Enum) > 0 {
+			prop["enum"] = f.Enum
+		}
+		props[f.Name] = prop
+		required = append(required, f.Name)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"shots": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":                 "object",
+					"properties":           props,
+					"required":             required,
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"shots"},
+		"additionalProperties": false,
+	}
+}
+
+// extraFieldNames lists the template's extra fields.
+func (p *decomposePrompt) extraFieldNames() []string {
+	names := make([]string, len(p.template.ExtraFields))
+	for i, f := range p.template.ExtraFields {
+		names[i] = f.Name
+	}
+	return names
+}
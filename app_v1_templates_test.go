@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"seedance-client/models"
+)
+
+func TestUnreadableDecomposeTemplate(t *testing.T) {
+	newTestDB(t)
+	a := &App{}
+	good, err := a.SaveDecomposeTemplate(DecomposeTemplateData{Name: "广告", SystemPrompt: "s", DurationOptions: []int{3, 8, 12}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := models.DecomposeTemplate{Name: "损坏", SystemPrompt: "s", DurationOptions: "[5,", IsDefault: true}
+	if err := models.DB.Create(&broken).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := a.ListDecomposeTemplates()
+	if err != nil {
+		t.Fatalf("one unreadable template failed the list: %v", err)
+	}
+	if len(list) != 3 || !list[0].Builtin || !list[0].IsDefault {
+		t.Fatalf("list = %+v", list)
+	}
+	for _, tpl := range list[1:] {
+		if (tpl.ID == broken.ID) != (tpl.Error != "") {
+			t.Errorf("template %q error = %q", tpl.Name, tpl.Error)
+		}
+	}
+
+	// The unreadable default falls back to the built-in template; selecting it fails
+	resolved, err := resolveDecomposeTemplate(0)
+	if err != nil || !resolved.Builtin {
+		t.Errorf("default = %+v, %v", resolved, err)
+	}
+	if _, err := resolveDecomposeTemplate(broken.ID); err == nil || !strings.Contains(err.Error(), "损坏") {
+		t.Errorf("selecting the unreadable template: %v", err)
+	}
+	resolved, err = resolveDecomposeTemplate(good.ID)
+	if err != nil || len(resolved.DurationOptions) != 3 || resolved.DurationOptions[0] != 3 {
+		t.Errorf("template = %+v, %v", resolved, err)
+	}
+}
+
+func TestDecomposeTemplateDurations(t *testing.T) {
+	cases := []struct {
+		options []int
+		want    []int
+		wantErr string
+	}{
+		{nil, []int{5, 10}, ""},
+		{[]int{12, 2, 8, 8}, []int{2, 8, 12}, ""},
+		{[]int{5, 15}, nil, "时长选项 15 超出范围（2-12 秒）"},
+		{[]int{1}, nil, "时长选项 1 超出范围（2-12 秒）"},
+	}
+	for _, tc := range cases {
+		tpl := DecomposeTemplateData{Name: "t", SystemPrompt: "s", DurationOptions: tc.options}
+		err := normalizeDecomposeTemplate(&tpl)
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("%v: err = %v, want %q", tc.options, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || len(tpl.DurationOptions) != len(tc.want) {
+			t.Errorf("%v: options = %v, %v, want %v", tc.options, tpl.DurationOptions, err, tc.want)
+			continue
+		}
+		for i := range tc.want {
+			if tpl.DurationOptions[i] != tc.want[i] {
+				t.Errorf("%v: options = %v, want %v", tc.options, tpl.DurationOptions, tc.want)
+			}
+		}
+	}
+}
+
+func TestNormalizeDuration(t *testing.T) {
+	cases := map[int]int{-3: 5, 0: 5, 1: 2, 2: 2, 7: 7, 8: 8, 12: 12, 30: 12}
+	for in, want := range cases {
+		if got := normalizeDuration(in); got != want {
+			t.Errorf("normalizeDuration(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
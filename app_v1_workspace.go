@@ -1,20 +1,17 @@
 package main
 
 import (
-	"archive/zip"
+	"bufio"
 	"bytes"
 	"context"
-	"encoding/csv"
 	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -54,16 +51,17 @@ type AssetVersionResponse struct {
 }
 
 type AssetCatalogResponse struct {
-	ID           uint                   `json:"id"`
-	ProjectID    uint                   `json:"project_id"`
-	AssetType    string                 `json:"asset_type"`
-	AssetCode    string                 `json:"asset_code"`
-	Name         string                 `json:"name"`
-	Prompt       string                 `json:"prompt"`
-	StoryboardID *uint                  `json:"storyboard_id,omitempty"`
-	Versions     []AssetVersionResponse `json:"versions"`
-	Active       *AssetVersionResponse  `json:"active"`
-	UpdatedAt    time.Time              `json:"updated_at"`
+	ID             uint                   `json:"id"`
+	ProjectID      uint                   `json:"project_id"`
+	AssetType      string                 `json:"asset_type"`
+	AssetCode      string                 `json:"asset_code"`
+	Name           string                 `json:"name"`
+	Prompt         string                 `json:"prompt"`
+	StoryboardID   *uint                  `json:"storyboard_id,omitempty"`
+	LibraryAssetID *uint                  `json:"library_asset_id,omitempty"` // linked to the shared asset library
+	Versions       []AssetVersionResponse `json:"versions"`
+	Active         *AssetVersionResponse  `json:"active"`
+	UpdatedAt      time.Time              `json:"updated_at"`
 }
 
 type ShotFrameVersionResponse struct {
@@ -97,6 +95,7 @@ type V1ShotData struct {
 	SoundDesign       string                     `json:"sound_design"`
 	EstimatedDuration int                        `json:"estimated_duration"`
 	DurationFine      int                        `json:"duration_fine"`
+	Extra             map[string]interface{}     `json:"extra,omitempty"`
 	Takes             []TakeResponse             `json:"takes"`
 	ActiveTake        *TakeResponse              `json:"active_take"`
 	StartFrames       []ShotFrameVersionResponse `json:"start_frames"`
@@ -126,16 +125,26 @@ type V1WorkspaceData struct {
 type StoryboardSourceFile struct {
 	Filename string `json:"filename"`
 	Content  string `json:"content"`
+	// Screenplay is set for Fountain and Final Draft files; see SeedScreenplayCatalog.
+	Screenplay *services.Screenplay `json:"screenplay,omitempty"`
 }
 
 type DecomposeStoryboardParams struct {
 	ProjectID       uint   `json:"project_id"`
 	SourceText      string `json:"source_text"`
 	LLMModelID      string `json:"llm_model_id"`
-	Provider        string `json:"provider"` // ark_default | ark_custom | openai_compatible
+	Provider        string `json:"provider"` // ark_default | ark_custom | openai_compatible | anthropic | ollama
 	APIKey          string `json:"api_key"`
 	BaseURL         string `json:"base_url"`
 	ReplaceExisting bool   `json:"replace_existing"`
+	// ProviderProfileID selects a saved provider profile; it overrides Provider, APIKey, BaseURL
+	// and, when the profile has one, LLMModelID.
+	ProviderProfileID uint `json:"provider_profile_id"`
+	// TemplateID selects a decomposition template; 0 uses the default one.
+	TemplateID uint `json:"template_id"`
+	// RequestID is chosen by the caller; it tags "decompose:shot" events and identifies the
+	// request for CancelDecomposition.
+	RequestID string `json:"request_id"`
 }
 
 type UpdateShotParams struct {
@@ -151,6 +160,8 @@ type UpdateShotParams struct {
 	SoundDesign       string      `json:"sound_design"`
 	EstimatedDuration int         `json:"estimated_duration"`
 	DurationFine      int         `json:"duration_fine"`
+	// Extra replaces the template-defined fields when set; nil leaves them alone.
+	Extra map[string]interface{} `json:"extra,omitempty"`
 }
 
 type CreateV1ShotParams struct {
@@ -201,6 +212,8 @@ type llmDecomposeShot struct {
 	VisualStyle       []EntityRef `json:"visual_style"`
 	SoundDesign       string      `json:"sound_design"`
 	EstimatedDuration int         `json:"estimated_duration"`
+	// Extra holds the template's extra fields; it is filled by parseDecomposeOutput.
+	Extra map[string]interface{} `json:"-"`
 }
 
 type llmDecomposeResponse struct {
@@ -244,6 +257,10 @@ func (a *App) GetV1Workspace(projectID uint) (*V1WorkspaceData, error) {
 			frameMap[f.StoryboardID][f.FrameType] = append(frameMap[f.StoryboardID][f.FrameType], f)
 		}
 	}
+	refMap, err := loadShotRefs(models.DB, storyboardIDs)
+	if err != nil {
+		return nil, err
+	}
 
 	shotList := make([]V1ShotData, 0, len(storyboards))
 	for _, sb := range storyboards {
@@ -280,13 +297,14 @@ func (a *App) GetV1Workspace(projectID uint) (*V1WorkspaceData, error) {
 			ShotSize:          sb.ShotSize,
 			CameraMovement:    sb.CameraMovement,
 			FrameContent:      sb.FrameContent,
-			Characters:        parseEntityRefs(sb.CharactersJSON),
-			Scenes:            parseEntityRefs(sb.ScenesJSON),
-			Elements:          parseEntityRefs(sb.ElementsJSON),
-			Styles:            parseEntityRefs(sb.StylesJSON),
+			Characters:        refMap[sb.ID]["character"],
+			Scenes:            refMap[sb.ID]["scene"],
+			Elements:          refMap[sb.ID]["element"],
+			Styles:            refMap[sb.ID]["style"],
 			SoundDesign:       sb.SoundDesign,
 			EstimatedDuration: normalizeDuration(sb.EstimatedDuration),
 			DurationFine:      sb.DurationFine,
+			Extra:             parseShotExtra(sb.ExtraJSON),
 			Takes:             takes,
 			ActiveTake:        activeTake,
 			StartFrames:       startFrames,
@@ -312,16 +330,17 @@ func (a *App) GetV1Workspace(projectID uint) (*V1WorkspaceData, error) {
 		}
 
 		catalogResp := AssetCatalogResponse{
-			ID:           c.ID,
-			ProjectID:    c.ProjectID,
-			AssetType:    c.AssetType,
-			AssetCode:    c.AssetCode,
-			Name:         c.Name,
-			Prompt:       c.Prompt,
-			StoryboardID: c.StoryboardID,
-			Versions:     versions,
-			Active:       chooseActiveAssetVersion(versions),
-			UpdatedAt:    c.UpdatedAt,
+			ID:             c.ID,
+			ProjectID:      c.ProjectID,
+			AssetType:      c.AssetType,
+			AssetCode:      c.AssetCode,
+			Name:           c.Name,
+			Prompt:         c.Prompt,
+			StoryboardID:   c.StoryboardID,
+			LibraryAssetID: c.LibraryAssetID,
+			Versions:       versions,
+			Active:         chooseActiveAssetVersion(versions),
+			UpdatedAt:      c.UpdatedAt,
 		}
 		assetCatalogs = append(assetCatalogs, catalogResp)
 	}
@@ -351,7 +370,7 @@ func (a *App) SelectStoryboardSourceFile() (*StoryboardSourceFile, error) {
 	result, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
 		Title: "Select Storyboard Source File",
 		Filters: []wailsRuntime.FileFilter{
-			{DisplayName: "Storyboard Source (*.md;*.markdown;*.txt;*.csv;*.tsv;*.xlsx)", Pattern: "*.md;*.markdown;*.txt;*.csv;*.tsv;*.xlsx"},
+			{DisplayName: "Storyboard Source (*.md;*.markdown;*.txt;*.csv;*.tsv;*.xlsx;*.docx;*.pdf;*.fountain;*.fdx)", Pattern: "*.md;*.markdown;*.txt;*.csv;*.tsv;*.xlsx;*.docx;*.pdf;*.fountain;*.spmd;*.fdx"},
 		},
 	})
 	if err != nil {
@@ -363,6 +382,7 @@ func (a *App) SelectStoryboardSourceFile() (*StoryboardSourceFile, error) {
 
 	ext := strings.ToLower(filepath.Ext(result))
 	var content string
+	var screenplay *services.Screenplay
 	switch ext {
 	case ".md", ".markdown", ".txt":
 		b, err := os.ReadFile(result)
@@ -370,37 +390,53 @@ func (a *App) SelectStoryboardSourceFile() (*StoryboardSourceFile, error) {
 			return nil, err
 		}
 		content = string(b)
-	case ".csv":
-		b, err := os.ReadFile(result)
+	case ".csv", ".tsv":
+		table, err := readShotTableFile(result, "")
 		if err != nil {
 			return nil, err
 		}
-		content, err = csvBytesToMarkdown(b, ',')
+		content = services.TableToMarkdown(table)
+	case ".xlsx":
+		sheets, err := services.ReadXLSXSheets(result, nil)
 		if err != nil {
 			return nil, err
 		}
-	case ".tsv":
-		b, err := os.ReadFile(result)
+		// Every sheet goes to the LLM, each under its own heading when there are several
+		var b strings.Builder
+		for _, sheet := range sheets {
+			if len(sheets) > 1 {
+				b.WriteString("## " + sheet.Name + "\n\n")
+			}
+			b.WriteString(services.TableToMarkdown(sheet.Rows) + "\n")
+		}
+		content = strings.TrimSpace(b.String())
+	case ".docx":
+		text, err := services.DocxToMarkdown(result)
 		if err != nil {
 			return nil, err
 		}
-		content, err = csvBytesToMarkdown(b, '\t')
+		content = text
+	case ".pdf":
+		text, err := services.PDFToMarkdown(result)
 		if err != nil {
 			return nil, err
 		}
-	case ".xlsx":
-		md, err := xlsxToMarkdown(result)
+		content = text
+	case ".fountain", ".spmd", ".fdx":
+		sp, err := readScreenplayFile(result)
 		if err != nil {
 			return nil, err
 		}
-		content = md
+		screenplay = sp
+		content = sp.Markdown()
 	default:
 		return nil, fmt.Errorf("unsupported file type: %s", ext)
 	}
 
 	return &StoryboardSourceFile{
-		Filename: filepath.Base(result),
-		Content:  content,
+		Filename:   filepath.Base(result),
+		Content:    content,
+		Screenplay: screenplay,
 	}, nil
 }
 
@@ -408,145 +444,62 @@ func (a *App) SelectStoryboardSourceFile() (*StoryboardSourceFile, error) {
 // LLM Decomposition
 // ============================================================
 
+// DecomposeStoryboardWithLLM decomposes source text into shots and saves them directly.
+// With ReplaceExisting the draft is merged into the existing shots (see ApplyStoryboardChangeset):
+// matched shots are updated in place and keep their takes. Unmatched shots are kept, since
+// removing them would lose their takes without a preview; use PreviewStoryboardDecomposition
+// to remove them. Otherwise the shots are appended after the existing ones.
 func (a *App) DecomposeStoryboardWithLLM(params DecomposeStoryboardParams) (*V1WorkspaceData, error) {
-	if params.ProjectID == 0 {
-		return nil, fmt.Errorf("project_id is required")
-	}
-	if strings.TrimSpace(params.SourceText) == "" {
-		return nil, fmt.Errorf("source_text is required")
-	}
-	if params.LLMModelID == "" {
-		params.LLMModelID = getDefaultLLMModel()
-	}
-	if params.Provider == "" {
-		params.Provider = "ark_default"
-	}
-
-	var project models.Project
-	if err := models.DB.First(&project, params.ProjectID).Error; err != nil {
-		return nil, fmt.Errorf("project not found")
-	}
-	if project.AspectRatio == "" {
-		project.AspectRatio = "16:9"
-	}
-
-	decoded, err := a.callStoryboardDecomposeLLM(params.Provider, params.APIKey, params.BaseURL, params.LLMModelID, params.SourceText)
+	shots, err := a.decomposeToDraft(&params)
 	if err != nil {
 		return nil, err
 	}
-	if len(decoded.Shots) == 0 {
-		return nil, fmt.Errorf("no shots returned by LLM")
-	}
 
-	tx := models.DB.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
+	if err := saveDraftShots(params.ProjectID, shots, params.ReplaceExisting); err != nil {
+		return nil, err
 	}
+	return a.GetV1Workspace(params.ProjectID)
+}
 
-	if params.ReplaceExisting {
-		var oldSBs []models.Storyboard
-		tx.Where("project_id = ?", params.ProjectID).Find(&oldSBs)
-		if len(oldSBs) > 0 {
-			ids := make([]uint, 0, len(oldSBs))
-			for _, sb := range oldSBs {
-				ids = append(ids, sb.ID)
+// saveDraftShots merges draft shots into the project's shots when replace is set, and appends
+// them after the existing shots otherwise. Removals are never applied here: they need the
+// preview, which shows the takes that would be lost.
+func saveDraftShots(projectID uint, shots []DraftShot, replace bool) error {
+	if replace {
+		cs, err := buildDecomposeChangeset(projectID, shots)
+		if err != nil {
+			return err
+		}
+		changes := make([]ShotChange, 0, len(cs.Changes))
+		for _, c := range cs.Changes {
+			if c.Kind != shotChangeRemoved && c.Kind != shotChangeUnchanged {
+				changes = append(changes, c)
 			}
-			tx.Where("storyboard_id IN ?", ids).Delete(&models.ShotFrameVersion{})
 		}
-		tx.Where("project_id = ?", params.ProjectID).Delete(&models.Storyboard{})
-		tx.Where("project_id = ?", params.ProjectID).Delete(&models.AssetCatalog{})
+		return applyDecomposeChanges(projectID, cs, changes)
 	}
 
-	orderBase := 1
-	if !params.ReplaceExisting {
-		var maxOrder int
-		tx.Model(&models.Storyboard{}).Where("project_id = ?", params.ProjectID).Select("COALESCE(MAX(shot_order),0)").Scan(&maxOrder)
-		orderBase = maxOrder + 1
+	var project models.Project
+	if err := models.DB.First(&project, projectID).Error; err != nil {
+		return fmt.Errorf("project not found")
 	}
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		return appendDraftShotsTx(tx, project, shots)
+	})
+}
 
-	defaultModel := config.GetDefaultModel()
-	defaultModelID := ""
-	if defaultModel != nil {
-		defaultModelID = defaultModel.ID
+// appendDraftShotsTx creates draft shots after the project's existing shots.
+func appendDraftShotsTx(tx *gorm.DB, project models.Project, shots []DraftShot) error {
+	var maxOrder int
+	if err := tx.Model(&models.Storyboard{}).Where("project_id = ?", project.ID).Select("COALESCE(MAX(shot_order),0)").Scan(&maxOrder).Error; err != nil {
+		return fmt.Errorf("加载分镜顺序失败：%w", err)
 	}
-	if defaultModelID == "" {
-		defaultModelID = "doubao-seedance-1-5-pro-251215"
-	}
-
-	for i, shot := range decoded.Shots {
-		charRefs := normalizeRefs("character", shot.Characters, i+1)
-		sceneRefs := normalizeRefs("scene", shot.Scenes, i+1)
-		elementRefs := normalizeRefs("element", shot.SpecialElements, i+1)
-		styleRefs := normalizeRefs("style", shot.VisualStyle, i+1)
-
-		charJSON, _ := refsToJSON(charRefs)
-		sceneJSON, _ := refsToJSON(sceneRefs)
-		elementJSON, _ := refsToJSON(elementRefs)
-		styleJSON, _ := refsToJSON(styleRefs)
-
-		sb := models.Storyboard{
-			ProjectID:         params.ProjectID,
-			ShotOrder:         orderBase + i,
-			ShotNo:            strings.TrimSpace(shot.ShotNo),
-			ShotSize:          strings.TrimSpace(shot.ShotSize),
-			CameraMovement:    strings.TrimSpace(shot.CameraMovement),
-			FrameContent:      strings.TrimSpace(shot.FrameContent),
-			CharactersJSON:    charJSON,
-			ScenesJSON:        sceneJSON,
-			ElementsJSON:      elementJSON,
-			StylesJSON:        styleJSON,
-			SoundDesign:       strings.TrimSpace(shot.SoundDesign),
-			EstimatedDuration: normalizeDuration(shot.EstimatedDuration),
-			DurationFine:      0,
-			CreatedAt:         time.Now(),
-			UpdatedAt:         time.Now(),
-		}
-		if err := tx.Create(&sb).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-
-		if err := syncCatalogRefsTx(tx, params.ProjectID, sb.ID, "character", charRefs); err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		if err := syncCatalogRefsTx(tx, params.ProjectID, sb.ID, "scene", sceneRefs); err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		if err := syncCatalogRefsTx(tx, params.ProjectID, sb.ID, "element", elementRefs); err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		if err := syncCatalogRefsTx(tx, params.ProjectID, sb.ID, "style", styleRefs); err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-
-		initialPrompt := composeShotPrompt(sb.FrameContent, charRefs, sceneRefs, elementRefs, styleRefs, sb.SoundDesign)
-		take := models.Take{
-			StoryboardID:   sb.ID,
-			Prompt:         initialPrompt,
-			ModelID:        defaultModelID,
-			Ratio:          project.AspectRatio,
-			Duration:       sb.EstimatedDuration,
-			GenerateAudio:  false,
-			ServiceTier:    "standard",
-			GenerationMode: "standard",
-			Status:         "Draft",
-			CreatedAt:      time.Now(),
-		}
-		if err := tx.Create(&take).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+	for i, shot := range shots {
+		if _, err := createStoryboardFromDraftTx(tx, project, normalizeDraftShot(shot, i+1), maxOrder+1+i); err != nil {
+			return err
 		}
 	}
-
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
-	}
-
-	return a.GetV1Workspace(params.ProjectID)
+	return nil
 }
 
 // ============================================================
@@ -666,58 +619,48 @@ func (a *App) UpdateShotMetadata(params UpdateShotParams) error {
 		return fmt.Errorf("加载分镜失败：%w", err)
 	}
 
+	tx := models.DB.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("数据库事务启动失败：%w", tx.Error)
+	}
+	if err := updateShotMetadataTx(tx, &sb, params); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("提交保存失败：%w", err)
+	}
+	return nil
+}
+
+// updateShotMetadataTx applies UpdateShotParams to a loaded shot and syncs its catalog refs.
+func updateShotMetadataTx(tx *gorm.DB, sb *models.Storyboard, params UpdateShotParams) error {
 	charRefs := normalizeRefs("character", params.Characters, sb.ShotOrder)
 	sceneRefs := normalizeRefs("scene", params.Scenes, sb.ShotOrder)
 	elementRefs := normalizeRefs("element", params.Elements, sb.ShotOrder)
 	styleRefs := normalizeRefs("style", params.Styles, sb.ShotOrder)
 
-	charJSON, _ := refsToJSON(charRefs)
-	sceneJSON, _ := refsToJSON(sceneRefs)
-	elementJSON, _ := refsToJSON(elementRefs)
-	styleJSON, _ := refsToJSON(styleRefs)
-
 	sb.ShotNo = strings.TrimSpace(params.ShotNo)
 	sb.ShotSize = strings.TrimSpace(params.ShotSize)
 	sb.CameraMovement = strings.TrimSpace(params.CameraMovement)
 	sb.FrameContent = strings.TrimSpace(params.FrameContent)
-	sb.CharactersJSON = charJSON
-	sb.ScenesJSON = sceneJSON
-	sb.ElementsJSON = elementJSON
-	sb.StylesJSON = styleJSON
 	sb.SoundDesign = strings.TrimSpace(params.SoundDesign)
 	sb.EstimatedDuration = normalizeDuration(params.EstimatedDuration)
 	sb.DurationFine = params.DurationFine
-	sb.UpdatedAt = time.Now()
-
-	tx := models.DB.Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("数据库事务启动失败：%w", tx.Error)
+	if params.Extra != nil {
+		sb.ExtraJSON = shotExtraJSON(params.Extra)
 	}
+	sb.UpdatedAt = time.Now()
 
-	if err := tx.Save(&sb).Error; err != nil {
-		tx.Rollback()
+	if err := tx.Save(sb).Error; err != nil {
 		return fmt.Errorf("保存分镜失败：%w", err)
 	}
-	if err := syncCatalogRefsTx(tx, sb.ProjectID, sb.ID, "character", charRefs); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("同步角色资产引用失败：%w", err)
-	}
-	if err := syncCatalogRefsTx(tx, sb.ProjectID, sb.ID, "scene", sceneRefs); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("同步场景资产引用失败：%w", err)
-	}
-	if err := syncCatalogRefsTx(tx, sb.ProjectID, sb.ID, "element", elementRefs); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("同步元素资产引用失败：%w", err)
-	}
-	if err := syncCatalogRefsTx(tx, sb.ProjectID, sb.ID, "style", styleRefs); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("同步风格资产引用失败：%w", err)
-	}
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("提交保存失败：%w", err)
-	}
-	return nil
+	return saveShotRefsTx(tx, sb.ProjectID, sb.ID, map[string][]EntityRef{
+		"character": charRefs,
+		"scene":     sceneRefs,
+		"element":   elementRefs,
+		"style":     styleRefs,
+	})
 }
 
 func (a *App) DeleteV1Shot(storyboardID uint) error {
@@ -740,6 +683,10 @@ func (a *App) DeleteV1Shot(storyboardID uint) error {
 		tx.Rollback()
 		return fmt.Errorf("删除分镜首尾帧版本失败：%w", err)
 	}
+	if err := tx.Where("storyboard_id = ?", storyboardID).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除分镜资产引用失败：%w", err)
+	}
 	if err := tx.Delete(&models.Storyboard{}, storyboardID).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("删除分镜失败：%w", err)
@@ -774,22 +721,17 @@ func (a *App) MergeShotWithNext(storyboardID uint) error {
 		return fmt.Errorf("查询下一镜失败：%w", err)
 	}
 
-	mergedChars := mergeRefs(parseEntityRefs(current.CharactersJSON), parseEntityRefs(next.CharactersJSON))
-	mergedScenes := mergeRefs(parseEntityRefs(current.ScenesJSON), parseEntityRefs(next.ScenesJSON))
-	mergedElements := mergeRefs(parseEntityRefs(current.ElementsJSON), parseEntityRefs(next.ElementsJSON))
-	mergedStyles := mergeRefs(parseEntityRefs(current.StylesJSON), parseEntityRefs(next.StylesJSON))
-
-	charJSON, _ := refsToJSON(mergedChars)
-	sceneJSON, _ := refsToJSON(mergedScenes)
-	elementJSON, _ := refsToJSON(mergedElements)
-	styleJSON, _ := refsToJSON(mergedStyles)
+	refs, err := loadShotRefs(models.DB, []uint{current.ID, next.ID})
+	if err != nil {
+		return fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+	merged := map[string][]EntityRef{}
+	for _, t := range catalogAssetTypes {
+		merged[t] = mergeRefs(refs[current.ID][t], refs[next.ID][t])
+	}
 
 	current.FrameContent = strings.TrimSpace(strings.TrimSpace(current.FrameContent) + "\n" + strings.TrimSpace(next.FrameContent))
 	current.SoundDesign = strings.TrimSpace(strings.TrimSpace(current.SoundDesign) + "\n" + strings.TrimSpace(next.SoundDesign))
-	current.CharactersJSON = charJSON
-	current.ScenesJSON = sceneJSON
-	current.ElementsJSON = elementJSON
-	current.StylesJSON = styleJSON
 	if current.ShotNo != "" && next.ShotNo != "" {
 		current.ShotNo = current.ShotNo + "+" + next.ShotNo
 	}
@@ -809,6 +751,14 @@ func (a *App) MergeShotWithNext(storyboardID uint) error {
 		tx.Rollback()
 		return fmt.Errorf("保存合并结果失败：%w", err)
 	}
+	if err := tx.Where("storyboard_id = ?", next.ID).Delete(&models.StoryboardAssetRef{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("删除被合并分镜的资产引用失败：%w", err)
+	}
+	if err := saveShotRefsTx(tx, current.ProjectID, current.ID, merged); err != nil {
+		tx.Rollback()
+		return err
+	}
 	if err := tx.Where("storyboard_id = ?", next.ID).Delete(&models.ShotFrameVersion{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("删除被合并分镜的首尾帧版本失败：%w", err)
@@ -882,10 +832,6 @@ func (a *App) SplitShot(params SplitShotParams) (uint, error) {
 		ShotSize:          sb.ShotSize,
 		CameraMovement:    sb.CameraMovement,
 		FrameContent:      secondContent,
-		CharactersJSON:    sb.CharactersJSON,
-		ScenesJSON:        sb.ScenesJSON,
-		ElementsJSON:      sb.ElementsJSON,
-		StylesJSON:        sb.StylesJSON,
 		SoundDesign:       sb.SoundDesign,
 		EstimatedDuration: sb.EstimatedDuration,
 		DurationFine:      sb.DurationFine,
@@ -896,6 +842,15 @@ func (a *App) SplitShot(params SplitShotParams) (uint, error) {
 		tx.Rollback()
 		return 0, fmt.Errorf("创建第二镜失败：%w", err)
 	}
+	refs, err := shotRefs(tx, sb.ID)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
+	if err := saveShotRefsTx(tx, sb.ProjectID, newSB.ID, refs); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
 
 	var project models.Project
 	tx.First(&project, sb.ProjectID)
@@ -918,6 +873,8 @@ func (a *App) SplitShot(params SplitShotParams) (uint, error) {
 	baseTake.ID = 0
 	baseTake.StoryboardID = newSB.ID
 	baseTake.TaskID = ""
+	baseTake.SubmittedPrompt = ""
+	baseTake.RequestSnapshot = ""
 	baseTake.Status = "Draft"
 	baseTake.VideoURL = ""
 	baseTake.LastFrameURL = ""
@@ -928,10 +885,10 @@ func (a *App) SplitShot(params SplitShotParams) (uint, error) {
 	baseTake.IsGood = false
 	baseTake.Prompt = composeShotPrompt(
 		newSB.FrameContent,
-		parseEntityRefs(newSB.CharactersJSON),
-		parseEntityRefs(newSB.ScenesJSON),
-		parseEntityRefs(newSB.ElementsJSON),
-		parseEntityRefs(newSB.StylesJSON),
+		refs["character"],
+		refs["scene"],
+		refs["element"],
+		refs["style"],
 		newSB.SoundDesign,
 	)
 	baseTake.CreatedAt = time.Now()
@@ -1208,17 +1165,20 @@ func getActiveShotFramePath(storyboardID uint, frameType string) string {
 	return active.ImagePath
 }
 
-func composeTakePromptWithAssetRefs(sb models.Storyboard, basePrompt string) string {
+// takePromptParts is the take prompt with reference sections for the shot's catalog assets,
+// named and described as the shot uses them. It is compiled into the submitted prompt by
+// compileTakePrompt.
+func takePromptParts(sb models.Storyboard, basePrompt string) (services.PromptParts, error) {
 	base := strings.TrimSpace(basePrompt)
 	projectID := sb.ProjectID
 
-	charRefs := parseEntityRefs(sb.CharactersJSON)
-	sceneRefs := parseEntityRefs(sb.ScenesJSON)
-	elementRefs := parseEntityRefs(sb.ElementsJSON)
-	styleRefs := parseEntityRefs(sb.StylesJSON)
+	refs, err := shotRefs(models.DB, sb.ID)
+	if err != nil {
+		return services.PromptParts{}, fmt.Errorf("加载分镜资产引用失败：%w", err)
+	}
 
 	catalogs := loadCatalogMap(projectID)
-	var sections []string
+	parts := services.PromptParts{Base: base}
 	appendSection := func(title string, refs []EntityRef, assetType string) {
 		lines := make([]string, 0, len(refs))
 		for _, ref := range refs {
@@ -1227,12 +1187,6 @@ func composeTakePromptWithAssetRefs(sb models.Storyboard, basePrompt string) str
 			name := ref.Name
 			prompt := ref.Prompt
 			if ok {
-				if catalog.Name != "" {
-					name = catalog.Name
-				}
-				if catalog.Prompt != "" {
-					prompt = catalog.Prompt
-				}
 				if active := chooseActiveCatalogVersionModel(catalog.Versions); active != nil && active.ImagePath != "" {
 					prompt = strings.TrimSpace(prompt + "（参考图: /" + active.ImagePath + "）")
 				}
@@ -1247,19 +1201,16 @@ func composeTakePromptWithAssetRefs(sb models.Storyboard, basePrompt string) str
 			}
 		}
 		if len(lines) > 0 {
-			sections = append(sections, title+"\n"+strings.Join(lines, "\n"))
+			parts.Sections = append(parts.Sections, services.PromptSection{Title: title, Lines: lines})
 		}
 	}
 
-	appendSection("角色参考", charRefs, "character")
-	appendSection("场景参考", sceneRefs, "scene")
-	appendSection("特殊元素参考", elementRefs, "element")
-	appendSection("风格参考", styleRefs, "style")
+	appendSection("角色参考", refs["character"], "character")
+	appendSection("场景参考", refs["scene"], "scene")
+	appendSection("特殊元素参考", refs["element"], "element")
+	appendSection("风格参考", refs["style"], "style")
 
-	if len(sections) == 0 {
-		return base
-	}
-	return strings.TrimSpace(base + "\n\n" + strings.Join(sections, "\n\n"))
+	return parts, nil
 }
 
 func getChainedFirstFramePath(storyboardID uint) string {
@@ -1307,11 +1258,17 @@ func getDefaultLLMModel() string {
 	return defaultLLMModelID
 }
 
+// normalizeDuration clamps a shot duration to the range the video models accept; 0 or less means unset.
 func normalizeDuration(v int) int {
-	if v >= 10 {
-		return 10
+	switch {
+	case v <= 0:
+		return 5
+	case v < minShotDuration:
+		return minShotDuration
+	case v > maxShotDuration:
+		return maxShotDuration
 	}
-	return 5
+	return v
 }
 
 func normalizeFrameType(v string) string {
@@ -1325,30 +1282,6 @@ func normalizeFrameType(v string) string {
 	}
 }
 
-func parseEntityRefs(raw string) []EntityRef {
-	if strings.TrimSpace(raw) == "" {
-		return []EntityRef{}
-	}
-	var refs []EntityRef
-	if err := json.Unmarshal([]byte(raw), &refs); err != nil {
-		return []EntityRef{}
-	}
-	for i := range refs {
-		refs[i].ID = strings.TrimSpace(refs[i].ID)
-		refs[i].Name = strings.TrimSpace(refs[i].Name)
-		refs[i].Prompt = strings.TrimSpace(refs[i].Prompt)
-	}
-	return refs
-}
-
-func refsToJSON(refs []EntityRef) (string, error) {
-	b, err := json.Marshal(refs)
-	if err != nil {
-		return "[]", err
-	}
-	return string(b), nil
-}
-
 func normalizeRefs(assetType string, refs []EntityRef, shotIndex int) []EntityRef {
 	out := make([]EntityRef, 0, len(refs))
 	for i, ref := range refs {
@@ -1610,65 +1543,50 @@ func (a *App) generateImageToLocal(modelID string, prompt string, inputImages []
 	return local, nil
 }
 
-func (a *App) callStoryboardDecomposeLLM(provider string, apiKey string, baseURL string, modelID string, sourceText string) (*llmDecomposeResponse, error) {
-	systemPrompt := `你是影视分镜结构化助手。将输入文案严格拆解为分镜JSON，确保字段完整并可用于后续视频生产流水线。要求：
-1) 输出必须是JSON，且必须符合给定schema；
-2) 每个分镜必须包含镜号、景别、运镜、画面内容、角色、场景、特殊元素、风格、声音设计、预估时长；
-3) 角色/场景/元素/风格均需包含 id/name/prompt；
-4) 同一场景的不同拍摄角度应视为不同场景，使用不同 id；
-5) estimated_duration 仅允许 5 或 10。`
-
-	schema := map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"shots": map[string]interface{}{
-				"type": "array",
-				"items": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"shot_no":            map[string]interface{}{"type": "string"},
-						"shot_size":          map[string]interface{}{"type": "string"},
-						"camera_movement":    map[string]interface{}{"type": "string"},
-						"frame_content":      map[string]interface{}{"type": "string"},
-						"characters":         entityRefSchema(),
-						"scenes":             entityRefSchema(),
-						"special_elements":   entityRefSchema(),
-						"visual_style":       entityRefSchema(),
-						"sound_design":       map[string]interface{}{"type": "string"},
-						"estimated_duration": map[string]interface{}{"type": "integer", "enum": []int{5, 10}},
-					},
-					"required": []string{
-						"shot_no",
-						"shot_size",
-						"camera_movement",
-						"frame_content",
-						"characters",
-						"scenes",
-						"special_elements",
-						"visual_style",
-						"sound_design",
-						"estimated_duration",
-					},
-					"additionalProperties": false,
-				},
-			},
-		},
-		"required":             []string{"shots"},
-		"additionalProperties": false,
+// decomposeChunkLLM decomposes one chunk of source text. part is empty for unchunked sources,
+// otherwise it tells the model which part of the script it is looking at. onShot, when set, is
+// called with every shot as soon as it has been streamed completely.
+func (a *App) decomposeChunkLLM(ctx context.Context, provider string, apiKey string, baseURL string, modelID string, prompt *decomposePrompt, sourceText string, part string, onShot func(llmDecomposeShot)) (*llmDecomposeResponse, error) {
+	systemPrompt := prompt.systemPrompt()
+	userPrompt := prompt.userPrompt(sourceText, part)
+	schema := prompt.schema()
+
+	var onText llmTextFunc
+	if onShot != nil {
+		stream := &services.JSONArrayStream{Emit: func(raw json.RawMessage) {
+			var shot llmDecomposeShot
+			if err := json.Unmarshal(raw, &shot); err == nil {
+				onShot(shot)
+			}
+		}}
+		onText = func(delta string) { stream.Write([]byte(delta)) }
 	}
+	return a.requestShotsLLM(ctx, provider, apiKey, baseURL, modelID, systemPrompt, userPrompt, schema, prompt.extraFieldNames(), onText)
+}
 
-	userPrompt := "请将以下分镜需求转换为结构化数据：\n\n" + sourceText
-	raw, err := a.requestDecomposeLLM(provider, apiKey, baseURL, modelID, systemPrompt, userPrompt, schema)
+// requestShotsLLM requests shots in the decomposition schema and validates the output,
+// re-asking once with the validation errors before giving up.
+func (a *App) requestShotsLLM(ctx context.Context, provider string, apiKey string, baseURL string, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}, extraFields []string, onText llmTextFunc) (*llmDecomposeResponse, error) {
+	raw, err := a.requestDecomposeLLM(ctx, provider, apiKey, baseURL, modelID, systemPrompt, userPrompt, schema, onText)
 	if err != nil {
 		return nil, err
 	}
-	raw = stripMarkdownJSONFence(raw)
+	decoded, problems := parseDecomposeOutput(raw, schema, extraFields)
+	if len(problems) == 0 {
+		return decoded, nil
+	}
 
-	var decoded llmDecomposeResponse
-	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
-		return nil, fmt.Errorf("invalid llm json output: %w", err)
+	// One targeted re-ask with the validation errors before giving up
+	log.Printf("Decompose: output failed validation, asking again: %s", strings.Join(problems, "; "))
+	raw, err = a.requestDecomposeLLM(ctx, provider, apiKey, baseURL, modelID, systemPrompt, decomposeRepairPrompt(userPrompt, raw, problems), schema, nil)
+	if err != nil {
+		return nil, err
 	}
-	return &decoded, nil
+	decoded, problems = parseDecomposeOutput(raw, schema, extraFields)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("[E_LLM_INVALID_OUTPUT] LLM 输出未通过校验（已重试 1 次）：%s", summarizeProblems(problems))
+	}
+	return decoded, nil
 }
 
 func entityRefSchema() map[string]interface{} {
@@ -1687,54 +1605,33 @@ func entityRefSchema() map[string]interface{} {
 	}
 }
 
-func stripMarkdownJSONFence(s string) string {
-	s = strings.TrimSpace(s)
-	if strings.HasPrefix(s, "```") {
-		s = strings.TrimPrefix(s, "```json")
-		s = strings.TrimPrefix(s, "```JSON")
-		s = strings.TrimPrefix(s, "```")
-		s = strings.TrimSuffix(s, "```")
+// llmTextFunc receives streamed completion text as it arrives.
+type llmTextFunc func(delta string)
+
+// requestDecomposeLLM runs a streaming structured-output completion and returns the full text.
+// onText, when set, is called with every delta; cancelling ctx aborts the stream.
+func (a *App) requestDecomposeLLM(ctx context.Context, provider string, apiKey string, baseURL string, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}, onText llmTextFunc) (string, error) {
+	llm, err := a.newLLMProvider(provider, apiKey, baseURL)
+	if err != nil {
+		return "", err
 	}
-	return strings.TrimSpace(s)
+	return llm.complete(ctx, llmRequest{
+		ModelID:      modelID,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Schema:       schema,
+	}, onText)
 }
 
-func (a *App) requestDecomposeLLM(provider string, apiKey string, baseURL string, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}) (string, error) {
-	switch provider {
-	case "ark_default":
-		if !a.HasAPIKey() {
-			return "", fmt.Errorf("[E_APIKEY_MISSING] 未配置 API Key：当前选择了“全局 Ark（使用设置里的 API Key）”，请先在右上角【设置】填写 API Key")
-		}
-		return requestWithArkClient(a.volcService.Client, modelID, systemPrompt, userPrompt, schema)
-	case "ark_custom":
-		key := strings.TrimSpace(apiKey)
-		if key == "" {
-			return "", fmt.Errorf("[E_APIKEY_EMPTY] 自定义 Ark 模式需要填写 API Key")
-		}
-		url := strings.TrimSpace(baseURL)
-		if url == "" {
-			url = "https://ark.cn-beijing.volces.com/api/v3"
-		}
-		client := arkruntime.NewClientWithApiKey(
-			key,
-			arkruntime.WithBaseUrl(url),
-		)
-		return requestWithArkClient(client, modelID, systemPrompt, userPrompt, schema)
-	case "openai_compatible":
-		key := strings.TrimSpace(apiKey)
-		url := strings.TrimSpace(baseURL)
-		if key == "" {
-			return "", fmt.Errorf("[E_APIKEY_EMPTY] OpenAI Compatible 模式需要填写 API Key")
-		}
-		if url == "" {
-			return "", fmt.Errorf("[E_BASEURL_EMPTY] OpenAI Compatible 模式需要填写 Base URL")
-		}
-		return requestWithOpenAICompatible(key, url, modelID, systemPrompt, userPrompt, schema)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", provider)
+// llmCancelled maps a cancelled request to a user-facing error.
+func llmCancelled(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return fmt.Errorf("[E_LLM_CANCELLED] 已取消拆解")
 	}
+	return fmt.Errorf("[E_LLM_TIMEOUT] LLM 请求超时：%w", ctx.Err())
 }
 
-func requestWithArkClient(client *arkruntime.Client, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}) (string, error) {
+func requestWithArkClient(ctx context.Context, client *arkruntime.Client, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}, onText llmTextFunc) (string, error) {
 	if client == nil {
 		return "", fmt.Errorf("LLM 客户端未初始化")
 	}
@@ -1766,17 +1663,48 @@ func requestWithArkClient(client *arkruntime.Client, modelID string, systemPromp
 		},
 	}
 
-	resp, err := client.CreateChatCompletion(context.Background(), req)
+	stream, err := client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", llmCancelled(ctx)
+		}
 		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 请求失败（Ark, model=%s）：%w", modelID, err)
 	}
-	if len(resp.Choices) == 0 || resp.Choices[0] == nil || resp.Choices[0].Message.Content == nil || resp.Choices[0].Message.Content.StringValue == nil {
+	defer stream.Close()
+
+	var out strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", llmCancelled(ctx)
+			}
+			return "", fmt.Errorf("[E_LLM_REQUEST] LLM 流式响应中断（Ark, model=%s）：%w", modelID, err)
+		}
+		for _, choice := range resp.Choices {
+			if choice == nil || choice.Delta.Content == "" {
+				continue
+			}
+			out.WriteString(choice.Delta.Content)
+			if onText != nil {
+				onText(choice.Delta.Content)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return "", llmCancelled(ctx)
+	}
+	result := strings.TrimSpace(out.String())
+	if result == "" {
 		return "", fmt.Errorf("[E_LLM_EMPTY] LLM 返回为空（Ark, model=%s）", modelID)
 	}
-	return strings.TrimSpace(*resp.Choices[0].Message.Content.StringValue), nil
+	return result, nil
 }
 
-func requestWithOpenAICompatible(apiKey string, baseURL string, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}) (string, error) {
+func requestWithOpenAICompatible(ctx context.Context, apiKey string, baseURL string, modelID string, systemPrompt string, userPrompt string, schema map[string]interface{}, onText llmTextFunc) (string, error) {
 	type openAIMessage struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
@@ -1792,6 +1720,7 @@ func requestWithOpenAICompatible(apiKey string, baseURL string, modelID string,
 			{Role: "user", Content: userPrompt},
 		},
 		"temperature": 0.2,
+		"stream":      true,
 		"response_format": openAIResponseFormat{
 			Type: "json_schema",
 			JSONSchema: map[string]interface{}{
@@ -1805,51 +1734,104 @@ func requestWithOpenAICompatible(apiKey string, baseURL string, modelID string,
 
 	requestBody, _ := json.Marshal(payload)
 	endpoint := strings.TrimRight(baseURL, "/") + "/chat/completions"
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(requestBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(requestBody))
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
 
-	httpClient := &http.Client{Timeout: 120 * time.Second}
-	resp, err := httpClient.Do(req)
+	resp, err := newStreamingHTTPClient().Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", llmCancelled(ctx)
+		}
 		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 请求失败（OpenAI Compatible, model=%s）：%w", modelID, err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("读取 LLM 响应失败：%w", err)
+	if resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Errors, and providers that ignore "stream", answer with a plain JSON body
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", llmCancelled(ctx)
+			}
+			return "", fmt.Errorf("读取 LLM 响应失败：%w", err)
+		}
+		text, err := parseOpenAICompatibleResponse(resp.StatusCode, body, modelID)
+		if err == nil && onText != nil {
+			onText(text)
+		}
+		return text, err
 	}
 
+	var out strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var event struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if event.Error != nil && event.Error.Message != "" {
+			return "", fmt.Errorf("[E_PROVIDER_HTTP] 提供方返回错误：%s", event.Error.Message)
+		}
+		for _, choice := range event.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			out.WriteString(choice.Delta.Content)
+			if onText != nil {
+				onText(choice.Delta.Content)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return "", llmCancelled(ctx)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("[E_LLM_REQUEST] LLM 流式响应中断（OpenAI Compatible, model=%s）：%w", modelID, err)
+	}
+	result := strings.TrimSpace(out.String())
+	if result == "" {
+		return "", fmt.Errorf("[E_LLM_EMPTY] LLM 返回为空（OpenAI Compatible, model=%s）", modelID)
+	}
+	return result, nil
+}
+
+// parseOpenAICompatibleResponse extracts the message text of a non-streamed chat completion.
+func parseOpenAICompatibleResponse(statusCode int, body []byte, modelID string) (string, error) {
 	var parsed map[string]interface{}
 	if err := json.Unmarshal(body, &parsed); err != nil {
 		return "", fmt.Errorf("解析 OpenAI Compatible 响应失败：%w", err)
 	}
 
-	if resp.StatusCode >= 300 {
+	if statusCode >= 300 {
 		if errObj, ok := parsed["error"].(map[string]interface{}); ok {
 			if msg, ok := errObj["message"].(string); ok && strings.TrimSpace(msg) != "" {
-				code := "E_PROVIDER_HTTP"
-				switch resp.StatusCode {
-				case 401, 403:
-					code = "E_HTTP_401"
-				case 429:
-					code = "E_HTTP_429"
-				}
-				return "", fmt.Errorf("[%s] 提供方返回错误（HTTP %d）：%s", code, resp.StatusCode, msg)
+				return "", fmt.Errorf("[%s] 提供方返回错误（HTTP %d）：%s", httpStatusErrorCode(statusCode), statusCode, msg)
 			}
 		}
-		code := "E_PROVIDER_HTTP"
-		switch resp.StatusCode {
-		case 401, 403:
-			code = "E_HTTP_401"
-		case 429:
-			code = "E_HTTP_429"
-		}
-		return "", fmt.Errorf("[%s] 提供方返回错误：HTTP %d", code, resp.StatusCode)
+		return "", fmt.Errorf("[%s] 提供方返回错误：HTTP %d", httpStatusErrorCode(statusCode), statusCode)
 	}
 
 	choices, ok := parsed["choices"].([]interface{})
@@ -1951,293 +1933,3 @@ func splitBySentence(s string) []string {
 	}
 	return out
 }
-
-func csvBytesToMarkdown(b []byte, comma rune) (string, error) {
-	reader := csv.NewReader(bytes.NewReader(b))
-	reader.Comma = comma
-	reader.FieldsPerRecord = -1
-	records, err := reader.ReadAll()
-	if err != nil {
-		return "", err
-	}
-	if len(records) == 0 {
-		return "", nil
-	}
-	maxCols := 0
-	for _, row := range records {
-		if len(row) > maxCols {
-			maxCols = len(row)
-		}
-	}
-	for i := range records {
-		for len(records[i]) < maxCols {
-			records[i] = append(records[i], "")
-		}
-	}
-
-	var sb strings.Builder
-	writeRow := func(row []string) {
-		sb.WriteString("|")
-		for _, cell := range row {
-			cell = strings.ReplaceAll(strings.TrimSpace(cell), "\n", " ")
-			cell = strings.ReplaceAll(cell, "|", "\\|")
-			sb.WriteString(" " + cell + " |")
-		}
-		sb.WriteString("\n")
-	}
-
-	writeRow(records[0])
-	sb.WriteString("|")
-	for i := 0; i < maxCols; i++ {
-		sb.WriteString(" --- |")
-	}
-	sb.WriteString("\n")
-	for i := 1; i < len(records); i++ {
-		writeRow(records[i])
-	}
-	return sb.String(), nil
-}
-
-type xlsxWorkbook struct {
-	Sheets []xlsxSheet `xml:"sheets>sheet"`
-}
-
-type xlsxSheet struct {
-	Name string `xml:"name,attr"`
-	ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
-}
-
-type xlsxRels struct {
-	Relationships []xlsxRel `xml:"Relationship"`
-}
-
-type xlsxRel struct {
-	ID     string `xml:"Id,attr"`
-	Target string `xml:"Target,attr"`
-}
-
-type xlsxSharedStrings struct {
-	Items []xlsxSI `xml:"si"`
-}
-
-type xlsxSI struct {
-	T string    `xml:"t"`
-	R []xlsxRun `xml:"r"`
-}
-
-type xlsxRun struct {
-	T string `xml:"t"`
-}
-
-type xlsxWorksheet struct {
-	Rows []xlsxRow `xml:"sheetData>row"`
-}
-
-type xlsxRow struct {
-	Cells []xlsxCell `xml:"c"`
-}
-
-type xlsxCell struct {
-	Ref string         `xml:"r,attr"`
-	T   string         `xml:"t,attr"`
-	V   string         `xml:"v"`
-	IS  *xlsxInlineStr `xml:"is"`
-}
-
-type xlsxInlineStr struct {
-	T string    `xml:"t"`
-	R []xlsxRun `xml:"r"`
-}
-
-func xlsxToMarkdown(filePath string) (string, error) {
-	zr, err := zip.OpenReader(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer zr.Close()
-
-	files := map[string]*zip.File{}
-	for _, f := range zr.File {
-		files[f.Name] = f
-	}
-
-	workbookData, err := readZipFile(files["xl/workbook.xml"])
-	if err != nil {
-		return "", fmt.Errorf("invalid xlsx workbook: %w", err)
-	}
-	var wb xlsxWorkbook
-	if err := xml.Unmarshal(workbookData, &wb); err != nil {
-		return "", err
-	}
-	if len(wb.Sheets) == 0 {
-		return "", fmt.Errorf("xlsx has no sheet")
-	}
-
-	relsData, err := readZipFile(files["xl/_rels/workbook.xml.rels"])
-	if err != nil {
-		return "", err
-	}
-	var rels xlsxRels
-	if err := xml.Unmarshal(relsData, &rels); err != nil {
-		return "", err
-	}
-	relMap := map[string]string{}
-	for _, rel := range rels.Relationships {
-		relMap[rel.ID] = rel.Target
-	}
-
-	target := relMap[wb.Sheets[0].ID]
-	if target == "" {
-		return "", fmt.Errorf("cannot find first sheet")
-	}
-	sheetPath := path.Clean("xl/" + target)
-	sheetData, err := readZipFile(files[sheetPath])
-	if err != nil {
-		return "", err
-	}
-
-	shared := []string{}
-	if ssFile, ok := files["xl/sharedStrings.xml"]; ok {
-		ssData, err := readZipFile(ssFile)
-		if err == nil {
-			var sst xlsxSharedStrings
-			if xml.Unmarshal(ssData, &sst) == nil {
-				for _, si := range sst.Items {
-					if si.T != "" {
-						shared = append(shared, si.T)
-						continue
-					}
-					var b strings.Builder
-					for _, run := range si.R {
-						b.WriteString(run.T)
-					}
-					shared = append(shared, b.String())
-				}
-			}
-		}
-	}
-
-	var ws xlsxWorksheet
-	if err := xml.Unmarshal(sheetData, &ws); err != nil {
-		return "", err
-	}
-
-	type rowData struct {
-		cols map[int]string
-	}
-	rows := map[int]rowData{}
-	maxRow := 0
-	maxCol := 0
-
-	for rowIdx, row := range ws.Rows {
-		r := rowIdx + 1
-		entry := rowData{cols: map[int]string{}}
-		for _, cell := range row.Cells {
-			col := excelColIndex(cell.Ref)
-			if col < 0 {
-				continue
-			}
-			val := strings.TrimSpace(cell.V)
-			switch cell.T {
-			case "s":
-				idx, _ := strconv.Atoi(val)
-				if idx >= 0 && idx < len(shared) {
-					val = shared[idx]
-				}
-			case "inlineStr":
-				if cell.IS != nil {
-					if cell.IS.T != "" {
-						val = cell.IS.T
-					} else {
-						var b strings.Builder
-						for _, run := range cell.IS.R {
-							b.WriteString(run.T)
-						}
-						val = b.String()
-					}
-				}
-			}
-			entry.cols[col] = strings.TrimSpace(val)
-			if col > maxCol {
-				maxCol = col
-			}
-		}
-		rows[r] = entry
-		if r > maxRow {
-			maxRow = r
-		}
-	}
-	if maxRow == 0 {
-		return "", nil
-	}
-
-	table := make([][]string, 0, maxRow)
-	for i := 1; i <= maxRow; i++ {
-		row := make([]string, maxCol+1)
-		if v, ok := rows[i]; ok {
-			for c := 0; c <= maxCol; c++ {
-				row[c] = strings.TrimSpace(v.cols[c])
-			}
-		}
-		table = append(table, row)
-	}
-
-	var b strings.Builder
-	writeRow := func(row []string) {
-		b.WriteString("|")
-		for _, cell := range row {
-			cell = strings.ReplaceAll(strings.TrimSpace(cell), "\n", " ")
-			cell = strings.ReplaceAll(cell, "|", "\\|")
-			b.WriteString(" " + cell + " |")
-		}
-		b.WriteString("\n")
-	}
-	writeRow(table[0])
-	b.WriteString("|")
-	for i := 0; i <= maxCol; i++ {
-		b.WriteString(" --- |")
-	}
-	b.WriteString("\n")
-	for i := 1; i < len(table); i++ {
-		writeRow(table[i])
-	}
-	return b.String(), nil
-}
-
-func readZipFile(f *zip.File) ([]byte, error) {
-	if f == nil {
-		return nil, fmt.Errorf("file not found in zip")
-	}
-	rc, err := f.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer rc.Close()
-	return io.ReadAll(rc)
-}
-
-func excelColIndex(ref string) int {
-	if ref == "" {
-		return -1
-	}
-	letters := make([]rune, 0, len(ref))
-	for _, r := range ref {
-		if r >= 'A' && r <= 'Z' {
-			letters = append(letters, r)
-			continue
-		}
-		if r >= 'a' && r <= 'z' {
-			letters = append(letters, r-32)
-			continue
-		}
-		break
-	}
-	if len(letters) == 0 {
-		return -1
-	}
-	col := 0
-	for _, r := range letters {
-		col = col*26 + int(r-'A'+1)
-	}
-	return col - 1
-}
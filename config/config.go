@@ -24,8 +24,15 @@ type Model struct {
 	SupportsAudio bool         `json:"supports_audio"`
 	Default       bool         `json:"default"`
 	Pricing       ModelPricing `json:"pricing"`
+	// MaxPromptLength is the longest text prompt, in characters, submitted to the model when
+	// prompt length enforcement is turned on. The API does not publish a limit, so these are
+	// conservative defaults that can be overridden per model in the settings.
+	MaxPromptLength int `json:"max_prompt_length,omitempty"`
 }
 
+// DefaultMaxPromptLength applies to models without their own max_prompt_length
+const DefaultMaxPromptLength = 800
+
 // ModelsConfig holds the list of available models
 type ModelsConfig struct {
 	Models []Model `json:"models"`
@@ -95,6 +102,14 @@ func GetAudioSupportedModelIDs() []string {
 	return ids
 }
 
+// GetMaxPromptLength returns the prompt length limit of a model
+func GetMaxPromptLength(modelID string) int {
+	if model := GetModelByID(modelID); model != nil && model.MaxPromptLength > 0 {
+		return model.MaxPromptLength
+	}
+	return DefaultMaxPromptLength
+}
+
 // GetPricePerMillion returns the price per million tokens for a take
 func GetPricePerMillion(modelID string, serviceTier string, generateAudio bool) float64 {
 	model := GetModelByID(modelID)
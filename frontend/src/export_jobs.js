@@ -0,0 +1,28 @@
+// Exports run as background jobs on the Go side. Start functions return a job id
+// (or an empty string when the user cancelled the dialog); progress and completion
+// arrive as "export:progress" / "export:done" events carrying that id.
+
+export function waitForExportJob(jobId, onProgress) {
+    return new Promise((resolve, reject) => {
+        if (!jobId) {
+            resolve(null);
+            return;
+        }
+        const offProgress = window.runtime.EventsOn('export:progress', (event) => {
+            if (event?.job_id === jobId && typeof onProgress === 'function') onProgress(event);
+        });
+        const offDone = window.runtime.EventsOn('export:done', (event) => {
+            if (event?.job_id !== jobId) return;
+            offProgress();
+            offDone();
+            if (event.status === 'succeeded') resolve(event);
+            else if (event.status === 'cancelled') resolve(null);
+            else reject(new Error(event.error || '导出失败'));
+        });
+    });
+}
+
+export function cancelExportJob(jobId) {
+    if (!jobId) return Promise.resolve();
+    return window.go.main.App.CancelExport(jobId);
+}
@@ -1,4 +1,5 @@
 import { defineStore } from 'pinia';
+import { waitForExportJob } from '../export_jobs.js';
 
 export const useWorkspaceStore = defineStore('workspace', {
   state: () => ({
@@ -15,6 +16,10 @@ export const useWorkspaceStore = defineStore('workspace', {
     pollTimer: null,
     projectVersion: 'v1.x',
     decomposeText: '',
+    decomposeRequestId: '',
+    decomposeStream: [],
+    decomposeTemplates: [],
+    llmProviders: [],
     imageModelDefault: '',
     apiConfig: {
       llmModel: '',
@@ -22,6 +27,8 @@ export const useWorkspaceStore = defineStore('workspace', {
       baseUrl: 'https://ark.cn-beijing.volces.com/api/v3',
       apiKey: '',
       replaceExisting: true,
+      templateId: 0,
+      providerProfileId: 0,
     },
   }),
 
@@ -131,9 +138,74 @@ export const useWorkspaceStore = defineStore('workspace', {
       await this.fetchWorkspace(this.projectId, { preserveSelection });
     },
 
+    // Screenplays (Fountain / FDX) also pre-seed the character and scene catalog, so the
+    // decomposition reuses those entries.
     async selectStoryboardSourceFile() {
       const result = await window.go.main.App.SelectStoryboardSourceFile();
       if (result?.content) this.decomposeText = result.content;
+      if (result?.screenplay && this.projectId) {
+        result.seeded = await window.go.main.App.SeedScreenplayCatalog(this.projectId, result.screenplay);
+        await this.refreshWorkspace(true);
+      }
+      return result;
+    },
+
+    async selectShotTableFile() {
+      return window.go.main.App.SelectShotTableFile();
+    },
+
+    async inspectShotTable(path, sheet, headerRow) {
+      return window.go.main.App.InspectShotTable(path, sheet || '', Number(headerRow));
+    },
+
+    async inspectShotTableSheets(path, sheets) {
+      return window.go.main.App.InspectShotTableSheets(path, sheets);
+    },
+
+    // Imports one sheet through the column mapping chosen in the UI, without an LLM. With
+    // newProjectName the sheet becomes a project of its own.
+    async importShotTable(table, newProjectName = '') {
+      const result = await window.go.main.App.ImportShotTable({
+        project_id: this.projectId,
+        path: table.path,
+        sheet: table.sheet || '',
+        header_row: Number(table.header_row),
+        columns: (table.columns || []).map((c) => ({ index: c.index, header: c.header, field: c.field || '' })),
+        replace_existing: !newProjectName && !!this.apiConfig.replaceExisting,
+        new_project_name: newProjectName,
+      });
+      if (!newProjectName) await this.refreshWorkspace(false);
+      return result;
+    },
+
+    // Exports every shot with its ID and field version, for editing in Excel and importing back
+    async exportShotList(format = 'xlsx') {
+      return window.go.main.App.ExportShotList({ project_id: this.projectId, format });
+    },
+
+    async exportStoryboardSheet({ format = 'pdf', shotsPerPage = 6, includePrompts = false } = {}) {
+      return window.go.main.App.ExportStoryboardSheet({
+        project_id: this.projectId,
+        format,
+        shots_per_page: shotsPerPage,
+        include_prompts: includePrompts,
+      });
+    },
+
+    async selectShotListFile() {
+      return window.go.main.App.SelectShotListFile();
+    },
+
+    // With dryRun the result only reports updates, new shots and conflicts; useSheet lists the
+    // conflict IDs to resolve in favour of the sheet.
+    async importShotList(path, { dryRun = false, useSheet = [] } = {}) {
+      const result = await window.go.main.App.ImportShotList({
+        project_id: this.projectId,
+        path,
+        dry_run: dryRun,
+        use_sheet: useSheet,
+      });
+      if (!dryRun) await this.refreshWorkspace(false);
       return result;
     },
 
@@ -141,14 +213,16 @@ export const useWorkspaceStore = defineStore('workspace', {
       const sourceText = (this.decomposeText || '').trim();
       if (!sourceText) throw new Error('请先输入分镜文案或导入文件');
 
-      if (this.apiConfig.provider === 'ark_default') {
+      if (this.apiConfig.providerProfileId) {
+        // Provider, key and base URL come from the saved profile
+      } else if (this.apiConfig.provider === 'ark_default') {
         const ok = await this.ensureHasGlobalAPIKey();
         if (!ok) throw new Error(this.error || '未配置 API Key');
-      } else if (!this.apiConfig.apiKey.trim()) {
+      } else if (this.apiConfig.provider !== 'ollama' && !this.apiConfig.apiKey.trim()) {
         throw new Error('请先填写独立 API Key');
       }
 
-      await window.go.main.App.DecomposeStoryboardWithLLM({
+      await this.withDecomposeStream((requestId) => window.go.main.App.DecomposeStoryboardWithLLM({
         project_id: this.projectId,
         source_text: sourceText,
         llm_model_id: this.apiConfig.llmModel,
@@ -156,6 +230,121 @@ export const useWorkspaceStore = defineStore('workspace', {
         api_key: this.apiConfig.apiKey || '',
         base_url: this.apiConfig.baseUrl || '',
         replace_existing: !!this.apiConfig.replaceExisting,
+        template_id: Number(this.apiConfig.templateId || 0),
+        provider_profile_id: Number(this.apiConfig.providerProfileId || 0),
+        request_id: requestId,
+      }));
+      await this.refreshWorkspace(false);
+    },
+
+    // Runs a decomposition call while collecting its "decompose:shot" events into decomposeStream,
+    // ordered by chunk and position, so the UI can show shots as they stream in.
+    async withDecomposeStream(call) {
+      const requestId = `decompose_${Date.now()}_${Math.random().toString(36).slice(2, 8)}`;
+      this.decomposeRequestId = requestId;
+      this.decomposeStream = [];
+      const off = window.runtime.EventsOn('decompose:shot', (event) => {
+        if (event?.request_id !== requestId) return;
+        const next = [...this.decomposeStream, event];
+        next.sort((a, b) => a.chunk - b.chunk || a.index - b.index);
+        this.decomposeStream = next;
+      });
+      try {
+        return await call(requestId);
+      } finally {
+        off();
+        if (this.decomposeRequestId === requestId) this.decomposeRequestId = '';
+      }
+    },
+
+    async cancelDecomposition() {
+      if (!this.decomposeRequestId) return;
+      await window.go.main.App.CancelDecomposition(this.decomposeRequestId);
+    },
+
+    async previewDecomposition() {
+      const sourceText = (this.decomposeText || '').trim();
+      if (!sourceText) throw new Error('请先输入分镜文案或导入文件');
+
+      if (this.apiConfig.providerProfileId) {
+        // Provider, key and base URL come from the saved profile
+      } else if (this.apiConfig.provider === 'ark_default') {
+        const ok = await this.ensureHasGlobalAPIKey();
+        if (!ok) throw new Error(this.error || '未配置 API Key');
+      } else if (this.apiConfig.provider !== 'ollama' && !this.apiConfig.apiKey.trim()) {
+        throw new Error('请先填写独立 API Key');
+      }
+
+      return this.withDecomposeStream((requestId) => window.go.main.App.PreviewStoryboardDecomposition({
+        project_id: this.projectId,
+        source_text: sourceText,
+        llm_model_id: this.apiConfig.llmModel,
+        provider: this.apiConfig.provider,
+        api_key: this.apiConfig.apiKey || '',
+        base_url: this.apiConfig.baseUrl || '',
+        template_id: Number(this.apiConfig.templateId || 0),
+        provider_profile_id: Number(this.apiConfig.providerProfileId || 0),
+        request_id: requestId,
+      }));
+    },
+
+    async fetchLLMProviders() {
+      this.llmProviders = (await window.go.main.App.ListLLMProviders()) || [];
+      const ids = this.llmProviders.map((p) => p.id);
+      if (this.apiConfig.providerProfileId && !ids.includes(this.apiConfig.providerProfileId)) {
+        this.apiConfig.providerProfileId = 0;
+      }
+      if (!this.apiConfig.providerProfileId) {
+        const preferred = this.llmProviders.find((p) => p.is_default);
+        if (preferred) this.apiConfig.providerProfileId = preferred.id;
+      }
+      return this.llmProviders;
+    },
+
+    async saveLLMProvider(profile) {
+      const saved = await window.go.main.App.SaveLLMProvider(profile);
+      await this.fetchLLMProviders();
+      return saved;
+    },
+
+    async deleteLLMProvider(id) {
+      await window.go.main.App.DeleteLLMProvider(Number(id));
+      await this.fetchLLMProviders();
+    },
+
+    async fetchDecomposeTemplates() {
+      this.decomposeTemplates = (await window.go.main.App.ListDecomposeTemplates()) || [];
+      const ids = this.decomposeTemplates.map((t) => t.id);
+      if (!ids.includes(this.apiConfig.templateId)) this.apiConfig.templateId = 0;
+      return this.decomposeTemplates;
+    },
+
+    async saveDecomposeTemplate(template) {
+      const saved = await window.go.main.App.SaveDecomposeTemplate(template);
+      await this.fetchDecomposeTemplates();
+      return saved;
+    },
+
+    async deleteDecomposeTemplate(id) {
+      await window.go.main.App.DeleteDecomposeTemplate(Number(id));
+      await this.fetchDecomposeTemplates();
+    },
+
+    async importDecomposeTemplates() {
+      const imported = await window.go.main.App.ImportDecomposeTemplates();
+      await this.fetchDecomposeTemplates();
+      return imported || [];
+    },
+
+    exportDecomposeTemplates(ids = []) {
+      return window.go.main.App.ExportDecomposeTemplates(ids.map(Number));
+    },
+
+    async applyDecomposition(changeset, selectedIds) {
+      await window.go.main.App.ApplyStoryboardChangeset({
+        project_id: this.projectId,
+        shots: changeset.shots,
+        selected: selectedIds,
       });
       await this.refreshWorkspace(false);
     },
@@ -175,6 +364,39 @@ export const useWorkspaceStore = defineStore('workspace', {
       await this.refreshWorkspace(true);
     },
 
+    // Asks the LLM for a rewrite of one shot; the proposal is only saved by applyShotRewrite.
+    async rewriteShot(storyboardId, action, instruction = '') {
+      return window.go.main.App.RewriteShotWithLLM({
+        storyboard_id: Number(storyboardId),
+        action,
+        instruction: instruction || '',
+        llm_model_id: this.apiConfig.llmModel,
+        provider: this.apiConfig.provider,
+        api_key: this.apiConfig.apiKey || '',
+        base_url: this.apiConfig.baseUrl || '',
+        provider_profile_id: Number(this.apiConfig.providerProfileId || 0),
+        template_id: Number(this.apiConfig.templateId || 0),
+        request_id: '',
+      });
+    },
+
+    async applyShotRewrite(proposal) {
+      if (proposal.second) {
+        const secondId = await window.go.main.App.SplitShot({
+          storyboard_id: Number(proposal.storyboard_id),
+          first_content: proposal.proposed.frame_content,
+          second_content: proposal.second.frame_content,
+        });
+        await window.go.main.App.UpdateShotMetadata({ ...proposal.second, storyboard_id: secondId });
+      }
+      await window.go.main.App.UpdateShotMetadata(proposal.proposed);
+      await this.refreshWorkspace(true);
+    },
+
+    async previewTakePrompt(takeId) {
+      return window.go.main.App.PreviewTakePrompt(Number(takeId));
+    },
+
     async deleteShot(storyboardId) {
       await window.go.main.App.DeleteV1Shot(Number(storyboardId));
       await this.refreshWorkspace(true);
@@ -203,6 +425,83 @@ export const useWorkspaceStore = defineStore('workspace', {
       await this.refreshWorkspace(true);
     },
 
+    async createAssetCatalog(assetType, name, prompt = '', assetCode = '') {
+      const result = await window.go.main.App.CreateAssetCatalog({
+        project_id: this.projectId,
+        asset_type: assetType,
+        asset_code: assetCode,
+        name,
+        prompt,
+      });
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    async deleteAssetCatalog(catalogId) {
+      const result = await window.go.main.App.DeleteAssetCatalog(Number(catalogId));
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    // Changes the type and/or code; shots referencing the asset follow it
+    async recodeAssetCatalog(catalogId, assetType, assetCode) {
+      const result = await window.go.main.App.RecodeAssetCatalog({
+        catalog_id: Number(catalogId),
+        asset_type: assetType || '',
+        asset_code: assetCode || '',
+      });
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    async attachAssetCatalog(catalogId, storyboardIds, attach = true) {
+      const params = { catalog_id: Number(catalogId), storyboard_ids: storyboardIds.map(Number) };
+      const result = attach
+        ? await window.go.main.App.AttachAssetCatalog(params)
+        : await window.go.main.App.DetachAssetCatalog(params);
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    // Shots referencing the asset, with the name and prompt each one uses
+    async getAssetCatalogShots(catalogId) {
+      return window.go.main.App.GetAssetCatalogShots(Number(catalogId));
+    },
+
+    async findAssetDuplicates(assetType = '') {
+      return window.go.main.App.FindAssetDuplicates({ project_id: this.projectId, asset_type: assetType, threshold: 0 });
+    },
+
+    async mergeAssetCatalogs(keepId, mergeIds) {
+      const result = await window.go.main.App.MergeAssetCatalogs({
+        keep_id: Number(keepId),
+        merge_ids: mergeIds.map(Number),
+      });
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    async publishAssetToLibrary(catalogId, tags = '') {
+      const result = await window.go.main.App.PublishAssetToLibrary({ catalog_id: Number(catalogId), tags });
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    async searchAssetLibrary(query = '', assetType = '') {
+      return window.go.main.App.SearchAssetLibrary({ query, asset_type: assetType, limit: 0, offset: 0 });
+    },
+
+    // mode is 'reference' to follow later library updates, or 'copy'
+    async importLibraryAsset(libraryAssetId, mode = 'reference') {
+      const result = await window.go.main.App.ImportLibraryAsset({
+        project_id: this.projectId,
+        library_asset_id: Number(libraryAssetId),
+        mode,
+      });
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
     async uploadAssetImage(catalogId) {
       await window.go.main.App.UploadAssetImage(Number(catalogId));
       await this.refreshWorkspace(true);
@@ -288,8 +587,18 @@ export const useWorkspaceStore = defineStore('workspace', {
     async generateTake(takeId) {
       const ok = await this.ensureHasGlobalAPIKey();
       if (!ok) throw new Error(this.error || '未配置 API Key');
-      await window.go.main.App.GenerateTakeVideo(Number(takeId));
+      const result = await window.go.main.App.GenerateTakeVideo(Number(takeId));
+      await this.refreshWorkspace(true);
+      return result;
+    },
+
+    // Resubmits a take's request snapshot unchanged as a new take.
+    async reproduceTake(takeId) {
+      const ok = await this.ensureHasGlobalAPIKey();
+      if (!ok) throw new Error(this.error || '未配置 API Key');
+      const result = await window.go.main.App.ReproduceTake(Number(takeId));
       await this.refreshWorkspace(true);
+      return result;
     },
 
     async toggleGoodTake(takeId) {
@@ -297,8 +606,9 @@ export const useWorkspaceStore = defineStore('workspace', {
       await this.refreshWorkspace(true);
     },
 
-    async exportProject() {
-      await window.go.main.App.ExportProject(Number(this.projectId));
+    async exportProject(onProgress) {
+      const jobId = await window.go.main.App.ExportProject(Number(this.projectId), {});
+      return waitForExportJob(jobId, onProgress);
     },
 
     updateTakeStatus(takeId, status) {
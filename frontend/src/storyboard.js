@@ -1,5 +1,6 @@
 import { applyLanguage } from './i18n.js';
 import { formatError, reportError, reportErrorOnce } from './errors.js';
+import { waitForExportJob } from './export_jobs.js';
 
 const PANELS = {
     breakdown: 'breakdown',
@@ -803,7 +804,8 @@ function attachCommonEvents() {
     rootContainer.querySelectorAll('[data-export-project]').forEach(btn => {
         btn.addEventListener('click', async () => {
             try {
-                await window.go.main.App.ExportProject(state.projectId);
+                const jobId = await window.go.main.App.ExportProject(state.projectId, {});
+                await waitForExportJob(jobId);
             } catch (err) {
                 if (err) reportError('导出失败', err);
             }
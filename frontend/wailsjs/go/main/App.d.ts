@@ -1,9 +1,24 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {services} from '../models';
+
+export function ApplyStoryboardChangeset(arg1:main.ApplyChangesetParams):Promise<main.V1WorkspaceData>;
+
+export function AttachAssetCatalog(arg1:main.AssetShotsParams):Promise<main.AssetCatalogChange>;
+
+export function BuildRoughCut(arg1:main.BuildRoughCutParams):Promise<services.RoughCutResult>;
+
+export function CancelDecomposition(arg1:string):Promise<void>;
+
+export function CancelExport(arg1:string):Promise<void>;
+
+export function CancelRoughCut(arg1:string):Promise<void>;
 
 export function CopyToUploads(arg1:string):Promise<string>;
 
+export function CreateAssetCatalog(arg1:main.CreateAssetCatalogParams):Promise<main.AssetCatalogResponse>;
+
 export function CreateProject(arg1:main.CreateProjectParams):Promise<void>;
 
 export function CreateStoryboard(arg1:main.CreateStoryboardParams):Promise<void>;
@@ -12,6 +27,14 @@ export function CreateV1Shot(arg1:main.CreateV1ShotParams):Promise<number>;
 
 export function DecomposeStoryboardWithLLM(arg1:main.DecomposeStoryboardParams):Promise<main.V1WorkspaceData>;
 
+export function DeleteAssetCatalog(arg1:number):Promise<main.AssetCatalogChange>;
+
+export function DeleteDecomposeTemplate(arg1:number):Promise<void>;
+
+export function DeleteLLMProvider(arg1:number):Promise<void>;
+
+export function DeleteLibraryAsset(arg1:number):Promise<void>;
+
 export function DeleteProject(arg1:number):Promise<void>;
 
 export function DeleteStoryboard(arg1:number):Promise<number>;
@@ -20,7 +43,19 @@ export function DeleteTake(arg1:number):Promise<main.DeleteTakeResult>;
 
 export function DeleteV1Shot(arg1:number):Promise<void>;
 
-export function ExportProject(arg1:number):Promise<void>;
+export function DetachAssetCatalog(arg1:main.AssetShotsParams):Promise<main.AssetCatalogChange>;
+
+export function ExportDecomposeTemplates(arg1:Array<number>):Promise<string>;
+
+export function ExportProject(arg1:number,arg2:services.ExportOptions):Promise<string>;
+
+export function ExportProjectToFolder(arg1:number,arg2:services.ExportOptions):Promise<string>;
+
+export function ExportShotList(arg1:main.ExportShotListParams):Promise<string>;
+
+export function ExportStoryboardSheet(arg1:main.ExportStoryboardSheetParams):Promise<string>;
+
+export function FindAssetDuplicates(arg1:main.FindAssetDuplicatesParams):Promise<Array<main.AssetDuplicateGroup>>;
 
 export function GenerateAssetImage(arg1:main.GenerateAssetImageParams):Promise<main.AssetVersionResponse>;
 
@@ -28,12 +63,18 @@ export function GenerateShotFrame(arg1:main.GenerateShotFrameParams):Promise<mai
 
 export function GenerateTakeVideo(arg1:number):Promise<Record<string, any>>;
 
+export function GetAssetCatalogShots(arg1:number):Promise<Array<main.AssetShotRef>>;
+
 export function GetModelVersions():Promise<Array<Record<string, string>>>;
 
 export function GetProject(arg1:number):Promise<main.ProjectDetailData>;
 
 export function GetProjects():Promise<main.ProjectsData>;
 
+export function GetPromptCompileSettings():Promise<main.PromptCompileSettings>;
+
+export function GetPromptModelLimits():Promise<Array<main.PromptModelLimit>>;
+
 export function GetSavedAPIKey():Promise<string>;
 
 export function GetTake(arg1:number):Promise<main.TakeResponse>;
@@ -44,12 +85,56 @@ export function GetV1Workspace(arg1:number):Promise<main.V1WorkspaceData>;
 
 export function HasAPIKey():Promise<boolean>;
 
+export function ImportDecomposeTemplates():Promise<Array<main.DecomposeTemplateData>>;
+
+export function ImportLibraryAsset(arg1:main.ImportLibraryAssetParams):Promise<main.AssetCatalogResponse>;
+
+export function ImportShotList(arg1:main.ImportShotListParams):Promise<main.ShotListImportResult>;
+
+export function ImportShotTable(arg1:main.ImportShotTableParams):Promise<main.ShotTableImportResult>;
+
+export function InspectShotTable(arg1:string,arg2:string,arg3:number):Promise<main.ShotTableFile>;
+
+export function InspectShotTableSheets(arg1:string,arg2:Array<string>):Promise<Array<main.ShotTableFile>>;
+
+export function ListDecomposeTemplates():Promise<Array<main.DecomposeTemplateData>>;
+
+export function ListLLMProviders():Promise<Array<main.LLMProviderData>>;
+
 export function ListTakes(arg1:number):Promise<Array<main.TakeResponse>>;
 
+export function MergeAssetCatalogs(arg1:main.MergeAssetCatalogsParams):Promise<main.AssetMergeResult>;
+
 export function MergeShotWithNext(arg1:number):Promise<void>;
 
+export function PreviewStoryboardDecomposition(arg1:main.DecomposeStoryboardParams):Promise<main.DecomposeChangeset>;
+
+export function PreviewTakePrompt(arg1:number):Promise<main.TakePromptPreview>;
+
+export function PublishAssetToLibrary(arg1:main.PublishAssetParams):Promise<main.LibraryAssetResponse>;
+
+export function RecodeAssetCatalog(arg1:main.RecodeAssetCatalogParams):Promise<main.AssetCatalogChange>;
+
+export function ReproduceTake(arg1:number):Promise<Record<string, any>>;
+
+export function RewriteShotWithLLM(arg1:main.RewriteShotParams):Promise<main.ShotRewriteProposal>;
+
+export function SaveDecomposeTemplate(arg1:main.DecomposeTemplateData):Promise<main.DecomposeTemplateData>;
+
+export function SaveLLMProvider(arg1:main.SaveLLMProviderParams):Promise<main.LLMProviderData>;
+
+export function SavePromptCompileSettings(arg1:main.PromptCompileSettings):Promise<void>;
+
+export function SearchAssetLibrary(arg1:main.SearchAssetLibraryParams):Promise<main.AssetLibraryPage>;
+
+export function SeedScreenplayCatalog(arg1:number,arg2:services.Screenplay):Promise<main.ScreenplayCatalogSeed>;
+
 export function SelectImageFile():Promise<string>;
 
+export function SelectShotListFile():Promise<string>;
+
+export function SelectShotTableFile():Promise<main.ShotTableFile>;
+
 export function SelectStoryboardSourceFile():Promise<main.StoryboardSourceFile>;
 
 export function SplitShot(arg1:main.SplitShotParams):Promise<number>;
@@ -2,10 +2,38 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyStoryboardChangeset(arg1) {
+  return window['go']['main']['App']['ApplyStoryboardChangeset'](arg1);
+}
+
+export function AttachAssetCatalog(arg1) {
+  return window['go']['main']['App']['AttachAssetCatalog'](arg1);
+}
+
+export function BuildRoughCut(arg1) {
+  return window['go']['main']['App']['BuildRoughCut'](arg1);
+}
+
+export function CancelDecomposition(arg1) {
+  return window['go']['main']['App']['CancelDecomposition'](arg1);
+}
+
+export function CancelExport(arg1) {
+  return window['go']['main']['App']['CancelExport'](arg1);
+}
+
+export function CancelRoughCut(arg1) {
+  return window['go']['main']['App']['CancelRoughCut'](arg1);
+}
+
 export function CopyToUploads(arg1) {
   return window['go']['main']['App']['CopyToUploads'](arg1);
 }
 
+export function CreateAssetCatalog(arg1) {
+  return window['go']['main']['App']['CreateAssetCatalog'](arg1);
+}
+
 export function CreateProject(arg1) {
   return window['go']['main']['App']['CreateProject'](arg1);
 }
@@ -22,6 +50,22 @@ export function DecomposeStoryboardWithLLM(arg1) {
   return window['go']['main']['App']['DecomposeStoryboardWithLLM'](arg1);
 }
 
+export function DeleteAssetCatalog(arg1) {
+  return window['go']['main']['App']['DeleteAssetCatalog'](arg1);
+}
+
+export function DeleteDecomposeTemplate(arg1) {
+  return window['go']['main']['App']['DeleteDecomposeTemplate'](arg1);
+}
+
+export function DeleteLLMProvider(arg1) {
+  return window['go']['main']['App']['DeleteLLMProvider'](arg1);
+}
+
+export function DeleteLibraryAsset(arg1) {
+  return window['go']['main']['App']['DeleteLibraryAsset'](arg1);
+}
+
 export function DeleteProject(arg1) {
   return window['go']['main']['App']['DeleteProject'](arg1);
 }
@@ -38,8 +82,32 @@ export function DeleteV1Shot(arg1) {
   return window['go']['main']['App']['DeleteV1Shot'](arg1);
 }
 
-export function ExportProject(arg1) {
-  return window['go']['main']['App']['ExportProject'](arg1);
+export function DetachAssetCatalog(arg1) {
+  return window['go']['main']['App']['DetachAssetCatalog'](arg1);
+}
+
+export function ExportDecomposeTemplates(arg1) {
+  return window['go']['main']['App']['ExportDecomposeTemplates'](arg1);
+}
+
+export function ExportProject(arg1, arg2) {
+  return window['go']['main']['App']['ExportProject'](arg1, arg2);
+}
+
+export function ExportProjectToFolder(arg1, arg2) {
+  return window['go']['main']['App']['ExportProjectToFolder'](arg1, arg2);
+}
+
+export function ExportShotList(arg1) {
+  return window['go']['main']['App']['ExportShotList'](arg1);
+}
+
+export function ExportStoryboardSheet(arg1) {
+  return window['go']['main']['App']['ExportStoryboardSheet'](arg1);
+}
+
+export function FindAssetDuplicates(arg1) {
+  return window['go']['main']['App']['FindAssetDuplicates'](arg1);
 }
 
 export function GenerateAssetImage(arg1) {
@@ -54,6 +122,10 @@ export function GenerateTakeVideo(arg1) {
   return window['go']['main']['App']['GenerateTakeVideo'](arg1);
 }
 
+export function GetAssetCatalogShots(arg1) {
+  return window['go']['main']['App']['GetAssetCatalogShots'](arg1);
+}
+
 export function GetModelVersions() {
   return window['go']['main']['App']['GetModelVersions']();
 }
@@ -66,6 +138,14 @@ export function GetProjects() {
   return window['go']['main']['App']['GetProjects']();
 }
 
+export function GetPromptCompileSettings() {
+  return window['go']['main']['App']['GetPromptCompileSettings']();
+}
+
+export function GetPromptModelLimits() {
+  return window['go']['main']['App']['GetPromptModelLimits']();
+}
+
 export function GetSavedAPIKey() {
   return window['go']['main']['App']['GetSavedAPIKey']();
 }
@@ -86,18 +166,106 @@ export function HasAPIKey() {
   return window['go']['main']['App']['HasAPIKey']();
 }
 
+export function ImportDecomposeTemplates() {
+  return window['go']['main']['App']['ImportDecomposeTemplates']();
+}
+
+export function ImportLibraryAsset(arg1) {
+  return window['go']['main']['App']['ImportLibraryAsset'](arg1);
+}
+
+export function ImportShotList(arg1) {
+  return window['go']['main']['App']['ImportShotList'](arg1);
+}
+
+export function ImportShotTable(arg1) {
+  return window['go']['main']['App']['ImportShotTable'](arg1);
+}
+
+export function InspectShotTable(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InspectShotTable'](arg1, arg2, arg3);
+}
+
+export function InspectShotTableSheets(arg1, arg2) {
+  return window['go']['main']['App']['InspectShotTableSheets'](arg1, arg2);
+}
+
+export function ListDecomposeTemplates() {
+  return window['go']['main']['App']['ListDecomposeTemplates']();
+}
+
+export function ListLLMProviders() {
+  return window['go']['main']['App']['ListLLMProviders']();
+}
+
 export function ListTakes(arg1) {
   return window['go']['main']['App']['ListTakes'](arg1);
 }
 
+export function MergeAssetCatalogs(arg1) {
+  return window['go']['main']['App']['MergeAssetCatalogs'](arg1);
+}
+
 export function MergeShotWithNext(arg1) {
   return window['go']['main']['App']['MergeShotWithNext'](arg1);
 }
 
+export function PreviewStoryboardDecomposition(arg1) {
+  return window['go']['main']['App']['PreviewStoryboardDecomposition'](arg1);
+}
+
+export function PreviewTakePrompt(arg1) {
+  return window['go']['main']['App']['PreviewTakePrompt'](arg1);
+}
+
+export function PublishAssetToLibrary(arg1) {
+  return window['go']['main']['App']['PublishAssetToLibrary'](arg1);
+}
+
+export function RecodeAssetCatalog(arg1) {
+  return window['go']['main']['App']['RecodeAssetCatalog'](arg1);
+}
+
+export function ReproduceTake(arg1) {
+  return window['go']['main']['App']['ReproduceTake'](arg1);
+}
+
+export function RewriteShotWithLLM(arg1) {
+  return window['go']['main']['App']['RewriteShotWithLLM'](arg1);
+}
+
+export function SaveDecomposeTemplate(arg1) {
+  return window['go']['main']['App']['SaveDecomposeTemplate'](arg1);
+}
+
+export function SaveLLMProvider(arg1) {
+  return window['go']['main']['App']['SaveLLMProvider'](arg1);
+}
+
+export function SavePromptCompileSettings(arg1) {
+  return window['go']['main']['App']['SavePromptCompileSettings'](arg1);
+}
+
+export function SearchAssetLibrary(arg1) {
+  return window['go']['main']['App']['SearchAssetLibrary'](arg1);
+}
+
+export function SeedScreenplayCatalog(arg1, arg2) {
+  return window['go']['main']['App']['SeedScreenplayCatalog'](arg1, arg2);
+}
+
 export function SelectImageFile() {
   return window['go']['main']['App']['SelectImageFile']();
 }
 
+export function SelectShotListFile() {
+  return window['go']['main']['App']['SelectShotListFile']();
+}
+
+export function SelectShotTableFile() {
+  return window['go']['main']['App']['SelectShotTableFile']();
+}
+
 export function SelectStoryboardSourceFile() {
   return window['go']['main']['App']['SelectStoryboardSourceFile']();
 }
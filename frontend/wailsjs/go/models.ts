@@ -26,6 +26,7 @@ export namespace config {
 	    supports_audio: boolean;
 	    default: boolean;
 	    pricing: ModelPricing;
+	    max_prompt_length?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Model(source);
@@ -38,6 +39,7 @@ export namespace config {
 	        this.supports_audio = source["supports_audio"];
 	        this.default = source["default"];
 	        this.pricing = this.convertValues(source["pricing"], ModelPricing);
+	        this.max_prompt_length = source["max_prompt_length"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -63,6 +65,106 @@ export namespace config {
 
 export namespace main {
 	
+	export class EntityRef {
+	    id: string;
+	    name: string;
+	    prompt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new EntityRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.prompt = source["prompt"];
+	    }
+	}
+	export class DraftShot {
+	    shot_no: string;
+	    shot_size: string;
+	    camera_movement: string;
+	    frame_content: string;
+	    characters: EntityRef[];
+	    scenes: EntityRef[];
+	    elements: EntityRef[];
+	    styles: EntityRef[];
+	    sound_design: string;
+	    estimated_duration: number;
+	    extra?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new DraftShot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shot_no = source["shot_no"];
+	        this.shot_size = source["shot_size"];
+	        this.camera_movement = source["camera_movement"];
+	        this.frame_content = source["frame_content"];
+	        this.characters = this.convertValues(source["characters"], EntityRef);
+	        this.scenes = this.convertValues(source["scenes"], EntityRef);
+	        this.elements = this.convertValues(source["elements"], EntityRef);
+	        this.styles = this.convertValues(source["styles"], EntityRef);
+	        this.sound_design = source["sound_design"];
+	        this.estimated_duration = source["estimated_duration"];
+	        this.extra = source["extra"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ApplyChangesetParams {
+	    project_id: number;
+	    shots: DraftShot[];
+	    selected: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ApplyChangesetParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.shots = this.convertValues(source["shots"], DraftShot);
+	        this.selected = source["selected"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class AssetVersionResponse {
 	    id: number;
 	    catalog_id: number;
@@ -120,6 +222,7 @@ export namespace main {
 	    name: string;
 	    prompt: string;
 	    storyboard_id?: number;
+	    library_asset_id?: number;
 	    versions: AssetVersionResponse[];
 	    active?: AssetVersionResponse;
 	    // Go type: time
@@ -138,6 +241,7 @@ export namespace main {
 	        this.name = source["name"];
 	        this.prompt = source["prompt"];
 	        this.storyboard_id = source["storyboard_id"];
+	        this.library_asset_id = source["library_asset_id"];
 	        this.versions = this.convertValues(source["versions"], AssetVersionResponse);
 	        this.active = this.convertValues(source["active"], AssetVersionResponse);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
@@ -161,219 +265,1408 @@ export namespace main {
 		    return a;
 		}
 	}
-	
-	export class CreateProjectParams {
-	    name: string;
-	    model_version: string;
-	    aspect_ratio: string;
+	export class AssetCatalogChange {
+	    catalog?: AssetCatalogResponse;
+	    shots: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new CreateProjectParams(source);
+	        return new AssetCatalogChange(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.model_version = source["model_version"];
-	        this.aspect_ratio = source["aspect_ratio"];
+	        this.catalog = this.convertValues(source["catalog"], AssetCatalogResponse);
+	        this.shots = source["shots"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class CreateStoryboardParams {
-	    project_id: number;
+	
+	export class AssetDuplicateMember {
+	    id: number;
+	    asset_code: string;
+	    name: string;
 	    prompt: string;
-	    model_id: string;
-	    ratio: string;
-	    duration: number;
-	    generate_audio: boolean;
-	    service_tier: string;
-	    execution_expires_after: number;
-	    first_frame_path: string;
-	    last_frame_path: string;
-	    chain_from_prev: boolean;
-	    generation_mode: string;
+	    versions: number;
+	    shots: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new CreateStoryboardParams(source);
+	        return new AssetDuplicateMember(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.project_id = source["project_id"];
+	        this.id = source["id"];
+	        this.asset_code = source["asset_code"];
+	        this.name = source["name"];
 	        this.prompt = source["prompt"];
-	        this.model_id = source["model_id"];
-	        this.ratio = source["ratio"];
-	        this.duration = source["duration"];
-	        this.generate_audio = source["generate_audio"];
-	        this.service_tier = source["service_tier"];
-	        this.execution_expires_after = source["execution_expires_after"];
-	        this.first_frame_path = source["first_frame_path"];
-	        this.last_frame_path = source["last_frame_path"];
-	        this.chain_from_prev = source["chain_from_prev"];
-	        this.generation_mode = source["generation_mode"];
+	        this.versions = source["versions"];
+	        this.shots = source["shots"];
 	    }
 	}
-	export class CreateV1ShotParams {
-	    project_id: number;
-	    after_storyboard_id: number;
+	export class AssetDuplicateGroup {
+	    asset_type: string;
+	    keep_id: number;
+	    similarity: number;
+	    reason: string;
+	    members: AssetDuplicateMember[];
 	
 	    static createFrom(source: any = {}) {
-	        return new CreateV1ShotParams(source);
+	        return new AssetDuplicateGroup(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.project_id = source["project_id"];
-	        this.after_storyboard_id = source["after_storyboard_id"];
-	    }
-	}
-	export class DecomposeStoryboardParams {
-	    project_id: number;
-	    source_text: string;
-	    llm_model_id: string;
-	    provider: string;
-	    api_key: string;
-	    base_url: string;
-	    replace_existing: boolean;
-	
-	    static createFrom(source: any = {}) {
-	        return new DecomposeStoryboardParams(source);
+	        this.asset_type = source["asset_type"];
+	        this.keep_id = source["keep_id"];
+	        this.similarity = source["similarity"];
+	        this.reason = source["reason"];
+	        this.members = this.convertValues(source["members"], AssetDuplicateMember);
 	    }
 	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.project_id = source["project_id"];
-	        this.source_text = source["source_text"];
-	        this.llm_model_id = source["llm_model_id"];
-	        this.provider = source["provider"];
-	        this.api_key = source["api_key"];
-	        this.base_url = source["base_url"];
-	        this.replace_existing = source["replace_existing"];
-	    }
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class DeleteTakeResult {
-	    success: boolean;
-	    storyboard_deleted: boolean;
-	    remaining_takes: number;
+	
+	export class LibraryAssetVersionResponse {
+	    id: number;
+	    library_asset_id: number;
+	    version_no: number;
+	    image_path: string;
+	    source_type: string;
+	    model_id: string;
+	    prompt: string;
+	    is_good: boolean;
+	    // Go type: time
+	    created_at: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new DeleteTakeResult(source);
+	        return new LibraryAssetVersionResponse(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.success = source["success"];
-	        this.storyboard_deleted = source["storyboard_deleted"];
-	        this.remaining_takes = source["remaining_takes"];
+	        this.id = source["id"];
+	        this.library_asset_id = source["library_asset_id"];
+	        this.version_no = source["version_no"];
+	        this.image_path = source["image_path"];
+	        this.source_type = source["source_type"];
+	        this.model_id = source["model_id"];
+	        this.prompt = source["prompt"];
+	        this.is_good = source["is_good"];
+	        this.created_at = this.convertValues(source["created_at"], null);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class EntityRef {
-	    id: string;
+	export class LibraryAssetResponse {
+	    id: number;
+	    asset_type: string;
 	    name: string;
 	    prompt: string;
+	    tags: string;
+	    versions: LibraryAssetVersionResponse[];
+	    active?: LibraryAssetVersionResponse;
+	    linked_projects: number;
+	    // Go type: time
+	    updated_at: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new EntityRef(source);
+	        return new LibraryAssetResponse(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.asset_type = source["asset_type"];
 	        this.name = source["name"];
 	        this.prompt = source["prompt"];
+	        this.tags = source["tags"];
+	        this.versions = this.convertValues(source["versions"], LibraryAssetVersionResponse);
+	        this.active = this.convertValues(source["active"], LibraryAssetVersionResponse);
+	        this.linked_projects = source["linked_projects"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
 	    }
-	}
-	export class GenerateAssetImageParams {
-	    catalog_id: number;
-	    model_id: string;
-	    prompt: string;
-	    input_images: string[];
 	
-	    static createFrom(source: any = {}) {
-	        return new GenerateAssetImageParams(source);
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AssetLibraryPage {
+	    total: number;
+	    items: LibraryAssetResponse[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AssetLibraryPage(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.catalog_id = source["catalog_id"];
-	        this.model_id = source["model_id"];
-	        this.prompt = source["prompt"];
-	        this.input_images = source["input_images"];
+	        this.total = source["total"];
+	        this.items = this.convertValues(source["items"], LibraryAssetResponse);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AssetMergeResult {
+	    keep_id: number;
+	    merged: number;
+	    moved_versions: number;
+	    rewritten_shots: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AssetMergeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keep_id = source["keep_id"];
+	        this.merged = source["merged"];
+	        this.moved_versions = source["moved_versions"];
+	        this.rewritten_shots = source["rewritten_shots"];
 	    }
 	}
-	export class GenerateShotFrameParams {
+	export class AssetShotRef {
 	    storyboard_id: number;
-	    frame_type: string;
-	    model_id: string;
+	    shot_order: number;
+	    shot_no: string;
+	    frame_content: string;
+	    name: string;
 	    prompt: string;
-	    input_images: string[];
+	    name_override: string;
+	    prompt_override: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new GenerateShotFrameParams(source);
+	        return new AssetShotRef(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.storyboard_id = source["storyboard_id"];
-	        this.frame_type = source["frame_type"];
-	        this.model_id = source["model_id"];
+	        this.shot_order = source["shot_order"];
+	        this.shot_no = source["shot_no"];
+	        this.frame_content = source["frame_content"];
+	        this.name = source["name"];
 	        this.prompt = source["prompt"];
-	        this.input_images = source["input_images"];
+	        this.name_override = source["name_override"];
+	        this.prompt_override = source["prompt_override"];
 	    }
 	}
-	export class TakeResponse {
-	    id: number;
-	    storyboard_id: number;
+	export class AssetShotsParams {
+	    catalog_id: number;
+	    storyboard_ids: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AssetShotsParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.catalog_id = source["catalog_id"];
+	        this.storyboard_ids = source["storyboard_ids"];
+	    }
+	}
+	
+	export class BuildRoughCutParams {
+	    project_id: number;
+	    request_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BuildRoughCutParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.request_id = source["request_id"];
+	    }
+	}
+	export class CreateAssetCatalogParams {
+	    project_id: number;
+	    asset_type: string;
+	    asset_code: string;
+	    name: string;
+	    prompt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CreateAssetCatalogParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.asset_type = source["asset_type"];
+	        this.asset_code = source["asset_code"];
+	        this.name = source["name"];
+	        this.prompt = source["prompt"];
+	    }
+	}
+	export class CreateProjectParams {
+	    name: string;
+	    model_version: string;
+	    aspect_ratio: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CreateProjectParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.model_version = source["model_version"];
+	        this.aspect_ratio = source["aspect_ratio"];
+	    }
+	}
+	export class CreateStoryboardParams {
+	    project_id: number;
 	    prompt: string;
-	    first_frame_path: string;
-	    last_frame_path: string;
 	    model_id: string;
 	    ratio: string;
 	    duration: number;
 	    generate_audio: boolean;
-	    task_id: string;
-	    status: string;
-	    video_url: string;
-	    last_frame_url: string;
-	    local_video_path: string;
-	    local_last_frame_path: string;
-	    download_status: string;
 	    service_tier: string;
-	    token_usage: number;
-	    expires_after: number;
-	    is_good: boolean;
+	    execution_expires_after: number;
+	    first_frame_path: string;
+	    last_frame_path: string;
 	    chain_from_prev: boolean;
 	    generation_mode: string;
-	    // Go type: time
-	    created_at: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new TakeResponse(source);
+	        return new CreateStoryboardParams(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.storyboard_id = source["storyboard_id"];
+	        this.project_id = source["project_id"];
 	        this.prompt = source["prompt"];
-	        this.first_frame_path = source["first_frame_path"];
-	        this.last_frame_path = source["last_frame_path"];
 	        this.model_id = source["model_id"];
 	        this.ratio = source["ratio"];
 	        this.duration = source["duration"];
 	        this.generate_audio = source["generate_audio"];
-	        this.task_id = source["task_id"];
-	        this.status = source["status"];
-	        this.video_url = source["video_url"];
-	        this.last_frame_url = source["last_frame_url"];
-	        this.local_video_path = source["local_video_path"];
-	        this.local_last_frame_path = source["local_last_frame_path"];
-	        this.download_status = source["download_status"];
 	        this.service_tier = source["service_tier"];
-	        this.token_usage = source["token_usage"];
-	        this.expires_after = source["expires_after"];
-	        this.is_good = source["is_good"];
+	        this.execution_expires_after = source["execution_expires_after"];
+	        this.first_frame_path = source["first_frame_path"];
+	        this.last_frame_path = source["last_frame_path"];
 	        this.chain_from_prev = source["chain_from_prev"];
 	        this.generation_mode = source["generation_mode"];
+	    }
+	}
+	export class CreateV1ShotParams {
+	    project_id: number;
+	    after_storyboard_id: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CreateV1ShotParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.after_storyboard_id = source["after_storyboard_id"];
+	    }
+	}
+	export class ShotChange {
+	    id: string;
+	    kind: string;
+	    draft_index: number;
+	    storyboard_id: number;
+	    matched_by: string;
+	    similarity: number;
+	    fields: string[];
+	    before?: DraftShot;
+	    after?: DraftShot;
+	    take_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShotChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.draft_index = source["draft_index"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.matched_by = source["matched_by"];
+	        this.similarity = source["similarity"];
+	        this.fields = source["fields"];
+	        this.before = this.convertValues(source["before"], DraftShot);
+	        this.after = this.convertValues(source["after"], DraftShot);
+	        this.take_count = source["take_count"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DecomposeChangeset {
+	    project_id: number;
+	    shots: DraftShot[];
+	    changes: ShotChange[];
+	    added: number;
+	    changed: number;
+	    removed: number;
+	    unchanged: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DecomposeChangeset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.shots = this.convertValues(source["shots"], DraftShot);
+	        this.changes = this.convertValues(source["changes"], ShotChange);
+	        this.added = source["added"];
+	        this.changed = source["changed"];
+	        this.removed = source["removed"];
+	        this.unchanged = source["unchanged"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DecomposeExtraField {
+	    name: string;
+	    type: string;
+	    description: string;
+	    enum?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DecomposeExtraField(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.description = source["description"];
+	        this.enum = source["enum"];
+	    }
+	}
+	export class DecomposeStoryboardParams {
+	    project_id: number;
+	    source_text: string;
+	    llm_model_id: string;
+	    provider: string;
+	    api_key: string;
+	    base_url: string;
+	    replace_existing: boolean;
+	    provider_profile_id: number;
+	    template_id: number;
+	    request_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DecomposeStoryboardParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.source_text = source["source_text"];
+	        this.llm_model_id = source["llm_model_id"];
+	        this.provider = source["provider"];
+	        this.api_key = source["api_key"];
+	        this.base_url = source["base_url"];
+	        this.replace_existing = source["replace_existing"];
+	        this.provider_profile_id = source["provider_profile_id"];
+	        this.template_id = source["template_id"];
+	        this.request_id = source["request_id"];
+	    }
+	}
+	export class DecomposeTemplateData {
+	    id: number;
+	    name: string;
+	    description: string;
+	    language: string;
+	    system_prompt: string;
+	    user_prompt_template: string;
+	    duration_options: number[];
+	    extra_fields: DecomposeExtraField[];
+	    is_default: boolean;
+	    builtin: boolean;
+	    // Go type: time
+	    updated_at: any;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DecomposeTemplateData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.language = source["language"];
+	        this.system_prompt = source["system_prompt"];
+	        this.user_prompt_template = source["user_prompt_template"];
+	        this.duration_options = source["duration_options"];
+	        this.extra_fields = this.convertValues(source["extra_fields"], DecomposeExtraField);
+	        this.is_default = source["is_default"];
+	        this.builtin = source["builtin"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DeleteTakeResult {
+	    success: boolean;
+	    storyboard_deleted: boolean;
+	    remaining_takes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeleteTakeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.storyboard_deleted = source["storyboard_deleted"];
+	        this.remaining_takes = source["remaining_takes"];
+	    }
+	}
+	
+	
+	export class ExportShotListParams {
+	    project_id: number;
+	    format: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportShotListParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.format = source["format"];
+	    }
+	}
+	export class ExportStoryboardSheetParams {
+	    project_id: number;
+	    format: string;
+	    shots_per_page: number;
+	    include_prompts: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportStoryboardSheetParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.format = source["format"];
+	        this.shots_per_page = source["shots_per_page"];
+	        this.include_prompts = source["include_prompts"];
+	    }
+	}
+	export class FindAssetDuplicatesParams {
+	    project_id: number;
+	    asset_type: string;
+	    threshold: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FindAssetDuplicatesParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.asset_type = source["asset_type"];
+	        this.threshold = source["threshold"];
+	    }
+	}
+	export class GenerateAssetImageParams {
+	    catalog_id: number;
+	    model_id: string;
+	    prompt: string;
+	    input_images: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GenerateAssetImageParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.catalog_id = source["catalog_id"];
+	        this.model_id = source["model_id"];
+	        this.prompt = source["prompt"];
+	        this.input_images = source["input_images"];
+	    }
+	}
+	export class GenerateShotFrameParams {
+	    storyboard_id: number;
+	    frame_type: string;
+	    model_id: string;
+	    prompt: string;
+	    input_images: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GenerateShotFrameParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.storyboard_id = source["storyboard_id"];
+	        this.frame_type = source["frame_type"];
+	        this.model_id = source["model_id"];
+	        this.prompt = source["prompt"];
+	        this.input_images = source["input_images"];
+	    }
+	}
+	export class ImportLibraryAssetParams {
+	    project_id: number;
+	    library_asset_id: number;
+	    mode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportLibraryAssetParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.library_asset_id = source["library_asset_id"];
+	        this.mode = source["mode"];
+	    }
+	}
+	export class ImportShotListParams {
+	    project_id: number;
+	    path: string;
+	    dry_run: boolean;
+	    use_sheet: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportShotListParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.path = source["path"];
+	        this.dry_run = source["dry_run"];
+	        this.use_sheet = source["use_sheet"];
+	    }
+	}
+	export class ImportShotTableParams {
+	    project_id: number;
+	    path: string;
+	    sheet: string;
+	    header_row: number;
+	    columns: services.ShotTableColumn[];
+	    replace_existing: boolean;
+	    new_project_name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportShotTableParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project_id = source["project_id"];
+	        this.path = source["path"];
+	        this.sheet = source["sheet"];
+	        this.header_row = source["header_row"];
+	        this.columns = this.convertValues(source["columns"], services.ShotTableColumn);
+	        this.replace_existing = source["replace_existing"];
+	        this.new_project_name = source["new_project_name"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LLMProviderData {
+	    id: number;
+	    name: string;
+	    provider_type: string;
+	    base_url: string;
+	    model: string;
+	    has_api_key: boolean;
+	    masked_api_key: string;
+	    key_error?: string;
+	    is_default: boolean;
+	    // Go type: time
+	    updated_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new LLMProviderData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.provider_type = source["provider_type"];
+	        this.base_url = source["base_url"];
+	        this.model = source["model"];
+	        this.has_api_key = source["has_api_key"];
+	        this.masked_api_key = source["masked_api_key"];
+	        this.key_error = source["key_error"];
+	        this.is_default = source["is_default"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class MergeAssetCatalogsParams {
+	    keep_id: number;
+	    merge_ids: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MergeAssetCatalogsParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keep_id = source["keep_id"];
+	        this.merge_ids = source["merge_ids"];
+	    }
+	}
+	export class TakeResponse {
+	    id: number;
+	    storyboard_id: number;
+	    prompt: string;
+	    first_frame_path: string;
+	    last_frame_path: string;
+	    model_id: string;
+	    ratio: string;
+	    duration: number;
+	    generate_audio: boolean;
+	    task_id: string;
+	    status: string;
+	    video_url: string;
+	    last_frame_url: string;
+	    local_video_path: string;
+	    local_last_frame_path: string;
+	    download_status: string;
+	    service_tier: string;
+	    token_usage: number;
+	    expires_after: number;
+	    is_good: boolean;
+	    chain_from_prev: boolean;
+	    generation_mode: string;
+	    submitted_prompt: string;
+	    request_snapshot: string;
+	    // Go type: time
+	    created_at: any;
+	    video_width: number;
+	    video_height: number;
+	    video_fps: number;
+	    video_codec: string;
+	    video_frames: number;
+	    media_duration: number;
+	    has_audio: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TakeResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.prompt = source["prompt"];
+	        this.first_frame_path = source["first_frame_path"];
+	        this.last_frame_path = source["last_frame_path"];
+	        this.model_id = source["model_id"];
+	        this.ratio = source["ratio"];
+	        this.duration = source["duration"];
+	        this.generate_audio = source["generate_audio"];
+	        this.task_id = source["task_id"];
+	        this.status = source["status"];
+	        this.video_url = source["video_url"];
+	        this.last_frame_url = source["last_frame_url"];
+	        this.local_video_path = source["local_video_path"];
+	        this.local_last_frame_path = source["local_last_frame_path"];
+	        this.download_status = source["download_status"];
+	        this.service_tier = source["service_tier"];
+	        this.token_usage = source["token_usage"];
+	        this.expires_after = source["expires_after"];
+	        this.is_good = source["is_good"];
+	        this.chain_from_prev = source["chain_from_prev"];
+	        this.generation_mode = source["generation_mode"];
+	        this.submitted_prompt = source["submitted_prompt"];
+	        this.request_snapshot = source["request_snapshot"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.video_width = source["video_width"];
+	        this.video_height = source["video_height"];
+	        this.video_fps = source["video_fps"];
+	        this.video_codec = source["video_codec"];
+	        this.video_frames = source["video_frames"];
+	        this.media_duration = source["media_duration"];
+	        this.has_audio = source["has_audio"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StoryboardData {
+	    id: number;
+	    project_id: number;
+	    takes: TakeResponse[];
+	    // Go type: time
+	    created_at: any;
+	    active_take?: TakeResponse;
+	
+	    static createFrom(source: any = {}) {
+	        return new StoryboardData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.takes = this.convertValues(source["takes"], TakeResponse);
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.active_take = this.convertValues(source["active_take"], TakeResponse);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProjectDetail {
+	    id: number;
+	    name: string;
+	    model_version: string;
+	    aspect_ratio: string;
+	    // Go type: time
+	    created_at: any;
+	    storyboards: StoryboardData[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectDetail(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.model_version = source["model_version"];
+	        this.aspect_ratio = source["aspect_ratio"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.storyboards = this.convertValues(source["storyboards"], StoryboardData);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProjectDetailData {
+	    project: ProjectDetail;
+	    models: config.Model[];
+	    audio_supported_models: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectDetailData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.project = this.convertValues(source["project"], ProjectDetail);
+	        this.models = this.convertValues(source["models"], config.Model);
+	        this.audio_supported_models = source["audio_supported_models"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProjectStats {
+	    total_videos: number;
+	    total_token_usage: number;
+	    total_cost: number;
+	    total_savings: number;
+	    model_video_count: Record<string, number>;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total_videos = source["total_videos"];
+	        this.total_token_usage = source["total_token_usage"];
+	        this.total_cost = source["total_cost"];
+	        this.total_savings = source["total_savings"];
+	        this.model_video_count = source["model_video_count"];
+	    }
+	}
+	export class ProjectsData {
+	    projects: models.Project[];
+	    stats: ProjectStats;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectsData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.projects = this.convertValues(source["projects"], models.Project);
+	        this.stats = this.convertValues(source["stats"], ProjectStats);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PromptCompileSettings {
+	    strip_local_paths: boolean;
+	    enforce_max_length: boolean;
+	    max_length_overrides: Record<string, number>;
+	    llm_rewrite: boolean;
+	    provider_profile_id: number;
+	    llm_model_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PromptCompileSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.strip_local_paths = source["strip_local_paths"];
+	        this.enforce_max_length = source["enforce_max_length"];
+	        this.max_length_overrides = source["max_length_overrides"];
+	        this.llm_rewrite = source["llm_rewrite"];
+	        this.provider_profile_id = source["provider_profile_id"];
+	        this.llm_model_id = source["llm_model_id"];
+	    }
+	}
+	export class PromptModelLimit {
+	    model_id: string;
+	    name: string;
+	    default: number;
+	    effective: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PromptModelLimit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model_id = source["model_id"];
+	        this.name = source["name"];
+	        this.default = source["default"];
+	        this.effective = source["effective"];
+	    }
+	}
+	export class PublishAssetParams {
+	    catalog_id: number;
+	    tags: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PublishAssetParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.catalog_id = source["catalog_id"];
+	        this.tags = source["tags"];
+	    }
+	}
+	export class RecodeAssetCatalogParams {
+	    catalog_id: number;
+	    asset_type: string;
+	    asset_code: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecodeAssetCatalogParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.catalog_id = source["catalog_id"];
+	        this.asset_type = source["asset_type"];
+	        this.asset_code = source["asset_code"];
+	    }
+	}
+	export class RewriteShotParams {
+	    storyboard_id: number;
+	    action: string;
+	    instruction: string;
+	    llm_model_id: string;
+	    provider: string;
+	    api_key: string;
+	    base_url: string;
+	    provider_profile_id: number;
+	    template_id: number;
+	    request_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RewriteShotParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.storyboard_id = source["storyboard_id"];
+	        this.action = source["action"];
+	        this.instruction = source["instruction"];
+	        this.llm_model_id = source["llm_model_id"];
+	        this.provider = source["provider"];
+	        this.api_key = source["api_key"];
+	        this.base_url = source["base_url"];
+	        this.provider_profile_id = source["provider_profile_id"];
+	        this.template_id = source["template_id"];
+	        this.request_id = source["request_id"];
+	    }
+	}
+	export class SaveLLMProviderParams {
+	    id: number;
+	    name: string;
+	    provider_type: string;
+	    base_url: string;
+	    model: string;
+	    api_key: string;
+	    is_default: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SaveLLMProviderParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.provider_type = source["provider_type"];
+	        this.base_url = source["base_url"];
+	        this.model = source["model"];
+	        this.api_key = source["api_key"];
+	        this.is_default = source["is_default"];
+	    }
+	}
+	export class ScreenplayCatalogSeed {
+	    characters: string[];
+	    scenes: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScreenplayCatalogSeed(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.characters = source["characters"];
+	        this.scenes = source["scenes"];
+	    }
+	}
+	export class SearchAssetLibraryParams {
+	    query: string;
+	    asset_type: string;
+	    limit: number;
+	    offset: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchAssetLibraryParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.query = source["query"];
+	        this.asset_type = source["asset_type"];
+	        this.limit = source["limit"];
+	        this.offset = source["offset"];
+	    }
+	}
+	
+	export class ShotFrameVersionResponse {
+	    id: number;
+	    storyboard_id: number;
+	    frame_type: string;
+	    version_no: number;
+	    image_path: string;
+	    source_type: string;
+	    model_id: string;
+	    prompt: string;
+	    status: string;
+	    is_good: boolean;
+	    // Go type: time
+	    created_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShotFrameVersionResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.frame_type = source["frame_type"];
+	        this.version_no = source["version_no"];
+	        this.image_path = source["image_path"];
+	        this.source_type = source["source_type"];
+	        this.model_id = source["model_id"];
+	        this.prompt = source["prompt"];
+	        this.status = source["status"];
+	        this.is_good = source["is_good"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ShotListConflict {
+	    id: string;
+	    storyboard_id: number;
+	    shot_no: string;
+	    row: number;
+	    field: string;
+	    label: string;
+	    app_value: string;
+	    sheet_value: string;
+	    resolved: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShotListConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.shot_no = source["shot_no"];
+	        this.row = source["row"];
+	        this.field = source["field"];
+	        this.label = source["label"];
+	        this.app_value = source["app_value"];
+	        this.sheet_value = source["sheet_value"];
+	        this.resolved = source["resolved"];
+	    }
+	}
+	export class V1ShotData {
+	    id: number;
+	    project_id: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    shot_order: number;
+	    shot_no: string;
+	    shot_size: string;
+	    camera_movement: string;
+	    frame_content: string;
+	    characters: EntityRef[];
+	    scenes: EntityRef[];
+	    elements: EntityRef[];
+	    styles: EntityRef[];
+	    sound_design: string;
+	    estimated_duration: number;
+	    duration_fine: number;
+	    extra?: Record<string, any>;
+	    takes: TakeResponse[];
+	    active_take?: TakeResponse;
+	    start_frames: ShotFrameVersionResponse[];
+	    end_frames: ShotFrameVersionResponse[];
+	    active_start_frame?: ShotFrameVersionResponse;
+	    active_end_frame?: ShotFrameVersionResponse;
+	
+	    static createFrom(source: any = {}) {
+	        return new V1ShotData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.shot_order = source["shot_order"];
+	        this.shot_no = source["shot_no"];
+	        this.shot_size = source["shot_size"];
+	        this.camera_movement = source["camera_movement"];
+	        this.frame_content = source["frame_content"];
+	        this.characters = this.convertValues(source["characters"], EntityRef);
+	        this.scenes = this.convertValues(source["scenes"], EntityRef);
+	        this.elements = this.convertValues(source["elements"], EntityRef);
+	        this.styles = this.convertValues(source["styles"], EntityRef);
+	        this.sound_design = source["sound_design"];
+	        this.estimated_duration = source["estimated_duration"];
+	        this.duration_fine = source["duration_fine"];
+	        this.extra = source["extra"];
+	        this.takes = this.convertValues(source["takes"], TakeResponse);
+	        this.active_take = this.convertValues(source["active_take"], TakeResponse);
+	        this.start_frames = this.convertValues(source["start_frames"], ShotFrameVersionResponse);
+	        this.end_frames = this.convertValues(source["end_frames"], ShotFrameVersionResponse);
+	        this.active_start_frame = this.convertValues(source["active_start_frame"], ShotFrameVersionResponse);
+	        this.active_end_frame = this.convertValues(source["active_end_frame"], ShotFrameVersionResponse);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class V1ProjectData {
+	    id: number;
+	    name: string;
+	    model_version: string;
+	    aspect_ratio: string;
+	    // Go type: time
+	    created_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new V1ProjectData(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.model_version = source["model_version"];
+	        this.aspect_ratio = source["aspect_ratio"];
 	        this.created_at = this.convertValues(source["created_at"], null);
 	    }
 	
@@ -395,25 +1688,28 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class StoryboardData {
-	    id: number;
-	    project_id: number;
-	    takes: TakeResponse[];
-	    // Go type: time
-	    created_at: any;
-	    active_take?: TakeResponse;
+	export class V1WorkspaceData {
+	    project: V1ProjectData;
+	    storyboards: V1ShotData[];
+	    asset_catalogs: AssetCatalogResponse[];
+	    models: config.Model[];
+	    audio_supported_models: string[];
+	    llm_model_default: string;
+	    image_model_default: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new StoryboardData(source);
+	        return new V1WorkspaceData(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.project_id = source["project_id"];
-	        this.takes = this.convertValues(source["takes"], TakeResponse);
-	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.active_take = this.convertValues(source["active_take"], TakeResponse);
+	        this.project = this.convertValues(source["project"], V1ProjectData);
+	        this.storyboards = this.convertValues(source["storyboards"], V1ShotData);
+	        this.asset_catalogs = this.convertValues(source["asset_catalogs"], AssetCatalogResponse);
+	        this.models = this.convertValues(source["models"], config.Model);
+	        this.audio_supported_models = source["audio_supported_models"];
+	        this.llm_model_default = source["llm_model_default"];
+	        this.image_model_default = source["image_model_default"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -434,27 +1730,32 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class ProjectDetail {
-	    id: number;
-	    name: string;
-	    model_version: string;
-	    aspect_ratio: string;
-	    // Go type: time
-	    created_at: any;
-	    storyboards: StoryboardData[];
+	export class ShotListImportResult {
+	    project_id: number;
+	    dry_run: boolean;
+	    updated: number;
+	    created: number;
+	    unchanged: number;
+	    missing: number;
+	    conflicts: ShotListConflict[];
+	    notes: string[];
+	    workspace?: V1WorkspaceData;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProjectDetail(source);
+	        return new ShotListImportResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.name = source["name"];
-	        this.model_version = source["model_version"];
-	        this.aspect_ratio = source["aspect_ratio"];
-	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.storyboards = this.convertValues(source["storyboards"], StoryboardData);
+	        this.project_id = source["project_id"];
+	        this.dry_run = source["dry_run"];
+	        this.updated = source["updated"];
+	        this.created = source["created"];
+	        this.unchanged = source["unchanged"];
+	        this.missing = source["missing"];
+	        this.conflicts = this.convertValues(source["conflicts"], ShotListConflict);
+	        this.notes = source["notes"];
+	        this.workspace = this.convertValues(source["workspace"], V1WorkspaceData);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -475,20 +1776,40 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class ProjectDetailData {
-	    project: ProjectDetail;
-	    models: config.Model[];
-	    audio_supported_models: string[];
+	export class UpdateShotParams {
+	    storyboard_id: number;
+	    shot_no: string;
+	    shot_size: string;
+	    camera_movement: string;
+	    frame_content: string;
+	    characters: EntityRef[];
+	    scenes: EntityRef[];
+	    elements: EntityRef[];
+	    styles: EntityRef[];
+	    sound_design: string;
+	    estimated_duration: number;
+	    duration_fine: number;
+	    extra?: Record<string, any>;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProjectDetailData(source);
+	        return new UpdateShotParams(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.project = this.convertValues(source["project"], ProjectDetail);
-	        this.models = this.convertValues(source["models"], config.Model);
-	        this.audio_supported_models = source["audio_supported_models"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.shot_no = source["shot_no"];
+	        this.shot_size = source["shot_size"];
+	        this.camera_movement = source["camera_movement"];
+	        this.frame_content = source["frame_content"];
+	        this.characters = this.convertValues(source["characters"], EntityRef);
+	        this.scenes = this.convertValues(source["scenes"], EntityRef);
+	        this.elements = this.convertValues(source["elements"], EntityRef);
+	        this.styles = this.convertValues(source["styles"], EntityRef);
+	        this.sound_design = source["sound_design"];
+	        this.estimated_duration = source["estimated_duration"];
+	        this.duration_fine = source["duration_fine"];
+	        this.extra = source["extra"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -509,38 +1830,70 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class ProjectStats {
-	    total_videos: number;
-	    total_token_usage: number;
-	    total_cost: number;
-	    total_savings: number;
-	    model_video_count: Record<string, number>;
+	export class ShotRewriteProposal {
+	    storyboard_id: number;
+	    action: string;
+	    before: DraftShot;
+	    proposed: UpdateShotParams;
+	    changed_fields: string[];
+	    second?: UpdateShotParams;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProjectStats(source);
+	        return new ShotRewriteProposal(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.total_videos = source["total_videos"];
-	        this.total_token_usage = source["total_token_usage"];
-	        this.total_cost = source["total_cost"];
-	        this.total_savings = source["total_savings"];
-	        this.model_video_count = source["model_video_count"];
+	        this.storyboard_id = source["storyboard_id"];
+	        this.action = source["action"];
+	        this.before = this.convertValues(source["before"], DraftShot);
+	        this.proposed = this.convertValues(source["proposed"], UpdateShotParams);
+	        this.changed_fields = source["changed_fields"];
+	        this.second = this.convertValues(source["second"], UpdateShotParams);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class ProjectsData {
-	    projects: models.Project[];
-	    stats: ProjectStats;
+	export class ShotTableFile {
+	    path: string;
+	    filename: string;
+	    sheet: string;
+	    sheets: string[];
+	    header_row: number;
+	    columns: services.ShotTableColumn[];
+	    preview: string[][];
+	    row_count: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new ProjectsData(source);
+	        return new ShotTableFile(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.projects = this.convertValues(source["projects"], models.Project);
-	        this.stats = this.convertValues(source["stats"], ProjectStats);
+	        this.path = source["path"];
+	        this.filename = source["filename"];
+	        this.sheet = source["sheet"];
+	        this.sheets = source["sheets"];
+	        this.header_row = source["header_row"];
+	        this.columns = this.convertValues(source["columns"], services.ShotTableColumn);
+	        this.preview = source["preview"];
+	        this.row_count = source["row_count"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -561,37 +1914,24 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class ShotFrameVersionResponse {
-	    id: number;
-	    storyboard_id: number;
-	    frame_type: string;
-	    version_no: number;
-	    image_path: string;
-	    source_type: string;
-	    model_id: string;
-	    prompt: string;
-	    status: string;
-	    is_good: boolean;
-	    // Go type: time
-	    created_at: any;
+	export class ShotTableImportResult {
+	    project_id: number;
+	    sheet: string;
+	    workspace?: V1WorkspaceData;
+	    imported: number;
+	    notes: string[];
 	
 	    static createFrom(source: any = {}) {
-	        return new ShotFrameVersionResponse(source);
+	        return new ShotTableImportResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.storyboard_id = source["storyboard_id"];
-	        this.frame_type = source["frame_type"];
-	        this.version_no = source["version_no"];
-	        this.image_path = source["image_path"];
-	        this.source_type = source["source_type"];
-	        this.model_id = source["model_id"];
-	        this.prompt = source["prompt"];
-	        this.status = source["status"];
-	        this.is_good = source["is_good"];
-	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.project_id = source["project_id"];
+	        this.sheet = source["sheet"];
+	        this.workspace = this.convertValues(source["workspace"], V1WorkspaceData);
+	        this.imported = source["imported"];
+	        this.notes = source["notes"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -632,6 +1972,7 @@ export namespace main {
 	export class StoryboardSourceFile {
 	    filename: string;
 	    content: string;
+	    screenplay?: services.Screenplay;
 	
 	    static createFrom(source: any = {}) {
 	        return new StoryboardSourceFile(source);
@@ -641,6 +1982,45 @@ export namespace main {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.filename = source["filename"];
 	        this.content = source["content"];
+	        this.screenplay = this.convertValues(source["screenplay"], services.Screenplay);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TakePromptPreview {
+	    original: string;
+	    prompt: string;
+	    notes: string[];
+	    max_length: number;
+	    rewritten: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TakePromptPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.original = source["original"];
+	        this.prompt = source["prompt"];
+	        this.notes = source["notes"];
+	        this.max_length = source["max_length"];
+	        this.rewritten = source["rewritten"];
 	    }
 	}
 	
@@ -669,69 +2049,18 @@ export namespace main {
 	    name: string;
 	    prompt: string;
 	
-	    static createFrom(source: any = {}) {
-	        return new UpdateAssetCatalogParams(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.catalog_id = source["catalog_id"];
-	        this.name = source["name"];
-	        this.prompt = source["prompt"];
-	    }
-	}
-	export class UpdateShotParams {
-	    storyboard_id: number;
-	    shot_no: string;
-	    shot_size: string;
-	    camera_movement: string;
-	    frame_content: string;
-	    characters: EntityRef[];
-	    scenes: EntityRef[];
-	    elements: EntityRef[];
-	    styles: EntityRef[];
-	    sound_design: string;
-	    estimated_duration: number;
-	    duration_fine: number;
-	
-	    static createFrom(source: any = {}) {
-	        return new UpdateShotParams(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.storyboard_id = source["storyboard_id"];
-	        this.shot_no = source["shot_no"];
-	        this.shot_size = source["shot_size"];
-	        this.camera_movement = source["camera_movement"];
-	        this.frame_content = source["frame_content"];
-	        this.characters = this.convertValues(source["characters"], EntityRef);
-	        this.scenes = this.convertValues(source["scenes"], EntityRef);
-	        this.elements = this.convertValues(source["elements"], EntityRef);
-	        this.styles = this.convertValues(source["styles"], EntityRef);
-	        this.sound_design = source["sound_design"];
-	        this.estimated_duration = source["estimated_duration"];
-	        this.duration_fine = source["duration_fine"];
-	    }
-	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
+	    static createFrom(source: any = {}) {
+	        return new UpdateAssetCatalogParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.catalog_id = source["catalog_id"];
+	        this.name = source["name"];
+	        this.prompt = source["prompt"];
+	    }
 	}
+	
 	export class UpdateStoryboardParams {
 	    storyboard_id: number;
 	    prompt: string;
@@ -784,161 +2113,8 @@ export namespace main {
 	        this.frame_type = source["frame_type"];
 	    }
 	}
-	export class V1ProjectData {
-	    id: number;
-	    name: string;
-	    model_version: string;
-	    aspect_ratio: string;
-	    // Go type: time
-	    created_at: any;
-	
-	    static createFrom(source: any = {}) {
-	        return new V1ProjectData(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.name = source["name"];
-	        this.model_version = source["model_version"];
-	        this.aspect_ratio = source["aspect_ratio"];
-	        this.created_at = this.convertValues(source["created_at"], null);
-	    }
-	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
-	}
-	export class V1ShotData {
-	    id: number;
-	    project_id: number;
-	    // Go type: time
-	    created_at: any;
-	    // Go type: time
-	    updated_at: any;
-	    shot_order: number;
-	    shot_no: string;
-	    shot_size: string;
-	    camera_movement: string;
-	    frame_content: string;
-	    characters: EntityRef[];
-	    scenes: EntityRef[];
-	    elements: EntityRef[];
-	    styles: EntityRef[];
-	    sound_design: string;
-	    estimated_duration: number;
-	    duration_fine: number;
-	    takes: TakeResponse[];
-	    active_take?: TakeResponse;
-	    start_frames: ShotFrameVersionResponse[];
-	    end_frames: ShotFrameVersionResponse[];
-	    active_start_frame?: ShotFrameVersionResponse;
-	    active_end_frame?: ShotFrameVersionResponse;
-	
-	    static createFrom(source: any = {}) {
-	        return new V1ShotData(source);
-	    }
-	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.project_id = source["project_id"];
-	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.updated_at = this.convertValues(source["updated_at"], null);
-	        this.shot_order = source["shot_order"];
-	        this.shot_no = source["shot_no"];
-	        this.shot_size = source["shot_size"];
-	        this.camera_movement = source["camera_movement"];
-	        this.frame_content = source["frame_content"];
-	        this.characters = this.convertValues(source["characters"], EntityRef);
-	        this.scenes = this.convertValues(source["scenes"], EntityRef);
-	        this.elements = this.convertValues(source["elements"], EntityRef);
-	        this.styles = this.convertValues(source["styles"], EntityRef);
-	        this.sound_design = source["sound_design"];
-	        this.estimated_duration = source["estimated_duration"];
-	        this.duration_fine = source["duration_fine"];
-	        this.takes = this.convertValues(source["takes"], TakeResponse);
-	        this.active_take = this.convertValues(source["active_take"], TakeResponse);
-	        this.start_frames = this.convertValues(source["start_frames"], ShotFrameVersionResponse);
-	        this.end_frames = this.convertValues(source["end_frames"], ShotFrameVersionResponse);
-	        this.active_start_frame = this.convertValues(source["active_start_frame"], ShotFrameVersionResponse);
-	        this.active_end_frame = this.convertValues(source["active_end_frame"], ShotFrameVersionResponse);
-	    }
-	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
-	}
-	export class V1WorkspaceData {
-	    project: V1ProjectData;
-	    storyboards: V1ShotData[];
-	    asset_catalogs: AssetCatalogResponse[];
-	    models: config.Model[];
-	    audio_supported_models: string[];
-	    llm_model_default: string;
-	    image_model_default: string;
-	
-	    static createFrom(source: any = {}) {
-	        return new V1WorkspaceData(source);
-	    }
 	
-	    constructor(source: any = {}) {
-	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.project = this.convertValues(source["project"], V1ProjectData);
-	        this.storyboards = this.convertValues(source["storyboards"], V1ShotData);
-	        this.asset_catalogs = this.convertValues(source["asset_catalogs"], AssetCatalogResponse);
-	        this.models = this.convertValues(source["models"], config.Model);
-	        this.audio_supported_models = source["audio_supported_models"];
-	        this.llm_model_default = source["llm_model_default"];
-	        this.image_model_default = source["image_model_default"];
-	    }
 	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
-	}
 
 }
 
@@ -967,8 +2143,17 @@ export namespace models {
 	    is_good: boolean;
 	    chain_from_prev: boolean;
 	    generation_mode: string;
+	    submitted_prompt: string;
+	    request_snapshot: string;
 	    // Go type: time
 	    created_at: any;
+	    video_width: number;
+	    video_height: number;
+	    video_fps: number;
+	    video_codec: string;
+	    video_frames: number;
+	    media_duration: number;
+	    has_audio: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Take(source);
@@ -998,7 +2183,16 @@ export namespace models {
 	        this.is_good = source["is_good"];
 	        this.chain_from_prev = source["chain_from_prev"];
 	        this.generation_mode = source["generation_mode"];
+	        this.submitted_prompt = source["submitted_prompt"];
+	        this.request_snapshot = source["request_snapshot"];
 	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.video_width = source["video_width"];
+	        this.video_height = source["video_height"];
+	        this.video_fps = source["video_fps"];
+	        this.video_codec = source["video_codec"];
+	        this.video_frames = source["video_frames"];
+	        this.media_duration = source["media_duration"];
+	        this.has_audio = source["has_audio"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -1039,6 +2233,7 @@ export namespace models {
 	    sound_design: string;
 	    estimated_duration: number;
 	    duration_fine: number;
+	    extra_json: string;
 	    active_take?: Take;
 	
 	    static createFrom(source: any = {}) {
@@ -1064,6 +2259,7 @@ export namespace models {
 	        this.sound_design = source["sound_design"];
 	        this.estimated_duration = source["estimated_duration"];
 	        this.duration_fine = source["duration_fine"];
+	        this.extra_json = source["extra_json"];
 	        this.active_take = this.convertValues(source["active_take"], Take);
 	    }
 	
@@ -1130,3 +2326,154 @@ export namespace models {
 
 }
 
+export namespace services {
+	
+	export class ExportOptions {
+	    shot_from: number;
+	    shot_to: number;
+	    shot_ids: number[];
+	    take_selection: string;
+	    include_last_frames: boolean;
+	    include_shot_list: boolean;
+	    filename_template: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shot_from = source["shot_from"];
+	        this.shot_to = source["shot_to"];
+	        this.shot_ids = source["shot_ids"];
+	        this.take_selection = source["take_selection"];
+	        this.include_last_frames = source["include_last_frames"];
+	        this.include_shot_list = source["include_shot_list"];
+	        this.filename_template = source["filename_template"];
+	    }
+	}
+	export class RoughCutResult {
+	    path: string;
+	    clips: number;
+	    duration: number;
+	    cached: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RoughCutResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.clips = source["clips"];
+	        this.duration = source["duration"];
+	        this.cached = source["cached"];
+	    }
+	}
+	export class ScreenplayElement {
+	    type: string;
+	    character?: string;
+	    parenthetical?: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScreenplayElement(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.character = source["character"];
+	        this.parenthetical = source["parenthetical"];
+	        this.text = source["text"];
+	    }
+	}
+	export class ScreenplayScene {
+	    heading: string;
+	    location: string;
+	    time: string;
+	    elements: ScreenplayElement[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScreenplayScene(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.heading = source["heading"];
+	        this.location = source["location"];
+	        this.time = source["time"];
+	        this.elements = this.convertValues(source["elements"], ScreenplayElement);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Screenplay {
+	    title: string;
+	    scenes: ScreenplayScene[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Screenplay(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.title = source["title"];
+	        this.scenes = this.convertValues(source["scenes"], ScreenplayScene);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class ShotTableColumn {
+	    index: number;
+	    header: string;
+	    field: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShotTableColumn(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.header = source["header"];
+	        this.field = source["field"];
+	    }
+	}
+
+}
+
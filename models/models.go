@@ -49,13 +49,14 @@ type Storyboard struct {
 	ShotSize          string `json:"shot_size"`
 	CameraMovement    string `json:"camera_movement"`
 	FrameContent      string `gorm:"type:text" json:"frame_content"`
-	CharactersJSON    string `gorm:"type:text" json:"characters_json"` // []EntityRef
-	ScenesJSON        string `gorm:"type:text" json:"scenes_json"`     // []EntityRef
-	ElementsJSON      string `gorm:"type:text" json:"elements_json"`   // []EntityRef
-	StylesJSON        string `gorm:"type:text" json:"styles_json"`     // []EntityRef
+	CharactersJSON    string `gorm:"type:text" json:"characters_json"` // legacy []EntityRef, migrated to StoryboardAssetRef
+	ScenesJSON        string `gorm:"type:text" json:"scenes_json"`     // legacy []EntityRef, migrated to StoryboardAssetRef
+	ElementsJSON      string `gorm:"type:text" json:"elements_json"`   // legacy []EntityRef, migrated to StoryboardAssetRef
+	StylesJSON        string `gorm:"type:text" json:"styles_json"`     // legacy []EntityRef, migrated to StoryboardAssetRef
 	SoundDesign       string `gorm:"type:text" json:"sound_design"`
-	EstimatedDuration int    `gorm:"default:5" json:"estimated_duration"` // 5/10 for now
+	EstimatedDuration int    `gorm:"default:5" json:"estimated_duration"` // seconds
 	DurationFine      int    `gorm:"default:0" json:"duration_fine"`      // reserved for fine control
+	ExtraJSON         string `gorm:"type:text" json:"extra_json"`         // map of decomposition template extra fields
 
 	// Virtual field for the active take (not stored in DB, populated during query)
 	ActiveTake *Take `gorm:"-" json:"active_take,omitempty"`
@@ -84,36 +85,86 @@ type Take struct {
 	IsGood             bool      `json:"is_good"` // "Good Take" marker
 	ChainFromPrev      bool      `json:"chain_from_prev"`
 	GenerationMode     string    `gorm:"default:standard" json:"generation_mode"` // standard / flat
+	SubmittedPrompt    string    `json:"submitted_prompt"`                        // Compiled prompt sent with the last submission
+	RequestSnapshot    string    `json:"request_snapshot"`                        // JSON services.VideoTaskRequest of the last submission
 	CreatedAt          time.Time `json:"created_at"`
+
+	// Media metadata read from the downloaded video
+	VideoWidth    int     `json:"video_width"`
+	VideoHeight   int     `json:"video_height"`
+	VideoFPS      float64 `json:"video_fps"`
+	VideoCodec    string  `json:"video_codec"`    // e.g. avc1, hvc1
+	VideoFrames   int     `json:"video_frames"`   // sample count of the video track
+	MediaDuration float64 `json:"media_duration"` // measured seconds, may differ from Duration
+	HasAudio      bool    `json:"has_audio"`
 }
 
 // AssetCatalog is a project-level reusable asset prompt definition.
 // AssetType: character | scene | element | style
 type AssetCatalog struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	ProjectID    uint           `gorm:"index:idx_catalog_project_type_code,priority:1;index" json:"project_id"`
-	AssetType    string         `gorm:"index:idx_catalog_project_type_code,priority:2;index" json:"asset_type"`
-	AssetCode    string         `gorm:"index:idx_catalog_project_type_code,priority:3" json:"asset_code"` // user-facing id
-	Name         string         `json:"name"`
-	Prompt       string         `gorm:"type:text" json:"prompt"`
-	StoryboardID *uint          `gorm:"index" json:"storyboard_id,omitempty"` // optional source storyboard
-	Versions     []AssetVersion `gorm:"foreignKey:CatalogID;constraint:OnDelete:CASCADE;" json:"versions"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	ProjectID      uint           `gorm:"index:idx_catalog_project_type_code,priority:1;index" json:"project_id"`
+	AssetType      string         `gorm:"index:idx_catalog_project_type_code,priority:2;index" json:"asset_type"`
+	AssetCode      string         `gorm:"index:idx_catalog_project_type_code,priority:3" json:"asset_code"` // user-facing id
+	Name           string         `json:"name"`
+	Prompt         string         `gorm:"type:text" json:"prompt"`
+	StoryboardID   *uint          `gorm:"index" json:"storyboard_id,omitempty"`    // optional source storyboard
+	LibraryAssetID *uint          `gorm:"index" json:"library_asset_id,omitempty"` // shared library asset whose updates it follows
+	Versions       []AssetVersion `gorm:"foreignKey:CatalogID;constraint:OnDelete:CASCADE;" json:"versions"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 type AssetVersion struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	CatalogID  uint      `gorm:"index" json:"catalog_id"`
-	VersionNo  int       `json:"version_no"`
-	ImagePath  string    `json:"image_path"`  // local cached image path
-	SourceType string    `json:"source_type"` // generated / uploaded
-	ModelID    string    `json:"model_id"`
-	Prompt     string    `gorm:"type:text" json:"prompt"`
-	TaskID     string    `json:"task_id"`
-	Status     string    `json:"status"` // Draft / Running / Succeeded / Failed
-	IsGood     bool      `json:"is_good"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	CatalogID        uint      `gorm:"index" json:"catalog_id"`
+	VersionNo        int       `json:"version_no"`
+	ImagePath        string    `json:"image_path"`  // local cached image path
+	SourceType       string    `json:"source_type"` // generated / uploaded / library
+	ModelID          string    `json:"model_id"`
+	Prompt           string    `gorm:"type:text" json:"prompt"`
+	TaskID           string    `json:"task_id"`
+	Status           string    `json:"status"` // Draft / Running / Succeeded / Failed
+	IsGood           bool      `json:"is_good"`
+	CreatedAt        time.Time `json:"created_at"`
+	LibraryVersionID *uint     `gorm:"index" json:"library_version_id,omitempty"` // library version it was published to or taken from
+}
+
+// StoryboardAssetRef links a shot to a catalog entry it uses. The overrides, when set, replace the
+// entry's name and prompt for this shot only.
+type StoryboardAssetRef struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	StoryboardID   uint   `gorm:"index" json:"storyboard_id"`
+	CatalogID      uint   `gorm:"index" json:"catalog_id"`
+	Position       int    `json:"position"` // order among the shot's refs of the entry's type
+	NameOverride   string `json:"name_override"`
+	PromptOverride string `gorm:"type:text" json:"prompt_override"`
+}
+
+// LibraryAsset is an asset shared by all projects, published from a project's catalog.
+type LibraryAsset struct {
+	ID              uint                  `gorm:"primaryKey" json:"id"`
+	AssetType       string                `gorm:"index" json:"asset_type"` // character | scene | element | style
+	Name            string                `gorm:"index" json:"name"`
+	Prompt          string                `gorm:"type:text" json:"prompt"`
+	Tags            string                `json:"tags"`                           // comma separated
+	SourceCatalogID uint                  `gorm:"index" json:"source_catalog_id"` // catalog entry first published
+	Versions        []LibraryAssetVersion `gorm:"foreignKey:LibraryAssetID;constraint:OnDelete:CASCADE;" json:"versions"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+type LibraryAssetVersion struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	LibraryAssetID  uint      `gorm:"index" json:"library_asset_id"`
+	VersionNo       int       `json:"version_no"`
+	ImagePath       string    `json:"image_path"`
+	SourceType      string    `json:"source_type"`
+	ModelID         string    `json:"model_id"`
+	Prompt          string    `gorm:"type:text" json:"prompt"`
+	SourceVersionID uint      `json:"source_version_id"` // asset version it was published from
+	IsGood          bool      `json:"is_good"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // ShotFrameVersion stores start/end frames per storyboard with versioning.
@@ -139,3 +190,32 @@ type Setting struct {
 }
 
 var DB *gorm.DB
+
+// DecomposeTemplate is a user-editable prompt profile for LLM storyboard decomposition.
+type DecomposeTemplate struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	Name               string    `gorm:"uniqueIndex" json:"name"`
+	Description        string    `json:"description"`
+	Language           string    `json:"language"` // output language of text fields, e.g. zh, en
+	SystemPrompt       string    `gorm:"type:text" json:"system_prompt"`
+	UserPromptTemplate string    `gorm:"type:text" json:"user_prompt_template"` // {{source_text}} etc.
+	DurationOptions    string    `json:"duration_options"`                      // JSON []int of allowed estimated_duration values
+	ExtraFieldsJSON    string    `gorm:"type:text" json:"extra_fields_json"`    // JSON []DecomposeExtraField
+	IsDefault          bool      `json:"is_default"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// LLMProvider is a saved LLM endpoint for decomposition and other text tasks.
+// APIKey is encrypted at rest (see services.EncryptSecret).
+type LLMProvider struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"uniqueIndex" json:"name"`
+	ProviderType string    `json:"provider_type"` // ark_default | ark_custom | openai_compatible | anthropic | ollama
+	BaseURL      string    `json:"base_url"`
+	Model        string    `json:"model"`
+	APIKey       string    `gorm:"type:text" json:"-"`
+	IsDefault    bool      `json:"is_default"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -1,6 +1,13 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"seedance-client/config"
 
 	"gorm.io/driver/sqlite"
@@ -21,7 +28,12 @@ func InitDB() {
 		&Setting{},
 		&AssetCatalog{},
 		&AssetVersion{},
+		&StoryboardAssetRef{},
+		&LibraryAsset{},
+		&LibraryAssetVersion{},
 		&ShotFrameVersion{},
+		&DecomposeTemplate{},
+		&LLMProvider{},
 	)
 
 	// Migrate existing Storyboard data to Takes
@@ -51,4 +63,105 @@ func InitDB() {
 	DB.Exec(`UPDATE storyboards SET shot_order = id WHERE shot_order IS NULL OR shot_order = 0`)
 	DB.Exec(`UPDATE storyboards SET estimated_duration = 5 WHERE estimated_duration IS NULL OR estimated_duration = 0`)
 	DB.Exec(`UPDATE takes SET generation_mode = 'standard' WHERE generation_mode IS NULL OR generation_mode = ''`)
+
+	migrateStoryboardAssetRefs()
+}
+
+// migrateStoryboardAssetRefs moves refs still stored as JSON on storyboards into
+// storyboard_asset_refs and clears the JSON, creating catalog entries for codes the catalog
+// lacks. A name or prompt that differs from the catalog entry becomes an override on the ref.
+// Refs without an ID get the code they would have been saved with, and a column that cannot be
+// parsed is left in place.
+func migrateStoryboardAssetRefs() {
+	var shots []Storyboard
+	if err := DB.Where("COALESCE(characters_json, '') != '' OR COALESCE(scenes_json, '') != '' OR COALESCE(elements_json, '') != '' OR COALESCE(styles_json, '') != ''").
+		Find(&shots).Error; err != nil {
+		log.Printf("Storyboard ref migration failed: %v", err)
+		return
+	}
+	type legacyRef struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Prompt string `json:"prompt"`
+	}
+	for _, sb := range shots {
+		columns := []struct{ assetType, column, raw string }{
+			{"character", "characters_json", sb.CharactersJSON},
+			{"scene", "scenes_json", sb.ScenesJSON},
+			{"element", "elements_json", sb.ElementsJSON},
+			{"style", "styles_json", sb.StylesJSON},
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			cleared := map[string]interface{}{}
+			for _, col := range columns {
+				var refs []legacyRef
+				if strings.TrimSpace(col.raw) != "" {
+					if err := json.Unmarshal([]byte(col.raw), &refs); err != nil {
+						// Kept as is, so nothing is lost; the shot shows no refs of this type
+						log.Printf("Storyboard %d: unreadable %s refs left unmigrated: %v", sb.ID, col.assetType, err)
+						continue
+					}
+				}
+				cleared[col.column] = ""
+				seen := map[uint]bool{}
+				for i, r := range refs {
+					code := strings.TrimSpace(r.ID)
+					name := strings.TrimSpace(r.Name)
+					prompt := strings.TrimSpace(r.Prompt)
+					if code == "" {
+						if name == "" && prompt == "" {
+							continue
+						}
+						// Same code as refs without an ID got when they were saved
+						code = fmt.Sprintf("%s_%02d_%02d", col.assetType, sb.ShotOrder, i+1)
+					}
+					var catalog AssetCatalog
+					err := tx.Where("project_id = ? AND asset_type = ? AND asset_code = ?", sb.ProjectID, col.assetType, code).First(&catalog).Error
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						catalogName := name
+						if catalogName == "" {
+							catalogName = code
+						}
+						sourceID := sb.ID
+						catalog = AssetCatalog{
+							ProjectID:    sb.ProjectID,
+							AssetType:    col.assetType,
+							AssetCode:    code,
+							Name:         catalogName,
+							Prompt:       prompt,
+							StoryboardID: &sourceID,
+							CreatedAt:    time.Now(),
+							UpdatedAt:    time.Now(),
+						}
+						err = tx.Create(&catalog).Error
+					}
+					if err != nil {
+						return err
+					}
+					if seen[catalog.ID] {
+						continue
+					}
+					seen[catalog.ID] = true
+					// Overrides as saveShotRefsTx sets them, so the shot keeps showing its own text
+					row := StoryboardAssetRef{StoryboardID: sb.ID, CatalogID: catalog.ID, Position: len(seen)}
+					if name != catalog.Name {
+						row.NameOverride = name
+					}
+					if prompt != catalog.Prompt {
+						row.PromptOverride = prompt
+					}
+					if err := tx.Create(&row).Error; err != nil {
+						return err
+					}
+				}
+			}
+			if len(cleared) == 0 {
+				return nil
+			}
+			return tx.Model(&Storyboard{}).Where("id = ?", sb.ID).Updates(cleared).Error
+		})
+		if err != nil {
+			log.Printf("Storyboard ref migration failed for storyboard %d: %v", sb.ID, err)
+		}
+	}
 }
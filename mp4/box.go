@@ -0,0 +1,143 @@
+// Package mp4 parses ISO base media files (MP4/MOV): the box tree, movie and
+// track headers, sample descriptions and sample tables.
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Box is an ISO-BMFF box held in memory.
+type Box struct {
+	Type    string
+	Payload []byte // box content without the header
+	Raw     []byte // header + payload
+}
+
+// Children parses the payload of a container box.
+func (b Box) Children() ([]Box, error) {
+	return ParseBoxes(b.Payload)
+}
+
+// ParseBoxes splits b into consecutive boxes.
+func ParseBoxes(b []byte) ([]Box, error) {
+	var boxes []Box
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, fmt.Errorf("truncated box header")
+		}
+		size := uint64(binary.BigEndian.Uint32(b[0:4]))
+		typ := string(b[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, fmt.Errorf("truncated largesize box %q", typ)
+			}
+			size = binary.BigEndian.Uint64(b[8:16])
+			header = 16
+		}
+		if size < header || size > uint64(len(b)) {
+			return nil, fmt.Errorf("invalid size for box %q", typ)
+		}
+		boxes = append(boxes, Box{Type: typ, Payload: b[header:size], Raw: b[:size]})
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+// FindBox returns the first box matching a path of types, descending into containers.
+func FindBox(boxes []Box, path ...string) (Box, bool) {
+	for i, typ := range path {
+		found := false
+		for _, box := range boxes {
+			if box.Type != typ {
+				continue
+			}
+			if i == len(path)-1 {
+				return box, true
+			}
+			children, err := box.Children()
+			if err != nil {
+				return Box{}, false
+			}
+			boxes = children
+			found = true
+			break
+		}
+		if !found {
+			return Box{}, false
+		}
+	}
+	return Box{}, false
+}
+
+// reader is a bounds-checked big-endian cursor over a box payload.
+// Reads past the end yield zero values and set err, so parsers can check once.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = fmt.Errorf("truncated box")
+		return nil
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *reader) skip(n int) { r.take(n) }
+
+func (r *reader) u8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) u16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) u64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// uVar reads a 64-bit field for version 1 boxes and a 32-bit one otherwise.
+func (r *reader) uVar(version uint8) uint64 {
+	if version == 1 {
+		return r.u64()
+	}
+	return uint64(r.u32())
+}
+
+// count reads an entry count and checks the remaining payload can hold that many entries.
+func (r *reader) count(entrySize int) int {
+	n := int(r.u32())
+	if r.err == nil && (n < 0 || n > len(r.b)/entrySize) {
+		r.err = fmt.Errorf("entry count %d exceeds box size", n)
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,211 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MaxMoovSize bounds how much of a file's moov box is loaded into memory.
+const MaxMoovSize = 64 << 20
+
+// ErrNoMoov is returned for files without a movie box, e.g. truncated downloads.
+var ErrNoMoov = errors.New("mp4: moov box not found")
+
+// Handler types of the tracks this package understands
+const (
+	HandlerVideo = "vide"
+	HandlerAudio = "soun"
+)
+
+// mp4Epoch is the reference of creation/modification times (1904-01-01 UTC).
+var mp4Epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Movie describes an MP4 file.
+type Movie struct {
+	MajorBrand       string
+	CompatibleBrands []string
+	Timescale        uint32
+	Duration         uint64 // in Timescale
+	CreationTime     time.Time
+	ModificationTime time.Time
+	// Fragmented files keep their samples in moof boxes; their tracks carry no sample tables.
+	Fragmented bool
+	Tracks     []*Track
+}
+
+// Seconds is the movie duration in seconds.
+func (m *Movie) Seconds() float64 {
+	if m.Timescale == 0 {
+		return 0
+	}
+	return float64(m.Duration) / float64(m.Timescale)
+}
+
+// Track returns the first track with the given handler type, or nil.
+func (m *Movie) Track(handler string) *Track {
+	for _, t := range m.Tracks {
+		if t.Handler == handler {
+			return t
+		}
+	}
+	return nil
+}
+
+// Video returns the first video track, or nil.
+func (m *Movie) Video() *Track { return m.Track(HandlerVideo) }
+
+// Audio returns the first audio track, or nil.
+func (m *Movie) Audio() *Track { return m.Track(HandlerAudio) }
+
+// HasAudio reports whether the movie has an audio track with samples.
+func (m *Movie) HasAudio() bool {
+	a := m.Audio()
+	return a != nil && (len(a.Samples) > 0 || m.Fragmented)
+}
+
+// ParseFile parses the MP4 file at path.
+func ParseFile(path string) (*Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads the top-level boxes of r and parses ftyp and moov.
+// Only box headers are read outside of those, so media data is never loaded.
+func Parse(r io.ReadSeeker) (*Movie, error) {
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	movie := &Movie{}
+	var moov []Box
+	foundMoov := false
+	var offset int64
+	header := make([]byte, 16)
+	for offset+8 <= fileSize {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		typ := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = fileSize - offset
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || size > fileSize-offset {
+			return nil, fmt.Errorf("mp4: invalid size for box %q at offset %d", typ, offset)
+		}
+
+		switch typ {
+		case "ftyp":
+			payload, err := readPayload(r, size-headerSize, 4096)
+			if err != nil {
+				return nil, err
+			}
+			movie.parseFtyp(payload)
+		case "moov":
+			if foundMoov {
+				break
+			}
+			payload, err := readPayload(r, size-headerSize, MaxMoovSize)
+			if err != nil {
+				return nil, err
+			}
+			if moov, err = ParseBoxes(payload); err != nil {
+				return nil, fmt.Errorf("mp4: moov: %w", err)
+			}
+			foundMoov = true
+		case "moof":
+			movie.Fragmented = true
+		}
+		offset += size
+	}
+	if !foundMoov {
+		return nil, ErrNoMoov
+	}
+	if err := movie.parseMoov(moov); err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+func readPayload(r io.Reader, size, limit int64) ([]byte, error) {
+	if size > limit {
+		return nil, fmt.Errorf("mp4: box too large (%d bytes)", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (m *Movie) parseFtyp(p []byte) {
+	if len(p) < 8 {
+		return
+	}
+	m.MajorBrand = string(p[0:4])
+	for p = p[8:]; len(p) >= 4; p = p[4:] {
+		m.CompatibleBrands = append(m.CompatibleBrands, string(p[0:4]))
+	}
+}
+
+func (m *Movie) parseMoov(moov []Box) error {
+	mvhd, ok := FindBox(moov, "mvhd")
+	if !ok {
+		return fmt.Errorf("mp4: mvhd box not found")
+	}
+	r := &reader{b: mvhd.Payload}
+	version := r.u8()
+	r.skip(3)
+	m.CreationTime = mp4Time(r.uVar(version))
+	m.ModificationTime = mp4Time(r.uVar(version))
+	m.Timescale = r.u32()
+	m.Duration = r.uVar(version)
+	if r.err != nil {
+		return fmt.Errorf("mp4: mvhd: %w", r.err)
+	}
+	if m.Timescale == 0 {
+		return fmt.Errorf("mp4: invalid movie timescale")
+	}
+	if _, ok := FindBox(moov, "mvex"); ok {
+		m.Fragmented = true
+	}
+
+	for _, box := range moov {
+		if box.Type != "trak" {
+			continue
+		}
+		track, err := parseTrack(box, m.Timescale)
+		if err != nil {
+			return err
+		}
+		m.Tracks = append(m.Tracks, track)
+	}
+	return nil
+}
+
+func mp4Time(secs uint64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return mp4Epoch.Add(time.Duration(secs) * time.Second)
+}
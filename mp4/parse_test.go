@@ -0,0 +1,102 @@
+package mp4
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The seed files hold a 64x36 avc1 track of 3 samples at 24 fps and a stereo
+// 44.1 kHz mp4a track of 2 samples. faststart.mp4 is laid out ftyp, moov, mdat and
+// moov_at_end.mp4 ftyp, mdat, moov.
+func TestParseSeeds(t *testing.T) {
+	for _, name := range []string{"faststart.mp4", "moov_at_end.mp4"} {
+		t.Run(name, func(t *testing.T) {
+			movie, err := ParseFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+			if movie.MajorBrand != "isom" || movie.Timescale != 1000 || movie.Duration != 125 {
+				t.Errorf("movie header = %q %d %d", movie.MajorBrand, movie.Timescale, movie.Duration)
+			}
+			video := movie.Video()
+			if video == nil {
+				t.Fatal("no video track")
+			}
+			if video.Codec != "avc1" || video.Width != 64 || video.Height != 36 || video.SampleCount() != 3 {
+				t.Errorf("video = %s %dx%d, %d samples", video.Codec, video.Width, video.Height, video.SampleCount())
+			}
+			if video.FPS() != 24 || !video.Samples[0].Sync || video.Samples[1].Sync {
+				t.Errorf("video fps %v, sync %v/%v", video.FPS(), video.Samples[0].Sync, video.Samples[1].Sync)
+			}
+			if len(video.DecoderConfig()) == 0 {
+				t.Error("missing avcC")
+			}
+			audio := movie.Audio()
+			if audio == nil || !movie.HasAudio() {
+				t.Fatal("no audio track")
+			}
+			if audio.Channels != 2 || audio.SampleRate != 44100 || !bytes.Equal(audio.AudioSpecificConfig(), []byte{0x12, 0x10}) {
+				t.Errorf("audio = %d ch %d Hz, asc %x", audio.Channels, audio.SampleRate, audio.AudioSpecificConfig())
+			}
+
+			// Sample offsets point into mdat, video chunk first
+			raw, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := video.Samples[0]
+			if got := raw[first.Offset : first.Offset+int64(first.Size)]; !bytes.Equal(got, []byte{1, 2, 3, 4, 5}) {
+				t.Errorf("first video sample = %v", got)
+			}
+			if audio.Samples[0].Offset != first.Offset+12 {
+				t.Errorf("audio offset %d, want %d", audio.Samples[0].Offset, first.Offset+12)
+			}
+		})
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	_, err := ParseFile(filepath.Join("testdata", "truncated.mp4"))
+	if err == nil {
+		t.Fatal("truncated file parsed")
+	}
+	if !errors.Is(err, ErrNoMoov) && !bytes.Contains([]byte(err.Error()), []byte("invalid size")) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	seeds, _ := filepath.Glob(filepath.Join("testdata", "*.mp4"))
+	for _, name := range seeds {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		movie, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		movie.Seconds()
+		movie.HasAudio()
+		for _, track := range movie.Tracks {
+			track.MediaDuration()
+			track.PresentedDuration()
+			track.Seconds()
+			track.FPS()
+			track.DecoderConfig()
+			track.AudioSpecificConfig()
+			track.SameCodec(track)
+			for _, s := range track.Samples {
+				if s.Offset < 0 {
+					t.Fatalf("negative sample offset %d", s.Offset)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,525 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// maxConstantSizeSamples bounds tracks whose sample count is not backed by a size table,
+// so a corrupt stsz cannot force a huge allocation.
+const maxConstantSizeSamples = 1 << 22
+
+// Sample locates one sample in the file.
+type Sample struct {
+	Offset    int64
+	Size      uint32
+	Duration  uint32 // media timescale
+	CTSOffset int32  // composition time offset, media timescale
+	Sync      bool
+}
+
+// Track describes one trak box. Sample tables are resolved to per-sample entries.
+type Track struct {
+	ID               uint32
+	Handler          string // vide, soun, ...
+	Codec            string // sample entry type: avc1, hvc1, mp4a, ...
+	Timescale        uint32
+	Duration         uint64 // media timescale (mdhd)
+	Language         string // ISO-639-2/T code, e.g. "und"
+	LanguageCode     uint16 // packed form as stored in mdhd
+	CreationTime     time.Time
+	ModificationTime time.Time
+
+	// Video: display size from the track header, falling back to the coded size.
+	Width, Height int
+	// Audio
+	SampleRate int
+	Channels   int
+
+	Samples      []Sample
+	HasSyncTable bool // false means every sample is a sync sample
+	// MediaTime is where presentation starts in media time, from the first non-empty edit
+	// (e.g. AAC priming or B-frame delay). EditDuration is that edit's length in media
+	// timescale, or 0 when the track has no edit list.
+	MediaTime    int64
+	EditDuration int64
+
+	// Raw boxes for remuxing
+	TrackHeader    []byte // tkhd payload
+	HandlerBox     []byte // raw hdlr box
+	MediaHeaderBox []byte // raw vmhd/smhd box, if any
+	SampleEntry    []byte // raw box of the first stsd entry
+	EntryCount     int    // number of stsd entries
+}
+
+// SampleCount is the number of samples in the track.
+func (t *Track) SampleCount() int { return len(t.Samples) }
+
+// MediaDuration sums the sample durations in media timescale.
+func (t *Track) MediaDuration() int64 {
+	var d int64
+	for _, s := range t.Samples {
+		d += int64(s.Duration)
+	}
+	return d
+}
+
+// PresentedDuration is how long the track plays, in media timescale, after its edit list.
+func (t *Track) PresentedDuration() int64 {
+	if t.EditDuration > 0 {
+		return t.EditDuration
+	}
+	if d := t.MediaDuration() - t.MediaTime; d > 0 {
+		return d
+	}
+	return int64(t.Duration)
+}
+
+// Seconds is the presented duration in seconds.
+func (t *Track) Seconds() float64 {
+	if t.Timescale == 0 {
+		return 0
+	}
+	return float64(t.PresentedDuration()) / float64(t.Timescale)
+}
+
+// SampleDelta returns the most common sample duration, i.e. the frame duration of
+// constant frame rate video. It is 0 when the track has no samples.
+func (t *Track) SampleDelta() uint32 {
+	counts := map[uint32]int{}
+	var best uint32
+	for _, s := range t.Samples {
+		if s.Duration == 0 {
+			continue
+		}
+		counts[s.Duration]++
+		if counts[s.Duration] > counts[best] {
+			best = s.Duration
+		}
+	}
+	return best
+}
+
+// FPS is the frame rate derived from the timescale and the dominant sample duration.
+func (t *Track) FPS() float64 {
+	delta := t.SampleDelta()
+	if delta == 0 {
+		return 0
+	}
+	return float64(t.Timescale) / float64(delta)
+}
+
+// entryFixedSize is the size of a sample entry's fixed fields, including the box header.
+func (t *Track) entryFixedSize() int {
+	switch t.Handler {
+	case HandlerVideo:
+		return 86 // VisualSampleEntry
+	case HandlerAudio:
+		return 36 // AudioSampleEntry (version 0)
+	}
+	return 16 // SampleEntry
+}
+
+// EntryChild returns the payload of a child box of the sample entry, e.g. "avcC" or "esds".
+func (t *Track) EntryChild(typ string) ([]byte, bool) {
+	fixed := t.entryFixedSize()
+	if len(t.SampleEntry) < fixed {
+		return nil, false
+	}
+	children, err := ParseBoxes(t.SampleEntry[fixed:])
+	if err != nil {
+		return nil, false
+	}
+	box, ok := FindBox(children, typ)
+	return box.Payload, ok
+}
+
+// DecoderConfig returns the codec configuration record of a video track (avcC, hvcC, av1C or vpcC).
+func (t *Track) DecoderConfig() []byte {
+	for _, typ := range []string{"avcC", "hvcC", "av1C", "vpcC"} {
+		if cfg, ok := t.EntryChild(typ); ok {
+			return cfg
+		}
+	}
+	return nil
+}
+
+// AudioSpecificConfig extracts the DecoderSpecificInfo from the esds box of an audio track,
+// ignoring the bitrate fields that vary per file.
+func (t *Track) AudioSpecificConfig() []byte {
+	esds, ok := t.EntryChild("esds")
+	if !ok || len(esds) < 4 {
+		return nil
+	}
+	p := esds[4:]
+	for len(p) > 2 {
+		tag := p[0]
+		p = p[1:]
+		size := 0
+		for i := 0; i < 4 && len(p) > 0; i++ {
+			b := p[0]
+			p = p[1:]
+			size = size<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		switch tag {
+		case 0x03: // ES_Descriptor: ES_ID(2), flags(1), optional fields
+			if len(p) < 3 {
+				return nil
+			}
+			flags := p[2]
+			p = p[3:]
+			if flags&0x80 != 0 {
+				if len(p) < 2 {
+					return nil
+				}
+				p = p[2:]
+			}
+			if flags&0x40 != 0 {
+				if len(p) < 1 || len(p) < 1+int(p[0]) {
+					return nil
+				}
+				p = p[1+int(p[0]):]
+			}
+			if flags&0x20 != 0 {
+				if len(p) < 2 {
+					return nil
+				}
+				p = p[2:]
+			}
+		case 0x04: // DecoderConfigDescriptor: objectType(1) streamType(1) bufferSize(3) maxBitrate(4) avgBitrate(4)
+			if len(p) < 13 {
+				return nil
+			}
+			p = p[13:]
+		case 0x05: // DecoderSpecificInfo
+			if size > len(p) {
+				return nil
+			}
+			return p[:size]
+		default:
+			if size > len(p) {
+				return nil
+			}
+			p = p[size:]
+		}
+	}
+	return nil
+}
+
+// SameCodec reports whether samples of other can be decoded with t's configuration:
+// same codec, coded size or audio layout, and decoder configuration. Bitrate boxes may differ.
+func (t *Track) SameCodec(other *Track) bool {
+	if t.Handler != other.Handler || t.Codec != other.Codec || t.EntryCount != 1 || other.EntryCount != 1 {
+		return false
+	}
+	if bytes.Equal(t.SampleEntry, other.SampleEntry) {
+		return true
+	}
+	a, b := t.SampleEntry, other.SampleEntry
+	switch t.Handler {
+	case HandlerVideo:
+		// coded width/height at 32..36 of the raw entry
+		return len(a) >= 36 && len(b) >= 36 && bytes.Equal(a[32:36], b[32:36]) &&
+			bytes.Equal(t.DecoderConfig(), other.DecoderConfig())
+	case HandlerAudio:
+		return t.Channels == other.Channels && t.SampleRate == other.SampleRate &&
+			bytes.Equal(t.AudioSpecificConfig(), other.AudioSpecificConfig())
+	}
+	return false
+}
+
+func parseTrack(trak Box, movieTimescale uint32) (*Track, error) {
+	children, err := trak.Children()
+	if err != nil {
+		return nil, fmt.Errorf("mp4: trak: %w", err)
+	}
+	t := &Track{}
+
+	tkhd, ok := FindBox(children, "tkhd")
+	if !ok {
+		return nil, fmt.Errorf("mp4: tkhd box not found")
+	}
+	t.TrackHeader = tkhd.Payload
+	r := &reader{b: tkhd.Payload}
+	version := r.u8()
+	r.skip(3)
+	r.uVar(version) // creation
+	r.uVar(version) // modification
+	t.ID = r.u32()
+	r.skip(4)
+	r.uVar(version) // duration, movie timescale
+	r.skip(8 + 2 + 2 + 2 + 2 + 36)
+	t.Width = int(r.u32() >> 16)
+	t.Height = int(r.u32() >> 16)
+	if r.err != nil {
+		return nil, fmt.Errorf("mp4: tkhd: %w", r.err)
+	}
+
+	if hdlr, ok := FindBox(children, "mdia", "hdlr"); ok && len(hdlr.Payload) >= 12 {
+		t.Handler = string(hdlr.Payload[8:12])
+		t.HandlerBox = hdlr.Raw
+	} else {
+		return nil, fmt.Errorf("mp4: hdlr box not found in track %d", t.ID)
+	}
+
+	mdhd, ok := FindBox(children, "mdia", "mdhd")
+	if !ok {
+		return nil, fmt.Errorf("mp4: mdhd box not found in track %d", t.ID)
+	}
+	r = &reader{b: mdhd.Payload}
+	version = r.u8()
+	r.skip(3)
+	t.CreationTime = mp4Time(r.uVar(version))
+	t.ModificationTime = mp4Time(r.uVar(version))
+	t.Timescale = r.u32()
+	t.Duration = r.uVar(version)
+	t.LanguageCode = r.u16()
+	if r.err != nil {
+		return nil, fmt.Errorf("mp4: mdhd: %w", r.err)
+	}
+	if t.Timescale == 0 {
+		return nil, fmt.Errorf("mp4: invalid timescale in track %d", t.ID)
+	}
+	t.Language = decodeLanguage(t.LanguageCode)
+
+	switch t.Handler {
+	case HandlerVideo:
+		if box, ok := FindBox(children, "mdia", "minf", "vmhd"); ok {
+			t.MediaHeaderBox = box.Raw
+		}
+	case HandlerAudio:
+		if box, ok := FindBox(children, "mdia", "minf", "smhd"); ok {
+			t.MediaHeaderBox = box.Raw
+		}
+	}
+
+	if stbl, ok := FindBox(children, "mdia", "minf", "stbl"); ok {
+		if err := t.parseSampleTable(stbl); err != nil {
+			return nil, fmt.Errorf("mp4: track %d: %w", t.ID, err)
+		}
+	}
+	if elst, ok := FindBox(children, "edts", "elst"); ok {
+		t.parseEditList(elst.Payload, movieTimescale)
+	}
+	return t, nil
+}
+
+// decodeLanguage unpacks the three 5-bit characters of an ISO-639-2/T code.
+func decodeLanguage(code uint16) string {
+	if code == 0 || code == 0x7fff {
+		return ""
+	}
+	return string([]byte{
+		byte(code>>10&0x1f) + 0x60,
+		byte(code>>5&0x1f) + 0x60,
+		byte(code&0x1f) + 0x60,
+	})
+}
+
+// parseEditList keeps the first non-empty edit, which is what encoders emit
+// to trim priming samples or composition delay.
+func (t *Track) parseEditList(p []byte, movieTimescale uint32) {
+	r := &reader{b: p}
+	version := r.u8()
+	r.skip(3)
+	entrySize := 12
+	if version == 1 {
+		entrySize = 20
+	}
+	n := r.count(entrySize)
+	for i := 0; i < n && r.err == nil; i++ {
+		segment := r.uVar(version)
+		var mediaTime int64
+		if version == 1 {
+			mediaTime = int64(r.u64())
+		} else {
+			mediaTime = int64(int32(r.u32()))
+		}
+		r.skip(4) // media rate
+		if mediaTime < 0 {
+			continue
+		}
+		t.MediaTime = mediaTime
+		if movieTimescale > 0 {
+			t.EditDuration = int64(segment) * int64(t.Timescale) / int64(movieTimescale)
+		}
+		return
+	}
+}
+
+func (t *Track) parseSampleTable(stbl Box) error {
+	boxes, err := stbl.Children()
+	if err != nil {
+		return err
+	}
+	table := func(typ string) (*reader, bool) {
+		box, ok := FindBox(boxes, typ)
+		if !ok {
+			return nil, false
+		}
+		r := &reader{b: box.Payload}
+		r.skip(4) // version/flags
+		return r, true
+	}
+
+	// Sample descriptions
+	r, ok := table("stsd")
+	if !ok {
+		return fmt.Errorf("stsd box not found")
+	}
+	r.skip(4)
+	if r.err != nil {
+		return fmt.Errorf("stsd: %w", r.err)
+	}
+	entries, err := ParseBoxes(r.b)
+	if err != nil {
+		return fmt.Errorf("stsd: %w", err)
+	}
+	t.EntryCount = len(entries)
+	if len(entries) > 0 {
+		t.SampleEntry = entries[0].Raw
+		t.Codec = entries[0].Type
+		e := entries[0].Raw
+		switch t.Handler {
+		case HandlerVideo:
+			if len(e) >= 36 && (t.Width == 0 || t.Height == 0) {
+				t.Width = int(e[32])<<8 | int(e[33])
+				t.Height = int(e[34])<<8 | int(e[35])
+			}
+		case HandlerAudio:
+			if len(e) >= 36 {
+				t.Channels = int(e[24])<<8 | int(e[25])
+				t.SampleRate = int(e[32])<<8 | int(e[33])
+			}
+		}
+	}
+
+	// Sample sizes
+	r, ok = table("stsz")
+	if !ok {
+		if _, compact := FindBox(boxes, "stz2"); compact {
+			return fmt.Errorf("stz2 sample sizes are not supported")
+		}
+		return fmt.Errorf("stsz box not found")
+	}
+	constSize := r.u32()
+	var count int
+	if constSize == 0 {
+		count = r.count(4)
+	} else {
+		count = int(r.u32())
+		if count < 0 || count > maxConstantSizeSamples {
+			return fmt.Errorf("stsz: implausible sample count %d", count)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("stsz: %w", r.err)
+	}
+	t.Samples = make([]Sample, count)
+	for i := range t.Samples {
+		if constSize != 0 {
+			t.Samples[i].Size = constSize
+		} else {
+			t.Samples[i].Size = r.u32()
+		}
+		t.Samples[i].Sync = true
+	}
+
+	// Durations
+	if r, ok = table("stts"); ok {
+		n, i := r.count(8), 0
+		for e := 0; e < n && r.err == nil; e++ {
+			sampleCount, delta := int(r.u32()), r.u32()
+			for j := 0; j < sampleCount && i < count; j++ {
+				t.Samples[i].Duration = delta
+				i++
+			}
+		}
+	}
+
+	// Composition offsets
+	if r, ok = table("ctts"); ok {
+		n, i := r.count(8), 0
+		for e := 0; e < n && r.err == nil; e++ {
+			sampleCount, offset := int(r.u32()), int32(r.u32())
+			for j := 0; j < sampleCount && i < count; j++ {
+				t.Samples[i].CTSOffset = offset
+				i++
+			}
+		}
+	}
+
+	// Sync samples
+	if r, ok = table("stss"); ok {
+		t.HasSyncTable = true
+		for i := range t.Samples {
+			t.Samples[i].Sync = false
+		}
+		n := r.count(4)
+		for e := 0; e < n && r.err == nil; e++ {
+			if num := int(r.u32()); num >= 1 && num <= count {
+				t.Samples[num-1].Sync = true
+			}
+		}
+	}
+
+	// Chunk offsets
+	var chunkOffsets []int64
+	if r, ok = table("stco"); ok {
+		n := r.count(4)
+		chunkOffsets = make([]int64, 0, n)
+		for e := 0; e < n && r.err == nil; e++ {
+			chunkOffsets = append(chunkOffsets, int64(r.u32()))
+		}
+	} else if r, ok = table("co64"); ok {
+		n := r.count(8)
+		chunkOffsets = make([]int64, 0, n)
+		for e := 0; e < n && r.err == nil; e++ {
+			offset := int64(r.u64())
+			if offset < 0 {
+				return fmt.Errorf("co64: chunk offset out of range")
+			}
+			chunkOffsets = append(chunkOffsets, offset)
+		}
+	} else if count > 0 {
+		return fmt.Errorf("chunk offset box not found")
+	}
+
+	// Sample-to-chunk: resolve each sample's file offset
+	type run struct{ firstChunk, samplesPerChunk int }
+	var runs []run
+	if r, ok = table("stsc"); ok {
+		n := r.count(12)
+		for e := 0; e < n && r.err == nil; e++ {
+			first, perChunk := int(r.u32()), int(r.u32())
+			r.skip(4) // sample description index
+			runs = append(runs, run{first, perChunk})
+		}
+	}
+	i := 0
+	for k, rn := range runs {
+		if rn.samplesPerChunk <= 0 {
+			continue // maps no samples; walking its chunks would only burn time
+		}
+		last := len(chunkOffsets)
+		if k+1 < len(runs) {
+			last = runs[k+1].firstChunk - 1
+		}
+		for chunk := rn.firstChunk; chunk >= 1 && chunk <= last && chunk <= len(chunkOffsets) && i < count; chunk++ {
+			offset := chunkOffsets[chunk-1]
+			for j := 0; j < rn.samplesPerChunk && i < count; j++ {
+				t.Samples[i].Offset = offset
+				offset += int64(t.Samples[i].Size)
+				i++
+			}
+		}
+	}
+	if i != count {
+		return fmt.Errorf("sample table is inconsistent (%d of %d samples mapped)", i, count)
+	}
+	return nil
+}
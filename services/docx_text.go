@@ -0,0 +1,286 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DocxToMarkdown extracts the text of a .docx file as markdown. Heading styles become "#"
+// headings, list paragraphs become "-" items and tables become markdown tables, so the structure
+// survives for decomposition.
+func DocxToMarkdown(filePath string) (string, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("无法打开 DOCX 文件：%w", err)
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	docFile := files["word/document.xml"]
+	if docFile == nil {
+		return "", fmt.Errorf("DOCX 文件缺少 word/document.xml")
+	}
+	b, err := readZipFile(docFile)
+	if err != nil {
+		return "", err
+	}
+	var doc docxNode
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("解析 DOCX 失败：%w", err)
+	}
+
+	d := docxDoc{headings: map[string]int{}}
+	if f := files["word/styles.xml"]; f != nil {
+		if b, err := readZipFile(f); err == nil {
+			d.loadStyles(b)
+		}
+	}
+
+	body := doc.child("body")
+	if body == nil {
+		return "", nil
+	}
+	var blocks []string
+	for _, n := range body.Nodes {
+		switch n.XMLName.Local {
+		case "p":
+			if text := d.paragraph(n); text != "" {
+				blocks = append(blocks, text)
+			}
+		case "tbl":
+			if table := d.table(n); len(table) > 0 {
+				blocks = append(blocks, strings.TrimRight(TableToMarkdown(table), "\n"))
+			}
+		case "sdt":
+			// Content controls (e.g. a table of contents) wrap ordinary paragraphs
+			if content := n.child("sdtContent"); content != nil {
+				for _, p := range content.Nodes {
+					if p.XMLName.Local == "p" {
+						if text := d.paragraph(p); text != "" {
+							blocks = append(blocks, text)
+						}
+					}
+				}
+			}
+		}
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// docxNode is a generic element of a WordprocessingML document. Element order is kept, which the
+// body needs since paragraphs and tables interleave.
+type docxNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []docxNode `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *docxNode) child(local string) *docxNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (n *docxNode) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+type docxDoc struct {
+	// headings maps paragraph style ids to heading levels; 0 is the document title.
+	headings map[string]int
+}
+
+var docxHeadingName = regexp.MustCompile(`^(?i:heading)\s*(\d)$|^标题\s*(\d)$`)
+
+// loadStyles finds the heading styles. Style ids are localized (a Chinese Word names "heading 1"
+// "1"), so they are recognized by style name, and by outline level for custom styles.
+func (d *docxDoc) loadStyles(b []byte) {
+	var styles docxNode
+	if err := xml.Unmarshal(b, &styles); err != nil {
+		return
+	}
+	for _, s := range styles.Nodes {
+		if s.XMLName.Local != "style" || s.attr("type") != "paragraph" {
+			continue
+		}
+		id := s.attr("styleId")
+		name := ""
+		if n := s.child("name"); n != nil {
+			name = strings.TrimSpace(n.attr("val"))
+		}
+		if m := docxHeadingName.FindStringSubmatch(name); m != nil {
+			level, _ := strconv.Atoi(m[1] + m[2])
+			d.headings[id] = level
+			continue
+		}
+		if strings.EqualFold(name, "title") || name == "标题" {
+			d.headings[id] = 0
+			continue
+		}
+		if ppr := s.child("pPr"); ppr != nil {
+			if o := ppr.child("outlineLvl"); o != nil {
+				if level, err := strconv.Atoi(o.attr("val")); err == nil && level < 9 {
+					d.headings[id] = level + 1
+				}
+			}
+		}
+	}
+}
+
+// paragraph renders a paragraph as a markdown line, prefixed as a heading or list item.
+func (d *docxDoc) paragraph(p docxNode) string {
+	text := strings.TrimSpace(docxText(p))
+	if text == "" {
+		return ""
+	}
+	ppr := p.child("pPr")
+	if ppr == nil {
+		return text
+	}
+	level := -1
+	if s := ppr.child("pStyle"); s != nil {
+		style := s.attr("val")
+		if l, ok := d.headings[style]; ok {
+			level = l
+		} else if m := docxHeadingName.FindStringSubmatch(style); m != nil {
+			level, _ = strconv.Atoi(m[1] + m[2])
+		}
+	}
+	if o := ppr.child("outlineLvl"); o != nil && level < 0 {
+		if l, err := strconv.Atoi(o.attr("val")); err == nil && l < 9 {
+			level = l + 1
+		}
+	}
+	if level >= 0 {
+		if level < 1 {
+			level = 1
+		}
+		if level > 6 {
+			level = 6
+		}
+		return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\n", " ")
+	}
+	if num := ppr.child("numPr"); num != nil {
+		indent := 0
+		if il := num.child("ilvl"); il != nil {
+			indent, _ = strconv.Atoi(il.attr("val"))
+		}
+		return strings.Repeat("  ", indent) + "- " + text
+	}
+	return text
+}
+
+// docxMaxTableColumns is the widest table Word creates; it bounds gridSpan when a table has no
+// tblGrid.
+const docxMaxTableColumns = 63
+
+// table renders a table as rows of cells. Merged cells repeat their text in every cell they
+// cover, as merged XLSX cells do; nested tables are flattened into their cell. A gridSpan never
+// extends a row past the table's grid columns.
+func (d *docxDoc) table(tbl docxNode) [][]string {
+	columns := 0
+	if grid := tbl.child("tblGrid"); grid != nil {
+		for _, c := range grid.Nodes {
+			if c.XMLName.Local == "gridCol" {
+				columns++
+			}
+		}
+	}
+	if columns == 0 {
+		columns = docxMaxTableColumns
+	}
+	var rows [][]string
+	// vMerge continuation cells take the text above them, by grid column
+	var above []string
+	for _, tr := range tbl.Nodes {
+		if tr.XMLName.Local != "tr" {
+			continue
+		}
+		var row []string
+		for _, tc := range tr.Nodes {
+			if tc.XMLName.Local != "tc" {
+				continue
+			}
+			span := 1
+			merged := false
+			if pr := tc.child("tcPr"); pr != nil {
+				if g := pr.child("gridSpan"); g != nil {
+					if n, err := strconv.Atoi(g.attr("val")); err == nil && n > 1 {
+						span = min(n, max(columns-len(row), 1))
+					}
+				}
+				if v := pr.child("vMerge"); v != nil && v.attr("val") != "restart" {
+					merged = true
+				}
+			}
+			var parts []string
+			for _, p := range tc.Nodes {
+				if text := strings.TrimSpace(docxText(p)); text != "" {
+					parts = append(parts, text)
+				}
+			}
+			text := strings.Join(parts, " ")
+			for i := 0; i < span; i++ {
+				col := len(row)
+				if merged && col < len(above) {
+					row = append(row, above[col])
+				} else {
+					row = append(row, text)
+				}
+			}
+		}
+		if len(row) == 0 {
+			continue
+		}
+		above = row
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// docxText returns the text of an element's runs. Tabs and breaks become spaces and newlines;
+// deleted text and field instructions are skipped.
+func docxText(n docxNode) string {
+	var sb strings.Builder
+	var walk func(n docxNode)
+	walk = func(n docxNode) {
+		switch n.XMLName.Local {
+		case "t":
+			sb.WriteString(n.Text)
+			return
+		case "tab":
+			sb.WriteString(" ")
+			return
+		case "br", "cr":
+			sb.WriteString("\n")
+			return
+		case "del", "instrText", "delText", "pPr", "rPr":
+			return
+		case "p":
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+		}
+		for _, c := range n.Nodes {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
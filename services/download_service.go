@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"seedance-client/config"
 	"seedance-client/models"
+	"seedance-client/mp4"
 	"time"
 
 	"github.com/google/uuid"
@@ -65,7 +67,16 @@ func DownloadTakeAssets(take *models.Take) error {
 	if take.DownloadStatus == "completed" {
 		// Verify files exist
 		if take.LocalVideoPath != "" {
-			if _, err := os.Stat(config.ToAbsolutePath(take.LocalVideoPath)); err == nil {
+			absPath := config.ToAbsolutePath(take.LocalVideoPath)
+			if _, err := os.Stat(absPath); err == nil {
+				// Backfill metadata for takes downloaded before it was recorded
+				if take.VideoCodec == "" {
+					if err := applyVideoMetadata(take, absPath); err != nil {
+						log.Printf("Take %d: failed to read video metadata: %v", take.ID, err)
+					} else {
+						models.DB.Save(take)
+					}
+				}
 				return nil // Already downloaded and exists
 			}
 		}
@@ -83,7 +94,19 @@ func DownloadTakeAssets(take *models.Take) error {
 			models.DB.Save(take)
 			return fmt.Errorf("video download failed: %w", err)
 		}
+		// A truncated or error-page download must not be cached as the take's video
+		absPath := config.ToAbsolutePath(localPath)
+		if err := applyVideoMetadata(take, absPath); err != nil {
+			os.Remove(absPath)
+			take.DownloadStatus = "failed"
+			models.DB.Save(take)
+			return fmt.Errorf("video verification failed: %w", err)
+		}
 		take.LocalVideoPath = localPath
+	} else if take.LocalVideoPath != "" && take.VideoCodec == "" {
+		if err := applyVideoMetadata(take, config.ToAbsolutePath(take.LocalVideoPath)); err != nil {
+			log.Printf("Take %d: failed to read video metadata: %v", take.ID, err)
+		}
 	}
 
 	// Download last frame
@@ -102,6 +125,33 @@ func DownloadTakeAssets(take *models.Take) error {
 	return nil
 }
 
+// applyVideoMetadata verifies that path holds a playable MP4 and records its properties on take.
+func applyVideoMetadata(take *models.Take, path string) error {
+	movie, err := mp4.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("not a valid MP4: %w", err)
+	}
+	video := movie.Video()
+	if video == nil {
+		return fmt.Errorf("no video track")
+	}
+	if video.SampleCount() == 0 && !movie.Fragmented {
+		return fmt.Errorf("video track has no samples")
+	}
+
+	take.VideoWidth = video.Width
+	take.VideoHeight = video.Height
+	take.VideoFPS = math.Round(video.FPS()*1000) / 1000
+	take.VideoCodec = video.Codec
+	take.VideoFrames = video.SampleCount()
+	take.MediaDuration = movie.Seconds()
+	if take.MediaDuration == 0 {
+		take.MediaDuration = video.Seconds()
+	}
+	take.HasAudio = movie.HasAudio()
+	return nil
+}
+
 // DownloadTakeAssetsAsync downloads assets in background
 func DownloadTakeAssetsAsync(takeID uint) {
 	go func() {
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// folderExportManifestName records what a previous folder export wrote, so re-exports
+// can skip unchanged media and remove files that are no longer part of the timeline.
+const folderExportManifestName = ".seedance-export.json"
+
+// FolderExportResult summarizes an export into a directory
+type FolderExportResult struct {
+	Dir     string `json:"dir"`
+	Written int    `json:"written"`
+	Skipped int    `json:"skipped"`
+	Removed int    `json:"removed"`
+}
+
+type folderExportManifest struct {
+	Files map[string]folderExportEntry `json:"files"`
+}
+
+type folderExportEntry struct {
+	Source        string `json:"source"`
+	SourceSize    int64  `json:"source_size,omitempty"`
+	SourceModTime int64  `json:"source_mod_time,omitempty"` // unix nanoseconds, local sources only
+	Size          int64  `json:"size"`
+	ModTime       int64  `json:"mod_time"` // unix nanoseconds of the exported file
+	SHA256        string `json:"sha256"`
+}
+
+// CreateExportFolder writes all videos, the FCPXML and optional extras into dir without zipping.
+// Media referenced by the FCPXML uses URLs relative to the FCPXML file, so the folder can be
+// opened in place or moved as a whole. Files that are already identical are left untouched.
+// On cancellation or failure, files this run newly created are removed again.
+func CreateExportFolder(ctx context.Context, dir string, projectName string, exports []ExportData, opts ExportOptions, progress ExportProgressFunc) (result *FolderExportResult, err error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, fmt.Errorf("export directory is empty")
+	}
+	if err := EnsureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	previous := loadFolderExportManifest(dir)
+	current := folderExportManifest{Files: map[string]folderExportEntry{}}
+	result = &FolderExportResult{Dir: dir}
+	var created []string
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Drop files that did not exist before this run and keep the manifest
+		// accurate for everything else, so the next export is still incremental.
+		for _, name := range created {
+			os.Remove(exportFilePath(dir, name))
+			delete(current.Files, name)
+		}
+		for name, entry := range previous.Files {
+			if _, ok := current.Files[name]; !ok {
+				current.Files[name] = entry
+			}
+		}
+		saveFolderExportManifest(dir, current)
+		result = nil
+	}()
+
+	tracker := newExportProgressTracker(exports, opts, progress)
+	tracker.phase(ExportPhasePreparing)
+
+	client := newExportHTTPClient()
+	syncFile := func(name, src string) error {
+		_, statErr := os.Stat(exportFilePath(dir, name))
+		entry, written, err := syncExportFile(ctx, client, dir, name, src, previous.Files[name], tracker)
+		if err != nil {
+			return err
+		}
+		current.Files[name] = entry
+		if written {
+			result.Written++
+			if os.IsNotExist(statErr) {
+				created = append(created, name)
+			}
+		} else {
+			result.Skipped++
+		}
+		return nil
+	}
+
+	for _, exp := range exports {
+		if err := syncFile(exp.Filename, exp.VideoURL); err != nil {
+			return nil, fmt.Errorf("failed to export video %s: %w", exp.Filename, err)
+		}
+		if opts.IncludeLastFrames && exp.LastFrameFilename != "" {
+			if err := syncFile(exp.LastFrameFilename, exp.LastFrameURL); err != nil {
+				return nil, fmt.Errorf("failed to export last frame %s: %w", exp.LastFrameFilename, err)
+			}
+		}
+	}
+
+	tracker.phase(ExportPhaseFinalizing)
+
+	// Remove media written by an earlier export that is no longer part of this one.
+	for name := range previous.Files {
+		if _, ok := current.Files[name]; ok {
+			continue
+		}
+		if err := os.Remove(exportFilePath(dir, name)); err == nil {
+			result.Removed++
+		}
+	}
+
+	// Probe the exported copies so remote sources are not downloaded a second time.
+	probePaths := make([]string, 0, len(exports))
+	for _, exp := range exports {
+		probePaths = append(probePaths, exportFilePath(dir, exp.Filename))
+	}
+	width, height, frameDuration := probeVideoFormat(probePaths...)
+
+	fcpxmlData, err := GenerateFCPXML(projectName, exports, width, height, frameDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate FCPXML: %w", err)
+	}
+	if err := writeFileIfChanged(filepath.Join(dir, "project.fcpxml"), fcpxmlData); err != nil {
+		return nil, fmt.Errorf("failed to write fcpxml: %w", err)
+	}
+
+	if opts.IncludeShotList {
+		csvData, err := GenerateShotListCSV(exports)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate shot list: %w", err)
+		}
+		if err := writeFileIfChanged(filepath.Join(dir, "shot_list.csv"), csvData); err != nil {
+			return nil, fmt.Errorf("failed to write shot list: %w", err)
+		}
+	}
+
+	if err := saveFolderExportManifest(dir, current); err != nil {
+		return nil, fmt.Errorf("failed to write export manifest: %w", err)
+	}
+	return result, nil
+}
+
+// syncExportFile makes dir/name a copy of src unless an identical file is already there.
+// It returns the manifest entry for the file and whether anything was written.
+func syncExportFile(ctx context.Context, client *http.Client, dir, name, src string, prev folderExportEntry, tracker *exportProgressTracker) (folderExportEntry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return folderExportEntry{}, false, err
+	}
+	src = strings.TrimSpace(src)
+	dest := exportFilePath(dir, name)
+	if err := EnsureDir(filepath.Dir(dest)); err != nil {
+		return folderExportEntry{}, false, err
+	}
+	destInfo, destErr := os.Stat(dest)
+	destUnchanged := destErr == nil && prev.Source == src &&
+		destInfo.Size() == prev.Size && destInfo.ModTime().UnixNano() == prev.ModTime
+
+	entry := folderExportEntry{Source: src}
+	if isHTTPURL(src) {
+		// Remote result URLs are unique per generation task, so an unchanged URL means unchanged content.
+		if destUnchanged {
+			tracker.skipFile(name, 0)
+			return prev, false, nil
+		}
+	} else {
+		abs := resolveLocalPath(src)
+		if abs == "" {
+			return folderExportEntry{}, false, fmt.Errorf("invalid local source: %q", src)
+		}
+		srcInfo, err := os.Stat(abs)
+		if err != nil {
+			return folderExportEntry{}, false, err
+		}
+		entry.SourceSize = srcInfo.Size()
+		entry.SourceModTime = srcInfo.ModTime().UnixNano()
+		if destUnchanged && prev.SourceSize == entry.SourceSize && prev.SourceModTime == entry.SourceModTime {
+			tracker.skipFile(name, srcInfo.Size())
+			return prev, false, nil
+		}
+		// Without a usable manifest entry, fall back to comparing content.
+		if destErr == nil && destInfo.Size() == srcInfo.Size() {
+			srcSum, err := fileSHA256(abs)
+			if err == nil {
+				if destSum, err := fileSHA256(dest); err == nil && destSum == srcSum {
+					entry.Size = destInfo.Size()
+					entry.ModTime = destInfo.ModTime().UnixNano()
+					entry.SHA256 = destSum
+					tracker.skipFile(name, srcInfo.Size())
+					return entry, false, nil
+				}
+			}
+		}
+	}
+
+	reader, size, err := openExportSource(ctx, client, src)
+	if err != nil {
+		return folderExportEntry{}, false, err
+	}
+	defer reader.Close()
+	tracker.startFile(name, size, isHTTPURL(src))
+	entry, written, err := replaceExportFile(ctx, dest, reader, entry, tracker)
+	if err != nil {
+		return folderExportEntry{}, false, err
+	}
+	tracker.finishFile()
+	return entry, written, nil
+}
+
+// replaceExportFile streams r into a temp file next to dest and renames it into place,
+// keeping the existing file when the new content turns out to be identical.
+// The temp file is removed on any failure, including cancellation.
+func replaceExportFile(ctx context.Context, dest string, r io.Reader, entry folderExportEntry, tracker *exportProgressTracker) (folderExportEntry, bool, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".partial-*")
+	if err != nil {
+		return folderExportEntry{}, false, err
+	}
+	tmpName := tmp.Name()
+	hasher := sha256.New()
+	size, err := copyWithProgress(ctx, io.MultiWriter(tmp, hasher), r, tracker)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return folderExportEntry{}, false, err
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+
+	if info, err := os.Stat(dest); err == nil && info.Size() == size {
+		if destSum, err := fileSHA256(dest); err == nil && destSum == sum {
+			os.Remove(tmpName)
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime().UnixNano()
+			entry.SHA256 = sum
+			return entry, false, nil
+		}
+	}
+
+	if err := os.Rename(tmpName, dest); err != nil {
+		os.Remove(tmpName)
+		return folderExportEntry{}, false, err
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		return folderExportEntry{}, false, err
+	}
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+	entry.SHA256 = sum
+	return entry, true, nil
+}
+
+// writeFileIfChanged rewrites path only when its content differs from data
+func writeFileIfChanged(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return WriteToFile(path, data)
+}
+
+func exportFilePath(dir, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(name))
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadFolderExportManifest(dir string) folderExportManifest {
+	manifest := folderExportManifest{Files: map[string]folderExportEntry{}}
+	data, err := os.ReadFile(filepath.Join(dir, folderExportManifestName))
+	if err != nil {
+		return manifest
+	}
+	if json.Unmarshal(data, &manifest) != nil || manifest.Files == nil {
+		return folderExportManifest{Files: map[string]folderExportEntry{}}
+	}
+	return manifest
+}
+
+func saveFolderExportManifest(dir string, manifest folderExportManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, folderExportManifestName), data, 0644)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Export phases reported through ExportProgress.Phase
+const (
+	ExportPhasePreparing  = "preparing"
+	ExportPhaseFile       = "file"
+	ExportPhaseFinalizing = "finalizing"
+)
+
+const (
+	// exportProgressInterval throttles byte-level progress callbacks.
+	exportProgressInterval = 200 * time.Millisecond
+	// exportStallTimeout aborts a remote download that delivers no data for this long.
+	exportStallTimeout = 60 * time.Second
+)
+
+// ExportProgress describes how far an export has come.
+// BytesTotal grows while running when remote sources only report their size once requested.
+type ExportProgress struct {
+	Phase      string `json:"phase"`
+	File       string `json:"file"`
+	FileIndex  int    `json:"file_index"` // 1-based
+	FileCount  int    `json:"file_count"`
+	FileBytes  int64  `json:"file_bytes"`
+	FileTotal  int64  `json:"file_total"` // -1 when unknown
+	BytesDone  int64  `json:"bytes_done"`
+	BytesTotal int64  `json:"bytes_total"`
+	Skipped    bool   `json:"skipped"` // file already up to date (folder export)
+}
+
+// ExportProgressFunc receives progress updates; it may be nil.
+type ExportProgressFunc func(ExportProgress)
+
+// exportProgressTracker accumulates per-file and overall byte counts and throttles callbacks.
+type exportProgressTracker struct {
+	mu       sync.Mutex
+	report   ExportProgressFunc
+	state    ExportProgress
+	lastEmit time.Time
+}
+
+// newExportProgressTracker counts every media file in exports and pre-sums the sizes of local sources.
+func newExportProgressTracker(exports []ExportData, opts ExportOptions, report ExportProgressFunc) *exportProgressTracker {
+	t := &exportProgressTracker{report: report}
+	for _, exp := range exports {
+		t.state.FileCount++
+		t.state.BytesTotal += localSourceSize(exp.VideoURL)
+		if opts.IncludeLastFrames && exp.LastFrameFilename != "" {
+			t.state.FileCount++
+			t.state.BytesTotal += localSourceSize(exp.LastFrameURL)
+		}
+	}
+	return t
+}
+
+func localSourceSize(src string) int64 {
+	if isHTTPURL(src) {
+		return 0
+	}
+	abs := resolveLocalPath(src)
+	if abs == "" {
+		return 0
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (t *exportProgressTracker) phase(phase string) {
+	t.mu.Lock()
+	t.state.Phase = phase
+	t.state.File = ""
+	t.state.Skipped = false
+	t.mu.Unlock()
+	t.emit(true)
+}
+
+// startFile begins a new file. size is the known length, or -1 if unknown;
+// remote sizes are added to the overall total here since they were not known upfront.
+func (t *exportProgressTracker) startFile(name string, size int64, remote bool) {
+	t.mu.Lock()
+	t.state.Phase = ExportPhaseFile
+	t.state.File = name
+	t.state.FileIndex++
+	t.state.FileBytes = 0
+	t.state.FileTotal = size
+	t.state.Skipped = false
+	if remote && size > 0 {
+		t.state.BytesTotal += size
+	}
+	t.mu.Unlock()
+	t.emit(true)
+}
+
+func (t *exportProgressTracker) add(n int64) {
+	t.mu.Lock()
+	t.state.FileBytes += n
+	t.state.BytesDone += n
+	t.mu.Unlock()
+	t.emit(false)
+}
+
+// skipFile records a file that did not need to be copied.
+func (t *exportProgressTracker) skipFile(name string, size int64) {
+	t.mu.Lock()
+	t.state.Phase = ExportPhaseFile
+	t.state.File = name
+	t.state.FileIndex++
+	t.state.FileBytes = size
+	t.state.FileTotal = size
+	t.state.BytesDone += size
+	t.state.Skipped = true
+	t.mu.Unlock()
+	t.emit(true)
+}
+
+func (t *exportProgressTracker) finishFile() {
+	t.emit(true)
+}
+
+func (t *exportProgressTracker) emit(force bool) {
+	if t == nil || t.report == nil {
+		return
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if !force && now.Sub(t.lastEmit) < exportProgressInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.lastEmit = now
+	snapshot := t.state
+	t.mu.Unlock()
+	t.report(snapshot)
+}
+
+// exportReader reports copied bytes and stops as soon as the export context is cancelled.
+type exportReader struct {
+	ctx     context.Context
+	r       io.Reader
+	tracker *exportProgressTracker
+}
+
+func (r *exportReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if n > 0 && r.tracker != nil {
+		r.tracker.add(int64(n))
+	}
+	return n, err
+}
+
+// copyWithProgress copies src to dst while reporting progress and honouring ctx.
+func copyWithProgress(ctx context.Context, dst io.Writer, src io.Reader, tracker *exportProgressTracker) (int64, error) {
+	return io.Copy(dst, &exportReader{ctx: ctx, r: src, tracker: tracker})
+}
+
+// newExportHTTPClient bounds connection setup and response headers; body reads are
+// guarded by the stall watchdog in openExportSource instead of an overall timeout,
+// since large videos can legitimately take many minutes.
+func newExportHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   15 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// stallGuardBody cancels its request when no data arrives within exportStallTimeout.
+type stallGuardBody struct {
+	body    io.ReadCloser
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	stalled bool
+}
+
+func (b *stallGuardBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(exportStallTimeout)
+	}
+	if err != nil && err != io.EOF {
+		b.mu.Lock()
+		stalled := b.stalled
+		b.mu.Unlock()
+		if stalled {
+			return n, fmt.Errorf("download stalled: no data for %s", exportStallTimeout)
+		}
+	}
+	return n, err
+}
+
+func (b *stallGuardBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.body.Close()
+}
+
+// openExportSource opens a remote URL or local path for streaming.
+// The returned size is -1 when the remote server does not report a length.
+func openExportSource(ctx context.Context, client *http.Client, src string) (io.ReadCloser, int64, error) {
+	if isHTTPURL(src) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, src, nil)
+		if err != nil {
+			cancel()
+			return nil, 0, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			cancel()
+			return nil, 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			cancel()
+			return nil, 0, fmt.Errorf("failed to download: status %d", resp.StatusCode)
+		}
+		guard := &stallGuardBody{body: resp.Body, cancel: cancel}
+		guard.timer = time.AfterFunc(exportStallTimeout, func() {
+			guard.mu.Lock()
+			guard.stalled = true
+			guard.mu.Unlock()
+			cancel()
+		})
+		return guard, resp.ContentLength, nil
+	}
+
+	abs := resolveLocalPath(src)
+	if abs == "" {
+		return nil, 0, fmt.Errorf("invalid local source: %q", src)
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
@@ -2,17 +2,23 @@ package services
 
 import (
 	"archive/zip"
-	"encoding/binary"
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"seedance-client/config"
 	"seedance-client/models"
+	"seedance-client/mp4"
 	"sort"
 	"strings"
 )
@@ -95,6 +101,93 @@ type ExportData struct {
 	// VideoURL may be a remote URL (http/https) or a local path (absolute or relative to data dir).
 	VideoURL string
 	Duration int
+
+	// Shot/take metadata carried along for filename templates and the shot list CSV.
+	Index          int
+	StoryboardID   uint
+	ShotNo         string
+	ShotSize       string
+	CameraMovement string
+	FrameContent   string
+	TakeID         uint
+	ModelID        string
+	Prompt         string
+	IsGood         bool
+
+	// LastFrameFilename/LastFrameURL are set only when last-frame stills are requested
+	// and the chosen take has a resolvable last frame.
+	LastFrameFilename string
+	LastFrameURL      string
+}
+
+// Take selection strategies for ExportOptions.TakeSelection
+const (
+	TakeSelectionGoodOrLatest = "good_or_latest" // latest Good Take, otherwise latest succeeded take
+	TakeSelectionGoodOnly     = "good_only"      // only shots with a Good Take are exported
+)
+
+// DefaultExportFilenameTemplate reproduces the historical {index}_{shotNo?}_{prompt} naming.
+const DefaultExportFilenameTemplate = "{index}_{shot_no}_{prompt}"
+
+// ExportOptions controls which shots and takes are exported and how files are named.
+type ExportOptions struct {
+	// ShotFrom/ShotTo select a 1-based, inclusive range of shots in timeline order.
+	// Zero means "from the first" / "to the last".
+	ShotFrom int `json:"shot_from"`
+	ShotTo   int `json:"shot_to"`
+	// ShotIDs selects storyboards explicitly and takes precedence over the range.
+	ShotIDs []uint `json:"shot_ids"`
+	// TakeSelection is good_or_latest (default) or good_only.
+	TakeSelection string `json:"take_selection"`
+	// IncludeLastFrames adds each chosen take's last-frame still under stills/.
+	IncludeLastFrames bool `json:"include_last_frames"`
+	// IncludeShotList adds shot_list.csv describing every exported clip.
+	IncludeShotList bool `json:"include_shot_list"`
+	// FilenameTemplate supports {index} {shot_no} {prompt} {take_id} {model}.
+	// The .mp4 extension is appended automatically.
+	FilenameTemplate string `json:"filename_template"`
+}
+
+// Normalize fills defaults and validates the options.
+func (o *ExportOptions) Normalize() error {
+	if o.ShotFrom < 0 || o.ShotTo < 0 {
+		return fmt.Errorf("invalid shot range: %d-%d", o.ShotFrom, o.ShotTo)
+	}
+	if o.ShotFrom > 0 && o.ShotTo > 0 && o.ShotFrom > o.ShotTo {
+		return fmt.Errorf("invalid shot range: %d-%d", o.ShotFrom, o.ShotTo)
+	}
+	switch strings.TrimSpace(o.TakeSelection) {
+	case "":
+		o.TakeSelection = TakeSelectionGoodOrLatest
+	case TakeSelectionGoodOrLatest, TakeSelectionGoodOnly:
+		o.TakeSelection = strings.TrimSpace(o.TakeSelection)
+	default:
+		return fmt.Errorf("unsupported take selection: %s", o.TakeSelection)
+	}
+	o.FilenameTemplate = strings.TrimSpace(o.FilenameTemplate)
+	if o.FilenameTemplate == "" {
+		o.FilenameTemplate = DefaultExportFilenameTemplate
+	}
+	return nil
+}
+
+// selects reports whether the storyboard at the 1-based timeline position is part of the export.
+func (o ExportOptions) selects(position int, storyboardID uint) bool {
+	if len(o.ShotIDs) > 0 {
+		for _, id := range o.ShotIDs {
+			if id == storyboardID {
+				return true
+			}
+		}
+		return false
+	}
+	if o.ShotFrom > 0 && position < o.ShotFrom {
+		return false
+	}
+	if o.ShotTo > 0 && position > o.ShotTo {
+		return false
+	}
+	return true
 }
 
 // sanitizeFilename removes special characters and limits length for filenames
@@ -116,6 +209,42 @@ func sanitizeFilename(s string) string {
 	return s
 }
 
+var (
+	filenameIllegalChars = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]`)
+	filenameRepeatedSeps = regexp.MustCompile(`[-_\s]{2,}`)
+	modelTokenChars      = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// renderExportFilename expands the filename template for one export entry.
+// Empty tokens collapse so "{index}_{shot_no}_{prompt}" yields "001_prompt" without a shot number.
+func renderExportFilename(template string, exp ExportData) string {
+	shotNo := ""
+	if v := strings.TrimSpace(exp.ShotNo); v != "" {
+		shotNo = sanitizeFilename(v)
+	}
+	takeID := ""
+	if exp.TakeID > 0 {
+		takeID = fmt.Sprintf("%d", exp.TakeID)
+	}
+	replacer := strings.NewReplacer(
+		"{index}", fmt.Sprintf("%03d", exp.Index),
+		"{shot_no}", shotNo,
+		"{prompt}", sanitizeFilename(exp.Prompt),
+		"{take_id}", takeID,
+		"{model}", modelTokenChars.ReplaceAllString(strings.TrimSpace(exp.ModelID), ""),
+	)
+	name := replacer.Replace(template)
+	name = filenameIllegalChars.ReplaceAllString(name, "")
+	name = filenameRepeatedSeps.ReplaceAllStringFunc(name, func(m string) string {
+		return m[:1]
+	})
+	name = strings.Trim(name, "_-. ")
+	if name == "" {
+		name = fmt.Sprintf("%03d", exp.Index)
+	}
+	return name
+}
+
 // GenerateFCPXML creates FCPXML 1.9 content for DaVinci Resolve
 func GenerateFCPXML(projectName string, exports []ExportData, width, height int, frameDuration string) ([]byte, error) {
 	// Create format
@@ -147,7 +276,7 @@ func GenerateFCPXML(projectName string, exports []ExportData, width, height int,
 			Format:   "r0",
 			MediaRep: MediaRep{
 				Kind: "original-media",
-				Src:  "./" + exp.Filename,
+				Src:  relativeMediaURL(exp.Filename),
 			},
 		}
 		assets = append(assets, asset)
@@ -200,10 +329,16 @@ func GenerateFCPXML(projectName string, exports []ExportData, width, height int,
 	return append(header, output...), nil
 }
 
-// PrepareExportData generates the list of files to export from succeeded storyboards
-func PrepareExportData(storyboards []models.Storyboard) []ExportData {
+// PrepareExportData generates the list of files to export from succeeded storyboards.
+// Invalid options are reported as an error.
+func PrepareExportData(storyboards []models.Storyboard, opts ExportOptions) ([]ExportData, error) {
+	if err := opts.Normalize(); err != nil {
+		return nil, err
+	}
+
 	var exports []ExportData
 	index := 1
+	usedNames := map[string]int{}
 
 	ordered := make([]models.Storyboard, 0, len(storyboards))
 	ordered = append(ordered, storyboards...)
@@ -224,10 +359,14 @@ func PrepareExportData(storyboards []models.Storyboard) []ExportData {
 		return a.ID < b.ID
 	})
 
-	for _, sb := range ordered {
+	for pos, sb := range ordered {
+		if !opts.selects(pos+1, sb.ID) {
+			continue
+		}
+
 		// Each storyboard exports exactly one material:
 		// 1) latest succeeded GoodTake with a resolvable source
-		// 2) otherwise latest succeeded take with a resolvable source
+		// 2) otherwise latest succeeded take with a resolvable source (unless good_only)
 		var latestGood *models.Take
 		var latestAny *models.Take
 
@@ -249,22 +388,10 @@ func PrepareExportData(storyboards []models.Storyboard) []ExportData {
 			if take.Status != "Succeeded" {
 				continue
 			}
-
-			videoSource := ""
-			if take.LocalVideoPath != "" {
-				abs := config.ToAbsolutePath(take.LocalVideoPath)
-				if _, err := os.Stat(abs); err == nil {
-					videoSource = abs
-				}
-			}
-			if videoSource == "" {
-				videoSource = strings.TrimSpace(take.VideoURL)
-			}
-			if videoSource == "" {
+			if resolveTakeVideoSource(take) == "" {
 				continue
 			}
 
-			// Keep the chosen take pointers only; source is re-derived below to avoid duplication.
 			if take.IsGood {
 				if better(take, latestGood) {
 					latestGood = take
@@ -276,100 +403,157 @@ func PrepareExportData(storyboards []models.Storyboard) []ExportData {
 		}
 
 		chosen := latestGood
-		if chosen == nil {
+		if chosen == nil && opts.TakeSelection != TakeSelectionGoodOnly {
 			chosen = latestAny
 		}
 		if chosen == nil {
 			continue
 		}
 
-		videoSource := ""
-		if chosen.LocalVideoPath != "" {
-			abs := config.ToAbsolutePath(chosen.LocalVideoPath)
-			if _, err := os.Stat(abs); err == nil {
-				videoSource = abs
-			}
-		}
-		if videoSource == "" {
-			videoSource = strings.TrimSpace(chosen.VideoURL)
-		}
-		if videoSource == "" {
-			continue
+		exp := ExportData{
+			VideoURL:       resolveTakeVideoSource(chosen),
+			Duration:       chosen.Duration,
+			Index:          index,
+			StoryboardID:   sb.ID,
+			ShotNo:         strings.TrimSpace(sb.ShotNo),
+			ShotSize:       strings.TrimSpace(sb.ShotSize),
+			CameraMovement: strings.TrimSpace(sb.CameraMovement),
+			FrameContent:   strings.TrimSpace(sb.FrameContent),
+			TakeID:         chosen.ID,
+			ModelID:        chosen.ModelID,
+			Prompt:         chosen.Prompt,
+			IsGood:         chosen.IsGood,
 		}
 
-		// Create filename: {index}_{shotNo?}_{sanitized_prompt}.mp4
-		promptPart := sanitizeFilename(chosen.Prompt)
-		shotNoRaw := strings.TrimSpace(sb.ShotNo)
-		shotPart := ""
-		if shotNoRaw != "" {
-			shotPart = sanitizeFilename(shotNoRaw)
-		}
-		var filename string
-		if shotPart != "" {
-			filename = fmt.Sprintf("%03d_%s_%s.mp4", index, shotPart, promptPart)
+		base := renderExportFilename(opts.FilenameTemplate, exp)
+		if n := usedNames[base]; n > 0 {
+			usedNames[base] = n + 1
+			base = fmt.Sprintf("%s_%d", base, n+1)
 		} else {
-			filename = fmt.Sprintf("%03d_%s.mp4", index, promptPart)
+			usedNames[base] = 1
 		}
+		exp.Filename = base + ".mp4"
 
-		exports = append(exports, ExportData{
-			Filename: filename,
-			VideoURL: videoSource,
-			Duration: chosen.Duration,
-		})
+		if opts.IncludeLastFrames {
+			if src := resolveTakeLastFrameSource(chosen); src != "" {
+				ext := strings.ToLower(path.Ext(strings.SplitN(src, "?", 2)[0]))
+				if ext == "" || len(ext) > 5 {
+					ext = ".png"
+				}
+				exp.LastFrameURL = src
+				exp.LastFrameFilename = "stills/" + base + "_last" + ext
+			}
+		}
+
+		exports = append(exports, exp)
 		index++
 	}
 
-	return exports
+	return exports, nil
+}
+
+// resolveTakeVideoSource prefers the cached local video and falls back to the remote URL.
+func resolveTakeVideoSource(take *models.Take) string {
+	if take.LocalVideoPath != "" {
+		abs := config.ToAbsolutePath(take.LocalVideoPath)
+		if _, err := os.Stat(abs); err == nil {
+			return abs
+		}
+	}
+	return strings.TrimSpace(take.VideoURL)
+}
+
+// resolveTakeLastFrameSource prefers the cached local last frame and falls back to the remote URL.
+func resolveTakeLastFrameSource(take *models.Take) string {
+	if take.LocalLastFramePath != "" {
+		abs := config.ToAbsolutePath(take.LocalLastFramePath)
+		if _, err := os.Stat(abs); err == nil {
+			return abs
+		}
+	}
+	return strings.TrimSpace(take.LastFrameURL)
 }
 
-// CreateExportZIP creates a ZIP file containing all videos and FCPXML
-func CreateExportZIP(w io.Writer, projectName string, exports []ExportData) error {
+// GenerateShotListCSV renders a shot list describing every exported clip.
+// A UTF-8 BOM is prepended so spreadsheet apps detect the encoding of Chinese text.
+func GenerateShotListCSV(exports []ExportData) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+	w := csv.NewWriter(&buf)
+	header := []string{"index", "shot_no", "shot_size", "camera_movement", "duration", "take_id", "good_take", "model", "filename", "last_frame", "frame_content", "prompt"}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, exp := range exports {
+		good := "no"
+		if exp.IsGood {
+			good = "yes"
+		}
+		row := []string{
+			fmt.Sprintf("%d", exp.Index),
+			exp.ShotNo,
+			exp.ShotSize,
+			exp.CameraMovement,
+			fmt.Sprintf("%d", exp.Duration),
+			fmt.Sprintf("%d", exp.TakeID),
+			good,
+			exp.ModelID,
+			exp.Filename,
+			exp.LastFrameFilename,
+			exp.FrameContent,
+			exp.Prompt,
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CreateExportZIP creates a ZIP file containing all videos and FCPXML,
+// plus the optional shot list and last-frame stills selected in opts.
+// Media is written first so a remote first clip can be probed without downloading it twice.
+// The export stops with ctx.Err() as soon as ctx is cancelled.
+func CreateExportZIP(ctx context.Context, w io.Writer, projectName string, exports []ExportData, opts ExportOptions, progress ExportProgressFunc) error {
 	zipWriter := zip.NewWriter(w)
-	defer zipWriter.Close()
 
-	// Default properties
-	width, height := 1280, 720
-	frameDuration := "100/2400s" // 24fps default
+	tracker := newExportProgressTracker(exports, opts, progress)
+	tracker.phase(ExportPhasePreparing)
 
-	// Inspect the first video to get properties if available
-	if len(exports) > 0 {
-		firstSrc := strings.TrimSpace(exports[0].VideoURL)
-		if isHTTPURL(firstSrc) {
+	// Add each video (remote download or local file copy)
+	client := newExportHTTPClient()
+	// Candidates for probing the timeline format: a temp copy of a remote first clip, or local sources.
+	var probePaths []string
+	for i, exp := range exports {
+		var tee io.Writer
+		if !isHTTPURL(exp.VideoURL) {
+			probePaths = append(probePaths, resolveLocalPath(exp.VideoURL))
+		} else if i == 0 {
 			tempFile, err := os.CreateTemp("", "scan_video_*.mp4")
 			if err == nil {
 				defer os.Remove(tempFile.Name()) // Clean up
 				defer tempFile.Close()
-
-				// Download full file to temp so we can seek.
-				if err := DownloadVideo(firstSrc, tempFile.Name()); err == nil {
-					f, err := os.Open(tempFile.Name())
-					if err == nil {
-						defer f.Close()
-						w, h, fd, err := GetVideoProperties(f)
-						if err == nil {
-							width = w
-							height = h
-							frameDuration = fd
-						}
-					}
-				}
+				tee = tempFile
+				probePaths = append(probePaths, tempFile.Name())
 			}
-		} else {
-			abs := resolveLocalPath(firstSrc)
-			if abs != "" {
-				if f, err := os.Open(abs); err == nil {
-					defer f.Close()
-					w, h, fd, err := GetVideoProperties(f)
-					if err == nil {
-						width = w
-						height = h
-						frameDuration = fd
-					}
-				}
+		}
+		if err := addVideoToZip(ctx, zipWriter, client, exp.Filename, exp.VideoURL, tracker, tee); err != nil {
+			return fmt.Errorf("failed to add video %s: %w", exp.Filename, err)
+		}
+		if opts.IncludeLastFrames && exp.LastFrameFilename != "" {
+			if err := addVideoToZip(ctx, zipWriter, client, exp.LastFrameFilename, exp.LastFrameURL, tracker, nil); err != nil {
+				return fmt.Errorf("failed to add last frame %s: %w", exp.LastFrameFilename, err)
 			}
 		}
 	}
 
+	tracker.phase(ExportPhaseFinalizing)
+	width, height, frameDuration := probeVideoFormat(probePaths...)
+
 	// Generate and add FCPXML
 	fcpxmlData, err := GenerateFCPXML(projectName, exports, width, height, frameDuration)
 	if err != nil {
@@ -384,258 +568,101 @@ func CreateExportZIP(w io.Writer, projectName string, exports []ExportData) erro
 		return fmt.Errorf("failed to write fcpxml: %w", err)
 	}
 
-	// Add each video (remote download or local file copy)
-	client := &http.Client{}
-	for _, exp := range exports {
-		if err := addVideoToZip(zipWriter, client, exp.Filename, exp.VideoURL); err != nil {
-			return fmt.Errorf("failed to add video %s: %w", exp.Filename, err)
+	if opts.IncludeShotList {
+		csvData, err := GenerateShotListCSV(exports)
+		if err != nil {
+			return fmt.Errorf("failed to generate shot list: %w", err)
+		}
+		csvFile, err := zipWriter.Create("shot_list.csv")
+		if err != nil {
+			return fmt.Errorf("failed to create shot list in zip: %w", err)
+		}
+		if _, err := csvFile.Write(csvData); err != nil {
+			return fmt.Errorf("failed to write shot list: %w", err)
 		}
 	}
 
-	return nil
+	return zipWriter.Close()
 }
 
-// GetVideoProperties attempts to parse MP4 atoms to find resolution and frame rate
-func GetVideoProperties(r io.ReadSeeker) (int, int, string, error) {
-	// Simple atom walker
-	buf := make([]byte, 8)
-	var timescale uint32
-	var width, height int
-
-	// Reset seeker
-	r.Seek(0, io.SeekStart)
-
-	// We need to find moov -> trak -> mdia -> mdhd (for timescale)
-	// and moov -> trak -> tkhd (for width/height)
-	// For FPS, standard FCPXML notation is "100/{fps*100}s" or similar.
-	// Actually FCPXML uses integer math. 25fps = 100/2500s. 24fps = 100/2400s.
-	// timescale / sample_duration = fps.
-
-	// Since full parsing is complex, we will search for specific atoms by walking.
-
-	// Helper to read atoms
-	readAtom := func() (string, int64, error) {
-		if _, err := io.ReadFull(r, buf); err != nil {
-			return "", 0, err
+// probeVideoFormat returns resolution and FCPXML frame duration of the first readable
+// local video among paths, falling back to 1280x720 at 24fps when none can be parsed.
+func probeVideoFormat(paths ...string) (int, int, string) {
+	for _, p := range paths {
+		if p == "" {
+			continue
 		}
-		size := uint32(binary.BigEndian.Uint32(buf[0:4]))
-		name := string(buf[4:8])
-		var size64 int64 = int64(size)
-		if size == 1 {
-			// Extended size
-			sysBuf := make([]byte, 8)
-			if _, err := io.ReadFull(r, sysBuf); err != nil {
-				return "", 0, err
-			}
-			size64 = int64(binary.BigEndian.Uint64(sysBuf))
+		f, err := os.Open(p)
+		if err != nil {
+			continue
 		}
-		return name, size64, nil
-	}
-
-	// Recursive finder is hard without struct definitions.
-	// We will implement a simplified scanner that looks for 'moov' and reads it into memory if small enough,
-	// or seeks into it.
-
-	// Better approach: Use a proper function that takes a limit.
-
-	fileSize, _ := r.Seek(0, io.SeekEnd)
-	r.Seek(0, io.SeekStart)
-
-	var parseAtoms func(offset int64, limit int64) error
-	parseAtoms = func(offset int64, limit int64) error {
-		current := offset
-		for current < offset+limit {
-			if _, err := r.Seek(current, io.SeekStart); err != nil {
-				return err
-			}
-			name, size, err := readAtom()
-			if err != nil {
-				return err
-			}
-
-			if size == 0 {
-				// Extend to end of file
-				size = fileSize - current
-			}
-
-			payloadStart := current + 8
-			if size == 1 {
-				payloadStart += 8
-			}
-			payloadSize := size - (payloadStart - current)
-
-			switch name {
-			case "moov", "trak", "mdia", "minf", "stbl":
-				// Container atoms, recurse
-				if err := parseAtoms(payloadStart, payloadSize); err != nil {
-					return err
-				}
-			case "mdhd":
-				// Media Header
-				// Version 1 byte, Flags 3 bytes
-				// Creation time 4/8, Mod time 4/8, Timescale 4, Duration 4/8
-				r.Seek(payloadStart, io.SeekStart)
-				header := make([]byte, 4)
-				io.ReadFull(r, header)
-				version := header[0]
-
-				if version == 1 {
-					r.Seek(payloadStart+4+8+8, io.SeekStart) // Skip version+flags + create + mod (8 bytes each)
-				} else {
-					r.Seek(payloadStart+4+4+4, io.SeekStart) // Skip version+flags + create + mod (4 bytes each)
-				}
-
-				// Read Timescale
-				io.ReadFull(r, header)
-				timescale = binary.BigEndian.Uint32(header)
-
-				// Read Duration (just for reference, not strict for FPS)
-				if version == 1 {
-					durBuf := make([]byte, 8)
-					io.ReadFull(r, durBuf)
-					// duration = uint64...
-				} else {
-					io.ReadFull(r, header)
-					// duration = binary.BigEndian.Uint32(header)
-				}
-
-			case "tkhd":
-				// Track Header
-				// Version 1, Flags 3
-				// create/mod...
-				// Width/Height are at fixed offsets
-				// version 0: 84 bytes matrix structure at end-ish
-				// We need to look up spec.
-				// Width and height are 16.16 fixed point values at the end of tkhd.
-				// For version 0: duration is at offset 20 (4 bytes), reserved 12 bytes, layer 2, alt group 2, vol 2, reserved 2, matrix 36 bytes, width 4 bytes, height 4 bytes.
-				// Offset to width: 4(ver/flags) + 4(create) + 4(mod) + 4(trackid) + 4(reserved) + 4(duration) + 8(reserved) + 2(layer) + 2(alt) + 2(vol) + 2(reserved) + 36(matrix) = 76 bytes?
-				// Let's rely on standard offsets relative to payload.
-
-				r.Seek(payloadStart, io.SeekStart)
-				verBuf := make([]byte, 1)
-				io.ReadFull(r, verBuf)
-				version := verBuf[0]
-
-				// Skip to width/height
-				// Ver 0: Width at offset 76 (from start of tkhd data, excluding headers?)
-				// Let's count bytes carefully.
-				// struct {
-				//   ver(1), flags(3)
-				//   creation(4), mod(4), track(4), reserved(4), duration(4)
-				//   reserved(8), layer(2), alt(2), vol(2), reserved(2)
-				//   matrix(36)
-				//   width(4), height(4)
-				// }
-				// 1+3+4+4+4+4+4 + 8+2+2+2+2 + 36 = 76.
-				// Width is at 76, Height at 80.
-
-				// Ver 1: creation(8), mod(8), track(4), reserved(4), duration(8)
-				// 1+3+8+8+4+4+8 + ... same ...
-				// 1+3+8+8+4+4+8 + 8+2+2+2+2 + 36 = 88.
-
-				var seekOffset int64 = 76
-				if version == 1 {
-					seekOffset = 88
-				}
-
-				r.Seek(payloadStart+seekOffset, io.SeekStart)
-				dimBuf := make([]byte, 8)
-				if _, err := io.ReadFull(r, dimBuf); err == nil {
-					wFixed := binary.BigEndian.Uint32(dimBuf[0:4])
-					hFixed := binary.BigEndian.Uint32(dimBuf[4:8])
-					width = int(wFixed >> 16)
-					height = int(hFixed >> 16)
-				}
-
-			case "stts":
-				// Time-to-sample
-				// Used to calculate frame duration
-				// ver(1), flags(3), count(4)
-				// entries: count, duration
-				// We assume constant frame rate, so first entry is enough.
-
-				// Only if we haven't found a valid fps yet?
-				// But we need to match it with timescale found in the SAME trak > mdia.
-				// This implies we should track context.
-				// For simplicity, we assume the first video track's properties apply.
-
-				r.Seek(payloadStart+4, io.SeekStart) // Skip ver/flags
-				countBuf := make([]byte, 4)
-				io.ReadFull(r, countBuf)
-				// count := binary.BigEndian.Uint32(countBuf)
-
-				// Read first entry
-				// sample_count(4), sample_delta(4)
-				entryBuf := make([]byte, 8)
-				io.ReadFull(r, entryBuf)
-				// sampleCount := binary.BigEndian.Uint32(entryBuf[0:4])
-				sampleDelta := binary.BigEndian.Uint32(entryBuf[4:8])
-
-				if timescale > 0 && sampleDelta > 0 {
-					// Found it!
-					// Normalize frame duration
-					return fmt.Errorf("FOUND_FPS:%s", matchFrameDuration(timescale, sampleDelta))
-				}
-			}
-
-			current += size
+		w, h, fd, err := GetVideoProperties(f)
+		f.Close()
+		if err == nil {
+			return w, h, fd
 		}
-		return nil
+		log.Printf("Export: could not read video format of %s: %v", filepath.Base(p), err)
 	}
+	return 1280, 720, "100/2400s"
+}
 
-	// Run parser
-	err := parseAtoms(0, fileSize)
+// relativeMediaURL builds a percent-encoded URL reference relative to the FCPXML file,
+// so names with spaces or CJK characters resolve once the export sits on disk.
+//
+// A "file://" URL always carries an authority and an absolute path (RFC 8089), so it
+// cannot point next to the document. The relative reference "./name.mp4" is resolved
+// against the FCPXML's own file:// location (RFC 3986 §5.2), which gives the file:// URL
+// of the media beside it wherever the folder or extracted ZIP is moved. The "./" prefix
+// keeps a name containing ":" from being read as a URL scheme.
+func relativeMediaURL(filename string) string {
+	u := url.URL{Path: "./" + filepath.ToSlash(filename)}
+	return u.EscapedPath()
+}
 
-	// Check if specialized error returned (hacky flow control but simple)
-	frameDuration := "100/2500s" // Default fallback if not found
-	if err != nil && strings.HasPrefix(err.Error(), "FOUND_FPS:") {
-		frameDuration = strings.TrimPrefix(err.Error(), "FOUND_FPS:")
-		err = nil
+// GetVideoProperties reads resolution and FCPXML frame duration from the first video track of an MP4.
+func GetVideoProperties(r io.ReadSeeker) (int, int, string, error) {
+	movie, err := mp4.Parse(r)
+	if err != nil {
+		return 0, 0, "", err
 	}
-
-	if width == 0 {
-		width = 1280
+	video := movie.Video()
+	if video == nil {
+		return 0, 0, "", fmt.Errorf("no video track")
 	}
-	if height == 0 {
-		height = 720
+	if video.Width == 0 || video.Height == 0 {
+		return 0, 0, "", fmt.Errorf("video track has no dimensions")
 	}
-
-	return width, height, frameDuration, nil
+	delta := video.SampleDelta()
+	if delta == 0 {
+		return 0, 0, "", fmt.Errorf("video track has no frame timing")
+	}
+	return video.Width, video.Height, matchFrameDuration(video.Timescale, delta), nil
 }
 
-// addVideoToZip downloads a video and adds it to the ZIP
-func addVideoToZip(zw *zip.Writer, client *http.Client, filename, url string) error {
+// addVideoToZip downloads a video (or still) and adds it to the ZIP.
+// When tee is non-nil the content is also written there, e.g. to probe a remote clip afterwards.
+func addVideoToZip(ctx context.Context, zw *zip.Writer, client *http.Client, filename, url string, tracker *exportProgressTracker, tee io.Writer) error {
 	src := strings.TrimSpace(url)
-	videoFile, err := zw.Create(filename)
+	reader, size, err := openExportSource(ctx, client, src)
 	if err != nil {
 		return err
 	}
+	defer reader.Close()
 
-	if isHTTPURL(src) {
-		resp, err := client.Get(src)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to download video: status %d", resp.StatusCode)
-		}
-		_, err = io.Copy(videoFile, resp.Body)
+	videoFile, err := zw.Create(filename)
+	if err != nil {
 		return err
 	}
-
-	abs := resolveLocalPath(src)
-	if abs == "" {
-		return fmt.Errorf("invalid local video source: %q", src)
+	tracker.startFile(filename, size, isHTTPURL(src))
+	dst := videoFile
+	if tee != nil {
+		dst = io.MultiWriter(videoFile, tee)
 	}
-	file, err := os.Open(abs)
-	if err != nil {
+	if _, err := copyWithProgress(ctx, dst, reader, tracker); err != nil {
 		return err
 	}
-	defer file.Close()
-	_, err = io.Copy(videoFile, file)
-	return err
+	tracker.finishFile()
+	return nil
 }
 
 func isHTTPURL(s string) bool {
@@ -656,6 +683,11 @@ func resolveLocalPath(p string) string {
 	// If it looks like a URL path (e.g. /downloads/xxx.mp4), treat it as data-dir relative.
 	if strings.HasPrefix(p, "/downloads/") || strings.HasPrefix(p, "/uploads/") {
 		p = strings.TrimPrefix(p, "/")
+	} else if filepath.IsAbs(p) {
+		// config.ToAbsolutePath strips a leading slash, so check real absolute paths first.
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
 	}
 	abs := config.ToAbsolutePath(p)
 	if _, err := os.Stat(abs); err == nil {
@@ -665,14 +697,17 @@ func resolveLocalPath(p string) string {
 }
 
 // ExportProjectToZip is a convenience function that handles the full export process
-func ExportProjectToZip(w io.Writer, project models.Project) error {
-	exports := PrepareExportData(project.Storyboards)
+func ExportProjectToZip(ctx context.Context, w io.Writer, project models.Project, opts ExportOptions) error {
+	exports, err := PrepareExportData(project.Storyboards, opts)
+	if err != nil {
+		return err
+	}
 
 	if len(exports) == 0 {
 		return fmt.Errorf("no succeeded videos available for export")
 	}
 
-	return CreateExportZIP(w, project.Name, exports)
+	return CreateExportZIP(ctx, w, project.Name, exports, opts, nil)
 }
 
 // GetExportFilename returns a safe filename for the ZIP download
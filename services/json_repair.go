@@ -0,0 +1,150 @@
+package services
+
+import (
+	"strings"
+)
+
+// RepairJSON makes near-JSON model output parseable. It drops text around the document (such as
+// markdown fences or commentary), escapes raw control characters inside strings, removes trailing
+// commas and, when the output was cut off, drops the incomplete trailing element of the outermost
+// array (such as a half-written shot) and closes the open brackets. It returns the repaired text and a description of each repair; the text is
+// returned unchanged apart from trimming when nothing could be done.
+func RepairJSON(s string) (string, []string) {
+	var repairs []string
+	s = strings.TrimSpace(s)
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return s, nil
+	}
+	if prefix := strings.Trim(s[:start], " \t\r\n`"); prefix != "" && !strings.EqualFold(prefix, "json") {
+		repairs = append(repairs, "去除了 JSON 之前的多余文本")
+	}
+
+	type safePoint struct {
+		length int
+		stack  []byte
+	}
+	var (
+		out      strings.Builder
+		stack    []byte
+		inString bool
+		escape   bool
+		safe     *safePoint
+		outer    int // stack depth of the outermost array, 0 until one is opened
+		complete bool
+		rest     string
+		fixedCtl bool
+		fixedTC  bool
+	)
+	out.Grow(len(s) - start)
+
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escape:
+				escape = false
+				out.WriteByte(c)
+			case c == '\\':
+				escape = true
+				out.WriteByte(c)
+			case c == '"':
+				inString = false
+				out.WriteByte(c)
+			case c == '\n':
+				out.WriteString(`\n`)
+				fixedCtl = true
+			case c == '\r':
+				out.WriteString(`\r`)
+				fixedCtl = true
+			case c == '\t':
+				out.WriteString(`\t`)
+				fixedCtl = true
+			default:
+				out.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			out.WriteByte(c)
+		case '{', '[':
+			stack = append(stack, c)
+			if c == '[' && (outer == 0 || len(stack) < outer) {
+				outer = len(stack)
+			}
+			out.WriteByte(c)
+		case '}', ']':
+			open := byte('{')
+			if c == ']' {
+				open = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				// Stray closer; skip it
+				continue
+			}
+			if trimTrailingComma(&out) {
+				fixedTC = true
+			}
+			out.WriteByte(c)
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				complete = true
+				rest = strings.TrimSpace(s[i+1:])
+			} else if len(stack) == outer {
+				// Only whole elements of the outermost array are kept; a cut inside a nested
+				// array would otherwise leave a partial element behind.
+				safe = &safePoint{length: out.Len(), stack: append([]byte(nil), stack...)}
+			}
+		default:
+			out.WriteByte(c)
+		}
+		if complete {
+			break
+		}
+	}
+
+	if fixedCtl {
+		repairs = append(repairs, "转义了字符串中的换行/制表符")
+	}
+	if fixedTC {
+		repairs = append(repairs, "去除了多余的尾随逗号")
+	}
+	if complete {
+		if strings.Trim(rest, " \t\r\n`") != "" {
+			repairs = append(repairs, "去除了 JSON 之后的多余文本")
+		}
+		return out.String(), repairs
+	}
+
+	// Truncated: cut back to the last complete array element and close what is still open
+	if safe == nil {
+		return strings.TrimSpace(s[start:]), repairs
+	}
+	var b strings.Builder
+	b.WriteString(out.String()[:safe.length])
+	for i := len(safe.stack) - 1; i >= 0; i-- {
+		if safe.stack[i] == '[' {
+			b.WriteByte(']')
+		} else {
+			b.WriteByte('}')
+		}
+	}
+	repairs = append(repairs, "输出被截断，已丢弃末尾不完整的内容并补全括号")
+	return b.String(), repairs
+}
+
+// trimTrailingComma removes a comma (and the whitespace after it) at the end of out.
+func trimTrailingComma(out *strings.Builder) bool {
+	s := out.String()
+	t := strings.TrimRight(s, " \t\r\n")
+	if !strings.HasSuffix(t, ",") {
+		return false
+	}
+	t = strings.TrimRight(t[:len(t)-1], " \t\r\n")
+	out.Reset()
+	out.WriteString(t)
+	return true
+}
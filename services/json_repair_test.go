@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    string
+		repairs []string
+	}{
+		{
+			name: "valid",
+			in:   ` {"shots":[{"a":1}]} `,
+			want: `{"shots":[{"a":1}]}`,
+		},
+		{
+			name: "markdown fence",
+			in:   "```json\n{\"shots\":[]}\n```",
+			want: `{"shots":[]}`,
+		},
+		{
+			name:    "commentary around the document",
+			in:      `好的，结果如下：{"shots":[]} 希望有帮助`,
+			want:    `{"shots":[]}`,
+			repairs: []string{"去除了 JSON 之前的多余文本", "去除了 JSON 之后的多余文本"},
+		},
+		{
+			name:    "trailing commas",
+			in:      "{\"shots\":[{\"a\":1, },\n],}",
+			want:    `{"shots":[{"a":1}]}`,
+			repairs: []string{"去除了多余的尾随逗号"},
+		},
+		{
+			name:    "raw control characters in strings",
+			in:      "{\"frame_content\":\"第一行\n第二行\t完\r\"}",
+			want:    `{"frame_content":"第一行\n第二行\t完\r"}`,
+			repairs: []string{"转义了字符串中的换行/制表符"},
+		},
+		{
+			name: "brackets and escaped quotes inside strings",
+			in:   `{"a":"say \"]}\" [","b":"\\"}`,
+			want: `{"a":"say \"]}\" [","b":"\\"}`,
+		},
+		{
+			name: "stray closer",
+			in:   `{"shots":[]]}`,
+			want: `{"shots":[]}`,
+		},
+		{
+			name:    "truncated after a complete shot",
+			in:      `{"shots":[{"no":"1","characters":[{"id":"x"}]},{"no":"2","characters":[{"id":"y"}],"fr`,
+			want:    `{"shots":[{"no":"1","characters":[{"id":"x"}]}]}`,
+			repairs: []string{"输出被截断，已丢弃末尾不完整的内容并补全括号"},
+		},
+		{
+			name:    "truncated after a comma",
+			in:      `{"shots":[{"no":"1"},`,
+			want:    `{"shots":[{"no":"1"}]}`,
+			repairs: []string{"输出被截断，已丢弃末尾不完整的内容并补全括号"},
+		},
+		{
+			name: "truncated inside the first shot's nested array",
+			in:   `{"shots":[{"characters":[{"id":"x"}],"fr`,
+			want: `{"shots":[{"characters":[{"id":"x"}],"fr`,
+		},
+		{
+			name:    "truncated top-level array",
+			in:      "```json\n[{\"a\":[1,2]},{\"a\":[3",
+			want:    `[{"a":[1,2]}]`,
+			repairs: []string{"输出被截断，已丢弃末尾不完整的内容并补全括号"},
+		},
+		{
+			name: "no JSON",
+			in:   " 抱歉，无法完成 ",
+			want: "抱歉，无法完成",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, repairs := RepairJSON(tc.in)
+			if got != tc.want {
+				t.Errorf("RepairJSON = %s, want %s", got, tc.want)
+			}
+			if !reflect.DeepEqual(repairs, tc.repairs) {
+				t.Errorf("repairs = %q, want %q", repairs, tc.repairs)
+			}
+			if tc.repairs != nil && !json.Valid([]byte(got)) {
+				t.Errorf("repaired output is not valid JSON: %s", got)
+			}
+		})
+	}
+}
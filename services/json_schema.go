@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The JSON schema helpers below understand the subset used for structured LLM output:
+// type (object, array, string, integer, number, boolean), properties, required,
+// additionalProperties: false, items and enum. Schemas are the map[string]interface{}
+// values sent as response_format; values are the output of json.Unmarshal into interface{}.
+
+// CoerceJSONSchema leniently fixes v towards schema and returns the fixed value with a description
+// of each fix: unknown fields are dropped, scalars are converted between types where unambiguous,
+// a lone object is wrapped in an array, and integers outside an enum are moved to the nearest
+// allowed value. Missing required fields and null numbers are left for ValidateJSONSchema to
+// report, since any value filled in for them would be invented.
+func CoerceJSONSchema(v interface{}, schema map[string]interface{}) (interface{}, []string) {
+	var fixes []string
+	return coerce(v, schema, "$", &fixes), fixes
+}
+
+func coerce(v interface{}, schema map[string]interface{}, path string, fixes *[]string) interface{} {
+	fix := func(format string, args ...interface{}) {
+		*fixes = append(*fixes, path+": "+fmt.Sprintf(format, args...))
+	}
+	switch schemaType(schema) {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			if v != nil {
+				return v // not fixable; reported by validation
+			}
+			obj = map[string]interface{}{}
+			fix("补全为空对象")
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, key := range sortedKeys(obj) {
+			sub, known := props[key].(map[string]interface{})
+			if !known {
+				if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+					delete(obj, key)
+					fix("删除未定义字段 %s", key)
+				}
+				continue
+			}
+			obj[key] = coerce(obj[key], sub, path+"."+key, fixes)
+		}
+		return obj
+
+	case "array":
+		var arr []interface{}
+		switch x := v.(type) {
+		case []interface{}:
+			arr = x
+		case nil:
+			fix("补全为空数组")
+			return []interface{}{}
+		case map[string]interface{}:
+			arr = []interface{}{x}
+			fix("单个对象已包装为数组")
+		default:
+			return v
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		if items != nil {
+			for i := range arr {
+				arr[i] = coerce(arr[i], items, fmt.Sprintf("%s[%d]", path, i), fixes)
+			}
+		}
+		return arr
+
+	case "string":
+		switch x := v.(type) {
+		case nil:
+			fix("null 已改为空字符串")
+			return ""
+		case float64:
+			fix("数字已转为字符串")
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		case bool:
+			fix("布尔值已转为字符串")
+			return strconv.FormatBool(x)
+		}
+		return v
+
+	case "integer", "number":
+		n, ok := toNumber(v)
+		if !ok {
+			return v // not fixable; reported by validation
+		}
+		if _, isNum := v.(float64); !isNum {
+			fix("%v 已转为数字", v)
+		}
+		if schemaType(schema) == "integer" && n != math.Trunc(n) {
+			fix("%v 已取整", n)
+			n = math.Round(n)
+		}
+		if enum := numberList(schema["enum"]); len(enum) > 0 && !containsNumber(enum, n) {
+			nearest := enum[0]
+			for _, e := range enum[1:] {
+				if math.Abs(e-n) < math.Abs(nearest-n) {
+					nearest = e
+				}
+			}
+			fix("%v 不在允许值 %v 中，已改为 %v", n, enum, nearest)
+			n = nearest
+		}
+		return n
+
+	case "boolean":
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				fix("字符串已转为布尔值")
+				return b
+			}
+		}
+		if v == nil {
+			fix("null 已改为 false")
+			return false
+		}
+		return v
+	}
+	return v
+}
+
+// ValidateJSONSchema checks v against schema and returns one message per violation,
+// prefixed with the JSON path of the offending value.
+func ValidateJSONSchema(v interface{}, schema map[string]interface{}) []string {
+	var errs []string
+	validate(v, schema, "$", &errs)
+	return errs
+}
+
+func validate(v interface{}, schema map[string]interface{}, path string, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+	switch schemaType(schema) {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("应为对象，实际为 %s", jsonTypeName(v))
+			return
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, key := range stringList(schema["required"]) {
+			if _, ok := obj[key]; !ok {
+				fail("缺少必填字段 %s", key)
+			}
+		}
+		for _, key := range sortedKeys(obj) {
+			sub, known := props[key].(map[string]interface{})
+			if !known {
+				if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+					fail("不允许的字段 %s", key)
+				}
+				continue
+			}
+			validate(obj[key], sub, path+"."+key, errs)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("应为数组，实际为 %s", jsonTypeName(v))
+			return
+		}
+		if items, _ := schema["items"].(map[string]interface{}); items != nil {
+			for i, item := range arr {
+				validate(item, items, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			fail("应为字符串，实际为 %s", jsonTypeName(v))
+			return
+		}
+		if enum := stringList(schema["enum"]); len(enum) > 0 && !containsString(enum, s) {
+			fail("%q 不在允许值 %v 中", s, enum)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("应为数字，实际为 %s", jsonTypeName(v))
+			return
+		}
+		if schemaType(schema) == "integer" && n != math.Trunc(n) {
+			fail("应为整数，实际为 %v", n)
+		}
+		if enum := numberList(schema["enum"]); len(enum) > 0 && !containsNumber(enum, n) {
+			fail("%v 不在允许值 %v 中", n, enum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("应为布尔值，实际为 %s", jsonTypeName(v))
+		}
+	}
+}
+
+func schemaType(schema map[string]interface{}) string {
+	t, _ := schema["type"].(string)
+	return t
+}
+
+// toNumber accepts JSON numbers and numeric strings with units, e.g. "10秒" or "5s".
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		s := strings.TrimSpace(x)
+		end := 0
+		for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || (end == 0 && s[end] == '-')) {
+			end++
+		}
+		if end == 0 {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(s[:end], 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "对象"
+	case []interface{}:
+		return "数组"
+	case string:
+		return "字符串"
+	case float64:
+		return "数字"
+	case bool:
+		return "布尔值"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func stringList(v interface{}) []string {
+	switch x := v.(type) {
+	case []string:
+		return x
+	case []interface{}:
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func numberList(v interface{}) []float64 {
+	switch x := v.(type) {
+	case []int:
+		out := make([]float64, len(x))
+		for i, n := range x {
+			out[i] = float64(n)
+		}
+		return out
+	case []float64:
+		return x
+	case []interface{}:
+		out := make([]float64, 0, len(x))
+		for _, item := range x {
+			switch n := item.(type) {
+			case float64:
+				out = append(out, n)
+			case int:
+				out = append(out, float64(n))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsNumber(list []float64, n float64) bool {
+	for _, x := range list {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// shotsSchema mirrors the decomposition schema: shots with an enum duration and strict refs.
+var shotsSchema = map[string]interface{}{
+	"type":                 "object",
+	"required":             []string{"shots"},
+	"additionalProperties": false,
+	"properties": map[string]interface{}{
+		"shots": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"required":             []string{"shot_no", "estimated_duration"},
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"shot_no":            map[string]interface{}{"type": "string"},
+					"shot_size":          map[string]interface{}{"type": "string", "enum": []string{"全景", "中景", "近景"}},
+					"estimated_duration": map[string]interface{}{"type": "integer", "enum": []int{5, 10}},
+					"is_key":             map[string]interface{}{"type": "boolean"},
+					"characters": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": false,
+							"properties": map[string]interface{}{
+								"id": map[string]interface{}{"type": "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestCoerceJSONSchema(t *testing.T) {
+	cases := []struct {
+		name   string
+		in     string
+		want   string
+		fixes  []string
+		errors []string
+	}{
+		{
+			name: "already valid",
+			in:   `{"shots":[{"shot_no":"1","estimated_duration":10}]}`,
+			want: `{"shots":[{"shot_no":"1","estimated_duration":10}]}`,
+		},
+		{
+			name:  "enum durations snap to the nearest option",
+			in:    `{"shots":[{"shot_no":"1","estimated_duration":7},{"shot_no":"2","estimated_duration":"8秒"},{"shot_no":"3","estimated_duration":30},{"shot_no":"4","estimated_duration":6.6}]}`,
+			want:  `{"shots":[{"shot_no":"1","estimated_duration":5},{"shot_no":"2","estimated_duration":10},{"shot_no":"3","estimated_duration":10},{"shot_no":"4","estimated_duration":5}]}`,
+			fixes: []string{"$.shots[0].estimated_duration: 7 不在允许值 [5 10] 中，已改为 5", "$.shots[1].estimated_duration: 8秒 已转为数字", "$.shots[1].estimated_duration: 8 不在允许值 [5 10] 中，已改为 10", "$.shots[2].estimated_duration: 30 不在允许值 [5 10] 中，已改为 10", "$.shots[3].estimated_duration: 6.6 已取整", "$.shots[3].estimated_duration: 7 不在允许值 [5 10] 中，已改为 5"},
+		},
+		{
+			name:  "scalars, unknown fields and a lone object",
+			in:    `{"shots":{"shot_no":3,"estimated_duration":5,"is_key":"true","mood":"sad","characters":[{"id":"c1","name":"x"}]},"note":1}`,
+			want:  `{"shots":[{"shot_no":"3","estimated_duration":5,"is_key":true,"characters":[{"id":"c1"}]}]}`,
+			fixes: []string{"$: 删除未定义字段 note", "$.shots: 单个对象已包装为数组", "$.shots[0].characters[0]: 删除未定义字段 name", "$.shots[0].is_key: 字符串已转为布尔值", "$.shots[0]: 删除未定义字段 mood", "$.shots[0].shot_no: 数字已转为字符串"},
+		},
+		{
+			name:  "null strings and arrays",
+			in:    `{"shots":[{"shot_no":null,"estimated_duration":5,"characters":null}]}`,
+			want:  `{"shots":[{"shot_no":"","estimated_duration":5,"characters":[]}]}`,
+			fixes: []string{"$.shots[0].characters: 补全为空数组", "$.shots[0].shot_no: null 已改为空字符串"},
+		},
+		{
+			name:   "missing and null required values are reported, not invented",
+			in:     `{"shots":[{"estimated_duration":null},{"shot_no":"2","estimated_duration":"很久","shot_size":"特写"}]}`,
+			want:   `{"shots":[{"estimated_duration":null},{"shot_no":"2","estimated_duration":"很久","shot_size":"特写"}]}`,
+			errors: []string{"$.shots[0]: 缺少必填字段 shot_no", "$.shots[0].estimated_duration: 应为数字，实际为 null", "$.shots[1].estimated_duration: 应为数字，实际为 字符串", "$.shots[1].shot_size: \"特写\" 不在允许值 [全景 中景 近景] 中"},
+		},
+		{
+			name:   "missing top-level array",
+			in:     `{}`,
+			want:   `{}`,
+			errors: []string{"$: 缺少必填字段 shots"},
+		},
+		{
+			name:   "wrong container types",
+			in:     `{"shots":"none"}`,
+			want:   `{"shots":"none"}`,
+			errors: []string{"$.shots: 应为数组，实际为 字符串"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, fixes := CoerceJSONSchema(decodeJSON(t, tc.in), shotsSchema)
+			if want := decodeJSON(t, tc.want); !reflect.DeepEqual(got, want) {
+				b, _ := json.Marshal(got)
+				t.Errorf("coerced = %s, want %s", b, tc.want)
+			}
+			if !reflect.DeepEqual(fixes, tc.fixes) {
+				t.Errorf("fixes = %q, want %q", fixes, tc.fixes)
+			}
+			if errs := ValidateJSONSchema(got, shotsSchema); !reflect.DeepEqual(errs, tc.errors) {
+				t.Errorf("validation = %q, want %q", errs, tc.errors)
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	cases := []struct {
+		in     string
+		errors []string
+	}{
+		{`{"shots":[]}`, nil},
+		{`[]`, []string{"$: 应为对象，实际为 数组"}},
+		{`{"shots":[{"shot_no":"1","estimated_duration":7}]}`, []string{"$.shots[0].estimated_duration: 7 不在允许值 [5 10] 中"}},
+		{`{"shots":[{"shot_no":"1","estimated_duration":5.5}]}`, []string{"$.shots[0].estimated_duration: 应为整数，实际为 5.5", "$.shots[0].estimated_duration: 5.5 不在允许值 [5 10] 中"}},
+		{`{"shots":[{"shot_no":"1","estimated_duration":5,"is_key":1,"x":0}],"y":0}`, []string{"$.shots[0].is_key: 应为布尔值，实际为 数字", "$.shots[0]: 不允许的字段 x", "$: 不允许的字段 y"}},
+	}
+	for _, tc := range cases {
+		if errs := ValidateJSONSchema(decodeJSON(t, tc.in), shotsSchema); !reflect.DeepEqual(errs, tc.errors) {
+			t.Errorf("ValidateJSONSchema(%s) = %q, want %q", tc.in, errs, tc.errors)
+		}
+	}
+}
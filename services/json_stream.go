@@ -0,0 +1,66 @@
+package services
+
+import "encoding/json"
+
+// JSONArrayStream extracts the elements of the first JSON array in a streamed document as soon as
+// each one is complete. It is fed arbitrary text fragments, e.g. deltas of a streamed chat completion,
+// and calls Emit with every complete object element of the array. Text around the document, such as
+// a markdown code fence, is ignored.
+type JSONArrayStream struct {
+	Emit func(json.RawMessage)
+
+	buf        []byte
+	pos        int
+	depth      int
+	arrayDepth int // depth inside the array, 0 until it is found
+	objStart   int
+	inString   bool
+	escape     bool
+	done       bool
+}
+
+// Write consumes the next fragment; it never fails.
+func (s *JSONArrayStream) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for ; s.pos < len(s.buf) && !s.done; s.pos++ {
+		c := s.buf[s.pos]
+		if s.inString {
+			switch {
+			case s.escape:
+				s.escape = false
+			case c == '\\':
+				s.escape = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+			if c == '[' && s.arrayDepth == 0 && s.depth <= 2 {
+				s.arrayDepth = s.depth
+			} else if c == '{' && s.arrayDepth > 0 && s.depth == s.arrayDepth+1 {
+				s.objStart = s.pos
+			}
+		case '}', ']':
+			if c == '}' && s.arrayDepth > 0 && s.depth == s.arrayDepth+1 && s.Emit != nil {
+				s.Emit(json.RawMessage(append([]byte(nil), s.buf[s.objStart:s.pos+1]...)))
+			}
+			if c == ']' && s.depth == s.arrayDepth {
+				s.done = true
+			}
+			if s.depth > 0 {
+				s.depth--
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// String returns everything written so far.
+func (s *JSONArrayStream) String() string {
+	return string(s.buf)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONArrayStream(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "shots object in a fence",
+			doc:  "```json\n{\"shots\": [\n {\"no\":\"1\",\"characters\":[{\"id\":\"c1\"}]},\n {\"no\":\"2\",\"text\":\"a } and ] and \\\" inside\"}\n]}\n```",
+			want: []string{`{"no":"1","characters":[{"id":"c1"}]}`, `{"no":"2","text":"a } and ] and \" inside"}`},
+		},
+		{
+			name: "top-level array",
+			doc:  `[{"a":1},{"a":{"b":2}}]`,
+			want: []string{`{"a":1}`, `{"a":{"b":2}}`},
+		},
+		{
+			name: "stops at the end of the first array",
+			doc:  `{"shots":[{"a":1}],"more":[{"a":2}]}`,
+			want: []string{`{"a":1}`},
+		},
+		{
+			name: "truncated element is not emitted",
+			doc:  `{"shots":[{"a":1},{"a":[{"b":2}],"c"`,
+			want: []string{`{"a":1}`},
+		},
+		{
+			name: "arrays nested deeper than the shots are skipped",
+			doc:  `{"meta":{"tags":[{"t":1}]},"shots":[{"a":1}]}`,
+			want: []string{`{"a":1}`},
+		},
+	}
+	for _, tc := range cases {
+		for _, size := range []int{1, 3, len(tc.doc)} {
+			var got []string
+			s := &JSONArrayStream{Emit: func(raw json.RawMessage) { got = append(got, string(raw)) }}
+			for i := 0; i < len(tc.doc); i += size {
+				s.Write([]byte(tc.doc[i:min(i+size, len(tc.doc))]))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s (fragments of %d): emitted %q, want %q", tc.name, size, got, tc.want)
+			}
+			if s.String() != tc.doc {
+				t.Errorf("%s: String() = %q", tc.name, s.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"os/exec"
+	"regexp"
+)
+
+var platformUUIDPattern = regexp.MustCompile(`"IOPlatformUUID"\s*=\s*"([^"]+)"`)
+
+func platformMachineID() string {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return ""
+	}
+	if m := platformUUIDPattern.FindSubmatch(out); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !darwin
+
+package services
+
+import "os"
+
+func platformMachineID() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if b, err := os.ReadFile(path); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
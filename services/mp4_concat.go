@@ -0,0 +1,441 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"seedance-client/mp4"
+)
+
+// ErrMP4Incompatible is returned when clips cannot be joined without re-encoding
+// (different codecs, resolutions or audio layouts, fragmented files, ...).
+var ErrMP4Incompatible = errors.New("mp4 clips are not compatible for remuxing")
+
+// checkConcatCompatible reports whether a track of another clip can be appended to t without re-encoding.
+func checkConcatCompatible(t, other *mp4.Track) error {
+	if t.EntryCount != 1 || other.EntryCount != 1 {
+		return fmt.Errorf("%w: multiple sample descriptions", ErrMP4Incompatible)
+	}
+	if t.Codec != other.Codec {
+		return fmt.Errorf("%w: %s codec differs (%s vs %s)", ErrMP4Incompatible, t.Handler, t.Codec, other.Codec)
+	}
+	if !t.SameCodec(other) {
+		if t.Handler == mp4.HandlerVideo {
+			return fmt.Errorf("%w: video resolution or decoder configuration differs", ErrMP4Incompatible)
+		}
+		return fmt.Errorf("%w: audio layout or decoder configuration differs", ErrMP4Incompatible)
+	}
+	return nil
+}
+
+// rescaleTime converts v from one timescale to another, rounding to nearest.
+func rescaleTime(v int64, from, to uint32) int64 {
+	if from == to || from == 0 {
+		return v
+	}
+	return (v*int64(to) + int64(from)/2) / int64(from)
+}
+
+// concatTrack is an output track assembled from one track per clip.
+type concatTrack struct {
+	handler   string
+	timescale uint32
+	first     *mp4.Track
+	samples   []mp4.Sample  // durations and cts offsets in output timescale
+	chunks    []concatChunk // exactly one per clip
+	edits     []concatEdit
+}
+
+type concatChunk struct {
+	firstSample int
+	count       int
+	offset      int64 // set once the mdat layout is known
+}
+
+type concatEdit struct {
+	segment   int64 // movie timescale
+	mediaTime int64 // -1 for an empty edit
+}
+
+// ConcatMP4Files joins progressive MP4 files into dst by copying samples, without re-encoding.
+// Every clip needs a video track with the same codec configuration; audio is kept only when
+// all clips carry compatible audio. Per-clip edit lists keep audio aligned to the video cut
+// points, so small encoder padding differences do not accumulate into drift.
+// The sample copy stops with ctx's error once ctx is cancelled.
+// It returns the total duration in seconds.
+func ConcatMP4Files(ctx context.Context, dst io.Writer, paths []string) (float64, error) {
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("no clips to concatenate")
+	}
+
+	files := make([]*os.File, len(paths))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	movies := make([]*mp4.Movie, len(paths))
+	for i, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return 0, err
+		}
+		files[i] = f
+		movie, err := mp4.Parse(f)
+		if err != nil {
+			return 0, fmt.Errorf("clip %d: %w", i+1, err)
+		}
+		if movie.Fragmented {
+			return 0, fmt.Errorf("clip %d: %w: fragmented mp4", i+1, ErrMP4Incompatible)
+		}
+		if movie.Video() == nil {
+			return 0, fmt.Errorf("clip %d: %w: no video track", i+1, ErrMP4Incompatible)
+		}
+		movies[i] = movie
+	}
+
+	video := movies[0].Video()
+	for i, m := range movies[1:] {
+		if err := checkConcatCompatible(video, m.Video()); err != nil {
+			return 0, fmt.Errorf("clip %d: %w", i+2, err)
+		}
+	}
+	audio := movies[0].Audio()
+	for _, m := range movies[1:] {
+		if audio == nil {
+			break
+		}
+		if other := m.Audio(); other == nil || checkConcatCompatible(audio, other) != nil {
+			audio = nil
+		}
+	}
+
+	movieTimescale := video.Timescale
+	clipLengths := make([]int64, len(movies)) // movie timescale
+	var total int64
+	for i, m := range movies {
+		v := m.Video()
+		clipLengths[i] = rescaleTime(v.PresentedDuration(), v.Timescale, movieTimescale)
+		total += clipLengths[i]
+	}
+
+	tracks := []*concatTrack{assembleConcatTrack(mp4.HandlerVideo, video, movies, clipLengths, movieTimescale)}
+	if audio != nil {
+		tracks = append(tracks, assembleConcatTrack(mp4.HandlerAudio, audio, movies, clipLengths, movieTimescale))
+	}
+
+	// Layout: ftyp, mdat (one chunk per track per clip, interleaved by clip), moov.
+	ftyp := mp4BoxBytes("ftyp", []byte("isom"), u32(0x200), []byte("isomiso2avc1mp41"))
+	var mdatSize int64
+	for _, t := range tracks {
+		for _, s := range t.samples {
+			mdatSize += int64(s.Size)
+		}
+	}
+	mdatHeader := 8
+	if mdatSize+8 > math.MaxUint32 {
+		mdatHeader = 16
+	}
+	pos := int64(len(ftyp) + mdatHeader)
+	for clip := range movies {
+		for _, t := range tracks {
+			c := &t.chunks[clip]
+			c.offset = pos
+			for _, s := range t.chunkSamples(clip) {
+				pos += int64(s.Size)
+			}
+		}
+	}
+
+	w := bufio.NewWriterSize(dst, 1<<20)
+	if _, err := w.Write(ftyp); err != nil {
+		return 0, err
+	}
+	if mdatHeader == 16 {
+		w.Write(u32(1))
+		w.Write([]byte("mdat"))
+		w.Write(u64(uint64(mdatSize + 16)))
+	} else {
+		w.Write(u32(uint32(mdatSize + 8)))
+		w.Write([]byte("mdat"))
+	}
+	for clip := range movies {
+		for _, t := range tracks {
+			for _, s := range t.chunkSamples(clip) {
+				if err := ctx.Err(); err != nil {
+					return 0, err
+				}
+				n, err := io.CopyN(w, io.NewSectionReader(files[clip], s.Offset, int64(s.Size)), int64(s.Size))
+				if err == io.EOF {
+					err = fmt.Errorf("sample at offset %d truncated: read %d of %d bytes", s.Offset, n, s.Size)
+				}
+				if err != nil {
+					return 0, fmt.Errorf("clip %d: %w", clip+1, err)
+				}
+			}
+		}
+	}
+
+	if _, err := w.Write(buildConcatMoov(tracks, movieTimescale, total)); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return float64(total) / float64(movieTimescale), nil
+}
+
+// assembleConcatTrack appends the matching track of every clip, rescaling sample times
+// to the first clip's timescale, and builds one edit per clip.
+func assembleConcatTrack(handler string, first *mp4.Track, movies []*mp4.Movie, clipLengths []int64, movieTimescale uint32) *concatTrack {
+	ct := &concatTrack{handler: handler, timescale: first.Timescale, first: first}
+	var mediaStart int64
+	for clip, m := range movies {
+		src := m.Track(handler)
+		ct.chunks = append(ct.chunks, concatChunk{firstSample: len(ct.samples), count: len(src.Samples)})
+
+		// Rescale cumulatively so rounding never accumulates.
+		var srcTime, outPrev int64
+		for _, s := range src.Samples {
+			srcTime += int64(s.Duration)
+			outNext := rescaleTime(srcTime, src.Timescale, ct.timescale)
+			out := s
+			out.Duration = uint32(outNext - outPrev)
+			out.CTSOffset = int32(rescaleTime(int64(s.CTSOffset), src.Timescale, ct.timescale))
+			if !src.HasSyncTable {
+				out.Sync = true
+			}
+			ct.samples = append(ct.samples, out)
+			outPrev = outNext
+		}
+
+		mediaTime := rescaleTime(src.MediaTime, src.Timescale, ct.timescale)
+		available := rescaleTime(src.PresentedDuration(), src.Timescale, movieTimescale)
+		segment := clipLengths[clip]
+		if available < segment {
+			segment = available
+		}
+		if segment > 0 {
+			ct.edits = append(ct.edits, concatEdit{segment: segment, mediaTime: mediaStart + mediaTime})
+		}
+		if gap := clipLengths[clip] - segment; gap > 0 {
+			ct.edits = append(ct.edits, concatEdit{segment: gap, mediaTime: -1})
+		}
+		mediaStart += outPrev
+	}
+	return ct
+}
+
+func (t *concatTrack) chunkSamples(clip int) []mp4.Sample {
+	c := t.chunks[clip]
+	return t.samples[c.firstSample : c.firstSample+c.count]
+}
+
+func (t *concatTrack) mediaDuration() int64 {
+	var d int64
+	for _, s := range t.samples {
+		d += int64(s.Duration)
+	}
+	return d
+}
+
+func buildConcatMoov(tracks []*concatTrack, movieTimescale uint32, duration int64) []byte {
+	var traks [][]byte
+	for i, t := range tracks {
+		traks = append(traks, buildConcatTrak(t, uint32(i+1), duration))
+	}
+
+	mvhd := mp4FullBox("mvhd", 1, 0,
+		u64(0), u64(0), u32(movieTimescale), u64(uint64(duration)),
+		u32(0x00010000), []byte{0x01, 0x00}, make([]byte, 10),
+		unityMatrix(), make([]byte, 24), u32(uint32(len(tracks)+1)))
+	return mp4BoxBytes("moov", append([][]byte{mvhd}, traks...)...)
+}
+
+func buildConcatTrak(t *concatTrack, trackID uint32, duration int64) []byte {
+	// Keep the matrix and dimensions of the first clip (rotation, display size).
+	matrix, dims := unityMatrix(), make([]byte, 8)
+	if src := t.first.TrackHeader; len(src) >= 84 {
+		off := 40
+		if src[0] == 1 {
+			off = 52
+		}
+		if len(src) >= off+44 {
+			matrix = src[off : off+36]
+			dims = src[off+36 : off+44]
+		}
+	}
+	volume := []byte{0, 0}
+	if t.handler == mp4.HandlerAudio {
+		volume = []byte{0x01, 0x00}
+	}
+	tkhd := mp4FullBox("tkhd", 1, 0x3,
+		u64(0), u64(0), u32(trackID), u32(0), u64(uint64(duration)),
+		make([]byte, 8), u16(0), u16(0), volume, u16(0), matrix, dims)
+
+	elstEntries := [][]byte{u32(uint32(len(t.edits)))}
+	for _, e := range t.edits {
+		elstEntries = append(elstEntries, u64(uint64(e.segment)), u64(uint64(e.mediaTime)), u32(0x00010000))
+	}
+	edts := mp4BoxBytes("edts", mp4FullBox("elst", 1, 0, elstEntries...))
+
+	mdhd := mp4FullBox("mdhd", 1, 0,
+		u64(0), u64(0), u32(t.timescale), u64(uint64(t.mediaDuration())), u16(t.first.LanguageCode), u16(0))
+
+	mediaHeader := t.first.MediaHeaderBox
+	if mediaHeader == nil {
+		if t.handler == mp4.HandlerAudio {
+			mediaHeader = mp4FullBox("smhd", 0, 0, make([]byte, 4))
+		} else {
+			mediaHeader = mp4FullBox("vmhd", 0, 1, make([]byte, 8))
+		}
+	}
+	dinf := mp4BoxBytes("dinf", mp4FullBox("dref", 0, 0, u32(1), mp4FullBox("url ", 0, 1)))
+	minf := mp4BoxBytes("minf", mediaHeader, dinf, buildConcatStbl(t))
+	mdia := mp4BoxBytes("mdia", mdhd, t.first.HandlerBox, minf)
+	return mp4BoxBytes("trak", tkhd, edts, mdia)
+}
+
+func buildConcatStbl(t *concatTrack) []byte {
+	stsd := mp4FullBox("stsd", 0, 0, u32(1), t.first.SampleEntry)
+
+	// stts: run-length encoded durations
+	var stts [][]byte
+	var sttsCount uint32
+	for i := 0; i < len(t.samples); {
+		j := i
+		for j < len(t.samples) && t.samples[j].Duration == t.samples[i].Duration {
+			j++
+		}
+		stts = append(stts, u32(uint32(j-i)), u32(t.samples[i].Duration))
+		sttsCount++
+		i = j
+	}
+	boxes := [][]byte{stsd, mp4FullBox("stts", 0, 0, append([][]byte{u32(sttsCount)}, stts...)...)}
+
+	// ctts only when some sample has a composition offset
+	hasCTS, negativeCTS := false, false
+	for _, s := range t.samples {
+		hasCTS = hasCTS || s.CTSOffset != 0
+		negativeCTS = negativeCTS || s.CTSOffset < 0
+	}
+	if hasCTS {
+		var ctts [][]byte
+		var cttsCount uint32
+		for i := 0; i < len(t.samples); {
+			j := i
+			for j < len(t.samples) && t.samples[j].CTSOffset == t.samples[i].CTSOffset {
+				j++
+			}
+			ctts = append(ctts, u32(uint32(j-i)), u32(uint32(t.samples[i].CTSOffset)))
+			cttsCount++
+			i = j
+		}
+		version := byte(0)
+		if negativeCTS {
+			version = 1
+		}
+		boxes = append(boxes, mp4FullBox("ctts", version, 0, append([][]byte{u32(cttsCount)}, ctts...)...))
+	}
+
+	// stss only when some sample is not a sync sample
+	var syncs [][]byte
+	for i, s := range t.samples {
+		if s.Sync {
+			syncs = append(syncs, u32(uint32(i+1)))
+		}
+	}
+	if len(syncs) < len(t.samples) {
+		boxes = append(boxes, mp4FullBox("stss", 0, 0, append([][]byte{u32(uint32(len(syncs)))}, syncs...)...))
+	}
+
+	// stsc: one chunk per clip, run-length encoded by samples per chunk
+	var stsc [][]byte
+	var stscCount uint32
+	for i, c := range t.chunks {
+		if i > 0 && t.chunks[i-1].count == c.count {
+			continue
+		}
+		stsc = append(stsc, u32(uint32(i+1)), u32(uint32(c.count)), u32(1))
+		stscCount++
+	}
+	boxes = append(boxes, mp4FullBox("stsc", 0, 0, append([][]byte{u32(stscCount)}, stsc...)...))
+
+	// stsz
+	constant := len(t.samples) > 0
+	for _, s := range t.samples {
+		if s.Size != t.samples[0].Size {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		boxes = append(boxes, mp4FullBox("stsz", 0, 0, u32(t.samples[0].Size), u32(uint32(len(t.samples)))))
+	} else {
+		sizes := [][]byte{u32(0), u32(uint32(len(t.samples)))}
+		for _, s := range t.samples {
+			sizes = append(sizes, u32(s.Size))
+		}
+		boxes = append(boxes, mp4FullBox("stsz", 0, 0, sizes...))
+	}
+
+	// stco, or co64 when offsets exceed 32 bits
+	large := false
+	for _, c := range t.chunks {
+		large = large || c.offset > math.MaxUint32
+	}
+	offsets := [][]byte{u32(uint32(len(t.chunks)))}
+	for _, c := range t.chunks {
+		if large {
+			offsets = append(offsets, u64(uint64(c.offset)))
+		} else {
+			offsets = append(offsets, u32(uint32(c.offset)))
+		}
+	}
+	if large {
+		boxes = append(boxes, mp4FullBox("co64", 0, 0, offsets...))
+	} else {
+		boxes = append(boxes, mp4FullBox("stco", 0, 0, offsets...))
+	}
+
+	return mp4BoxBytes("stbl", boxes...)
+}
+
+func mp4BoxBytes(typ string, parts ...[]byte) []byte {
+	size := 8
+	for _, p := range parts {
+		size += len(p)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, u32(uint32(size))...)
+	out = append(out, typ...)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func mp4FullBox(typ string, version byte, flags uint32, parts ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return mp4BoxBytes(typ, append([][]byte{header}, parts...)...)
+}
+
+func unityMatrix() []byte {
+	m := make([]byte, 36)
+	binary.BigEndian.PutUint32(m[0:], 0x00010000)
+	binary.BigEndian.PutUint32(m[16:], 0x00010000)
+	binary.BigEndian.PutUint32(m[32:], 0x40000000)
+	return m
+}
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
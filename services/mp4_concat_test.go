@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"seedance-client/mp4"
+)
+
+func TestConcatMP4Files(t *testing.T) {
+	paths := []string{
+		filepath.Join("..", "mp4", "testdata", "faststart.mp4"),
+		filepath.Join("..", "mp4", "testdata", "moov_at_end.mp4"),
+		filepath.Join("..", "mp4", "testdata", "faststart.mp4"),
+	}
+	var want float64
+	var wantVideo, wantAudio int
+	for _, p := range paths {
+		src, err := mp4.ParseFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		video := src.Video()
+		want += float64(video.PresentedDuration()) / float64(video.Timescale)
+		wantVideo += video.SampleCount()
+		wantAudio += src.Audio().SampleCount()
+	}
+
+	var out bytes.Buffer
+	seconds, err := ConcatMP4Files(context.Background(), &out, paths)
+	if err != nil {
+		t.Fatalf("ConcatMP4Files: %v", err)
+	}
+	if math.Abs(seconds-want) > 1e-9 {
+		t.Errorf("duration = %v, want %v", seconds, want)
+	}
+
+	movie, err := mp4.Parse(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("parse joined file: %v", err)
+	}
+	if len(movie.Tracks) != 2 {
+		t.Fatalf("%d tracks, want 2", len(movie.Tracks))
+	}
+	if math.Abs(movie.Seconds()-want) > 1e-9 {
+		t.Errorf("movie duration = %v, want %v", movie.Seconds(), want)
+	}
+	video, audio := movie.Video(), movie.Audio()
+	if video.SampleCount() != wantVideo || audio.SampleCount() != wantAudio {
+		t.Errorf("samples = %d video, %d audio, want %d, %d", video.SampleCount(), audio.SampleCount(), wantVideo, wantAudio)
+	}
+	if video.Width != 64 || video.Height != 36 || audio.Channels != 2 || audio.SampleRate != 44100 {
+		t.Errorf("joined tracks = %dx%d, %d ch %d Hz", video.Width, video.Height, audio.Channels, audio.SampleRate)
+	}
+
+	// Every clip's first video sample is copied to where the sample table points
+	raw := out.Bytes()
+	per := wantVideo / len(paths)
+	for clip := range paths {
+		s := video.Samples[clip*per]
+		if got := raw[s.Offset : s.Offset+int64(s.Size)]; !bytes.Equal(got, []byte{1, 2, 3, 4, 5}) {
+			t.Errorf("clip %d first video sample = %v", clip+1, got)
+		}
+		if !s.Sync {
+			t.Errorf("clip %d does not start on a sync sample", clip+1)
+		}
+	}
+}
+
+func TestConcatMP4FilesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	seed := filepath.Join("..", "mp4", "testdata", "faststart.mp4")
+	_, err := ConcatMP4Files(ctx, &bytes.Buffer{}, []string{seed, seed})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestConcatMP4FilesTruncated(t *testing.T) {
+	seed := filepath.Join("..", "mp4", "testdata", "faststart.mp4")
+	_, err := ConcatMP4Files(context.Background(), &bytes.Buffer{}, []string{seed, filepath.Join("..", "mp4", "testdata", "truncated.mp4")})
+	if err == nil {
+		t.Fatal("joined a truncated clip")
+	}
+}
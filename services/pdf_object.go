@@ -0,0 +1,554 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// A minimal reader for the PDF object syntax: enough to reach page content streams, fonts and
+// their ToUnicode maps for text extraction. Cross-reference tables are not needed, since objects
+// are found by scanning the file.
+
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ Num, Gen int }
+	pdfStream  struct {
+		Dict pdfDict
+		Data []byte // raw, still encoded
+	}
+)
+
+// ============================================================
+// Lexer
+// ============================================================
+
+type pdfLexer struct {
+	b   []byte
+	pos int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token reads one token: a float64, pdfName, pdfString, or a pdfKeyword for operators and
+// delimiters. It returns false at the end of input.
+func (l *pdfLexer) token() (any, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.b) {
+		return nil, false
+	}
+	c := l.b[l.pos]
+	switch c {
+	case '(':
+		return l.literalString(), true
+	case '<':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), true
+		}
+		return l.hexString(), true
+	case '>':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), true
+		}
+		l.pos++
+		return pdfKeyword(">"), true
+	case '[', ']', '{', '}', ')':
+		l.pos++
+		return pdfKeyword(string(c)), true
+	case '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapePDFName(l.b[start:l.pos])), true
+	}
+	start := l.pos
+	for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+		l.pos++
+	}
+	word := string(l.b[start:l.pos])
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, true
+		}
+	}
+	return pdfKeyword(word), true
+}
+
+func unescapePDFName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(out)
+			}
+		case '\\':
+			if l.pos >= len(l.b) {
+				continue
+			}
+			e := l.b[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// line continuation
+				if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for n := 0; n < 2 && l.pos < len(l.b) && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; n++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return pdfString(out)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.b) && l.b[l.pos] != '>' {
+		if c := l.b[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out, _ := hex.DecodeString(string(digits))
+	return pdfString(out)
+}
+
+// value reads one complete value, composing arrays, dictionaries and references.
+func (l *pdfLexer) value() (any, bool) {
+	tok, ok := l.token()
+	if !ok {
+		return nil, false
+	}
+	return l.compose(tok), true
+}
+
+func (l *pdfLexer) compose(tok any) any {
+	kw, ok := tok.(pdfKeyword)
+	if !ok {
+		return tok
+	}
+	switch kw {
+	case "[":
+		return pdfArray(l.items("]"))
+	case "<<":
+		items := l.items(">>")
+		d := pdfDict{}
+		for i := 0; i+1 < len(items); i += 2 {
+			if k, ok := items[i].(pdfName); ok {
+				d[k] = items[i+1]
+			}
+		}
+		return d
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	return kw
+}
+
+func (l *pdfLexer) items(end pdfKeyword) []any {
+	var out []any
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return out
+		}
+		if kw, isKw := tok.(pdfKeyword); isKw {
+			switch kw {
+			case end, "endobj", "stream":
+				return out
+			case "R":
+				if len(out) >= 2 {
+					num, ok1 := out[len(out)-2].(float64)
+					gen, ok2 := out[len(out)-1].(float64)
+					if ok1 && ok2 {
+						out = append(out[:len(out)-2], pdfRef{int(num), int(gen)})
+						continue
+					}
+				}
+			}
+		}
+		out = append(out, l.compose(tok))
+	}
+}
+
+// ============================================================
+// Document
+// ============================================================
+
+type pdfDoc struct {
+	objs map[int]any
+}
+
+var (
+	pdfObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfEncrypted = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+)
+
+// parsePDF indexes every object of a PDF file, including objects packed in object streams. A
+// later definition of an object (an incremental update) replaces an earlier one.
+func parsePDF(data []byte) (*pdfDoc, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\r "), []byte("%PDF")) {
+		return nil, fmt.Errorf("不是有效的 PDF 文件")
+	}
+	if pdfEncrypted.Match(data) {
+		return nil, fmt.Errorf("PDF 已加密，无法提取文本")
+	}
+	doc := &pdfDoc{objs: map[int]any{}}
+	pos := 0
+	for pos < len(data) {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{b: data, pos: pos + loc[1]}
+		v, _ := l.value()
+		pos = l.pos
+
+		dict, isDict := v.(pdfDict)
+		next := l.pos
+		if tok, ok := l.token(); isDict && ok && tok == any(pdfKeyword("stream")) {
+			start := l.pos
+			if start < len(data) && data[start] == '\r' {
+				start++
+			}
+			if start < len(data) && data[start] == '\n' {
+				start++
+			}
+			end := -1
+			if n, ok := dict["Length"].(float64); ok && n >= 0 && start+int(n) <= len(data) {
+				rest := bytes.TrimLeft(data[start+int(n):], "\x00\t\n\r ")
+				if bytes.HasPrefix(rest, []byte("endstream")) {
+					end = start + int(n)
+				}
+			}
+			if end < 0 {
+				idx := bytes.Index(data[start:], []byte("endstream"))
+				if idx < 0 {
+					break
+				}
+				end = start + idx
+				for end > start && (data[end-1] == '\n' || data[end-1] == '\r') {
+					end--
+				}
+			}
+			doc.objs[num] = &pdfStream{Dict: dict, Data: data[start:end]}
+			pos = end + len("endstream")
+			continue
+		}
+		pos = next
+		doc.objs[num] = v
+	}
+
+	// Object streams, in object order so the result does not depend on map iteration
+	var nums []int
+	for num, v := range doc.objs {
+		if s, ok := v.(*pdfStream); ok && s.Dict["Type"] == pdfName("ObjStm") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		doc.unpackObjectStream(doc.objs[num].(*pdfStream))
+	}
+	if len(doc.objs) == 0 {
+		return nil, fmt.Errorf("PDF 文件中没有可读取的对象")
+	}
+	return doc, nil
+}
+
+func (d *pdfDoc) unpackObjectStream(s *pdfStream) {
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return
+	}
+	n, _ := d.resolve(s.Dict["N"]).(float64)
+	first, _ := d.resolve(s.Dict["First"]).(float64)
+	header := &pdfLexer{b: data}
+	for i := 0; i < int(n); i++ {
+		num, ok1 := header.token()
+		off, ok2 := header.token()
+		objNum, isNum := num.(float64)
+		offset, isOff := off.(float64)
+		if !ok1 || !ok2 || !isNum || !isOff {
+			return
+		}
+		at := int(first) + int(offset)
+		if at < 0 || at >= len(data) {
+			continue
+		}
+		if _, exists := d.objs[int(objNum)]; exists {
+			continue
+		}
+		l := &pdfLexer{b: data, pos: at}
+		if v, ok := l.value(); ok {
+			d.objs[int(objNum)] = v
+		}
+	}
+}
+
+// resolve follows indirect references.
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objs[ref.Num]
+	}
+	return nil
+}
+
+// dict resolves v to a dictionary; for a stream it is the stream dictionary.
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.Dict
+	}
+	return nil
+}
+
+func (d *pdfDoc) array(v any) pdfArray {
+	a, _ := d.resolve(v).(pdfArray)
+	return a
+}
+
+func (d *pdfDoc) number(v any) float64 {
+	f, _ := d.resolve(v).(float64)
+	return f
+}
+
+func (d *pdfDoc) name(v any) pdfName {
+	n, _ := d.resolve(v).(pdfName)
+	return n
+}
+
+// decodeStream applies a stream's filters. Flate, ASCIIHex and ASCII85 are supported, which
+// covers text content; image-only filters are reported as unsupported.
+func (d *pdfDoc) decodeStream(s *pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch f := d.resolve(s.Dict["Filter"]).(type) {
+	case pdfName:
+		filters = []pdfName{f}
+	case pdfArray:
+		for _, v := range f {
+			filters = append(filters, d.name(v))
+		}
+	}
+	data := s.Data
+	for _, f := range filters {
+		switch f {
+		case "FlateDecode", "Fl":
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			out, err := io.ReadAll(r)
+			r.Close()
+			// Truncated streams still yield their readable prefix
+			if err != nil && len(out) == 0 {
+				return nil, err
+			}
+			data = out
+		case "ASCIIHexDecode", "AHx":
+			l := &pdfLexer{b: append([]byte{'<'}, data...)}
+			data = []byte(l.hexString())
+		case "ASCII85Decode", "A85":
+			src := bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			if i := bytes.Index(src, []byte("~>")); i >= 0 {
+				src = src[:i]
+			}
+			out := make([]byte, 4*len(src)/5+4)
+			n, _, err := ascii85.Decode(out, src, true)
+			if err != nil {
+				return nil, err
+			}
+			data = out[:n]
+		default:
+			return nil, fmt.Errorf("unsupported filter: %s", f)
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page with the resources it inherits from the page tree.
+type pdfPage struct {
+	Dict      pdfDict
+	Resources pdfDict
+}
+
+// pages lists the pages in reading order by walking the page tree from the document catalog,
+// falling back to every page object in object order.
+func (d *pdfDoc) pages() []pdfPage {
+	var nums []int
+	for num := range d.objs {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	var pages []pdfPage
+	visited := map[int]bool{}
+	var walk func(v any, inherited pdfDict)
+	walk = func(v any, inherited pdfDict) {
+		if ref, ok := v.(pdfRef); ok {
+			if visited[ref.Num] {
+				return
+			}
+			visited[ref.Num] = true
+		}
+		node := d.dict(v)
+		if node == nil {
+			return
+		}
+		res := inherited
+		if r := d.dict(node["Resources"]); r != nil {
+			res = r
+		}
+		if kids := d.array(node["Kids"]); kids != nil {
+			for _, kid := range kids {
+				walk(kid, res)
+			}
+			return
+		}
+		if d.name(node["Type"]) == "Page" || node["Contents"] != nil {
+			pages = append(pages, pdfPage{Dict: node, Resources: res})
+		}
+	}
+	// The last catalog wins, as incremental updates append a new one
+	for i := len(nums) - 1; i >= 0 && len(pages) == 0; i-- {
+		if cat := d.dict(d.objs[nums[i]]); cat != nil && d.name(cat["Type"]) == "Catalog" {
+			walk(cat["Pages"], nil)
+		}
+	}
+	if len(pages) == 0 {
+		for _, num := range nums {
+			if p := d.dict(d.objs[num]); p != nil && d.name(p["Type"]) == "Page" {
+				pages = append(pages, pdfPage{Dict: p, Resources: d.dict(p["Resources"])})
+			}
+		}
+	}
+	return pages
+}
+
+// contents returns the concatenated content streams of a page.
+func (d *pdfDoc) contents(page pdfDict) []byte {
+	var parts []any
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		parts = []any{c}
+	case pdfArray:
+		parts = c
+	}
+	var out []byte
+	for _, p := range parts {
+		s, ok := d.resolve(p).(*pdfStream)
+		if !ok {
+			continue
+		}
+		if data, err := d.decodeStream(s); err == nil {
+			out = append(out, data...)
+			out = append(out, '\n')
+		}
+	}
+	return out
+}
@@ -0,0 +1,755 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PDFToMarkdown extracts the text of a text-based PDF as markdown. Lines set noticeably larger
+// than the body text become headings, and runs of lines split into aligned columns become
+// markdown tables. Scanned PDFs have no text and return an error.
+func PDFToMarkdown(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", fmt.Errorf("PDF 中没有页面")
+	}
+
+	e := &pdfExtractor{doc: doc, fonts: map[pdfRef]*pdfFont{}}
+	for i, page := range pages {
+		e.page = i
+		e.run(doc.contents(page.Dict), page.Resources, 0)
+	}
+	text := e.markdown()
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("PDF 中没有可提取的文本，可能是扫描件")
+	}
+	return text, nil
+}
+
+// ============================================================
+// Fonts
+// ============================================================
+
+type pdfCodespace struct{ lo, hi []byte }
+
+type pdfFont struct {
+	composite bool // Type0: two-byte codes unless the CMap says otherwise
+	ucs2      bool // a predefined Unicode CMap, so codes are UTF-16 without a ToUnicode map
+	ranges    []pdfCodespace
+	toUnicode map[string][]rune
+	diffs     map[byte]rune
+	widths    map[int]float64
+	defWidth  float64
+}
+
+// pdfGlyph is one decoded character code.
+type pdfGlyph struct {
+	text  string
+	width float64 // in text space units, before the font size
+	space bool    // the single-byte code 32, which word spacing applies to
+}
+
+func (d *pdfDoc) loadFont(v any) *pdfFont {
+	fd := d.dict(v)
+	if fd == nil {
+		return nil
+	}
+	f := &pdfFont{widths: map[int]float64{}, defWidth: 500}
+	if d.name(fd["Subtype"]) == "Type0" {
+		f.composite = true
+		f.defWidth = 1000
+		if enc := string(d.name(fd["Encoding"])); strings.HasPrefix(enc, "Uni") &&
+			(strings.Contains(enc, "UCS2") || strings.Contains(enc, "UTF16")) {
+			f.ucs2 = true
+		}
+		if desc := d.array(fd["DescendantFonts"]); len(desc) > 0 {
+			cid := d.dict(desc[0])
+			if dw, ok := d.resolve(cid["DW"]).(float64); ok {
+				f.defWidth = dw
+			}
+			f.loadCIDWidths(d, d.array(cid["W"]))
+		}
+	} else {
+		first := int(d.number(fd["FirstChar"]))
+		for i, w := range d.array(fd["Widths"]) {
+			f.widths[first+i] = d.number(w)
+		}
+		if desc := d.dict(fd["FontDescriptor"]); desc != nil {
+			if mw, ok := d.resolve(desc["MissingWidth"]).(float64); ok && mw > 0 {
+				f.defWidth = mw
+			}
+		}
+		if enc := d.dict(fd["Encoding"]); enc != nil {
+			f.loadDifferences(d, d.array(enc["Differences"]))
+		}
+	}
+	if s, ok := d.resolve(fd["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(s); err == nil {
+			f.parseToUnicode(data)
+		}
+	}
+	return f
+}
+
+// loadCIDWidths reads a W array: "c [w1 w2 ...]" or "cFirst cLast w".
+func (f *pdfFont) loadCIDWidths(d *pdfDoc, w pdfArray) {
+	for i := 0; i < len(w); {
+		c := int(d.number(w[i]))
+		if i+1 < len(w) {
+			if list, ok := d.resolve(w[i+1]).(pdfArray); ok {
+				for j, v := range list {
+					f.widths[c+j] = d.number(v)
+				}
+				i += 2
+				continue
+			}
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last, width := int(d.number(w[i+1])), d.number(w[i+2])
+		for cid := c; cid <= last && cid-c < 65536; cid++ {
+			f.widths[cid] = width
+		}
+		i += 3
+	}
+}
+
+func (f *pdfFont) loadDifferences(d *pdfDoc, diffs pdfArray) {
+	code := 0
+	for _, v := range diffs {
+		switch t := d.resolve(v).(type) {
+		case float64:
+			code = int(t)
+		case pdfName:
+			if r, ok := glyphNameRune(string(t)); ok && code < 256 {
+				if f.diffs == nil {
+					f.diffs = map[byte]rune{}
+				}
+				f.diffs[byte(code)] = r
+			}
+			code++
+		}
+	}
+}
+
+// parseToUnicode reads the codespace ranges and bfchar/bfrange mappings of a ToUnicode CMap.
+func (f *pdfFont) parseToUnicode(data []byte) {
+	f.toUnicode = map[string][]rune{}
+	l := &pdfLexer{b: data}
+	read := func(end pdfKeyword) []any {
+		var items []any
+		for {
+			v, ok := l.value()
+			if !ok || v == any(end) {
+				return items
+			}
+			items = append(items, v)
+		}
+	}
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		switch tok {
+		case any(pdfKeyword("begincodespacerange")):
+			items := read("endcodespacerange")
+			for i := 0; i+1 < len(items); i += 2 {
+				lo, ok1 := items[i].(pdfString)
+				hi, ok2 := items[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					f.ranges = append(f.ranges, pdfCodespace{[]byte(lo), []byte(hi)})
+				}
+			}
+		case any(pdfKeyword("beginbfchar")):
+			items := read("endbfchar")
+			for i := 0; i+1 < len(items); i += 2 {
+				src, ok := items[i].(pdfString)
+				if !ok {
+					continue
+				}
+				switch dst := items[i+1].(type) {
+				case pdfString:
+					f.toUnicode[string(src)] = utf16BE(string(dst))
+				case pdfName:
+					if r, ok := glyphNameRune(string(dst)); ok {
+						f.toUnicode[string(src)] = []rune{r}
+					}
+				}
+			}
+		case any(pdfKeyword("beginbfrange")):
+			items := read("endbfrange")
+			for i := 0; i+2 < len(items); i += 3 {
+				lo, ok1 := items[i].(pdfString)
+				hi, ok2 := items[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 {
+					continue
+				}
+				start, end := codeValue(string(lo)), codeValue(string(hi))
+				for c := start; c <= end && c-start < 65536; c++ {
+					key := codeBytes(c, len(lo))
+					switch dst := items[i+2].(type) {
+					case pdfString:
+						runes := utf16BE(string(dst))
+						if len(runes) > 0 {
+							runes = append([]rune(nil), runes...)
+							runes[len(runes)-1] += rune(c - start)
+						}
+						f.toUnicode[key] = runes
+					case pdfArray:
+						if k := c - start; k < len(dst) {
+							if s, ok := dst[k].(pdfString); ok {
+								f.toUnicode[key] = utf16BE(string(s))
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func codeValue(s string) int {
+	v := 0
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | int(s[i])
+	}
+	return v
+}
+
+func codeBytes(v int, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+func utf16BE(s string) []rune {
+	if len(s)%2 == 1 {
+		s += "\x00"
+	}
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return utf16.Decode(units)
+}
+
+// codeLength returns the byte length of the character code starting at s[0].
+func (f *pdfFont) codeLength(s string) int {
+	for _, r := range f.ranges {
+		n := len(r.lo)
+		if n > len(s) {
+			continue
+		}
+		match := true
+		for i := 0; i < n; i++ {
+			if s[i] < r.lo[i] || s[i] > r.hi[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+	if f.composite && len(s) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// decode splits a shown string into character codes and maps each to Unicode.
+func (f *pdfFont) decode(s string) []pdfGlyph {
+	var glyphs []pdfGlyph
+	for len(s) > 0 {
+		n := f.codeLength(s)
+		code := s[:n]
+		s = s[n:]
+
+		g := pdfGlyph{space: n == 1 && code[0] == ' '}
+		if runes, ok := f.toUnicode[code]; ok {
+			g.text = string(runes)
+		} else if f.ucs2 {
+			g.text = string(utf16BE(code))
+		} else if n == 1 {
+			if r, ok := f.diffs[code[0]]; ok {
+				g.text = string(r)
+			} else {
+				g.text = string(winAnsiRune(code[0]))
+			}
+		}
+		if w, ok := f.widths[codeValue(code)]; ok {
+			g.width = w / 1000
+		} else {
+			g.width = f.defWidth / 1000
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// winAnsiRune maps a WinAnsiEncoding byte; it differs from Latin-1 only in 0x80–0x9F.
+func winAnsiRune(b byte) rune {
+	if r, ok := winAnsiHigh[b]; ok {
+		return r
+	}
+	if b < 0x20 && b != '\t' {
+		return ' '
+	}
+	return rune(b)
+}
+
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ',
+	0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“',
+	0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›',
+	0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '’', "quoteleft": '‘', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "period": '.',
+	"slash": '/', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"underscore": '_', "braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"quotedblleft": '“', "quotedblright": '”', "endash": '–', "emdash": '—', "bullet": '•',
+	"ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "zero": '0', "one": '1', "two": '2',
+	"three": '3', "four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+}
+
+// glyphNameRune maps a glyph name from an encoding's Differences array: single letters,
+// "uniXXXX" names and common punctuation.
+func glyphNameRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		var v rune
+		if _, err := fmt.Sscanf(name[3:], "%04X", &v); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// ============================================================
+// Content Streams
+// ============================================================
+
+// pdfLine is a line of text as laid out on the page. Text separated by a wide gap goes into
+// separate cells, which is how table columns are recognized.
+type pdfLine struct {
+	page  int
+	y     float64
+	cells []string
+	sizes map[float64]int // font size -> characters set in it
+}
+
+// size is the font size most of the line is set in.
+func (l *pdfLine) size() float64 {
+	best, count := 0.0, -1
+	for s, n := range l.sizes {
+		if n > count || (n == count && s > best) {
+			best, count = s, n
+		}
+	}
+	return best
+}
+
+func (l *pdfLine) text() string {
+	return strings.TrimSpace(strings.Join(l.cells, " "))
+}
+
+type pdfTextState struct {
+	font                              *pdfFont
+	size, charSpace, wordSpace, scale float64
+	leading                           float64
+}
+
+type pdfExtractor struct {
+	doc   *pdfDoc
+	fonts map[pdfRef]*pdfFont
+	page  int
+
+	state pdfTextState
+	saved []pdfTextState
+	tm    [6]float64
+	tlm   [6]float64
+	lines []*pdfLine
+	cur   *pdfLine
+	lastX float64
+}
+
+// pdfMaxFormDepth limits nested form XObjects.
+const pdfMaxFormDepth = 8
+
+func (e *pdfExtractor) run(content []byte, resources pdfDict, depth int) {
+	e.state = pdfTextState{scale: 1}
+	e.saved = nil
+	e.tm = [6]float64{1, 0, 0, 1, 0, 0}
+	e.tlm = e.tm
+	e.interpret(content, resources, depth)
+}
+
+func (e *pdfExtractor) interpret(content []byte, resources pdfDict, depth int) {
+	l := &pdfLexer{b: content}
+	var ops []any
+	num := func(i int) float64 {
+		if i < len(ops) {
+			f, _ := ops[i].(float64)
+			return f
+		}
+		return 0
+	}
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		v := l.compose(tok)
+		op, isOp := v.(pdfKeyword)
+		if !isOp {
+			ops = append(ops, v)
+			continue
+		}
+		switch op {
+		case "BT":
+			e.tm = [6]float64{1, 0, 0, 1, 0, 0}
+			e.tlm = e.tm
+		case "q":
+			e.saved = append(e.saved, e.state)
+		case "Q":
+			if n := len(e.saved); n > 0 {
+				e.state = e.saved[n-1]
+				e.saved = e.saved[:n-1]
+			}
+		case "Tf":
+			if len(ops) >= 2 {
+				name, _ := ops[0].(pdfName)
+				e.state.font = e.fontFor(resources, name)
+				e.state.size = num(1)
+			}
+		case "Tc":
+			e.state.charSpace = num(0)
+		case "Tw":
+			e.state.wordSpace = num(0)
+		case "Tz":
+			e.state.scale = num(0) / 100
+		case "TL":
+			e.state.leading = num(0)
+		case "Td":
+			e.moveText(num(0), num(1))
+		case "TD":
+			e.state.leading = -num(1)
+			e.moveText(num(0), num(1))
+		case "Tm":
+			if len(ops) >= 6 {
+				for i := range e.tm {
+					e.tm[i] = num(i)
+				}
+				e.tlm = e.tm
+			}
+		case "T*":
+			e.moveText(0, -e.state.leading)
+		case "Tj":
+			if len(ops) > 0 {
+				e.show(ops[len(ops)-1])
+			}
+		case "'":
+			e.moveText(0, -e.state.leading)
+			if len(ops) > 0 {
+				e.show(ops[len(ops)-1])
+			}
+		case "\"":
+			if len(ops) >= 3 {
+				e.state.wordSpace, e.state.charSpace = num(0), num(1)
+				e.moveText(0, -e.state.leading)
+				e.show(ops[2])
+			}
+		case "TJ":
+			if len(ops) > 0 {
+				arr, _ := ops[len(ops)-1].(pdfArray)
+				for _, item := range arr {
+					if adj, ok := item.(float64); ok {
+						e.advance(-adj / 1000 * e.state.size * e.state.scale)
+					} else {
+						e.show(item)
+					}
+				}
+			}
+		case "Do":
+			if len(ops) > 0 && depth < pdfMaxFormDepth {
+				name, _ := ops[0].(pdfName)
+				e.form(resources, name, depth)
+			}
+		case "BI":
+			e.skipInlineImage(l)
+		}
+		ops = ops[:0]
+	}
+}
+
+func (e *pdfExtractor) fontFor(resources pdfDict, name pdfName) *pdfFont {
+	fonts := e.doc.dict(resources["Font"])
+	if fonts == nil {
+		return nil
+	}
+	v := fonts[name]
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if f, ok := e.fonts[ref]; ok {
+			return f
+		}
+	}
+	f := e.doc.loadFont(v)
+	if isRef {
+		e.fonts[ref] = f
+	}
+	return f
+}
+
+// form runs a form XObject, which some producers wrap whole pages in.
+func (e *pdfExtractor) form(resources pdfDict, name pdfName, depth int) {
+	xobjects := e.doc.dict(resources["XObject"])
+	if xobjects == nil {
+		return
+	}
+	s, ok := e.doc.resolve(xobjects[name]).(*pdfStream)
+	if !ok || e.doc.name(s.Dict["Subtype"]) != "Form" {
+		return
+	}
+	data, err := e.doc.decodeStream(s)
+	if err != nil {
+		return
+	}
+	res := resources
+	if r := e.doc.dict(s.Dict["Resources"]); r != nil {
+		res = r
+	}
+	state, saved, tm, tlm := e.state, e.saved, e.tm, e.tlm
+	e.saved = nil
+	e.interpret(data, res, depth+1)
+	e.state, e.saved, e.tm, e.tlm = state, saved, tm, tlm
+}
+
+// skipInlineImage skips the binary data between ID and EI.
+func (e *pdfExtractor) skipInlineImage(l *pdfLexer) {
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		if tok == any(pdfKeyword("ID")) {
+			break
+		}
+	}
+	b := l.b
+	for i := l.pos + 1; i+2 <= len(b); i++ {
+		if b[i] == 'E' && b[i+1] == 'I' && isPDFSpace(b[i-1]) && (i+2 == len(b) || isPDFSpace(b[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(b)
+}
+
+func (e *pdfExtractor) moveText(tx, ty float64) {
+	m := &e.tlm
+	m[4] += tx*m[0] + ty*m[2]
+	m[5] += tx*m[1] + ty*m[3]
+	e.tm = e.tlm
+}
+
+func (e *pdfExtractor) advance(tx float64) {
+	e.tm[4] += tx * e.tm[0]
+	e.tm[5] += tx * e.tm[1]
+}
+
+func (e *pdfExtractor) show(v any) {
+	s, ok := v.(pdfString)
+	if !ok || e.state.font == nil {
+		return
+	}
+	size := e.state.size * math.Hypot(e.tm[2], e.tm[3])
+	if size <= 0 {
+		size = math.Abs(e.state.size)
+	}
+	size = math.Round(size*2) / 2
+	e.place(e.tm[4], e.tm[5], size)
+	for _, g := range e.state.font.decode(string(s)) {
+		e.write(g.text, size)
+		adv := g.width*e.state.size + e.state.charSpace
+		if g.space {
+			adv += e.state.wordSpace
+		}
+		e.advance(adv * e.state.scale)
+	}
+	e.lastX = e.tm[4]
+}
+
+// place starts a new line when the baseline moves, and separates text on the same line by a
+// space or, across a wide gap, a new cell.
+func (e *pdfExtractor) place(x, y, size float64) {
+	if e.cur == nil || e.cur.page != e.page || math.Abs(y-e.cur.y) > 0.5*math.Max(size, e.cur.size()) {
+		e.cur = &pdfLine{page: e.page, y: y, cells: []string{""}, sizes: map[float64]int{}}
+		e.lines = append(e.lines, e.cur)
+		return
+	}
+	gap := x - e.lastX
+	last := &e.cur.cells[len(e.cur.cells)-1]
+	switch {
+	case gap > 1.5*size && strings.TrimSpace(*last) != "":
+		e.cur.cells = append(e.cur.cells, "")
+	case (gap > 0.2*size || gap < -size) && *last != "" && !strings.HasSuffix(*last, " "):
+		*last += " "
+	}
+}
+
+func (e *pdfExtractor) write(text string, size float64) {
+	if text == "" || e.cur == nil {
+		return
+	}
+	last := &e.cur.cells[len(e.cur.cells)-1]
+	if text == " " && (*last == "" || strings.HasSuffix(*last, " ")) {
+		return
+	}
+	*last += text
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			e.cur.sizes[size]++
+		}
+	}
+}
+
+// ============================================================
+// Markdown
+// ============================================================
+
+// pdfHeadingMaxRunes keeps long lines set in a large size (pull quotes, cover blurbs) from being
+// taken as headings.
+const pdfHeadingMaxRunes = 60
+
+func (e *pdfExtractor) markdown() string {
+	var lines []*pdfLine
+	for _, l := range e.lines {
+		for i := range l.cells {
+			l.cells[i] = strings.TrimSpace(l.cells[i])
+		}
+		if l.text() != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	// Body size is the size most characters are set in; larger sizes rank as heading levels
+	chars := map[float64]int{}
+	for _, l := range lines {
+		for s, n := range l.sizes {
+			chars[s] += n
+		}
+	}
+	body, most := 0.0, -1
+	for s, n := range chars {
+		if n > most || (n == most && s < body) {
+			body, most = s, n
+		}
+	}
+	var headingSizes []float64
+	for s := range chars {
+		if s >= body*1.15 {
+			headingSizes = append(headingSizes, s)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(headingSizes)))
+	headingLevel := func(l *pdfLine) int {
+		if len(l.cells) > 1 || len([]rune(l.text())) > pdfHeadingMaxRunes {
+			return 0
+		}
+		size := l.size()
+		for i, s := range headingSizes {
+			if size == s {
+				return min(i+1, 3)
+			}
+		}
+		return 0
+	}
+
+	// Typical line spacing of body text, to tell paragraph breaks from wrapped lines
+	var gaps []float64
+	for i := 1; i < len(lines); i++ {
+		if lines[i].page == lines[i-1].page {
+			if gap := math.Abs(lines[i].y - lines[i-1].y); gap > 0 {
+				gaps = append(gaps, gap)
+			}
+		}
+	}
+	leading := body * 1.2
+	if len(gaps) > 0 {
+		sort.Float64s(gaps)
+		leading = gaps[len(gaps)/2]
+	}
+
+	var blocks []string
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, strings.Join(para, "\n"))
+			para = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		if i > 0 {
+			prev := lines[i-1]
+			if prev.page != l.page || math.Abs(l.y-prev.y) > 1.5*leading {
+				flush()
+			}
+		}
+		if level := headingLevel(l); level > 0 {
+			flush()
+			blocks = append(blocks, strings.Repeat("#", level)+" "+l.text())
+			continue
+		}
+		// Consecutive lines split into columns form a table
+		if len(l.cells) > 1 {
+			j := i
+			var table [][]string
+			for j < len(lines) && len(lines[j].cells) > 1 && lines[j].page == l.page {
+				table = append(table, lines[j].cells)
+				j++
+			}
+			if len(table) > 1 {
+				flush()
+				blocks = append(blocks, strings.TrimRight(TableToMarkdown(table), "\n"))
+				i = j - 1
+				continue
+			}
+		}
+		para = append(para, strings.Join(l.cells, "  "))
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfWriter assembles a PDF file from numbered objects.
+type pdfWriter struct {
+	objs [][]byte // object n is objs[n-1]
+}
+
+// reserve allocates an object number to be filled in with set.
+func (w *pdfWriter) reserve() int {
+	w.objs = append(w.objs, nil)
+	return len(w.objs)
+}
+
+func (w *pdfWriter) set(n int, body string) {
+	w.objs[n-1] = []byte(body)
+}
+
+func (w *pdfWriter) add(body string) int {
+	n := w.reserve()
+	w.set(n, body)
+	return n
+}
+
+// addStream adds a stream object. Content streams are compressed; data that is already
+// compressed (JPEG) is passed with its filter in dict and compress false.
+func (w *pdfWriter) addStream(dict string, data []byte, compress bool) int {
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+	n := w.reserve()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	w.objs[n-1] = b.Bytes()
+	return n
+}
+
+// bytes renders the file with a cross-reference table; root is the catalog object.
+func (w *pdfWriter) bytes(root int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objs))
+	for i, body := range w.objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(body)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objs)+1, root, xref)
+	return b.Bytes()
+}
+
+// addCJKFont adds STSong-Light, one of the standard Chinese fonts PDF viewers provide without
+// embedding. Text is shown as UTF-16BE; ASCII is half width.
+func (w *pdfWriter) addCJKFont() int {
+	desc := w.add("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880]" +
+		" /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	cid := w.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light"+
+		" /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >>"+
+		" /FontDescriptor %d 0 R /DW 1000 /W [1 95 500] >>", desc))
+	return w.add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-UniGB-UTF16-H"+
+		" /Encoding /UniGB-UTF16-H /DescendantFonts [%d 0 R] >>", cid))
+}
+
+// pdfTextWidth measures text in the CJK font: ASCII is half an em, everything else a full em.
+func pdfTextWidth(s string, size float64) float64 {
+	w := 0.0
+	for _, r := range s {
+		if r < 0x80 {
+			w += 0.5
+		} else {
+			w += 1
+		}
+	}
+	return w * size
+}
+
+// pdfCanvas builds a page content stream. Coordinates are in points from the top-left corner,
+// flipped to PDF's bottom-left origin as they are written.
+type pdfCanvas struct {
+	buf    bytes.Buffer
+	height float64
+}
+
+func (c *pdfCanvas) text(x, y float64, size float64, gray float64, s string) {
+	if s == "" {
+		return
+	}
+	var hex strings.Builder
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&hex, "%04X", u)
+	}
+	fmt.Fprintf(&c.buf, "BT %.2f g /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", gray, size, x, c.height-y-size*0.88, hex.String())
+}
+
+func (c *pdfCanvas) strokeRect(x, y, w, h, gray, lineWidth float64) {
+	fmt.Fprintf(&c.buf, "%.2f G %.2f w %.2f %.2f %.2f %.2f re S\n", gray, lineWidth, x, c.height-y-h, w, h)
+}
+
+func (c *pdfCanvas) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&c.buf, "%.2f g %.2f %.2f %.2f %.2f re f\n", gray, x, c.height-y-h, w, h)
+}
+
+func (c *pdfCanvas) image(name string, x, y, w, h float64) {
+	fmt.Fprintf(&c.buf, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, c.height-y-h, name)
+}
+
+// wrapText breaks text into lines no wider than width, preferring to break Latin text at spaces.
+// At most maxLines lines are returned; a truncated last line ends with an ellipsis.
+func wrapText(text string, size, width float64, maxLines int) []string {
+	if maxLines <= 0 {
+		return nil
+	}
+	var lines []string
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n") {
+		runes := []rune(strings.TrimSpace(para))
+		for len(runes) > 0 {
+			n, w, lastSpace := 0, 0.0, -1
+			for n < len(runes) {
+				cw := pdfTextWidth(string(runes[n]), size)
+				if w+cw > width && n > 0 {
+					break
+				}
+				if runes[n] == ' ' {
+					lastSpace = n
+				}
+				w += cw
+				n++
+			}
+			if n < len(runes) && lastSpace > n/2 {
+				n = lastSpace + 1
+			}
+			lines = append(lines, strings.TrimSpace(string(runes[:n])))
+			runes = runes[n:]
+		}
+	}
+	if len(lines) > maxLines {
+		last := []rune(lines[maxLines-1])
+		for len(last) > 0 && pdfTextWidth(string(last)+"…", size) > width {
+			last = last[:len(last)-1]
+		}
+		lines = append(lines[:maxLines-1], string(last)+"…")
+	}
+	return lines
+}
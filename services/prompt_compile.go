@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PromptSection is a titled list of reference lines appended to a video prompt,
+// e.g. "角色参考" followed by one line per character.
+type PromptSection struct {
+	Title string
+	Lines []string
+}
+
+// PromptParts is a video prompt before compilation: the shot prompt and its reference sections.
+type PromptParts struct {
+	Base     string
+	Sections []PromptSection
+}
+
+// String joins the parts the way they are sent when no compilation is applied.
+func (p PromptParts) String() string {
+	blocks := []string{strings.TrimSpace(p.Base)}
+	for _, s := range p.Sections {
+		if len(s.Lines) == 0 {
+			continue
+		}
+		blocks = append(blocks, s.Title+"\n"+strings.Join(s.Lines, "\n"))
+	}
+	return strings.TrimSpace(strings.Join(blocks, "\n\n"))
+}
+
+// PromptCompileOptions selects the deterministic compilation steps.
+type PromptCompileOptions struct {
+	StripLocalPaths bool
+	MaxRunes        int // 0 means no limit
+}
+
+var (
+	// （参考图: /uploads/x.png） as added for catalog assets with an image
+	referenceImageNote = regexp.MustCompile(`\s*[（(]\s*参考图\s*[:：][^）)]*[）)]`)
+	// Bare local file paths and file:// URLs of media files
+	localMediaPath = regexp.MustCompile(`(?i)(?:file://)?(?:[a-z]:[\\/]|[\\/])?(?:[^\s\\/（）()，。；、:：]+[\\/])+[^\s\\/（）()，。；、]+\.(?:png|jpe?g|webp|gif|bmp|mp4|mov|webm)`)
+	blankLines     = regexp.MustCompile(`\n{3,}`)
+)
+
+// CompilePrompt turns prompt parts into the text submitted to the video model. It removes local
+// file paths, which the remote model cannot resolve, and fits the result into MaxRunes by dropping
+// reference lines from the end before cutting the base prompt at a sentence boundary. It returns
+// the prompt and a note for every change.
+func CompilePrompt(parts PromptParts, opts PromptCompileOptions) (string, []string) {
+	var notes []string
+	base := parts.Base
+	sections := make([]PromptSection, 0, len(parts.Sections))
+	for _, s := range parts.Sections {
+		sections = append(sections, PromptSection{Title: s.Title, Lines: append([]string(nil), s.Lines...)})
+	}
+
+	if opts.StripLocalPaths {
+		removed := 0
+		strip := func(s string) string {
+			out := referenceImageNote.ReplaceAllStringFunc(s, func(string) string { removed++; return "" })
+			out, n := removeLocalPaths(out)
+			removed += n
+			return out
+		}
+		base = strip(base)
+		for i := range sections {
+			for j, line := range sections[i].Lines {
+				sections[i].Lines[j] = strings.TrimRight(strip(line), " :：")
+			}
+		}
+		if removed > 0 {
+			notes = append(notes, fmt.Sprintf("移除了 %d 处本地文件路径", removed))
+		}
+	}
+	base = tidyPrompt(base)
+
+	result := PromptParts{Base: base, Sections: sections}.String()
+	if opts.MaxRunes <= 0 || utf8.RuneCountInString(result) <= opts.MaxRunes {
+		return result, notes
+	}
+
+	// Over the limit: references go first, from the last section backwards
+	dropped := 0
+	for i := len(sections) - 1; i >= 0 && utf8.RuneCountInString(result) > opts.MaxRunes; i-- {
+		for len(sections[i].Lines) > 0 && utf8.RuneCountInString(result) > opts.MaxRunes {
+			sections[i].Lines = sections[i].Lines[:len(sections[i].Lines)-1]
+			dropped++
+			result = PromptParts{Base: base, Sections: sections}.String()
+		}
+	}
+	if dropped > 0 {
+		notes = append(notes, fmt.Sprintf("超出长度上限 %d 字，删去了 %d 条参考信息", opts.MaxRunes, dropped))
+	}
+	if utf8.RuneCountInString(result) > opts.MaxRunes {
+		result = cutAtSentence(result, opts.MaxRunes)
+		notes = append(notes, fmt.Sprintf("提示词超出长度上限 %d 字，已截断", opts.MaxRunes))
+	}
+	return result, notes
+}
+
+// removeLocalPaths deletes local media paths from s; remote URLs such as https://host/a.png are kept.
+func removeLocalPaths(s string) (string, int) {
+	var b strings.Builder
+	removed, last := 0, 0
+	for _, loc := range localMediaPath.FindAllStringIndex(s, -1) {
+		if loc[0] < last || strings.HasSuffix(s[:loc[0]], ":/") || strings.HasSuffix(s[:loc[0]], ":") {
+			continue
+		}
+		// Take the spaces around the path with it, keeping one between two words
+		before := strings.TrimRight(s[last:loc[0]], " \t")
+		end := loc[1]
+		for end < len(s) && (s[end] == ' ' || s[end] == '\t') {
+			end++
+		}
+		b.WriteString(before)
+		if before != "" && end < len(s) && isWordByte(before[len(before)-1]) && isWordByte(s[end]) {
+			b.WriteByte(' ')
+		}
+		last = end
+		removed++
+	}
+	b.WriteString(s[last:])
+	return b.String(), removed
+}
+
+func isWordByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// TruncatePrompt cuts s to at most maxRunes, preferring a sentence boundary.
+func TruncatePrompt(s string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	return cutAtSentence(s, maxRunes)
+}
+
+func tidyPrompt(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// cutAtSentence keeps at most maxRunes runes, ending at the last sentence end when there is one
+// in the second half of the allowed text.
+func cutAtSentence(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	runes = runes[:maxRunes]
+	for i := len(runes) - 1; i >= maxRunes/2; i-- {
+		switch runes[i] {
+		case '。', '！', '？', '；', '.', '!', '?', ';', '\n':
+			return strings.TrimSpace(string(runes[:i+1]))
+		}
+	}
+	return strings.TrimSpace(string(runes))
+}
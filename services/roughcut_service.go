@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"seedance-client/config"
+	"seedance-client/models"
+	"seedance-client/mp4"
+)
+
+// roughCutDir lives under downloads/ so the existing file handler serves it.
+const roughCutDir = "downloads/roughcuts"
+
+// roughCutFormatVersion is part of the cache key; bump it when the remux output changes.
+const roughCutFormatVersion = "1"
+
+// roughCutMu guards the cache directory: looking up a cached cut and installing a finished one.
+// Downloads and remuxing run unlocked, so builds of different projects do not wait on each other.
+var roughCutMu sync.Mutex
+
+// RoughCutResult describes a rough-cut preview of a project
+type RoughCutResult struct {
+	Path     string  `json:"path"`     // data-dir relative, e.g. downloads/roughcuts/project_1_xxx.mp4
+	Clips    int     `json:"clips"`    // number of shots included
+	Duration float64 `json:"duration"` // seconds
+	Cached   bool    `json:"cached"`
+}
+
+// BuildRoughCut concatenates the active take of every shot, in ShotOrder, into a single MP4.
+// Takes are chosen exactly like the default export (latest Good Take, else latest succeeded take),
+// so the preview matches what an NLE export would contain. The result is cached per project and
+// rebuilt only when the set of active takes or their media changes.
+func BuildRoughCut(ctx context.Context, project models.Project) (*RoughCutResult, error) {
+	exports, err := PrepareExportData(project.Storyboards, ExportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(exports) == 0 {
+		return nil, fmt.Errorf("no succeeded videos available for rough cut")
+	}
+
+	key := roughCutKey(exports)
+	relPath := filepath.ToSlash(filepath.Join(roughCutDir, fmt.Sprintf("project_%d_%s.mp4", project.ID, key)))
+	absPath := config.ToAbsolutePath(relPath)
+	roughCutMu.Lock()
+	duration, err := cachedRoughCut(absPath)
+	roughCutMu.Unlock()
+	if err == nil {
+		return &RoughCutResult{Path: relPath, Clips: len(exports), Duration: duration, Cached: true}, nil
+	}
+
+	if err := EnsureDir(filepath.Dir(absPath)); err != nil {
+		return nil, fmt.Errorf("failed to create rough cut directory: %w", err)
+	}
+
+	// Remote-only takes are fetched to temp files first; the remuxer needs random access.
+	client := newExportHTTPClient()
+	paths := make([]string, 0, len(exports))
+	for _, exp := range exports {
+		if !isHTTPURL(exp.VideoURL) {
+			abs := resolveLocalPath(exp.VideoURL)
+			if abs == "" {
+				return nil, fmt.Errorf("video for shot %s not found: %s", exp.ShotNo, exp.VideoURL)
+			}
+			paths = append(paths, abs)
+			continue
+		}
+		tmpPath, err := fetchToTemp(ctx, client, exp.VideoURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download video for shot %s: %w", exp.ShotNo, err)
+		}
+		defer os.Remove(tmpPath)
+		paths = append(paths, tmpPath)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(absPath), ".partial-*")
+	if err != nil {
+		return nil, err
+	}
+	duration, err = ConcatMP4Files(ctx, tmp, paths)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	roughCutMu.Lock()
+	defer roughCutMu.Unlock()
+	if err := os.Rename(tmp.Name(), absPath); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	removeRoughCuts(project.ID, absPath)
+	return &RoughCutResult{Path: relPath, Clips: len(exports), Duration: duration}, nil
+}
+
+// InvalidateRoughCut removes every cached rough cut of a project.
+func InvalidateRoughCut(projectID uint) {
+	roughCutMu.Lock()
+	defer roughCutMu.Unlock()
+	removeRoughCuts(projectID, "")
+}
+
+// removeRoughCuts deletes cached rough cuts of a project except keep.
+func removeRoughCuts(projectID uint, keep string) {
+	pattern := filepath.Join(config.ToAbsolutePath(roughCutDir), fmt.Sprintf("project_%d_*.mp4", projectID))
+	matches, _ := filepath.Glob(pattern)
+	for _, m := range matches {
+		if m != keep {
+			os.Remove(m)
+		}
+	}
+}
+
+// roughCutKey fingerprints the active takes and their media. Local files contribute size and
+// modification time so a re-downloaded take invalidates the cache; remote results are immutable per URL.
+func roughCutKey(exports []ExportData) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%s\n", roughCutFormatVersion)
+	for _, exp := range exports {
+		fmt.Fprintf(h, "%d|%d|%s", exp.StoryboardID, exp.TakeID, strings.TrimSpace(exp.VideoURL))
+		if !isHTTPURL(exp.VideoURL) {
+			if info, err := os.Stat(resolveLocalPath(exp.VideoURL)); err == nil {
+				fmt.Fprintf(h, "|%d|%d", info.Size(), info.ModTime().UnixNano())
+			}
+		}
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// cachedRoughCut returns the duration of the cached cut at absPath, or an error when there is none.
+func cachedRoughCut(absPath string) (float64, error) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		return 0, fmt.Errorf("empty rough cut %s", absPath)
+	}
+	return roughCutDuration(absPath)
+}
+
+// roughCutDuration reads the duration of a cached rough cut from its movie header.
+func roughCutDuration(path string) (float64, error) {
+	movie, err := mp4.ParseFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return movie.Seconds(), nil
+}
+
+// fetchToTemp downloads src into a temp file and returns its path.
+func fetchToTemp(ctx context.Context, client *http.Client, src string) (string, error) {
+	reader, _, err := openExportSource(ctx, client, src)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	tmp, err := os.CreateTemp("", "roughcut_*.mp4")
+	if err != nil {
+		return "", err
+	}
+	_, err = copyWithProgress(ctx, tmp, reader, nil)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
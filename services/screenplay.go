@@ -0,0 +1,435 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Screenplay is a script parsed from Fountain or Final Draft into scenes.
+type Screenplay struct {
+	Title  string            `json:"title"`
+	Scenes []ScreenplayScene `json:"scenes"`
+}
+
+// ScreenplayScene is one scene; text before the first scene heading forms a scene without a
+// heading.
+type ScreenplayScene struct {
+	Heading  string              `json:"heading"`  // as written, e.g. "INT. KITCHEN - NIGHT"
+	Location string              `json:"location"` // e.g. "KITCHEN"
+	Time     string              `json:"time"`     // e.g. "NIGHT"
+	Elements []ScreenplayElement `json:"elements"`
+}
+
+// ScreenplayElement is a paragraph of a scene.
+type ScreenplayElement struct {
+	Type          string `json:"type"` // action | dialogue | transition
+	Character     string `json:"character,omitempty"`
+	Parenthetical string `json:"parenthetical,omitempty"`
+	Text          string `json:"text"`
+}
+
+// Characters lists the speaking characters in order of first appearance.
+func (s *Screenplay) Characters() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, scene := range s.Scenes {
+		for _, e := range scene.Elements {
+			if e.Type != "dialogue" || e.Character == "" {
+				continue
+			}
+			key := strings.ToLower(e.Character)
+			if !seen[key] {
+				seen[key] = true
+				names = append(names, e.Character)
+			}
+		}
+	}
+	return names
+}
+
+// Locations lists the scene locations in order of first appearance.
+func (s *Screenplay) Locations() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, scene := range s.Scenes {
+		key := strings.ToLower(scene.Location)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, scene.Location)
+	}
+	return names
+}
+
+// Markdown renders the screenplay as decomposition source text: a heading per scene, action as
+// paragraphs and dialogue as "CHARACTER (parenthetical): line".
+func (s *Screenplay) Markdown() string {
+	var b strings.Builder
+	if s.Title != "" {
+		b.WriteString("# " + s.Title + "\n\n")
+	}
+	for _, scene := range s.Scenes {
+		if scene.Heading != "" {
+			b.WriteString("## " + scene.Heading + "\n\n")
+		}
+		for _, e := range scene.Elements {
+			switch e.Type {
+			case "dialogue":
+				b.WriteString(e.Character)
+				if e.Parenthetical != "" {
+					b.WriteString(" " + e.Parenthetical)
+				}
+				b.WriteString(": " + e.Text + "\n\n")
+			case "transition":
+				b.WriteString("> " + e.Text + "\n\n")
+			default:
+				b.WriteString(e.Text + "\n\n")
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ============================================================
+// Fountain
+// ============================================================
+
+var (
+	fountainBoneyard     = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	fountainNote         = regexp.MustCompile(`(?s)[ \t]*\[\[.*?\]\]`)
+	fountainHeadingRe    = regexp.MustCompile(`(?i)^(int|ext|est|int\.?/ext|i/e)[\. ]`)
+	fountainCJKHeadingRe = regexp.MustCompile(`^(内景|外景|内外景|内/外|外/内)`)
+	fountainTitleKeyRe   = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*:`)
+	fountainTransitionRe = regexp.MustCompile(`^[A-Z0-9 .'\-]+TO:$`)
+	fountainExtensionRe  = regexp.MustCompile(`\s*(\([^)]*\)\s*)+$`)
+)
+
+// ParseFountain parses a Fountain screenplay. Scene headings start with INT./EXT./EST./I/E,
+// 内景/外景, or are forced with a leading "."; character cues are all-caps lines followed by
+// dialogue, or forced with "@". Notes, boneyard, sections and synopses are dropped.
+func ParseFountain(text string) *Screenplay {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = fountainBoneyard.ReplaceAllString(text, "")
+	text = fountainNote.ReplaceAllString(text, "")
+	lines := strings.Split(text, "\n")
+
+	sp := &Screenplay{}
+	i := parseFountainTitlePage(lines, sp)
+
+	scene := &ScreenplayScene{}
+	flush := func() {
+		if scene.Heading != "" || len(scene.Elements) > 0 {
+			sp.Scenes = append(sp.Scenes, *scene)
+		}
+	}
+	blankBefore := true
+	for i < len(lines) {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			blankBefore = true
+			i++
+			continue
+		}
+		next := ""
+		if i+1 < len(lines) {
+			next = strings.TrimSpace(lines[i+1])
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#") || strings.HasPrefix(line, "=") && !strings.HasPrefix(line, "==="):
+			// Section or synopsis
+			i++
+		case strings.HasPrefix(line, "==="):
+			// Page break
+			i++
+		case blankBefore && isFountainHeading(line):
+			flush()
+			heading := strings.TrimSpace(strings.TrimPrefix(line, "."))
+			location, timeOfDay := ParseSceneHeading(heading)
+			scene = &ScreenplayScene{Heading: stripSceneNumber(heading), Location: location, Time: timeOfDay}
+			i++
+		case isFountainTransition(line, blankBefore, next):
+			scene.Elements = append(scene.Elements, ScreenplayElement{Type: "transition", Text: strings.TrimSpace(strings.TrimPrefix(line, ">"))})
+			i++
+		case blankBefore && next != "" && isFountainCue(line):
+			i = parseFountainDialogue(lines, i, scene)
+		default:
+			// Action runs to the next blank line
+			var paragraph []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				l := strings.TrimSpace(lines[i])
+				l = strings.TrimPrefix(l, "!")
+				if strings.HasPrefix(l, ">") && strings.HasSuffix(l, "<") {
+					l = strings.TrimSpace(l[1 : len(l)-1])
+				}
+				paragraph = append(paragraph, l)
+				i++
+			}
+			scene.Elements = append(scene.Elements, ScreenplayElement{Type: "action", Text: strings.Join(paragraph, "\n")})
+		}
+		blankBefore = false
+	}
+	flush()
+	return sp
+}
+
+// parseFountainTitlePage reads "Key: value" lines at the top of the file and returns the index
+// of the first line after them.
+func parseFountainTitlePage(lines []string, sp *Screenplay) int {
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start >= len(lines) || !fountainTitleKeyRe.MatchString(strings.TrimSpace(lines[start])) {
+		return 0
+	}
+	i := start
+	key := ""
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		line := lines[i]
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && fountainTitleKeyRe.MatchString(line) {
+			k, v, _ := strings.Cut(line, ":")
+			key = strings.ToLower(strings.TrimSpace(k))
+			if key == "title" && strings.TrimSpace(v) != "" {
+				sp.Title = strings.TrimSpace(v)
+			}
+			continue
+		}
+		if key == "title" {
+			sp.Title = strings.TrimSpace(sp.Title + " " + strings.TrimSpace(line))
+		}
+	}
+	sp.Title = strings.Trim(sp.Title, "*_ ")
+	return i
+}
+
+func parseFountainDialogue(lines []string, i int, scene *ScreenplayScene) int {
+	character := cleanCharacterCue(strings.TrimPrefix(strings.TrimSpace(lines[i]), "@"))
+	i++
+	e := ScreenplayElement{Type: "dialogue", Character: character}
+	var speech []string
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+		l := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(l, "(") && strings.HasSuffix(l, ")") {
+			if len(speech) == 0 && e.Parenthetical == "" {
+				e.Parenthetical = l
+			} else {
+				speech = append(speech, l)
+			}
+		} else {
+			speech = append(speech, l)
+		}
+		i++
+	}
+	e.Text = strings.Join(speech, "\n")
+	scene.Elements = append(scene.Elements, e)
+	return i
+}
+
+func isFountainHeading(line string) bool {
+	if strings.HasPrefix(line, ".") {
+		return len(line) > 1 && line[1] != '.'
+	}
+	return fountainHeadingRe.MatchString(line) || fountainCJKHeadingRe.MatchString(line)
+}
+
+func isFountainTransition(line string, blankBefore bool, next string) bool {
+	if strings.HasPrefix(line, ">") {
+		return !strings.HasSuffix(line, "<")
+	}
+	return blankBefore && next == "" && fountainTransitionRe.MatchString(line)
+}
+
+// isFountainCue reports whether a line is a character cue: forced with "@", or an all-caps
+// name, possibly with extensions such as "(V.O.)". A "!" forces action, even in caps.
+func isFountainCue(line string) bool {
+	if strings.HasPrefix(line, "@") {
+		return len(line) > 1
+	}
+	if strings.HasPrefix(line, "!") {
+		return false
+	}
+	name := fountainExtensionRe.ReplaceAllString(strings.TrimSuffix(line, "^"), "")
+	hasLetter := false
+	for _, r := range name {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsUpper(r) {
+			hasLetter = true
+		}
+	}
+	return hasLetter
+}
+
+// cleanCharacterCue removes extensions such as "(V.O.)" or "(CONT'D)" and the dual-dialogue
+// marker "^" from a character cue.
+func cleanCharacterCue(cue string) string {
+	cue = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(cue), "^"))
+	return strings.TrimSpace(fountainExtensionRe.ReplaceAllString(cue, ""))
+}
+
+// ============================================================
+// Final Draft (.fdx)
+// ============================================================
+
+type fdxDocument struct {
+	Paragraphs []fdxParagraph `xml:"Content>Paragraph"`
+	TitlePage  []fdxParagraph `xml:"TitlePage>Content>Paragraph"`
+}
+
+type fdxParagraph struct {
+	Type         string         `xml:"Type,attr"`
+	Number       string         `xml:"Number,attr"`
+	Texts        []string       `xml:"Text"`
+	DualDialogue []fdxParagraph `xml:"DualDialogue>Paragraph"`
+}
+
+func (p fdxParagraph) text() string {
+	return strings.TrimSpace(strings.Join(p.Texts, ""))
+}
+
+// ParseFDX parses a Final Draft .fdx document. The title is the first line of the title page.
+func ParseFDX(data []byte) (*Screenplay, error) {
+	var doc fdxDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("无法解析 FDX 文件：%w", err)
+	}
+
+	sp := &Screenplay{}
+	for _, p := range doc.TitlePage {
+		if t := p.text(); t != "" {
+			sp.Title = t
+			break
+		}
+	}
+
+	// Dual dialogue is read as the two speeches in order
+	var paragraphs []fdxParagraph
+	for _, p := range doc.Paragraphs {
+		if len(p.DualDialogue) > 0 {
+			paragraphs = append(paragraphs, p.DualDialogue...)
+			continue
+		}
+		paragraphs = append(paragraphs, p)
+	}
+
+	scene := &ScreenplayScene{}
+	flush := func() {
+		if scene.Heading != "" || len(scene.Elements) > 0 {
+			sp.Scenes = append(sp.Scenes, *scene)
+		}
+	}
+	var dialogue *ScreenplayElement
+	endDialogue := func() {
+		if dialogue != nil {
+			scene.Elements = append(scene.Elements, *dialogue)
+			dialogue = nil
+		}
+	}
+	for _, p := range paragraphs {
+		text := p.text()
+		switch p.Type {
+		case "Scene Heading":
+			endDialogue()
+			flush()
+			location, timeOfDay := ParseSceneHeading(text)
+			scene = &ScreenplayScene{Heading: text, Location: location, Time: timeOfDay}
+		case "Character":
+			endDialogue()
+			dialogue = &ScreenplayElement{Type: "dialogue", Character: cleanCharacterCue(text)}
+		case "Parenthetical":
+			if dialogue == nil {
+				continue
+			}
+			if dialogue.Text == "" && dialogue.Parenthetical == "" {
+				dialogue.Parenthetical = text
+			} else {
+				dialogue.Text = strings.TrimSpace(dialogue.Text + "\n" + text)
+			}
+		case "Dialogue":
+			if dialogue == nil {
+				scene.Elements = append(scene.Elements, ScreenplayElement{Type: "action", Text: text})
+				continue
+			}
+			dialogue.Text = strings.TrimSpace(dialogue.Text + "\n" + text)
+		case "Transition":
+			endDialogue()
+			if text != "" {
+				scene.Elements = append(scene.Elements, ScreenplayElement{Type: "transition", Text: text})
+			}
+		default:
+			// Action, Shot, General and custom paragraph types
+			endDialogue()
+			if text != "" {
+				scene.Elements = append(scene.Elements, ScreenplayElement{Type: "action", Text: text})
+			}
+		}
+	}
+	endDialogue()
+	flush()
+	return sp, nil
+}
+
+// ============================================================
+// Scene Headings
+// ============================================================
+
+var (
+	sceneNumberRe     = regexp.MustCompile(`\s*#[^#]+#\s*$`)
+	headingPrefixRe   = regexp.MustCompile(`(?i)^\.?\s*(int\.?\s*/\s*ext\.?|ext\.?\s*/\s*int\.?|i\s*/\s*e\.?|int\.|ext\.|est\.|int |ext |est |内外景|内/外|外/内|内景|外景)\s*`)
+	headingTimeSepRe  = regexp.MustCompile(`\s+[-–—]+\s+`)
+	headingTimeTokens = map[string]bool{
+		"日": true, "夜": true, "晨": true, "昏": true, "白天": true, "夜晚": true, "清晨": true, "早晨": true,
+		"黄昏": true, "傍晚": true, "深夜": true, "午后": true, "中午": true, "日内": true, "夜内": true,
+		"日外": true, "夜外": true, "内": true, "外": true,
+	}
+)
+
+// ParseSceneHeading splits a scene heading such as "INT. KITCHEN - NIGHT" or "内景 客厅 日"
+// into its location and time of day. Either may be empty.
+func ParseSceneHeading(heading string) (string, string) {
+	h := stripSceneNumber(strings.TrimSpace(heading))
+	h = headingPrefixRe.ReplaceAllString(h, "")
+	if parts := headingTimeSepRe.Split(h, -1); len(parts) > 1 {
+		return strings.TrimSpace(strings.Join(parts[:len(parts)-1], " - ")), strings.TrimSpace(parts[len(parts)-1])
+	}
+	// Chinese headings put the time last, separated by spaces: "客厅 日" or "1-3 客厅 日 内"
+	fields := strings.Fields(h)
+	var timeParts []string
+	for len(fields) > 1 && headingTimeTokens[fields[len(fields)-1]] {
+		timeParts = append([]string{fields[len(fields)-1]}, timeParts...)
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) > 1 && isSceneIndex(fields[0]) {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " "), strings.Join(timeParts, " ")
+}
+
+func stripSceneNumber(heading string) string {
+	return strings.TrimSpace(sceneNumberRe.ReplaceAllString(heading, ""))
+}
+
+// isSceneIndex reports whether a token is a scene number such as "12", "1-3" or "12A".
+func isSceneIndex(token string) bool {
+	digits := false
+	for _, r := range token {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == '-' || r == '.' || r >= 'A' && r <= 'Z':
+		default:
+			return false
+		}
+	}
+	return digits
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSceneHeading(t *testing.T) {
+	cases := []struct {
+		heading, location, time string
+	}{
+		{"INT. KITCHEN - NIGHT", "KITCHEN", "NIGHT"},
+		{"ext. street – day", "street", "day"},
+		{"EXT. STREET - DAY #12#", "STREET", "DAY"},
+		{"INT./EXT. CAR - MOVING - NIGHT", "CAR - MOVING", "NIGHT"},
+		{"I/E CAR - DAY", "CAR", "DAY"},
+		{"EST. CITY SKYLINE", "CITY SKYLINE", ""},
+		{"FLASHBACK", "FLASHBACK", ""},
+		{"内景 客厅 日", "客厅", "日"},
+		{"外景 街道 夜 #3A#", "街道", "夜"},
+		{"内外景 车内 - 黄昏", "车内", "黄昏"},
+		{"1-3 客厅 日 内", "客厅", "日 内"},
+		{"12A 天台 深夜", "天台", "深夜"},
+		{"内景 客厅", "客厅", ""},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		location, timeOfDay := ParseSceneHeading(tc.heading)
+		if location != tc.location || timeOfDay != tc.time {
+			t.Errorf("ParseSceneHeading(%q) = %q, %q, want %q, %q", tc.heading, location, timeOfDay, tc.location, tc.time)
+		}
+	}
+}
+
+func TestFountainCues(t *testing.T) {
+	cases := []struct {
+		line string
+		cue  bool
+		name string
+	}{
+		{"JOHN", true, "JOHN"},
+		{"JOHN (V.O.)", true, "JOHN"},
+		{"DR. SMITH (O.S.) (CONT'D)", true, "DR. SMITH"},
+		{"MARY ^", true, "MARY"},
+		{"@McCLANE", true, "McCLANE"},
+		{"@", false, ""},
+		{"John", false, ""},
+		{"R2D2", true, "R2D2"},
+		{"123", false, ""},
+		{"张三", false, ""},
+		{"!LOUD NOISE", false, ""},
+	}
+	for _, tc := range cases {
+		if got := isFountainCue(tc.line); got != tc.cue {
+			t.Errorf("isFountainCue(%q) = %v, want %v", tc.line, got, tc.cue)
+		}
+		if tc.cue {
+			if got := cleanCharacterCue(strings.TrimPrefix(tc.line, "@")); got != tc.name {
+				t.Errorf("cleanCharacterCue(%q) = %q, want %q", tc.line, got, tc.name)
+			}
+		}
+	}
+}
+
+func TestParseFountain(t *testing.T) {
+	script := "Title: **The Night**\n" +
+		"Credit: Written by\n" +
+		"Author: A. Writer\n" +
+		"\n" +
+		"FADE IN:\n" +
+		"\n" +
+		"INT. KITCHEN - NIGHT #1#\n" +
+		"\n" +
+		"John enters./* old\ndraft */ He sits.[[check blocking]]\n" +
+		"\n" +
+		"JOHN (V.O.) (CONT'D)\n" +
+		"(quietly)\n" +
+		"Where is she?\n" +
+		"\n" +
+		"MARY ^\n" +
+		"Gone.\n" +
+		"\n" +
+		"!SILENCE.\n" +
+		"It lasts.\n" +
+		"\n" +
+		"CUT TO:\n" +
+		"\n" +
+		".FLASHBACK\n" +
+		"\n" +
+		"@McCLANE\n" +
+		"Yippee.\n" +
+		"\n" +
+		"# Act Two\n" +
+		"= The search begins\n" +
+		"\n" +
+		"内景 客厅 日\r\n" +
+		"\r\n" +
+		"@张三\r\n" +
+		"（低声）你好。\r\n" +
+		"\r\n" +
+		"> THE END <\n"
+
+	got := ParseFountain(script)
+	want := &Screenplay{
+		Title: "The Night",
+		Scenes: []ScreenplayScene{
+			{Elements: []ScreenplayElement{{Type: "action", Text: "FADE IN:"}}},
+			{
+				Heading: "INT. KITCHEN - NIGHT", Location: "KITCHEN", Time: "NIGHT",
+				Elements: []ScreenplayElement{
+					{Type: "action", Text: "John enters. He sits."},
+					{Type: "dialogue", Character: "JOHN", Parenthetical: "(quietly)", Text: "Where is she?"},
+					{Type: "dialogue", Character: "MARY", Text: "Gone."},
+					{Type: "action", Text: "SILENCE.\nIt lasts."},
+					{Type: "transition", Text: "CUT TO:"},
+				},
+			},
+			{
+				Heading: "FLASHBACK", Location: "FLASHBACK",
+				Elements: []ScreenplayElement{{Type: "dialogue", Character: "McCLANE", Text: "Yippee."}},
+			},
+			{
+				Heading: "内景 客厅 日", Location: "客厅", Time: "日",
+				Elements: []ScreenplayElement{
+					{Type: "dialogue", Character: "张三", Text: "（低声）你好。"},
+					{Type: "action", Text: "THE END"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseFountain =\n%+v\nwant\n%+v", got, want)
+	}
+	if chars := got.Characters(); !reflect.DeepEqual(chars, []string{"JOHN", "MARY", "McCLANE", "张三"}) {
+		t.Errorf("characters = %q", chars)
+	}
+	if locations := got.Locations(); !reflect.DeepEqual(locations, []string{"KITCHEN", "FLASHBACK", "客厅"}) {
+		t.Errorf("locations = %q", locations)
+	}
+}
+
+func TestParseFountainWithoutTitlePage(t *testing.T) {
+	got := ParseFountain("\uFEFFEXT. ROOF - DAY\n\nWind.\n")
+	want := &Screenplay{Scenes: []ScreenplayScene{{
+		Heading: "EXT. ROOF - DAY", Location: "ROOF", Time: "DAY",
+		Elements: []ScreenplayElement{{Type: "action", Text: "Wind."}},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFountain = %+v", got)
+	}
+	// A heading needs a blank line before it
+	got = ParseFountain("Wind.\nINT. HALL - DAY\n")
+	if len(got.Scenes) != 1 || got.Scenes[0].Heading != "" || got.Scenes[0].Elements[0].Text != "Wind.\nINT. HALL - DAY" {
+		t.Errorf("heading without a blank line = %+v", got)
+	}
+}
+
+func TestParseFDX(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8" standalone="no" ?>
+<FinalDraft DocumentType="Script" Template="No" Version="5">
+<Content>
+<Paragraph Type="Action"><Text>Rain.</Text></Paragraph>
+<Paragraph Number="1" Type="Scene Heading"><Text>INT. KITCHEN - NIGHT</Text></Paragraph>
+<Paragraph Type="Action"><Text>John </Text><Text Style="Bold">enters</Text><Text>.</Text></Paragraph>
+<Paragraph Type="Character"><Text>JOHN (CONT'D)</Text></Paragraph>
+<Paragraph Type="Parenthetical"><Text>(quietly)</Text></Paragraph>
+<Paragraph Type="Dialogue"><Text>Where is she?</Text></Paragraph>
+<Paragraph Type="Parenthetical"><Text>(beat)</Text></Paragraph>
+<Paragraph Type="Dialogue"><Text>Mary?</Text></Paragraph>
+<Paragraph><DualDialogue>
+<Paragraph Type="Character"><Text>MARY</Text></Paragraph>
+<Paragraph Type="Dialogue"><Text>Here.</Text></Paragraph>
+<Paragraph Type="Character"><Text>BOB (O.S.)</Text></Paragraph>
+<Paragraph Type="Dialogue"><Text>There.</Text></Paragraph>
+</DualDialogue></Paragraph>
+<Paragraph Type="Transition"><Text>CUT TO:</Text></Paragraph>
+<Paragraph Type="Scene Heading"><Text>内景 客厅 日</Text></Paragraph>
+<Paragraph Type="Dialogue"><Text>无人应答。</Text></Paragraph>
+<Paragraph Type="Shot"><Text>CLOSE ON the door</Text></Paragraph>
+<Paragraph Type="Parenthetical"><Text>(stray)</Text></Paragraph>
+<Paragraph Type="Transition"><Text></Text></Paragraph>
+</Content>
+<TitlePage><Content>
+<Paragraph Type="Text"><Text></Text></Paragraph>
+<Paragraph Type="Text"><Text>The Night</Text></Paragraph>
+<Paragraph Type="Text"><Text>by A. Writer</Text></Paragraph>
+</Content></TitlePage>
+</FinalDraft>`
+	got, err := ParseFDX([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Screenplay{
+		Title: "The Night",
+		Scenes: []ScreenplayScene{
+			{Elements: []ScreenplayElement{{Type: "action", Text: "Rain."}}},
+			{
+				Heading: "INT. KITCHEN - NIGHT", Location: "KITCHEN", Time: "NIGHT",
+				Elements: []ScreenplayElement{
+					{Type: "action", Text: "John enters."},
+					{Type: "dialogue", Character: "JOHN", Parenthetical: "(quietly)", Text: "Where is she?\n(beat)\nMary?"},
+					{Type: "dialogue", Character: "MARY", Text: "Here."},
+					{Type: "dialogue", Character: "BOB", Text: "There."},
+					{Type: "transition", Text: "CUT TO:"},
+				},
+			},
+			{
+				Heading: "内景 客厅 日", Location: "客厅", Time: "日",
+				Elements: []ScreenplayElement{
+					{Type: "action", Text: "无人应答。"},
+					{Type: "action", Text: "CLOSE ON the door"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseFDX =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := ParseFDX([]byte("not xml")); err == nil {
+		t.Error("parsed a non-FDX file")
+	}
+}
+
+func TestScreenplayMarkdown(t *testing.T) {
+	sp := &Screenplay{
+		Title: "The Night",
+		Scenes: []ScreenplayScene{
+			{Elements: []ScreenplayElement{{Type: "action", Text: "Rain."}}},
+			{Heading: "INT. KITCHEN - NIGHT", Elements: []ScreenplayElement{
+				{Type: "dialogue", Character: "JOHN", Parenthetical: "(quietly)", Text: "Where?"},
+				{Type: "dialogue", Character: "MARY", Text: "Here."},
+				{Type: "transition", Text: "CUT TO:"},
+			}},
+		},
+	}
+	want := "# The Night\n\nRain.\n\n## INT. KITCHEN - NIGHT\n\nJOHN (quietly): Where?\n\nMARY: Here.\n\n> CUT TO:"
+	if got := sp.Markdown(); got != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", got, want)
+	}
+}
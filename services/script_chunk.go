@@ -0,0 +1,155 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// sceneHeadingPattern matches lines that open a new scene: screenplay sluglines (INT./EXT.),
+// Chinese script headings (内景/外景, 第3场, 场景12) and markdown headings.
+var sceneHeadingPattern = regexp.MustCompile(`(?i)^\s*(?:` +
+	`(?:\d+[\.\-、:：\s]*)?(?:int\.?/ext\.?|i/e|int\.|ext\.|int |ext |内景|外景|内/外景|日/夜)` +
+	`|第\s*[0-9一二三四五六七八九十百千零〇]+\s*[场幕集节章]` +
+	`|场景\s*[0-9一二三四五六七八九十百千零〇]+` +
+	`|#{1,6}\s` +
+	`)`)
+
+// SplitScriptChunks splits a script into chunks of at most maxRunes runes, cutting at scene
+// headings when there are any and at paragraph breaks otherwise. Scenes are never split unless a
+// single scene is longer than maxRunes, in which case it is cut at paragraph, line and finally rune
+// boundaries. Text shorter than maxRunes is returned as a single chunk.
+func SplitScriptChunks(text string, maxRunes int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+	if maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes {
+		return []string{text}
+	}
+
+	blocks := splitScenes(text)
+	if len(blocks) <= 1 {
+		blocks = splitParagraphs(text)
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	curRunes := 0
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+		curRunes = 0
+	}
+	for _, block := range blocks {
+		for _, piece := range fitBlock(block, maxRunes) {
+			n := utf8.RuneCountInString(piece)
+			if curRunes > 0 && curRunes+n+2 > maxRunes {
+				flush()
+			}
+			if curRunes > 0 {
+				cur.WriteString("\n\n")
+				curRunes += 2
+			}
+			cur.WriteString(piece)
+			curRunes += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitScenes cuts text before every scene heading line.
+func splitScenes(text string) []string {
+	var blocks []string
+	var cur []string
+	for _, line := range strings.Split(text, "\n") {
+		if sceneHeadingPattern.MatchString(line) && len(cur) > 0 {
+			blocks = append(blocks, strings.TrimSpace(strings.Join(cur, "\n")))
+			cur = nil
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		blocks = append(blocks, strings.TrimSpace(strings.Join(cur, "\n")))
+	}
+	return nonEmpty(blocks)
+}
+
+var blankLinePattern = regexp.MustCompile(`\n\s*\n`)
+
+func splitParagraphs(text string) []string {
+	return nonEmpty(blankLinePattern.Split(text, -1))
+}
+
+// fitBlock returns block unchanged when it fits, otherwise cuts it into pieces of at most maxRunes.
+func fitBlock(block string, maxRunes int) []string {
+	if utf8.RuneCountInString(block) <= maxRunes {
+		return []string{block}
+	}
+	if paras := splitParagraphs(block); len(paras) > 1 {
+		return packPieces(paras, "\n\n", maxRunes)
+	}
+	if lines := nonEmpty(strings.Split(block, "\n")); len(lines) > 1 {
+		return packPieces(lines, "\n", maxRunes)
+	}
+	runes := []rune(block)
+	var out []string
+	for len(runes) > maxRunes {
+		cut := sentenceCut(runes[:maxRunes])
+		out = append(out, strings.TrimSpace(string(runes[:cut])))
+		runes = runes[cut:]
+	}
+	if s := strings.TrimSpace(string(runes)); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// packPieces joins consecutive pieces while they fit, recursing into pieces that are too long.
+func packPieces(pieces []string, sep string, maxRunes int) []string {
+	var out []string
+	cur, curRunes := "", 0
+	for _, p := range pieces {
+		for _, q := range fitBlock(p, maxRunes) {
+			n := utf8.RuneCountInString(q)
+			if curRunes > 0 && curRunes+len(sep)+n > maxRunes {
+				out = append(out, cur)
+				cur, curRunes = "", 0
+			}
+			if curRunes > 0 {
+				cur += sep
+				curRunes += len(sep)
+			}
+			cur += q
+			curRunes += n
+		}
+	}
+	if cur != "" {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// sentenceCut prefers to cut after the last sentence-ending punctuation in the second half of runes.
+func sentenceCut(runes []rune) int {
+	for i := len(runes) - 1; i >= len(runes)/2; i-- {
+		switch runes[i] {
+		case '。', '！', '？', '；', '.', '!', '?', ';':
+			return i + 1
+		}
+	}
+	return len(runes)
+}
+
+func nonEmpty(items []string) []string {
+	out := items[:0]
+	for _, s := range items {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSceneHeadingPattern(t *testing.T) {
+	cases := []struct {
+		line string
+		want bool
+	}{
+		{"INT. HOUSE - DAY", true},
+		{"ext. street - night", true},
+		{"INT./EXT. CAR - DAY", true},
+		{"12. EXT. STREET - NIGHT", true},
+		{"内景 客厅 日", true},
+		{"外景　街道　夜", true},
+		{"第3场", true},
+		{"第十二场 天台", true},
+		{"场景 12", true},
+		{"## 第一幕", true},
+		{"Interior design matters.", false},
+		{"他走进内景棚", false},
+		{"#hashtag", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := sceneHeadingPattern.MatchString(tc.line); got != tc.want {
+			t.Errorf("sceneHeadingPattern(%q) = %v, want %v", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestSplitScriptChunks(t *testing.T) {
+	scene := func(heading string, n int) string {
+		return heading + "\n" + strings.Repeat("字", n)
+	}
+	cases := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     []string
+	}{
+		{
+			name:     "empty",
+			text:     " \r\n ",
+			maxRunes: 10,
+			want:     nil,
+		},
+		{
+			name:     "fits in one chunk",
+			text:     "内景 客厅 日\r\n他坐下。\r\n",
+			maxRunes: 100,
+			want:     []string{"内景 客厅 日\n他坐下。"},
+		},
+		{
+			name:     "scenes are packed and never split",
+			text:     scene("内景 客厅 日", 30) + "\n" + scene("外景 街道 夜", 30) + "\n\n" + scene("内景 客厅 夜", 30),
+			maxRunes: 90,
+			want: []string{
+				scene("内景 客厅 日", 30) + "\n\n" + scene("外景 街道 夜", 30),
+				scene("内景 客厅 夜", 30),
+			},
+		},
+		{
+			name:     "paragraphs without headings",
+			text:     "第一段。\n\n第二段。\n \n第三段，比较长的一段。",
+			maxRunes: 12,
+			want:     []string{"第一段。\n\n第二段。", "第三段，比较长的一段。"},
+		},
+		{
+			name:     "long scene cut at lines",
+			text:     "INT. HOUSE - DAY\nAAAA AAAA\nBBBB BBBB\nCCCC CCCC",
+			maxRunes: 20,
+			want:     []string{"INT. HOUSE - DAY", "AAAA AAAA\nBBBB BBBB", "CCCC CCCC"},
+		},
+		{
+			name:     "long line cut after a sentence",
+			text:     "一二三四五。六七八九十",
+			maxRunes: 8,
+			want:     []string{"一二三四五。", "六七八九十"},
+		},
+		{
+			name:     "no punctuation cuts at the limit",
+			text:     strings.Repeat("字", 10),
+			maxRunes: 4,
+			want:     []string{"字字字字", "字字字字", "字字"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SplitScriptChunks(tc.text, tc.maxRunes)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("chunks = %q, want %q", got, tc.want)
+			}
+			for i, c := range got {
+				if n := utf8.RuneCountInString(c); n > tc.maxRunes {
+					t.Errorf("chunk %d has %d runes, limit %d", i, n, tc.maxRunes)
+				}
+			}
+			strip := func(s string) string { return strings.Join(strings.Fields(s), "") }
+			if strip(strings.Join(got, "")) != strip(tc.text) {
+				t.Errorf("chunks lost text: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"seedance-client/config"
+)
+
+// Secrets (API keys) are stored encrypted with AES-256-GCM. The key is derived from the machine ID
+// and a random salt file in the data directory, so a copied database cannot be decrypted on another
+// computer and a copied data directory alone is not enough without the same machine.
+const (
+	secretPrefix   = "enc:v1:"
+	secretSaltFile = ".secret_salt"
+)
+
+// ErrSecretUndecryptable is returned for secrets encrypted on another machine or with a lost salt.
+var ErrSecretUndecryptable = errors.New("无法解密已保存的密钥（可能来自另一台电脑），请重新填写")
+
+var (
+	secretKeyOnce sync.Once
+	secretKey     []byte
+	secretKeyErr  error
+)
+
+// IsEncryptedSecret reports whether s was produced by EncryptSecret.
+func IsEncryptedSecret(s string) bool {
+	return strings.HasPrefix(s, secretPrefix)
+}
+
+// EncryptSecret encrypts plain for storage. An empty secret stays empty.
+func EncryptSecret(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), []byte(secretPrefix))
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret. Values without the encryption prefix are legacy
+// plaintext and are returned unchanged.
+func DecryptSecret(stored string) (string, error) {
+	if !IsEncryptedSecret(stored) {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	if err != nil {
+		return "", ErrSecretUndecryptable
+	}
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrSecretUndecryptable
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(secretPrefix))
+	if err != nil {
+		return "", ErrSecretUndecryptable
+	}
+	return string(plain), nil
+}
+
+// MaskSecret shows only the ends of a secret, e.g. "sk-a…wxyz".
+func MaskSecret(s string) string {
+	r := []rune(s)
+	if len(r) <= 8 {
+		return strings.Repeat("•", len(r))
+	}
+	return string(r[:4]) + "…" + string(r[len(r)-4:])
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	secretKeyOnce.Do(func() {
+		salt, err := loadSecretSalt()
+		if err != nil {
+			secretKeyErr = fmt.Errorf("初始化密钥存储失败：%w", err)
+			return
+		}
+		h := sha256.New()
+		h.Write([]byte("seedance-client/secret/v1\x00"))
+		h.Write([]byte(MachineID()))
+		h.Write([]byte{0})
+		h.Write(salt)
+		secretKey = h.Sum(nil)
+	})
+	if secretKeyErr != nil {
+		return nil, secretKeyErr
+	}
+	block, err := aes.NewCipher(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadSecretSalt reads the salt file, creating it on first use. An unreadable or short salt
+// file is an error rather than replaced, since a new salt would lose every stored secret.
+func loadSecretSalt() ([]byte, error) {
+	path := filepath.Join(config.GetDataDir(), secretSaltFile)
+	salt, err := os.ReadFile(path)
+	if err == nil {
+		if len(salt) < 32 {
+			return nil, fmt.Errorf("密钥盐文件 %s 已损坏（%d 字节）", path, len(salt))
+		}
+		return salt, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	salt = make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, salt, 0600); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+var (
+	machineIDOnce sync.Once
+	machineID     string
+)
+
+// MachineID returns a stable identifier of this computer, falling back to the host name.
+func MachineID() string {
+	machineIDOnce.Do(func() {
+		machineID = strings.TrimSpace(platformMachineID())
+		if machineID == "" {
+			machineID, _ = os.Hostname()
+		}
+	})
+	return machineID
+}
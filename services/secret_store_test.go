@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"seedance-client/config"
+)
+
+// useSecretDir points the data directory at dir and drops the cached key, so the salt in dir is used.
+func useSecretDir(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("SEEDANCE_DATA_DIR", dir)
+	config.InitDataDir()
+	secretKeyOnce, secretKey, secretKeyErr = sync.Once{}, nil, nil
+	t.Cleanup(func() { secretKeyOnce, secretKey, secretKeyErr = sync.Once{}, nil, nil })
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	useSecretDir(t, dir)
+	for _, plain := range []string{"sk-abcdef123456", "密钥 with spaces", "k_1"} {
+		stored, err := EncryptSecret(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncryptedSecret(stored) || strings.Contains(stored, plain) {
+			t.Errorf("stored %q for %q", stored, plain)
+		}
+		again, _ := EncryptSecret(plain)
+		if again == stored {
+			t.Error("nonce reused")
+		}
+		got, err := DecryptSecret(stored)
+		if err != nil || got != plain {
+			t.Errorf("DecryptSecret = %q, %v, want %q", got, err, plain)
+		}
+	}
+	if stored, err := EncryptSecret(""); stored != "" || err != nil {
+		t.Errorf("EncryptSecret(\"\") = %q, %v", stored, err)
+	}
+
+	// The key survives a restart with the same salt file
+	salt, err := os.ReadFile(filepath.Join(dir, secretSaltFile))
+	if err != nil || len(salt) != 32 {
+		t.Fatalf("salt = %d bytes, %v", len(salt), err)
+	}
+	stored, _ := EncryptSecret("persist")
+	useSecretDir(t, dir)
+	if got, err := DecryptSecret(stored); err != nil || got != "persist" {
+		t.Errorf("after reload = %q, %v", got, err)
+	}
+}
+
+func TestSecretRejectsBadCiphertext(t *testing.T) {
+	useSecretDir(t, t.TempDir())
+	stored, err := EncryptSecret("sk-abcdef123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	cases := map[string]string{
+		"tampered":    secretPrefix + base64.StdEncoding.EncodeToString(tampered),
+		"not base64":  secretPrefix + "%%%",
+		"too short":   secretPrefix + base64.StdEncoding.EncodeToString(sealed[:4]),
+		"empty":       secretPrefix,
+		"other nonce": secretPrefix + base64.StdEncoding.EncodeToString(append(bytes.Repeat([]byte{0}, 12), sealed[12:]...)),
+	}
+	for name, value := range cases {
+		if got, err := DecryptSecret(value); !errors.Is(err, ErrSecretUndecryptable) || got != "" {
+			t.Errorf("%s: DecryptSecret = %q, %v", name, got, err)
+		}
+	}
+
+	// A secret encrypted with another salt (another machine or a lost salt file) is rejected too
+	useSecretDir(t, t.TempDir())
+	if _, err := DecryptSecret(stored); !errors.Is(err, ErrSecretUndecryptable) {
+		t.Errorf("other salt: %v", err)
+	}
+}
+
+func TestSecretLegacyPlaintext(t *testing.T) {
+	useSecretDir(t, t.TempDir())
+	for _, legacy := range []string{"sk-plain-key", "", "enc:v0:old"} {
+		if IsEncryptedSecret(legacy) {
+			t.Errorf("%q reported as encrypted", legacy)
+		}
+		if got, err := DecryptSecret(legacy); err != nil || got != legacy {
+			t.Errorf("DecryptSecret(%q) = %q, %v", legacy, got, err)
+		}
+	}
+}
+
+func TestSecretDamagedSalt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, secretSaltFile)
+	if err := os.WriteFile(path, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	useSecretDir(t, dir)
+	if _, err := EncryptSecret("sk-abcdef123456"); err == nil || !strings.Contains(err.Error(), "已损坏（5 字节）") {
+		t.Errorf("EncryptSecret with a damaged salt: %v", err)
+	}
+	if _, err := DecryptSecret(secretPrefix + "AAAA"); err == nil || errors.Is(err, ErrSecretUndecryptable) {
+		t.Errorf("DecryptSecret with a damaged salt: %v", err)
+	}
+	// The damaged file is kept for recovery rather than replaced
+	if salt, _ := os.ReadFile(path); string(salt) != "short" {
+		t.Errorf("salt file rewritten: %q", salt)
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	cases := map[string]string{"": "", "abc": "•••", "12345678": "••••••••", "sk-abcdef123456": "sk-a…3456"}
+	for in, want := range cases {
+		if got := MaskSecret(in); got != want {
+			t.Errorf("MaskSecret(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Shot fields a spreadsheet column can be mapped to. An empty field ignores the column.
+const (
+	ShotFieldShotNo         = "shot_no"
+	ShotFieldShotSize       = "shot_size"
+	ShotFieldCameraMovement = "camera_movement"
+	ShotFieldFrameContent   = "frame_content"
+	ShotFieldDuration       = "estimated_duration"
+	ShotFieldSoundDesign    = "sound_design"
+	ShotFieldCharacters     = "characters"
+	ShotFieldScenes         = "scenes"
+	ShotFieldElements       = "elements"
+	ShotFieldStyles         = "styles"
+)
+
+// ShotTableColumn maps one spreadsheet column to a shot field.
+type ShotTableColumn struct {
+	Index  int    `json:"index"`
+	Header string `json:"header"`
+	Field  string `json:"field"` // one of the ShotField constants, "" to ignore
+}
+
+// ShotTableRow is one spreadsheet row read through a column mapping. Entity columns are split
+// into names; several columns mapped to the same text field are joined.
+type ShotTableRow struct {
+	Line int // 1-based row number in the sheet
+	// Section is the text of the last section row above, such as a merged "第3场 内 客厅 日".
+	Section        string
+	ShotNo         string
+	ShotSize       string
+	CameraMovement string
+	FrameContent   string
+	SoundDesign    string
+	Duration       int // seconds, 0 when the cell is empty or unreadable
+	Characters     []string
+	Scenes         []string
+	Elements       []string
+	Styles         []string
+}
+
+// shotHeaderAliases lists common header names of each field, normalized with headerKey.
+var shotHeaderAliases = []struct {
+	field   string
+	aliases []string
+}{
+	{ShotFieldShotNo, []string{"镜号", "镜头号", "分镜号", "镜头编号", "分镜编号", "序号", "编号", "镜次", "shot", "shotno", "shotnumber", "shot#", "no", "#", "id"}},
+	{ShotFieldShotSize, []string{"景别", "镜头景别", "景", "size", "shotsize", "shottype", "framing", "type"}},
+	{ShotFieldCameraMovement, []string{"运镜", "镜头运动", "摄影机运动", "运动方式", "运镜方式", "机位", "camera", "cameramovement", "cameramove", "cameramotion", "movement", "move"}},
+	{ShotFieldFrameContent, []string{"画面内容", "画面描述", "镜头内容", "镜头描述", "画面", "内容", "描述", "剧情", "动作", "framecontent", "frame", "content", "description", "action", "visual", "visuals", "picture"}},
+	{ShotFieldDuration, []string{"时长", "预计时长", "镜头时长", "秒数", "时间", "秒", "duration", "length", "time", "seconds", "secs", "sec"}},
+	{ShotFieldSoundDesign, []string{"声音设计", "声音", "音效", "对白", "台词", "音乐", "旁白", "sounddesign", "sound", "audio", "sfx", "dialogue", "dialog", "music"}},
+	{ShotFieldCharacters, []string{"角色", "人物", "出场人物", "出场角色", "演员", "characters", "character", "cast"}},
+	{ShotFieldScenes, []string{"场景", "地点", "场地", "拍摄地点", "scenes", "scene", "location", "setting"}},
+	{ShotFieldElements, []string{"特殊元素", "元素", "道具", "elements", "element", "props", "prop"}},
+	{ShotFieldStyles, []string{"视觉风格", "风格", "画风", "styles", "style", "visualstyle", "look"}},
+}
+
+// headerNoteRe matches a trailing note such as "时长(秒)" or "Duration [s]".
+var headerNoteRe = regexp.MustCompile(`\s*[（(\[【][^）)\]】]*[）)\]】]\s*$`)
+
+// headerKey normalizes a header for alias matching: unit notes, case, spaces and punctuation
+// other than '#' are ignored.
+func headerKey(h string) string {
+	h = headerNoteRe.ReplaceAllString(strings.TrimSpace(h), "")
+	var b strings.Builder
+	for _, r := range strings.ToLower(h) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '#' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// DetectShotColumns maps headers to shot fields by their common Chinese and English names.
+// Exact names are matched first; a header that only contains a Chinese alias, such as
+// "画面内容描述", is matched next, longest alias first. Each field is assigned at most once.
+func DetectShotColumns(header []string) []ShotTableColumn {
+	columns := make([]ShotTableColumn, len(header))
+	keys := make([]string, len(header))
+	for i, h := range header {
+		columns[i] = ShotTableColumn{Index: i, Header: strings.TrimSpace(h)}
+		keys[i] = headerKey(h)
+	}
+	taken := map[string]bool{}
+
+	for _, entry := range shotHeaderAliases {
+		for i := range columns {
+			if columns[i].Field != "" || taken[entry.field] || keys[i] == "" {
+				continue
+			}
+			for _, alias := range entry.aliases {
+				if keys[i] == alias {
+					columns[i].Field = entry.field
+					taken[entry.field] = true
+					break
+				}
+			}
+		}
+	}
+
+	for i := range columns {
+		if columns[i].Field != "" || keys[i] == "" {
+			continue
+		}
+		best, bestLen := "", 0
+		for _, entry := range shotHeaderAliases {
+			if taken[entry.field] {
+				continue
+			}
+			for _, alias := range entry.aliases {
+				n := len([]rune(alias))
+				if n >= 2 && n > bestLen && !isASCII(alias) && strings.Contains(keys[i], alias) {
+					best, bestLen = entry.field, n
+				}
+			}
+		}
+		if best != "" {
+			columns[i].Field = best
+			taken[best] = true
+		}
+	}
+	return columns
+}
+
+// DetectShotTableHeader returns the index of the header row: the row among the first ten that
+// maps the most columns, at least two. It returns -1 when no row looks like a header.
+func DetectShotTableHeader(table [][]string) int {
+	best, bestCount := -1, 1
+	for i := 0; i < len(table) && i < 10; i++ {
+		count := 0
+		for _, c := range DetectShotColumns(table[i]) {
+			if c.Field != "" {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+// MapShotTable reads the rows below headerRow through the column mapping. Empty rows are
+// skipped and section rows (see sectionRowText) are passed on as ShotTableRow.Section of the
+// rows below them; the returned notes describe cells that could not be read.
+func MapShotTable(table [][]string, headerRow int, columns []ShotTableColumn) ([]ShotTableRow, []string) {
+	var rows []ShotTableRow
+	var notes []string
+	section := ""
+	for i := headerRow + 1; i < len(table); i++ {
+		if text, ok := sectionRowText(table[i]); ok {
+			section = text
+			continue
+		}
+		row := ShotTableRow{Line: i + 1, Section: section}
+		empty := true
+		for _, col := range columns {
+			if col.Field == "" || col.Index < 0 || col.Index >= len(table[i]) {
+				continue
+			}
+			cell := strings.TrimSpace(table[i][col.Index])
+			if cell == "" {
+				continue
+			}
+			empty = false
+			switch col.Field {
+			case ShotFieldShotNo:
+				row.ShotNo = joinCell(row.ShotNo, cell)
+			case ShotFieldShotSize:
+				row.ShotSize = joinCell(row.ShotSize, cell)
+			case ShotFieldCameraMovement:
+				row.CameraMovement = joinCell(row.CameraMovement, cell)
+			case ShotFieldFrameContent:
+				row.FrameContent = joinCell(row.FrameContent, cell)
+			case ShotFieldSoundDesign:
+				row.SoundDesign = joinCell(row.SoundDesign, cell)
+			case ShotFieldDuration:
+				if d, ok := ParseShotDuration(cell); ok {
+					row.Duration = d
+				} else {
+					notes = append(notes, fmt.Sprintf("第 %d 行：无法识别时长“%s”", row.Line, cell))
+				}
+			case ShotFieldCharacters:
+				row.Characters = append(row.Characters, SplitNameList(cell)...)
+			case ShotFieldScenes:
+				row.Scenes = append(row.Scenes, SplitNameList(cell)...)
+			case ShotFieldElements:
+				row.Elements = append(row.Elements, SplitNameList(cell)...)
+			case ShotFieldStyles:
+				row.Styles = append(row.Styles, SplitNameList(cell)...)
+			}
+		}
+		if empty {
+			continue
+		}
+		if row.FrameContent == "" {
+			notes = append(notes, fmt.Sprintf("第 %d 行：没有画面内容", row.Line))
+		}
+		rows = append(rows, row)
+	}
+	return rows, notes
+}
+
+// sectionHeadingRe matches scene headings written in a single cell.
+var sectionHeadingRe = regexp.MustCompile(`(?i)^(第\s*[0-9一二三四五六七八九十百零〇]+\s*[场集幕]|场景\s*[0-9一二三四五六七八九十]|内景|外景|内外景|int\.|ext\.|int/ext|scene\s*\d)`)
+
+// sectionRowText reports whether a row is a section header rather than a shot: a scene header
+// merged across most of the row, which reads as the same text in every filled cell, or a single
+// cell holding a scene heading. Numbers are never section headers.
+func sectionRowText(row []string) (string, bool) {
+	text, filled := "", 0
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		if filled > 0 && cell != text {
+			return "", false
+		}
+		text = cell
+		filled++
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return "", false
+	}
+	if filled >= 2 && filled*2 > len(row) || filled == 1 && sectionHeadingRe.MatchString(text) {
+		return text, true
+	}
+	return "", false
+}
+
+func joinCell(existing, cell string) string {
+	if existing == "" {
+		return cell
+	}
+	return existing + "\n" + cell
+}
+
+var (
+	durationClockRe  = regexp.MustCompile(`^(?:(\d+):)?(\d+):(\d{1,2})(?:[.,]\d+)?$`)
+	durationMinSecRe = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?)\s*(?:分钟|分|min|mins|minutes?|m))?\s*(?:(\d+(?:\.\d+)?)\s*(?:秒钟|秒|s|sec|secs|seconds?|"|″)?)?$`)
+	durationRangeRe  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*[-~～至到]\s*(\d+(?:\.\d+)?)\s*(?:秒钟|秒|s|sec|secs|seconds?)?$`)
+)
+
+// ParseShotDuration reads a duration cell such as "5", "5s", "5秒", "4.5", "0:05", "0:00:05", "1分30秒"
+// or "4-6秒" (the midpoint) and returns whole seconds.
+func ParseShotDuration(cell string) (int, bool) {
+	s := strings.ToLower(strings.TrimSpace(cell))
+	s = strings.ReplaceAll(s, "：", ":")
+	if s == "" {
+		return 0, false
+	}
+	if m := durationClockRe.FindStringSubmatch(s); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.Atoi(m[3])
+		return hour*3600 + min*60 + sec, true
+	}
+	if m := durationRangeRe.FindStringSubmatch(s); m != nil {
+		lo, _ := strconv.ParseFloat(m[1], 64)
+		hi, _ := strconv.ParseFloat(m[2], 64)
+		return roundSeconds((lo + hi) / 2), true
+	}
+	if m := durationMinSecRe.FindStringSubmatch(s); m != nil && (m[1] != "" || m[2] != "") {
+		total := 0.0
+		if m[1] != "" {
+			min, _ := strconv.ParseFloat(m[1], 64)
+			total += min * 60
+		}
+		if m[2] != "" {
+			sec, _ := strconv.ParseFloat(m[2], 64)
+			total += sec
+		}
+		return roundSeconds(total), true
+	}
+	return 0, false
+}
+
+func roundSeconds(v float64) int {
+	return int(math.Round(v))
+}
+
+// SplitNameList splits a cell such as "张三、李四" or "Anna, Ben" into names, dropping
+// duplicates and empty entries.
+func SplitNameList(cell string) []string {
+	parts := strings.FieldsFunc(cell, func(r rune) bool {
+		switch r {
+		case '、', '，', ',', '；', ';', '/', '／', '|', '\n':
+			return true
+		}
+		return false
+	})
+	seen := map[string]bool{}
+	var names []string
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		names = append(names, p)
+	}
+	return names
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
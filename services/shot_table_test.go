@@ -0,0 +1,185 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectShotColumns(t *testing.T) {
+	cases := []struct {
+		name   string
+		header []string
+		want   []string
+	}{
+		{
+			name:   "chinese",
+			header: []string{"镜号", "景别", "运镜", "画面内容", "时长（秒）", "对白", "角色", "场景"},
+			want:   []string{ShotFieldShotNo, ShotFieldShotSize, ShotFieldCameraMovement, ShotFieldFrameContent, ShotFieldDuration, ShotFieldSoundDesign, ShotFieldCharacters, ShotFieldScenes},
+		},
+		{
+			name:   "english",
+			header: []string{"Shot #", "Shot Size", "Camera Movement", "Description", "Duration [s]", "Cast", "Props", "Look"},
+			want:   []string{ShotFieldShotNo, ShotFieldShotSize, ShotFieldCameraMovement, ShotFieldFrameContent, ShotFieldDuration, ShotFieldCharacters, ShotFieldElements, ShotFieldStyles},
+		},
+		{
+			name:   "contained chinese alias",
+			header: []string{"序号", "画面内容描述", "备注"},
+			want:   []string{ShotFieldShotNo, ShotFieldFrameContent, ""},
+		},
+		{
+			name:   "field assigned once",
+			header: []string{"画面", "内容", "描述"},
+			want:   []string{ShotFieldFrameContent, "", ""},
+		},
+		{
+			name:   "exact match wins over contained",
+			header: []string{"镜头描述说明", "画面内容"},
+			want:   []string{"", ShotFieldFrameContent},
+		},
+		{
+			name:   "blank and unknown",
+			header: []string{"", "  ", "remarks"},
+			want:   []string{"", "", ""},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			columns := DetectShotColumns(tc.header)
+			got := make([]string, len(columns))
+			for i, c := range columns {
+				if c.Index != i {
+					t.Errorf("column %d has index %d", i, c.Index)
+				}
+				got[i] = c.Field
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fields = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectShotTableHeader(t *testing.T) {
+	cases := []struct {
+		name  string
+		table [][]string
+		want  int
+	}{
+		{
+			name:  "first row",
+			table: [][]string{{"镜号", "画面内容", "时长"}, {"1", "推门而入", "5"}},
+			want:  0,
+		},
+		{
+			name: "below a title",
+			table: [][]string{
+				{"《夜归》分镜脚本", "", ""},
+				{"导演：某某", "", ""},
+				{"镜号", "景别", "画面"},
+				{"1", "全景", "雨夜街道"},
+			},
+			want: 2,
+		},
+		{
+			name:  "single mapped column is not a header",
+			table: [][]string{{"画面", "x", "y"}, {"a", "b", "c"}},
+			want:  -1,
+		},
+		{
+			name:  "no header",
+			table: [][]string{{"1", "推门而入", "5"}, {"2", "转身", "10"}},
+			want:  -1,
+		},
+		{
+			name:  "empty",
+			table: nil,
+			want:  -1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectShotTableHeader(tc.table); got != tc.want {
+				t.Errorf("DetectShotTableHeader = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMapShotTable(t *testing.T) {
+	table := [][]string{
+		{"镜号", "画面", "补充", "时长", "角色", "场景"},
+		{"第1场 内 客厅 日", "第1场 内 客厅 日", "第1场 内 客厅 日", "第1场 内 客厅 日", "第1场 内 客厅 日", "第1场 内 客厅 日"},
+		{"1", "推门而入", "雨声", "5秒", "张三、李四", ""},
+		{"", "", "", "", "", ""},
+		{"2", "", "", "很久", "张三", "走廊"},
+		{"EXT. 街道 - 夜"},
+		{"3", "奔跑", "", "0:10", "Anna, Ben, Anna", ""},
+		{"4", "短行"},
+	}
+	columns := []ShotTableColumn{
+		{Index: 0, Field: ShotFieldShotNo},
+		{Index: 1, Field: ShotFieldFrameContent},
+		{Index: 2, Field: ShotFieldFrameContent},
+		{Index: 3, Field: ShotFieldDuration},
+		{Index: 4, Field: ShotFieldCharacters},
+		{Index: 5, Field: ShotFieldScenes},
+		{Index: 9, Field: ShotFieldStyles}, // beyond every row
+	}
+	rows, notes := MapShotTable(table, 0, columns)
+	want := []ShotTableRow{
+		{Line: 3, Section: "第1场 内 客厅 日", ShotNo: "1", FrameContent: "推门而入\n雨声", Duration: 5, Characters: []string{"张三", "李四"}},
+		{Line: 5, Section: "第1场 内 客厅 日", ShotNo: "2", Characters: []string{"张三"}, Scenes: []string{"走廊"}},
+		{Line: 7, Section: "EXT. 街道 - 夜", ShotNo: "3", FrameContent: "奔跑", Duration: 10, Characters: []string{"Anna", "Ben"}},
+		{Line: 8, Section: "EXT. 街道 - 夜", ShotNo: "4", FrameContent: "短行"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows =\n%+v\nwant\n%+v", rows, want)
+	}
+	wantNotes := []string{"第 5 行：无法识别时长“很久”", "第 5 行：没有画面内容"}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", notes, wantNotes)
+	}
+
+	// Without a header row every row is data
+	rows, _ = MapShotTable([][]string{{"1", "开场"}}, -1, columns[:2])
+	if len(rows) != 1 || rows[0].Line != 1 || rows[0].FrameContent != "开场" {
+		t.Errorf("headerless rows = %+v", rows)
+	}
+}
+
+func TestParseShotDuration(t *testing.T) {
+	cases := []struct {
+		cell string
+		want int
+		ok   bool
+	}{
+		{"5", 5, true},
+		{" 10 ", 10, true},
+		{"5s", 5, true},
+		{"5 sec", 5, true},
+		{"5秒", 5, true},
+		{"5秒钟", 5, true},
+		{"4.5", 5, true},
+		{"4.4s", 4, true},
+		{"0:05", 5, true},
+		{"0：08", 8, true},
+		{"1:02:03", 3723, true},
+		{"0:05.5", 5, true},
+		{"1分30秒", 90, true},
+		{"2分钟", 120, true},
+		{"1m 5s", 65, true},
+		{"4-6秒", 5, true},
+		{"3~4", 4, true},
+		{"5\"", 5, true},
+		{"", 0, false},
+		{"很久", 0, false},
+		{"五秒", 0, false},
+		{"5 frames", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := ParseShotDuration(tc.cell)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseShotDuration(%q) = %d, %v, want %d, %v", tc.cell, got, ok, tc.want, tc.ok)
+		}
+	}
+}
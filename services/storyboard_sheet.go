@@ -0,0 +1,414 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StoryboardSheet is the content of a printable storyboard.
+type StoryboardSheet struct {
+	Title       string
+	Subtitle    string
+	AspectRatio string // "16:9"; frames are drawn in this shape
+	Shots       []StoryboardSheetShot
+}
+
+// StoryboardSheetShot is one shot of a storyboard sheet.
+type StoryboardSheetShot struct {
+	ShotNo         string
+	ShotSize       string
+	CameraMovement string
+	Duration       int
+	FrameContent   string
+	Characters     []string
+	Scenes         []string
+	SoundDesign    string
+	Prompt         string
+	Images         []StoryboardSheetImage
+}
+
+// StoryboardSheetImage is a frame or take thumbnail; Path is a local path as stored in the database.
+type StoryboardSheetImage struct {
+	Label string
+	Path  string
+}
+
+// StoryboardSheetOptions controls the layout.
+type StoryboardSheetOptions struct {
+	ShotsPerPage   int // 1–12
+	IncludePrompts bool
+}
+
+const (
+	// sheetImageMaxPx bounds embedded images; frames are printed a few inches wide at most.
+	sheetImageMaxPx = 960
+	// A4 landscape in points
+	sheetPageWidth  = 842.0
+	sheetPageHeight = 595.0
+	sheetMargin     = 28.0
+)
+
+// sheetGrid returns the columns and rows a page of n shots is laid out in.
+func sheetGrid(n int) (int, int) {
+	cols := map[int]int{1: 1, 2: 2, 3: 3, 4: 2, 5: 3, 6: 3, 7: 4, 8: 4, 9: 3, 10: 4, 11: 4, 12: 4}[n]
+	if cols == 0 {
+		cols = 3
+	}
+	return cols, (n + cols - 1) / cols
+}
+
+func (o StoryboardSheetOptions) perPage() int {
+	return min(max(o.ShotsPerPage, 1), 12)
+}
+
+// aspect returns the frame height over width.
+func (s StoryboardSheet) aspect() float64 {
+	parts := strings.Split(s.AspectRatio, ":")
+	if len(parts) == 2 {
+		w, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		h, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 == nil && err2 == nil && w > 0 && h > 0 {
+			return h / w
+		}
+	}
+	return 9.0 / 16.0
+}
+
+func (shot StoryboardSheetShot) heading() string {
+	parts := []string{"#" + shot.ShotNo}
+	for _, p := range []string{shot.ShotSize, shot.CameraMovement} {
+		if strings.TrimSpace(p) != "" {
+			parts = append(parts, p)
+		}
+	}
+	if shot.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%d 秒", shot.Duration))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (shot StoryboardSheetShot) entities() string {
+	var parts []string
+	if len(shot.Characters) > 0 {
+		parts = append(parts, "角色："+strings.Join(shot.Characters, "、"))
+	}
+	if len(shot.Scenes) > 0 {
+		parts = append(parts, "场景："+strings.Join(shot.Scenes, "、"))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// sheetImage is an image decoded, shrunk and re-encoded as JPEG for embedding.
+type sheetImage struct {
+	jpeg          []byte
+	width, height int
+}
+
+// loadSheetImages loads every image of the sheet once; images that are missing or in an
+// unsupported format are left out.
+func loadSheetImages(sheet StoryboardSheet) map[string]*sheetImage {
+	images := map[string]*sheetImage{}
+	for _, shot := range sheet.Shots {
+		for _, img := range shot.Images {
+			if _, done := images[img.Path]; done {
+				continue
+			}
+			images[img.Path] = loadSheetImage(img.Path)
+		}
+	}
+	return images
+}
+
+func loadSheetImage(path string) *sheetImage {
+	abs := resolveLocalPath(path)
+	if abs == "" {
+		return nil
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil
+	}
+	dst := shrinkImage(src, sheetImageMaxPx)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil
+	}
+	return &sheetImage{jpeg: buf.Bytes(), width: dst.Bounds().Dx(), height: dst.Bounds().Dy()}
+}
+
+// shrinkImage scales an image to fit maxPx by averaging source pixels, flattening
+// transparency onto white.
+func shrinkImage(src image.Image, maxPx int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	scale := math.Min(1, float64(maxPx)/float64(max(w, h)))
+	nw, nh := max(1, int(math.Round(float64(w)*scale))), max(1, int(math.Round(float64(h)*scale)))
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := y*h/nh, max((y+1)*h/nh, y*h/nh+1)
+		for x := 0; x < nw; x++ {
+			x0, x1 := x*w/nw, max((x+1)*w/nw, x*w/nw+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					white := uint64(0xffff - ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), 0xffff})
+		}
+	}
+	return dst
+}
+
+// ============================================================
+// PDF
+// ============================================================
+
+// RenderStoryboardPDF lays the shots out on A4 landscape pages in a grid, each with its frames,
+// shot details and optionally the prompt.
+func RenderStoryboardPDF(sheet StoryboardSheet, opts StoryboardSheetOptions) ([]byte, error) {
+	if len(sheet.Shots) == 0 {
+		return nil, fmt.Errorf("没有可导出的分镜")
+	}
+	perPage := opts.perPage()
+	cols, rows := sheetGrid(perPage)
+	pageCount := (len(sheet.Shots) + perPage - 1) / perPage
+
+	w := &pdfWriter{}
+	catalog := w.reserve()
+	pagesObj := w.reserve()
+	font := w.addCJKFont()
+
+	images := loadSheetImages(sheet)
+	imageObjs := map[string]int{}
+	for path, img := range images {
+		if img == nil {
+			continue
+		}
+		imageObjs[path] = w.addStream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d"+
+			" /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode", img.width, img.height), img.jpeg, false)
+	}
+
+	const headerH, gap, pad = 24.0, 10.0, 6.0
+	cellW := (sheetPageWidth - 2*sheetMargin - float64(cols-1)*gap) / float64(cols)
+	cellH := (sheetPageHeight - 2*sheetMargin - headerH - float64(rows-1)*gap) / float64(rows)
+	aspect := sheet.aspect()
+
+	var kids []string
+	for p := 0; p < pageCount; p++ {
+		c := &pdfCanvas{height: sheetPageHeight}
+		c.text(sheetMargin, sheetMargin, 12, 0, sheet.Title)
+		pageNo := fmt.Sprintf("%d / %d", p+1, pageCount)
+		c.text(sheetPageWidth-sheetMargin-pdfTextWidth(pageNo, 9), sheetMargin+2, 9, 0.4, pageNo)
+		if sheet.Subtitle != "" {
+			c.text(sheetMargin+pdfTextWidth(sheet.Title, 12)+12, sheetMargin+2, 9, 0.4, sheet.Subtitle)
+		}
+
+		xobjects := map[string]int{}
+		for i := 0; i < perPage; i++ {
+			idx := p*perPage + i
+			if idx >= len(sheet.Shots) {
+				break
+			}
+			shot := sheet.Shots[idx]
+			x := sheetMargin + float64(i%cols)*(cellW+gap)
+			y := sheetMargin + headerH + float64(i/cols)*(cellH+gap)
+			c.strokeRect(x, y, cellW, cellH, 0.7, 0.5)
+
+			// Frames share the row side by side, in the project's aspect ratio
+			innerW := cellW - 2*pad
+			frames := max(len(shot.Images), 1)
+			frameW := (innerW - float64(frames-1)*4) / float64(frames)
+			frameH := frameW * aspect
+			if maxH := (cellH - 2*pad) * 0.6; frameH > maxH {
+				frameH = maxH
+				frameW = frameH / aspect
+			}
+			fy := y + pad
+			if len(shot.Images) == 0 {
+				c.fillRect(x+pad, fy, frameW, frameH, 0.93)
+				c.text(x+pad+4, fy+4, 7, 0.55, "暂无画面")
+			}
+			for k, img := range shot.Images {
+				fx := x + pad + float64(k)*(frameW+4)
+				c.fillRect(fx, fy, frameW, frameH, 0.93)
+				if obj, ok := imageObjs[img.Path]; ok {
+					im := images[img.Path]
+					scale := math.Min(frameW/float64(im.width), frameH/float64(im.height))
+					iw, ih := float64(im.width)*scale, float64(im.height)*scale
+					name := fmt.Sprintf("Im%d", obj)
+					xobjects[name] = obj
+					c.image(name, fx+(frameW-iw)/2, fy+(frameH-ih)/2, iw, ih)
+				} else {
+					c.text(fx+4, fy+frameH/2-4, 7, 0.55, "图片不可用")
+				}
+				c.fillRect(fx, fy, pdfTextWidth(img.Label, 6.5)+6, 10, 0.25)
+				c.text(fx+3, fy+1.5, 6.5, 1, img.Label)
+			}
+
+			// Details below the frames, until the cell is full
+			ty := fy + frameH + 5
+			bottom := y + cellH - pad
+			block := func(text string, size, gray float64, maxLines int) {
+				lineH := size * 1.35
+				avail := int((bottom - ty) / lineH)
+				for _, line := range wrapText(text, size, innerW, min(maxLines, avail)) {
+					c.text(x+pad, ty, size, gray, line)
+					ty += lineH
+				}
+			}
+			block(shot.heading(), 8.5, 0, 1)
+			if e := shot.entities(); e != "" {
+				block(e, 7, 0.35, 1)
+			}
+			block(shot.FrameContent, 7.5, 0, 6)
+			if shot.SoundDesign != "" {
+				block("声音："+shot.SoundDesign, 7, 0.35, 2)
+			}
+			if opts.IncludePrompts && shot.Prompt != "" {
+				block("提示词："+shot.Prompt, 6.5, 0.45, 8)
+			}
+		}
+
+		content := w.addStream("", c.buf.Bytes(), true)
+		var xo strings.Builder
+		for name, obj := range xobjects {
+			fmt.Fprintf(&xo, " /%s %d 0 R", name, obj)
+		}
+		page := w.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R"+
+			" /Resources << /Font << /F1 %d 0 R >> /XObject <<%s >> >> >>",
+			pagesObj, sheetPageWidth, sheetPageHeight, content, font, xo.String()))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	w.set(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+	return w.bytes(catalog), nil
+}
+
+// ============================================================
+// HTML
+// ============================================================
+
+// RenderStoryboardHTML renders the sheet as a self-contained HTML page with the same grid as the
+// PDF; images are embedded, and printing gives one grid per A4 landscape page.
+func RenderStoryboardHTML(sheet StoryboardSheet, opts StoryboardSheetOptions) ([]byte, error) {
+	if len(sheet.Shots) == 0 {
+		return nil, fmt.Errorf("没有可导出的分镜")
+	}
+	perPage := opts.perPage()
+	cols, rows := sheetGrid(perPage)
+	images := loadSheetImages(sheet)
+
+	type htmlImage struct {
+		Label string
+		Src   template.URL
+	}
+	type htmlShot struct {
+		StoryboardSheetShot
+		Heading  string
+		Entities string
+		Frames   []htmlImage
+	}
+	type htmlPage struct {
+		Number int
+		Shots  []htmlShot
+	}
+	var pages []htmlPage
+	for i, shot := range sheet.Shots {
+		if i%perPage == 0 {
+			pages = append(pages, htmlPage{Number: len(pages) + 1})
+		}
+		hs := htmlShot{StoryboardSheetShot: shot, Heading: shot.heading(), Entities: shot.entities()}
+		for _, img := range shot.Images {
+			fi := htmlImage{Label: img.Label}
+			if im := images[img.Path]; im != nil {
+				fi.Src = template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(im.jpeg))
+			}
+			hs.Frames = append(hs.Frames, fi)
+		}
+		pages[len(pages)-1].Shots = append(pages[len(pages)-1].Shots, hs)
+	}
+
+	aspect := sheet.aspect()
+	data := map[string]interface{}{
+		"Title":          sheet.Title,
+		"Subtitle":       sheet.Subtitle,
+		"Pages":          pages,
+		"PageCount":      len(pages),
+		"Cols":           cols,
+		"Rows":           rows,
+		"Aspect":         template.CSS(fmt.Sprintf("%d / %d", 1000, int(math.Round(1000*aspect)))),
+		"IncludePrompts": opts.IncludePrompts,
+	}
+	var buf bytes.Buffer
+	if err := storyboardSheetTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var storyboardSheetTemplate = template.Must(template.New("sheet").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+@page { size: A4 landscape; margin: 10mm; }
+* { box-sizing: border-box; }
+body { margin: 0; color: #222; font-family: "PingFang SC", "Microsoft YaHei", "Noto Sans CJK SC", sans-serif; }
+.page { width: 277mm; height: 190mm; margin: 0 auto 10mm; display: flex; flex-direction: column; break-after: page; page-break-after: always; }
+.page:last-child { break-after: auto; page-break-after: auto; margin-bottom: 0; }
+header { display: flex; align-items: baseline; gap: 4mm; margin-bottom: 3mm; }
+header h1 { font-size: 14px; margin: 0; }
+header .sub, header .no { font-size: 10px; color: #777; }
+header .no { margin-left: auto; }
+.grid { flex: 1; display: grid; gap: 3.5mm; grid-template-columns: repeat({{.Cols}}, 1fr); grid-template-rows: repeat({{.Rows}}, minmax(0, 1fr)); }
+.shot { border: 0.5px solid #bbb; padding: 2mm; overflow: hidden; display: flex; flex-direction: column; gap: 1mm; font-size: 10px; line-height: 1.35; }
+.frames { display: flex; gap: 1.5mm; }
+.frame { flex: 1; aspect-ratio: {{.Aspect}}; max-height: 60%; background: #eee; position: relative; display: flex; align-items: center; justify-content: center; color: #999; font-size: 9px; }
+.frame img { width: 100%; height: 100%; object-fit: contain; }
+.frame span { position: absolute; left: 0; top: 0; background: rgba(0, 0, 0, 0.7); color: #fff; font-size: 8px; padding: 0 3px; }
+.heading { font-weight: 600; }
+.muted { color: #666; font-size: 9px; }
+.content { white-space: pre-wrap; }
+.prompt { color: #777; font-size: 8.5px; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+{{range .Pages}}<section class="page">
+<header><h1>{{$.Title}}</h1>{{if $.Subtitle}}<span class="sub">{{$.Subtitle}}</span>{{end}}<span class="no">{{.Number}} / {{$.PageCount}}</span></header>
+<div class="grid">
+{{range .Shots}}<div class="shot">
+<div class="frames">{{if not .Frames}}<div class="frame">暂无画面</div>{{end}}{{range .Frames}}<div class="frame">{{if .Src}}<img src="{{.Src}}" alt="{{.Label}}">{{else}}图片不可用{{end}}<span>{{.Label}}</span></div>{{end}}</div>
+<div class="heading">{{.Heading}}</div>
+{{if .Entities}}<div class="muted">{{.Entities}}</div>{{end}}
+<div class="content">{{.FrameContent}}</div>
+{{if .SoundDesign}}<div class="muted">声音：{{.SoundDesign}}</div>{{end}}
+{{if and $.IncludePrompts .Prompt}}<div class="prompt">提示词：{{.Prompt}}</div>{{end}}
+</div>
+{{end}}</div>
+</section>
+{{end}}</body>
+</html>
+`))
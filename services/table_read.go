@@ -0,0 +1,504 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ReadDelimitedTable parses CSV or TSV bytes into rows of trimmed cells. A UTF-8 BOM is ignored
+// and rows may have different lengths.
+func ReadDelimitedTable(b []byte, comma rune) ([][]string, error) {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(b))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range records {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	return padTable(records), nil
+}
+
+// SheetTable is the content of one worksheet.
+type SheetTable struct {
+	Name string     `json:"name"`
+	Rows [][]string `json:"rows"`
+}
+
+// ReadXLSXTable reads the first sheet of an .xlsx file, see ReadXLSXSheets.
+func ReadXLSXTable(filePath string) ([][]string, error) {
+	book, err := openXLSX(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer book.close()
+	return book.readSheet(0)
+}
+
+// ListXLSXSheets returns the sheet names of an .xlsx file in workbook order.
+func ListXLSXSheets(filePath string) ([]string, error) {
+	book, err := openXLSX(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer book.close()
+	names := make([]string, len(book.sheets))
+	for i, sheet := range book.sheets {
+		names[i] = sheet.Name
+	}
+	return names, nil
+}
+
+// ReadXLSXSheets reads the named sheets of an .xlsx file, or every sheet when names is empty.
+// Merged cells are expanded so every cell of a merged range holds its value, and numbers and
+// dates are formatted as their number format shows them. Empty rows and cells are kept, so row
+// i of a sheet is row i+1 in Excel.
+func ReadXLSXSheets(filePath string, names []string) ([]SheetTable, error) {
+	book, err := openXLSX(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer book.close()
+
+	indexes := make([]int, 0, len(book.sheets))
+	if len(names) == 0 {
+		for i := range book.sheets {
+			indexes = append(indexes, i)
+		}
+	}
+	for _, name := range names {
+		found := -1
+		for i, sheet := range book.sheets {
+			if sheet.Name == name {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, fmt.Errorf("工作表不存在：%s", name)
+		}
+		indexes = append(indexes, found)
+	}
+
+	tables := make([]SheetTable, 0, len(indexes))
+	for _, i := range indexes {
+		rows, err := book.readSheet(i)
+		if err != nil {
+			return nil, fmt.Errorf("读取工作表 %s 失败：%w", book.sheets[i].Name, err)
+		}
+		tables = append(tables, SheetTable{Name: book.sheets[i].Name, Rows: rows})
+	}
+	return tables, nil
+}
+
+// xlsxBook is an opened workbook with the parts shared by all sheets.
+type xlsxBook struct {
+	zr       *zip.ReadCloser
+	files    map[string]*zip.File
+	sheets   []xlsxSheet
+	paths    []string // worksheet part of each sheet
+	shared   []string
+	numFmts  []xlsxNumFmt // number format of each cell style
+	date1904 bool
+}
+
+// xlsxNumFmt is the number format of a cell style.
+type xlsxNumFmt struct {
+	ID   int
+	Code string
+}
+
+func openXLSX(filePath string) (*xlsxBook, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	book := &xlsxBook{zr: zr, files: map[string]*zip.File{}}
+	for _, f := range zr.File {
+		book.files[f.Name] = f
+	}
+	if err := book.load(); err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return book, nil
+}
+
+func (b *xlsxBook) close() {
+	b.zr.Close()
+}
+
+func (b *xlsxBook) load() error {
+	workbookData, err := readZipFile(b.files["xl/workbook.xml"])
+	if err != nil {
+		return fmt.Errorf("invalid xlsx workbook: %w", err)
+	}
+	var wb xlsxWorkbook
+	if err := xml.Unmarshal(workbookData, &wb); err != nil {
+		return err
+	}
+	if len(wb.Sheets) == 0 {
+		return fmt.Errorf("xlsx has no sheet")
+	}
+	b.date1904 = wb.Properties.Date1904 == "1" || strings.EqualFold(wb.Properties.Date1904, "true")
+
+	relsData, err := readZipFile(b.files["xl/_rels/workbook.xml.rels"])
+	if err != nil {
+		return err
+	}
+	var rels xlsxRels
+	if err := xml.Unmarshal(relsData, &rels); err != nil {
+		return err
+	}
+	relMap := map[string]string{}
+	for _, rel := range rels.Relationships {
+		relMap[rel.ID] = rel.Target
+	}
+	for _, sheet := range wb.Sheets {
+		target := relMap[sheet.ID]
+		if target == "" {
+			return fmt.Errorf("cannot find sheet %s", sheet.Name)
+		}
+		sheetPath := path.Clean("xl/" + target)
+		if strings.HasPrefix(target, "/") {
+			sheetPath = strings.TrimPrefix(target, "/")
+		}
+		b.sheets = append(b.sheets, sheet)
+		b.paths = append(b.paths, sheetPath)
+	}
+
+	if ssFile, ok := b.files["xl/sharedStrings.xml"]; ok {
+		if ssData, err := readZipFile(ssFile); err == nil {
+			var sst xlsxSharedStrings
+			if xml.Unmarshal(ssData, &sst) == nil {
+				for _, si := range sst.Items {
+					b.shared = append(b.shared, si.text())
+				}
+			}
+		}
+	}
+
+	// Styles are optional; without them numbers are shown as General
+	if stFile, ok := b.files["xl/styles.xml"]; ok {
+		if stData, err := readZipFile(stFile); err == nil {
+			var st xlsxStyleSheet
+			if xml.Unmarshal(stData, &st) == nil {
+				codes := map[int]string{}
+				for _, f := range st.NumFmts {
+					codes[f.ID] = f.Code
+				}
+				for _, xf := range st.CellXfs {
+					code, ok := codes[xf.NumFmtID]
+					if !ok {
+						code = builtinNumFmts[xf.NumFmtID]
+					}
+					b.numFmts = append(b.numFmts, xlsxNumFmt{ID: xf.NumFmtID, Code: code})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maxSheetCells bounds the cells of a sheet after padding to its widest row, so a stray cell
+// far from the data cannot make the table explode.
+const maxSheetCells = 1000000
+
+func (b *xlsxBook) readSheet(index int) ([][]string, error) {
+	sheetData, err := readZipFile(b.files[b.paths[index]])
+	if err != nil {
+		return nil, err
+	}
+	var ws xlsxWorksheet
+	if err := xml.Unmarshal(sheetData, &ws); err != nil {
+		return nil, err
+	}
+
+	// Only non-empty cells extend the table, so formatted but empty cells and rows do not count
+	var table [][]string
+	cols := 0
+	for rowIdx, row := range ws.Rows {
+		r := rowIdx
+		if n, err := strconv.Atoi(row.R); err == nil && n > 0 {
+			r = n - 1
+		}
+		for cellIdx, cell := range row.Cells {
+			col := cellIdx
+			if cell.Ref != "" {
+				col, _ = parseCellRef(cell.Ref)
+			}
+			if col < 0 {
+				continue
+			}
+			val := strings.TrimSpace(b.cellText(cell))
+			if val == "" {
+				continue
+			}
+			if rows := max(r+1, len(table)); int64(rows)*int64(max(col+1, cols)) > maxSheetCells {
+				return nil, fmt.Errorf("工作表过大：单元格 %s 超出 %d 个单元格的上限", cellName(col, r), maxSheetCells)
+			}
+			for len(table) <= r {
+				table = append(table, nil)
+			}
+			for len(table[r]) <= col {
+				table[r] = append(table[r], "")
+			}
+			table[r][col] = val
+			cols = max(cols, col+1)
+		}
+	}
+
+	// Merged ranges are clamped to the populated extent; whole-column merges are common
+	for _, m := range ws.MergeCells {
+		c1, r1, c2, r2, ok := parseCellRange(m.Ref)
+		if !ok || r1 >= len(table) || c1 >= len(table[r1]) {
+			continue
+		}
+		val := table[r1][c1]
+		if val == "" {
+			continue
+		}
+		r2 = min(r2, len(table)-1)
+		c2 = min(c2, cols-1)
+		for r := r1; r <= r2; r++ {
+			for len(table[r]) <= c2 {
+				table[r] = append(table[r], "")
+			}
+			for c := c1; c <= c2; c++ {
+				table[r][c] = val
+			}
+		}
+	}
+	return padTable(table), nil
+}
+
+// cellName formats 0-based column and row indexes as a reference such as "AB12".
+func cellName(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+func (b *xlsxBook) cellText(cell xlsxCell) string {
+	val := cell.V
+	switch cell.T {
+	case "s":
+		idx, _ := strconv.Atoi(strings.TrimSpace(val))
+		if idx >= 0 && idx < len(b.shared) {
+			return b.shared[idx]
+		}
+		return ""
+	case "inlineStr":
+		if cell.IS != nil {
+			return cell.IS.text()
+		}
+		return ""
+	case "b":
+		if strings.TrimSpace(val) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e", "d":
+		return val
+	}
+	style, err := strconv.Atoi(cell.S)
+	if err != nil || style < 0 || style >= len(b.numFmts) {
+		return formatXLSXNumber(val, 0, "", b.date1904)
+	}
+	f := b.numFmts[style]
+	return formatXLSXNumber(val, f.ID, f.Code, b.date1904)
+}
+
+// TableToMarkdown renders a table as a markdown table, with the first row as the header.
+func TableToMarkdown(table [][]string) string {
+	if len(table) == 0 {
+		return ""
+	}
+	cols := 0
+	for _, row := range table {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		sb.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cell = strings.ReplaceAll(strings.TrimSpace(cell), "\n", " ")
+			cell = strings.ReplaceAll(cell, "|", "\\|")
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(table[0])
+	sb.WriteString("|")
+	for i := 0; i < cols; i++ {
+		sb.WriteString(" --- |")
+	}
+	sb.WriteString("\n")
+	for _, row := range table[1:] {
+		writeRow(row)
+	}
+	return sb.String()
+}
+
+// padTable extends every row to the width of the widest one.
+func padTable(table [][]string) [][]string {
+	cols := 0
+	for _, row := range table {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	for i := range table {
+		for len(table[i]) < cols {
+			table[i] = append(table[i], "")
+		}
+	}
+	return table
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheet `xml:"sheets>sheet"`
+}
+
+type xlsxSheet struct {
+	Name string `xml:"name,attr"`
+	ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+type xlsxRels struct {
+	Relationships []xlsxRel `xml:"Relationship"`
+}
+
+type xlsxRel struct {
+	ID     string `xml:"Id,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText is a shared string or inline string: plain text or a list of formatted runs.
+type xlsxRichText struct {
+	T string    `xml:"t"`
+	R []xlsxRun `xml:"r"`
+}
+
+func (s xlsxRichText) text() string {
+	if s.T != "" || len(s.R) == 0 {
+		return s.T
+	}
+	var b strings.Builder
+	for _, run := range s.R {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxRun struct {
+	T string `xml:"t"`
+}
+
+type xlsxWorksheet struct {
+	Rows       []xlsxRow `xml:"sheetData>row"`
+	MergeCells []struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"mergeCells>mergeCell"`
+}
+
+type xlsxRow struct {
+	R     string     `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref string        `xml:"r,attr"`
+	T   string        `xml:"t,attr"`
+	S   string        `xml:"s,attr"` // cell style index
+	V   string        `xml:"v"`
+	IS  *xlsxRichText `xml:"is"`
+}
+
+type xlsxStyleSheet struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f == nil {
+		return nil, fmt.Errorf("file not found in zip")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// parseCellRef converts a cell reference such as "AB12" to 0-based column and row indexes. The
+// row is -1 when the reference has no row, as in "AB".
+func parseCellRef(ref string) (int, int) {
+	col, i := 0, 0
+	for ; i < len(ref); i++ {
+		c := ref[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			col = col*26 + int(c-'A'+1)
+		case c >= 'a' && c <= 'z':
+			col = col*26 + int(c-'a'+1)
+		default:
+			row, err := strconv.Atoi(strings.TrimLeft(ref[i:], "$"))
+			if err != nil {
+				return col - 1, -1
+			}
+			return col - 1, row - 1
+		}
+	}
+	return col - 1, -1
+}
+
+// parseCellRange parses a range such as "A1:C3" into its corners.
+func parseCellRange(ref string) (c1, r1, c2, r2 int, ok bool) {
+	from, to, found := strings.Cut(ref, ":")
+	if !found {
+		to = from
+	}
+	c1, r1 = parseCellRef(from)
+	c2, r2 = parseCellRef(to)
+	if c1 < 0 || r1 < 0 || c2 < c1 || r2 < r1 {
+		return 0, 0, 0, 0, false
+	}
+	return c1, r1, c2, r2, true
+}
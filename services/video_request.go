@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"seedance-client/config"
+)
+
+// VideoTaskRequest is a snapshot of everything sent to create a video task. Frames are recorded by
+// local path and content hash instead of their bytes, so the snapshot stays small and a later
+// resubmission can prove it sends the same images.
+type VideoTaskRequest struct {
+	ModelID               string             `json:"model_id"`
+	Prompt                string             `json:"prompt"`
+	Content               []VideoContentItem `json:"content"`
+	Ratio                 string             `json:"ratio"`
+	Duration              int                `json:"duration,omitempty"`
+	GenerateAudio         bool               `json:"generate_audio"`
+	Watermark             bool               `json:"watermark"`
+	ReturnLastFrame       bool               `json:"return_last_frame"`
+	ServiceTier           string             `json:"service_tier,omitempty"` // only "flex" is sent
+	ExecutionExpiresAfter int64              `json:"execution_expires_after,omitempty"`
+	SubmittedAt           time.Time          `json:"submitted_at"`
+	// ReproducedFromTakeID is set when the request resubmits another take's snapshot.
+	ReproducedFromTakeID uint `json:"reproduced_from_take_id,omitempty"`
+}
+
+// VideoContentItem is one content item of the request, in submission order.
+type VideoContentItem struct {
+	Type        string `json:"type"`           // text | image_url
+	Role        string `json:"role,omitempty"` // first_frame | last_frame
+	Text        string `json:"text,omitempty"`
+	ImagePath   string `json:"image_path,omitempty"`
+	ImageSHA256 string `json:"image_sha256,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+
+	dataURL string
+}
+
+// NewVideoTaskRequest fills the parameters the API would otherwise default: an empty ratio is sent
+// as "adaptive", and a flex task without an expiry gets 24 hours. Standard tier sends no tier.
+func NewVideoTaskRequest(modelID string, prompt string, ratio string, duration int, generateAudio bool, serviceTier string, expiresAfter int64) *VideoTaskRequest {
+	req := &VideoTaskRequest{
+		ModelID:         modelID,
+		Prompt:          prompt,
+		Content:         []VideoContentItem{{Type: "text", Text: prompt}},
+		Ratio:           ratio,
+		GenerateAudio:   generateAudio,
+		Watermark:       false,
+		ReturnLastFrame: true,
+	}
+	if req.Ratio == "" {
+		req.Ratio = "adaptive"
+	}
+	if duration > 0 {
+		req.Duration = duration
+	}
+	if serviceTier == "flex" {
+		req.ServiceTier = "flex"
+		req.ExecutionExpiresAfter = expiresAfter
+		if req.ExecutionExpiresAfter <= 0 {
+			req.ExecutionExpiresAfter = 86400
+		}
+	}
+	return req
+}
+
+// AddFrame reads an image and appends it as a content item with the given role.
+func (r *VideoTaskRequest) AddFrame(role string, path string) error {
+	item, err := loadFrame(role, path)
+	if err != nil {
+		return err
+	}
+	r.Content = append(r.Content, item)
+	return nil
+}
+
+// ParseVideoTaskRequest decodes a stored snapshot and reloads its frames. It fails when a frame
+// file is missing or its content no longer matches the recorded hash.
+func ParseVideoTaskRequest(snapshot string) (*VideoTaskRequest, error) {
+	var req VideoTaskRequest
+	if err := json.Unmarshal([]byte(snapshot), &req); err != nil {
+		return nil, fmt.Errorf("请求快照无法解析：%w", err)
+	}
+	for i, item := range req.Content {
+		if item.Type != "image_url" {
+			continue
+		}
+		loaded, err := loadFrame(item.Role, item.ImagePath)
+		if err != nil {
+			return nil, err
+		}
+		if loaded.ImageSHA256 != item.ImageSHA256 {
+			return nil, fmt.Errorf("[E_SNAPSHOT_CHANGED] %s文件已被修改，无法原样重现：%s", frameRoleLabel(item.Role), item.ImagePath)
+		}
+		req.Content[i].dataURL = loaded.dataURL
+	}
+	return &req, nil
+}
+
+// JSON encodes the snapshot for storage.
+func (r *VideoTaskRequest) JSON() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// FramePath returns the local path of the frame with the given role, or "".
+func (r *VideoTaskRequest) FramePath(role string) string {
+	for _, item := range r.Content {
+		if item.Type == "image_url" && item.Role == role {
+			return item.ImagePath
+		}
+	}
+	return ""
+}
+
+func loadFrame(role string, path string) (VideoContentItem, error) {
+	data, err := os.ReadFile(config.ToAbsolutePath(path))
+	if err != nil {
+		return VideoContentItem{}, fmt.Errorf("读取%s失败：%w", frameRoleLabel(role), err)
+	}
+	mimeType := "image/png"
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".jpg") || strings.HasSuffix(lower, ".jpeg") {
+		mimeType = "image/jpeg"
+	}
+	sum := sha256.Sum256(data)
+	return VideoContentItem{
+		Type:        "image_url",
+		Role:        role,
+		ImagePath:   path,
+		ImageSHA256: hex.EncodeToString(sum[:]),
+		MimeType:    mimeType,
+		dataURL:     fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data)),
+	}, nil
+}
+
+func frameRoleLabel(role string) string {
+	switch role {
+	case "first_frame":
+		return "首帧"
+	case "last_frame":
+		return "尾帧"
+	}
+	return "图片"
+}
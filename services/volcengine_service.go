@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
 	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
@@ -29,74 +31,51 @@ func (s *VolcEngineService) SetAPIKey(apiKey string) {
 	)
 }
 
-func (s *VolcEngineService) CreateVideoTask(modelID string, prompt string, firstFrameURL string, lastFrameURL string, ratio string, duration int, generateAudio bool, serviceTier string, expiresAfter int64) (string, error) {
+// CreateVideoTask submits a video task exactly as described by req and returns the task ID.
+// req.SubmittedAt is set to the submission time.
+func (s *VolcEngineService) CreateVideoTask(r *VideoTaskRequest) (string, error) {
 	ctx := context.Background()
 
-	contentItems := []*model.CreateContentGenerationContentItem{
-		{
-			Type: model.ContentGenerationContentItemTypeText,
-			Text: volcengine.String(prompt),
-		},
-	}
-
-	if firstFrameURL != "" {
-		contentItems = append(contentItems, &model.CreateContentGenerationContentItem{
-			Type: model.ContentGenerationContentItemTypeImage,
-			ImageURL: &model.ImageURL{
-				URL: firstFrameURL,
-			},
-			Role: volcengine.String("first_frame"),
-		})
-	}
-
-	if lastFrameURL != "" {
-		contentItems = append(contentItems, &model.CreateContentGenerationContentItem{
-			Type: model.ContentGenerationContentItemTypeImage,
-			ImageURL: &model.ImageURL{
-				URL: lastFrameURL,
-			},
-			Role: volcengine.String("last_frame"),
-		})
+	contentItems := make([]*model.CreateContentGenerationContentItem, 0, len(r.Content))
+	for _, item := range r.Content {
+		switch item.Type {
+		case "text":
+			contentItems = append(contentItems, &model.CreateContentGenerationContentItem{
+				Type: model.ContentGenerationContentItemTypeText,
+				Text: volcengine.String(item.Text),
+			})
+		case "image_url":
+			if item.dataURL == "" {
+				return "", fmt.Errorf("%s未加载", frameRoleLabel(item.Role))
+			}
+			contentItems = append(contentItems, &model.CreateContentGenerationContentItem{
+				Type: model.ContentGenerationContentItemTypeImage,
+				ImageURL: &model.ImageURL{
+					URL: item.dataURL,
+				},
+				Role: volcengine.String(item.Role),
+			})
+		}
 	}
 
 	req := model.CreateContentGenerationTaskRequest{
-		Model:           modelID,
+		Model:           r.ModelID,
 		Content:         contentItems,
-		Watermark:       volcengine.Bool(false),
-		ReturnLastFrame: volcengine.Bool(true),
-		GenerateAudio:   volcengine.Bool(generateAudio),
+		Watermark:       volcengine.Bool(r.Watermark),
+		ReturnLastFrame: volcengine.Bool(r.ReturnLastFrame),
+		GenerateAudio:   volcengine.Bool(r.GenerateAudio),
+		Ratio:           volcengine.String(r.Ratio),
 	}
-
-	// Handle Ratio
-	if ratio != "" {
-		req.Ratio = volcengine.String(ratio)
-	} else {
-		req.Ratio = volcengine.String("adaptive")
+	if r.Duration > 0 {
+		req.Duration = volcengine.Int64(int64(r.Duration))
 	}
-
-	// Handle Duration
-	if duration > 0 {
-		req.Duration = volcengine.Int64(int64(duration))
-	}
-
-	// Handle Service Tier
-	// Only set ServiceTier when explicitly "flex", otherwise let API use default behavior
-	// and do not send service_tier/execution_expires_after at all.
-	if serviceTier == "flex" {
+	// Only send service_tier/execution_expires_after for flex; otherwise the API default applies.
+	if r.ServiceTier == "flex" {
 		req.ServiceTier = volcengine.String("flex")
-		// Use provided expiresAfter or default to 86400 (24h)
-		if expiresAfter > 0 {
-			req.ExecutionExpiresAfter = volcengine.Int64(expiresAfter)
-		} else {
-			req.ExecutionExpiresAfter = volcengine.Int64(86400)
-		}
+		req.ExecutionExpiresAfter = volcengine.Int64(r.ExecutionExpiresAfter)
 	}
-	// When serviceTier is "standard" or empty, do NOT set ServiceTier field
-
-	// Helper for 1.5 Pro audio generation if needed, but defaults are usually fine.
-	// Documentation says 'Seedance 1.5 pro' supports GenerateAudio.
-	// Let's assume default behavior for now or add it if requested.
 
+	r.SubmittedAt = time.Now()
 	resp, err := s.Client.CreateContentGenerationTask(ctx, req)
 	if err != nil {
 		return "", err
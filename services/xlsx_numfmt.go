@@ -0,0 +1,236 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// builtinNumFmts are the number formats Excel does not store in styles.xml. IDs 27-36 and 50-58
+// are locale-specific; the zh-CN ones are used, so the time formats among them stay times.
+var builtinNumFmts = map[int]string{
+	0:  "General",
+	1:  "0",
+	2:  "0.00",
+	3:  "#,##0",
+	4:  "#,##0.00",
+	9:  "0%",
+	10: "0.00%",
+	11: "0.00E+00",
+	12: "# ?/?",
+	13: "# ??/??",
+	14: "yyyy-mm-dd",
+	15: "d-mmm-yy",
+	16: "d-mmm",
+	17: "mmm-yy",
+	18: "h:mm AM/PM",
+	19: "h:mm:ss AM/PM",
+	20: "h:mm",
+	21: "h:mm:ss",
+	22: "yyyy-mm-dd h:mm",
+	37: "#,##0 ;(#,##0)",
+	38: "#,##0 ;[Red](#,##0)",
+	39: "#,##0.00;(#,##0.00)",
+	40: "#,##0.00;[Red](#,##0.00)",
+	45: "mm:ss",
+	46: "[h]:mm:ss",
+	47: "mm:ss.0",
+	48: "##0.0E+0",
+	49: "@",
+
+	27: `yyyy"年"m"月"`,
+	28: `m"月"d"日"`,
+	29: `m"月"d"日"`,
+	30: "m-d-yy",
+	31: `yyyy"年"m"月"d"日"`,
+	32: `h"时"mm"分"`,
+	33: `h"时"mm"分"ss"秒"`,
+	34: `上午/下午h"时"mm"分"`,
+	35: `上午/下午h"时"mm"分"ss"秒"`,
+	36: `yyyy"年"m"月"`,
+	50: `yyyy"年"m"月"`,
+	51: `m"月"d"日"`,
+	52: `yyyy"年"m"月"`,
+	53: `m"月"d"日"`,
+	54: `m"月"d"日"`,
+	55: `上午/下午h"时"mm"分"`,
+	56: `上午/下午h"时"mm"分"ss"秒"`,
+	57: `yyyy"年"m"月"`,
+	58: `m"月"d"日"`,
+}
+
+// formatXLSXNumber renders a numeric cell value the way its number format displays it, so a
+// date shows as "2024-03-01", a duration formatted as mm:ss as "0:05" and 0.5 in a percent
+// format as "50%". Values that are not numbers are returned unchanged.
+func formatXLSXNumber(raw string, fmtID int, code string, date1904 bool) string {
+	raw = strings.TrimSpace(raw)
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return raw
+	}
+	if code == "" {
+		code = builtinNumFmts[fmtID]
+	}
+	section := firstFormatSection(code)
+	tokens := formatTokens(section)
+
+	switch {
+	case section == "" || strings.EqualFold(section, "general") || section == "@":
+		return formatGeneral(v)
+	case strings.ContainsAny(tokens, "yd") || (strings.Contains(tokens, "m") && !strings.ContainsAny(tokens, "hs")):
+		return formatSerialDate(v, tokens, date1904)
+	case strings.ContainsAny(tokens, "hs") || strings.Contains(tokens, "m"):
+		return formatSerialTime(v, tokens)
+	case strings.Contains(tokens, "%"):
+		return strconv.FormatFloat(v*100, 'f', formatDecimals(section), 64) + "%"
+	case strings.Contains(tokens, "e"):
+		return strconv.FormatFloat(v, 'E', formatDecimals(section), 64)
+	case strings.ContainsAny(section, "0#"):
+		s := strconv.FormatFloat(v, 'f', formatDecimals(section), 64)
+		if strings.Contains(section, ",") {
+			s = groupThousands(s)
+		}
+		return s
+	}
+	return formatGeneral(v)
+}
+
+// firstFormatSection returns the part of a format code used for positive numbers.
+func firstFormatSection(code string) string {
+	inQuote := false
+	for i, r := range code {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == ';' && !inQuote:
+			return strings.TrimSpace(code[:i])
+		}
+	}
+	return strings.TrimSpace(code)
+}
+
+// formatTokens lowercases a format section and drops literal text, escaped characters and
+// bracketed colors or locales, keeping the elapsed-time markers [h], [m] and [s].
+func formatTokens(section string) string {
+	var b strings.Builder
+	lower := strings.ToLower(section)
+	for i := 0; i < len(lower); i++ {
+		c := lower[i]
+		switch c {
+		case '"':
+			end := strings.IndexByte(lower[i+1:], '"')
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 1
+		case '\\', '_', '*':
+			i++
+		case '[':
+			end := strings.IndexByte(lower[i:], ']')
+			if end < 0 {
+				return b.String()
+			}
+			inner := lower[i+1 : i+end]
+			if inner != "" && strings.Trim(inner, "hms") == "" {
+				b.WriteString("[" + inner + "]")
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func formatGeneral(v float64) string {
+	// 15 significant digits, as Excel shows them, without float noise such as 4.4000000000000004
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 15, 64), 64)
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+func formatDecimals(section string) int {
+	dot := strings.IndexByte(section, '.')
+	if dot < 0 {
+		return 0
+	}
+	n := 0
+	for _, c := range section[dot+1:] {
+		if c != '0' && c != '#' && c != '?' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac
+	}
+	return sign + b.String()
+}
+
+// serialTime converts an Excel date serial to a time; fractions are rounded to the second.
+func serialTime(v float64, date1904 bool) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(v)
+	secs := math.Round((v - days) * 86400)
+	return base.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+}
+
+func formatSerialDate(v float64, tokens string, date1904 bool) string {
+	t := serialTime(v, date1904)
+	layout := "2006-01-02"
+	if strings.Contains(tokens, "h") {
+		layout += " 15:04"
+		if strings.Contains(tokens, "s") {
+			layout += ":05"
+		}
+	}
+	return t.Format(layout)
+}
+
+// formatSerialTime shows a time of day or a duration. Formats without hours, such as mm:ss,
+// show total minutes; elapsed formats such as [h]:mm:ss show total hours.
+func formatSerialTime(v float64, tokens string) string {
+	total := int64(math.Round(math.Abs(v) * 86400))
+	elapsed := strings.Contains(tokens, "[")
+	if !elapsed && v >= 1 && strings.Contains(tokens, "h") {
+		total %= 86400
+	}
+	sign := ""
+	if v < 0 {
+		sign = "-"
+	}
+	h, m, s := total/3600, total/60%60, total%60
+	if !strings.Contains(tokens, "h") {
+		return sign + strconv.FormatInt(total/60, 10) + ":" + twoDigits(s)
+	}
+	if !strings.Contains(tokens, "s") {
+		return sign + strconv.FormatInt(h, 10) + ":" + twoDigits(m)
+	}
+	return sign + strconv.FormatInt(h, 10) + ":" + twoDigits(m) + ":" + twoDigits(s)
+}
+
+func twoDigits(v int64) string {
+	if v < 10 {
+		return "0" + strconv.FormatInt(v, 10)
+	}
+	return strconv.FormatInt(v, 10)
+}